@@ -51,6 +51,7 @@ type TransactOpts struct {
 	Value    *big.Int // Funds to transfer along along the transaction (nil = 0 = no funds)
 	GasPrice *big.Int // Gas price to use for the transaction execution (nil = gas price oracle)
 	GasLimit *big.Int // Gas limit to set for the transaction execution (nil = estimate + 10%)
+	Txtype   uint64   // Wanchain transaction type (0 = types.NORMAL_TX)
 
 	Context context.Context // Network context to support cancellation and timeouts (nil = no timeout)
 }
@@ -212,6 +213,9 @@ func (c *BoundContract) transact(opts *TransactOpts, contract *common.Address, i
 	} else {
 		rawTx = types.NewTransaction(nonce, c.address, value, gasLimit, gasPrice, input)
 	}
+	if opts.Txtype != 0 {
+		rawTx.SetTxtype(opts.Txtype)
+	}
 	if opts.Signer == nil {
 		return nil, errors.New("no signer to authorize the transaction with")
 	}
@@ -0,0 +1,159 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/params"
+)
+
+var (
+	// ErrNoOTAKey is returned by privacy transactions without the key of the
+	// one-time address paying for them.
+	ErrNoOTAKey = errors.New("no one-time address key to pay the privacy transaction with")
+
+	// ErrPrivacyValue is returned by privacy transactions carrying a value,
+	// which the privacy envelope can't transfer.
+	ErrPrivacyValue = errors.New("privacy transactions can't transfer value")
+
+	// ErrNoOTABalance is returned by privacy transactions without a gas limit
+	// whose one-time address balance is not known.
+	ErrNoOTABalance = errors.New("no one-time address balance to derive the gas limit from")
+
+	// ErrPrivacyStampGas is returned by privacy transactions whose one-time
+	// address balance can't pay for the call.
+	ErrPrivacyStampGas = errors.New("one-time address balance too low to pay for the call")
+)
+
+// PrivacyTransactOpts is the collection of authorization data required to wrap
+// a contract call in a Wanchain privacy transaction. The gas is paid from the
+// balance of a one-time address (OTA), proven by a ring signature of the
+// sender address that hides the OTA among others of the same balance.
+type PrivacyTransactOpts struct {
+	*TransactOpts
+
+	OTAKey     *ecdsa.PrivateKey  // Private key of the one-time address paying the gas (mandatory)
+	OTABalance *big.Int           // Balance of the one-time address, all of it pays the gas (mandatory without GasLimit)
+	MixKeys    []*ecdsa.PublicKey // Public keys of one-time addresses of the same balance to mix with
+}
+
+// NewPrivacyTransactOpts creates the options of a privacy transaction sent
+// with opts, paid from the one-time address of otaKey holding otaBalance and
+// mixed with the one-time addresses mixSet, in the format returned by
+// wan_getOTAMixSet.
+func NewPrivacyTransactOpts(opts *TransactOpts, otaKey *ecdsa.PrivateKey, otaBalance *big.Int, mixSet []string) (*PrivacyTransactOpts, error) {
+	mixKeys := make([]*ecdsa.PublicKey, 0, len(mixSet))
+	for _, mix := range mixSet {
+		wAddr, err := hexutil.Decode(mix)
+		if err != nil || len(wAddr) != common.WAddressLength {
+			return nil, fmt.Errorf("invalid one-time address %s", mix)
+		}
+		pubA, _, err := keystore.GeneratePKPairFromWAddress(wAddr)
+		if err != nil {
+			return nil, err
+		}
+		mixKeys = append(mixKeys, pubA)
+	}
+	return &PrivacyTransactOpts{TransactOpts: opts, OTAKey: otaKey, OTABalance: otaBalance, MixKeys: mixKeys}, nil
+}
+
+// ringSign signs the sender address with the one-time address key, mixed
+// with the other one-time addresses.
+func (opts *PrivacyTransactOpts) ringSign() (string, error) {
+	if opts.OTAKey == nil {
+		return "", ErrNoOTAKey
+	}
+	publicKeys := append([]*ecdsa.PublicKey{&opts.OTAKey.PublicKey}, opts.MixKeys...)
+	ringKeys, keyImage, ws, qs, err := crypto.RingSign(opts.From.Bytes(), opts.OTAKey.D, publicKeys)
+	if err != nil {
+		return "", err
+	}
+	return vm.EncodeRingSignOut(ringKeys, keyImage, ws, qs), nil
+}
+
+// PrivacyTransact invokes the (paid) contract method with params as input
+// values inside a privacy transaction.
+func (c *BoundContract) PrivacyTransact(opts *PrivacyTransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	if opts.Value != nil && opts.Value.Sign() != 0 {
+		return nil, ErrPrivacyValue
+	}
+	input, err := c.abi.Pack(method, params...)
+	if err != nil {
+		return nil, err
+	}
+	ringSigned, err := opts.ringSign()
+	if err != nil {
+		return nil, err
+	}
+	data, err := core.PackPrivacyTxData(ringSigned, input)
+	if err != nil {
+		return nil, err
+	}
+	txOpts := *opts.TransactOpts
+	txOpts.Txtype = types.PRIVACY_TX
+	if txOpts.GasLimit == nil {
+		if opts.OTABalance == nil {
+			return nil, ErrNoOTABalance
+		}
+		if txOpts.GasPrice == nil {
+			if txOpts.GasPrice, err = c.transactor.SuggestGasPrice(ensureContext(opts.Context)); err != nil {
+				return nil, fmt.Errorf("failed to suggest gas price: %v", err)
+			}
+		}
+		// The whole balance of the one-time address is spent on gas, so the
+		// pool only takes a gas limit covering it (see core.ValidPrivacyTx).
+		txOpts.GasLimit = new(big.Int).Div(opts.OTABalance, txOpts.GasPrice)
+		if err := c.checkStampGas(&txOpts, input, len(opts.MixKeys)+1); err != nil {
+			return nil, err
+		}
+	}
+	return c.transact(&txOpts, &c.address, data)
+}
+
+// checkStampGas estimates the plain call, as the envelope is unpacked before
+// execution, and checks that the gas the EVM gets from the one-time address
+// balance after the ring signature check pays for it.
+func (c *BoundContract) checkStampGas(opts *TransactOpts, input []byte, ringSize int) error {
+	if code, err := c.transactor.PendingCodeAt(ensureContext(opts.Context), c.address); err != nil {
+		return err
+	} else if len(code) == 0 {
+		return ErrNoCode
+	}
+	msg := ethereum.CallMsg{From: opts.From, To: &c.address, Data: input}
+	gas, err := c.transactor.EstimateGas(ensureContext(opts.Context), msg)
+	if err != nil {
+		return fmt.Errorf("failed to estimate gas needed: %v", err)
+	}
+	ringGas := new(big.Int).SetUint64(params.RequiredGasPerMixPub*uint64(ringSize) + params.SstoreSetGas)
+	if new(big.Int).Sub(opts.GasLimit, ringGas).Cmp(gas) < 0 {
+		return ErrPrivacyStampGas
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package bind_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/wanchain/go-wanchain"
+	"github.com/wanchain/go-wanchain/accounts/abi"
+	"github.com/wanchain/go-wanchain/accounts/abi/bind"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+)
+
+// recordingTransactor is a ContractTransactor keeping the last transaction
+// sent through it.
+type recordingTransactor struct {
+	sent *types.Transaction
+}
+
+func (t *recordingTransactor) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return []byte{0x00}, nil
+}
+func (t *recordingTransactor) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	return 3, nil
+}
+func (t *recordingTransactor) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(180000000000), nil
+}
+func (t *recordingTransactor) EstimateGas(ctx context.Context, call ethereum.CallMsg) (*big.Int, error) {
+	return big.NewInt(50000), nil
+}
+func (t *recordingTransactor) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	t.sent = tx
+	return nil
+}
+
+// otaWAddr returns the one-time address of a public key, both halves of the
+// address being the same key.
+func otaWAddr(pub *ecdsa.PublicKey) []byte {
+	return append(keystore.ECDSAPKCompression(pub), keystore.ECDSAPKCompression(pub)...)
+}
+
+const privacyTestABI = `[{"constant":false,"inputs":[{"name":"_to","type":"address"},{"name":"_value","type":"uint256"}],"name":"transfer","outputs":[],"type":"function"}]`
+
+func TestPrivacyTransact(t *testing.T) {
+	parsed, err := abi.JSON(strings.NewReader(privacyTestABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := crypto.GenerateKey()
+	otaKey, _ := crypto.GenerateKey()
+	mixKey, _ := crypto.GenerateKey()
+
+	// Both one-time addresses of the ring hold the same stamp balance
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	balance := vm.GetSupportStampOTABalances()[0]
+	for _, pub := range []*ecdsa.PublicKey{&otaKey.PublicKey, &mixKey.PublicKey} {
+		if _, err := vm.AddOTAIfNotExist(statedb, balance, otaWAddr(pub)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	transactor := new(recordingTransactor)
+	contract := bind.NewBoundContract(common.HexToAddress("0x01"), parsed, nil, transactor)
+	opts := &bind.PrivacyTransactOpts{
+		TransactOpts: bind.NewKeyedTransactor(key),
+		OTAKey:       otaKey,
+		OTABalance:   balance,
+		MixKeys:      []*ecdsa.PublicKey{&mixKey.PublicKey},
+	}
+	to, amount := common.HexToAddress("0x02"), big.NewInt(10)
+	tx, err := contract.PrivacyTransact(opts, "transfer", to, amount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx != transactor.sent {
+		t.Fatal("transaction not sent")
+	}
+	if tx.Txtype() != types.PRIVACY_TX {
+		t.Fatalf("txtype mismatch: have %d, want %d", tx.Txtype(), types.PRIVACY_TX)
+	}
+
+	// The envelope carries the plain call and a ring signature of the sender
+	var combined struct {
+		RingSignedData string
+		CxtCallParams  []byte
+	}
+	if err := core.TokenAbi.Unpack(&combined, "combine", tx.Data()[4:]); err != nil {
+		t.Fatal(err)
+	}
+	input, _ := parsed.Pack("transfer", to, amount)
+	if !bytes.Equal(combined.CxtCallParams, input) {
+		t.Fatalf("call data mismatch: have %x, want %x", combined.CxtCallParams, input)
+	}
+	err, pubs, image, ws, qs := vm.DecodeRingSignOut(combined.RingSignedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pubs) != 2 {
+		t.Fatalf("ring size mismatch: have %d, want 2", len(pubs))
+	}
+	if !crypto.VerifyRingSign(opts.From.Bytes(), pubs, image, ws, qs) {
+		t.Fatal("ring signature does not verify")
+	}
+
+	// The default gas limit is what the stamp pays for, as the pool requires
+	intrGas := core.IntrinsicGas(tx.Data(), tx.To(), true)
+	if err := core.ValidPrivacyTx(statedb, tx.Hash(), opts.From.Bytes(), tx.Data(), tx.GasPrice(), intrGas, tx.Value(), tx.Gas()); err != nil {
+		t.Fatalf("privacy transaction rejected: %v", err)
+	}
+	if want := new(big.Int).Div(balance, tx.GasPrice()); tx.Gas().Cmp(want) != 0 {
+		t.Fatalf("gas limit mismatch: have %v, want %v", tx.Gas(), want)
+	}
+}
+
+func TestPrivacyTransactRejectsValue(t *testing.T) {
+	parsed, _ := abi.JSON(strings.NewReader(privacyTestABI))
+	key, _ := crypto.GenerateKey()
+	otaKey, _ := crypto.GenerateKey()
+
+	contract := bind.NewBoundContract(common.HexToAddress("0x01"), parsed, nil, new(recordingTransactor))
+	opts := &bind.PrivacyTransactOpts{TransactOpts: bind.NewKeyedTransactor(key), OTAKey: otaKey}
+	opts.Value = big.NewInt(1)
+	if _, err := contract.PrivacyTransact(opts, "transfer", common.Address{}, big.NewInt(1)); err != bind.ErrPrivacyValue {
+		t.Fatalf("error mismatch: have %v, want %v", err, bind.ErrPrivacyValue)
+	}
+	opts.Value = nil
+	if _, err := contract.PrivacyTransact(opts, "transfer", common.Address{}, big.NewInt(1)); err != bind.ErrNoOTABalance {
+		t.Fatalf("error mismatch: have %v, want %v", err, bind.ErrNoOTABalance)
+	}
+	// Too small a stamp can't pay for the call
+	opts.OTABalance = new(big.Int).Mul(big.NewInt(50000), big.NewInt(180000000000))
+	if _, err := contract.PrivacyTransact(opts, "transfer", common.Address{}, big.NewInt(1)); err != bind.ErrPrivacyStampGas {
+		t.Fatalf("error mismatch: have %v, want %v", err, bind.ErrPrivacyStampGas)
+	}
+	opts.OTAKey = nil
+	if _, err := contract.PrivacyTransact(opts, "transfer", common.Address{}, big.NewInt(1)); err != bind.ErrNoOTAKey {
+		t.Fatalf("error mismatch: have %v, want %v", err, bind.ErrNoOTAKey)
+	}
+}
+
+func TestTransactTxtype(t *testing.T) {
+	parsed, _ := abi.JSON(strings.NewReader(privacyTestABI))
+	key, _ := crypto.GenerateKey()
+
+	transactor := new(recordingTransactor)
+	contract := bind.NewBoundContract(common.HexToAddress("0x01"), parsed, nil, transactor)
+	opts := bind.NewKeyedTransactor(key)
+	if _, err := contract.Transact(opts, "transfer", common.Address{}, big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+	if transactor.sent.Txtype() != types.NORMAL_TX {
+		t.Fatalf("default txtype mismatch: have %d, want %d", transactor.sent.Txtype(), types.NORMAL_TX)
+	}
+	opts.Txtype = types.POS_TX
+	if _, err := contract.Transact(opts, "transfer", common.Address{}, big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+	if transactor.sent.Txtype() != types.POS_TX {
+		t.Fatalf("txtype mismatch: have %d, want %d", transactor.sent.Txtype(), types.POS_TX)
+	}
+}
@@ -187,6 +187,12 @@ package {{.Package}}
 		return _{{$contract.Type}}.Contract.{{$contract.Type}}Transactor.contract.Transact(opts, method, params...)
 	}
 
+	// PrivacyTransact invokes the (paid) contract method with params as input values
+	// inside a privacy transaction.
+	func (_{{$contract.Type}} *{{$contract.Type}}Raw) PrivacyTransact(opts *bind.PrivacyTransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+		return _{{$contract.Type}}.Contract.{{$contract.Type}}Transactor.contract.PrivacyTransact(opts, method, params...)
+	}
+
 	// Call invokes the (constant) contract method with params as input values and
 	// sets the output to result. The result type might be a single field for simple
 	// returns, a slice of interfaces for anonymous returns and a struct for named
@@ -206,6 +212,12 @@ package {{.Package}}
 		return _{{$contract.Type}}.Contract.contract.Transact(opts, method, params...)
 	}
 
+	// PrivacyTransact invokes the (paid) contract method with params as input values
+	// inside a privacy transaction.
+	func (_{{$contract.Type}} *{{$contract.Type}}TransactorRaw) PrivacyTransact(opts *bind.PrivacyTransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+		return _{{$contract.Type}}.Contract.contract.PrivacyTransact(opts, method, params...)
+	}
+
 	{{range .Calls}}
 		// {{.Normalized.Name}} is a free data retrieval call binding the contract method 0x{{printf "%x" .Original.Id}}.
 		//
@@ -249,6 +261,14 @@ package {{.Package}}
 			return _{{$contract.Type}}.contract.Transact(opts, "{{.Original.Name}}" {{range .Normalized.Inputs}}, {{.Name}}{{end}})
 		}
 
+		// Privacy{{.Normalized.Name}} is a paid mutator transaction binding the contract method 0x{{printf "%x" .Original.Id}},
+		// sent inside a privacy transaction.
+		//
+		// Solidity: {{.Original.String}}
+		func (_{{$contract.Type}} *{{$contract.Type}}Transactor) Privacy{{.Normalized.Name}}(opts *bind.PrivacyTransactOpts {{range .Normalized.Inputs}}, {{.Name}} {{bindtype .Type}} {{end}}) (*types.Transaction, error) {
+			return _{{$contract.Type}}.contract.PrivacyTransact(opts, "{{.Original.Name}}" {{range .Normalized.Inputs}}, {{.Name}}{{end}})
+		}
+
 		// {{.Normalized.Name}} is a paid mutator transaction binding the contract method 0x{{printf "%x" .Original.Id}}.
 		//
 		// Solidity: {{.Original.String}}
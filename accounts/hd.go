@@ -17,11 +17,18 @@
 package accounts
 
 import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"math"
 	"math/big"
 	"strings"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/crypto"
 )
 
 // DefaultRootDerivationPath is the root path to which custom derivation endpoints
@@ -135,3 +142,82 @@ func (path DerivationPath) String() string {
 	}
 	return result
 }
+
+// PrivacyKeyBranch is the change component under which the privacy keys of
+// deterministic dual-key accounts are derived. Wanchain accounts have no change
+// addresses, so the key at m/44'/5718350'/0'/0/i is paired with the privacy key
+// at m/44'/5718350'/0'/1/i.
+const PrivacyKeyBranch = 1
+
+var errInvalidChildKey = errors.New("invalid child key, use the next index")
+
+// PrivacyKeyPath returns the derivation path of the privacy key paired with the
+// account key at path, the same path on the privacy key branch.
+func PrivacyKeyPath(path DerivationPath) (DerivationPath, error) {
+	if len(path) < 2 || path[len(path)-2] != 0 {
+		return nil, fmt.Errorf("path %v has no non-hardened change component 0 to pair a privacy key with", path)
+	}
+	privacy := make(DerivationPath, len(path))
+	copy(privacy, path)
+	privacy[len(path)-2] = PrivacyKeyBranch
+	return privacy, nil
+}
+
+// DeriveKey derives the secp256k1 private key at path from a BIP-32 seed.
+func DeriveKey(seed []byte, path DerivationPath) (*ecdsa.PrivateKey, error) {
+	mac := hmac.New(sha512.New, []byte("Bitcoin seed"))
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	key, chainCode := new(big.Int).SetBytes(sum[:32]), sum[32:]
+	n := crypto.S256().Params().N
+	if key.Sign() == 0 || key.Cmp(n) >= 0 {
+		return nil, errors.New("invalid seed, master key out of range")
+	}
+	for _, index := range path {
+		data := make([]byte, 0, 37)
+		if index >= 0x80000000 {
+			data = append(append(data, 0), common.LeftPadBytes(key.Bytes(), 32)...)
+		} else {
+			x, y := crypto.S256().ScalarBaseMult(common.LeftPadBytes(key.Bytes(), 32))
+			data = append(append(data, byte(2+y.Bit(0))), common.LeftPadBytes(x.Bytes(), 32)...)
+		}
+		var indexBytes [4]byte
+		binary.BigEndian.PutUint32(indexBytes[:], index)
+		data = append(data, indexBytes[:]...)
+
+		mac := hmac.New(sha512.New, chainCode)
+		mac.Write(data)
+		sum := mac.Sum(nil)
+
+		tweak := new(big.Int).SetBytes(sum[:32])
+		if tweak.Cmp(n) >= 0 {
+			return nil, errInvalidChildKey
+		}
+		key.Add(key, tweak).Mod(key, n)
+		if key.Sign() == 0 {
+			return nil, errInvalidChildKey
+		}
+		chainCode = sum[32:]
+	}
+	return crypto.ToECDSA(common.LeftPadBytes(key.Bytes(), 32))
+}
+
+// DeriveKeyPair derives the two private keys of the dual-key account at path
+// from a BIP-32 seed: the account key at path and the privacy key at its
+// PrivacyKeyPath.
+func DeriveKeyPair(seed []byte, path DerivationPath) (*ecdsa.PrivateKey, *ecdsa.PrivateKey, error) {
+	privacyPath, err := PrivacyKeyPath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := DeriveKey(seed, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	key2, err := DeriveKey(seed, privacyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, key2, nil
+}
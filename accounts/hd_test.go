@@ -17,8 +17,12 @@
 package accounts
 
 import (
+	"encoding/hex"
 	"reflect"
 	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/crypto"
 )
 
 // Tests that HD derivation paths can be correctly parsed into our internal binary
@@ -77,3 +81,72 @@ func TestHDPathParsing(t *testing.T) {
 		}
 	}
 }
+
+// Tests BIP-32 key derivation against test vector 1 of the specification.
+func TestHDDeriveKey(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	tests := []struct {
+		path string
+		key  string
+	}{
+		{"m/0'", "edb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{"m/0'/1", "3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{"m/0'/1/2'", "cbce0d719ecf7431d88e6a89fa1483e02e35092af60c042b1df2ff59fa424dca"},
+		{"m/0'/1/2'/2", "0f479245fb19a38a1954c5c7c0ebab2f9bdfd96a17563ef28a6a4b1a2a764ef4"},
+		{"m/0'/1/2'/2/1000000000", "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+	}
+	for i, tt := range tests {
+		path, _ := ParseDerivationPath(tt.path)
+		key, err := DeriveKey(seed, path)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if have := hex.EncodeToString(crypto.FromECDSA(key)); have != tt.key {
+			t.Errorf("test %d: key mismatch: have %s, want %s", i, have, tt.key)
+		}
+	}
+}
+
+// Tests that both keys of a dual-key account are derived from the seed of the
+// mnemonic "abandon abandon ... about" without passphrase.
+func TestHDDeriveKeyPair(t *testing.T) {
+	seed, _ := hex.DecodeString("5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4")
+
+	// The well known first Ethereum account of the mnemonic
+	path, _ := ParseDerivationPath("m/44'/60'/0'/0/0")
+	key, err := DeriveKey(seed, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr := crypto.PubkeyToAddress(key.PublicKey); addr != common.HexToAddress("0x9858EfFD232B4033E47d90003D41EC34EcaEda94") {
+		t.Fatalf("address mismatch: have %x", addr)
+	}
+	tests := []struct {
+		index     uint32
+		key, key2 string
+	}{
+		{0, "069446843118a97b03d8ff87a54419494a00b063a07c3604e3c7f8af24cbc185", "4d306dd6f78b4c91a593df277a822ca7a620338084cf5ad6987312dd8d0da230"},
+		{1, "33ac6047a649741b1289aa44196626698d7835fadf86c6c94e6cb983ec13a30d", "a2fd2ffa069e398ec302e1e18c6eeeba2c527c5c9e09caed46cbff13defad722"},
+	}
+	for i, tt := range tests {
+		path := append(DerivationPath{}, DefaultBaseDerivationPath...)
+		path[len(path)-1] = tt.index
+		key, key2, err := DeriveKeyPair(seed, path)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if have := hex.EncodeToString(crypto.FromECDSA(key)); have != tt.key {
+			t.Errorf("test %d: account key mismatch: have %s, want %s", i, have, tt.key)
+		}
+		if have := hex.EncodeToString(crypto.FromECDSA(key2)); have != tt.key2 {
+			t.Errorf("test %d: privacy key mismatch: have %s, want %s", i, have, tt.key2)
+		}
+	}
+	// Paths without a change component can't pair a privacy key
+	for _, input := range []string{"m/44'/5718350'/0'", "m/44'/5718350'/0'/1/0", "m/44'/5718350'/0'/0'/0"} {
+		path, _ := ParseDerivationPath(input)
+		if _, _, err := DeriveKeyPair(seed, path); err == nil {
+			t.Errorf("path %s: expected error", input)
+		}
+	}
+}
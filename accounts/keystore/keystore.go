@@ -48,6 +48,8 @@ var (
 	ErrNoMatch = errors.New("no key for given address or file")
 	ErrDecrypt = errors.New("could not decrypt key with given passphrase")
 	ErrInvalidKmsInfo = errors.New("invalid AWS KMS info")
+	ErrOTANotOwned    = errors.New("one-time address not generated for the account")
+	ErrOTANoOwner     = errors.New("no account owning the one-time address decrypts with the given passphrase")
 )
 
 // KeyStoreType is the reflect type of a keystore backend.
@@ -307,6 +309,53 @@ func (ks *KeyStore) ComputeOTAPPKeys(a accounts.Account, AX, AY, BX, BY string)
 	return []string{pub1X, pub1Y, priv1D, priv2D}, err
 }
 
+// ComputeOTAPrivateKeyWithPassphrase derives the private key of the one-time
+// address otaWAddr if it was generated for the account, whose keys are
+// decrypted with the given passphrase. The caller should zero the returned key
+// after use.
+func (ks *KeyStore) ComputeOTAPrivateKeyWithPassphrase(a accounts.Account, passphrase string, otaWAddr []byte) (*ecdsa.PrivateKey, error) {
+	otaPub, otaR, err := GeneratePKPairFromWAddress(otaWAddr)
+	if err != nil {
+		return nil, err
+	}
+	_, key, err := ks.getDecryptedKey(a, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroKey(key.PrivateKey)
+	defer zeroKey(key.PrivateKey2)
+
+	otaKey, _, err := crypto.GenerateOneTimePrivateKey2528(key.PrivateKey, key.PrivateKey2, otaPub, otaR)
+	if err != nil {
+		return nil, err
+	}
+	otaKey.PublicKey.Curve = crypto.S256()
+	otaKey.PublicKey.X, otaKey.PublicKey.Y = crypto.S256().ScalarBaseMult(otaKey.D.Bytes())
+	if otaKey.X.Cmp(otaPub.X) != 0 || otaKey.Y.Cmp(otaPub.Y) != 0 {
+		zeroKey(otaKey)
+		return nil, ErrOTANotOwned
+	}
+	return otaKey, nil
+}
+
+// FindOTAOwner returns the account the one-time address otaWAddr was generated
+// for, among the accounts whose keys decrypt with the given passphrase, along
+// with the OTA private key. The accounts are decrypted in turn, so the cost
+// grows with the size of the keystore. The caller should zero the returned key
+// after use.
+func (ks *KeyStore) FindOTAOwner(passphrase string, otaWAddr []byte) (accounts.Account, *ecdsa.PrivateKey, error) {
+	if _, _, err := GeneratePKPairFromWAddress(otaWAddr); err != nil {
+		return accounts.Account{}, nil, err
+	}
+	for _, a := range ks.Accounts() {
+		otaKey, err := ks.ComputeOTAPrivateKeyWithPassphrase(a, passphrase, otaWAddr)
+		if err == nil {
+			return a, otaKey, nil
+		}
+	}
+	return accounts.Account{}, nil, ErrOTANoOwner
+}
+
 // SignHashWithPassphrase signs hash if the private key matching the given address
 // can be decrypted with the given passphrase. The produced signature is in the
 // [R || S || V] format where V is 0 or 1.
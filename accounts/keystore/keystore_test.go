@@ -18,6 +18,7 @@
 package keystore
 
 import (
+	"crypto/ecdsa"
 	"io/ioutil"
 	"math/rand"
 	"os"
@@ -534,3 +535,91 @@ func TestComputeOTAPPKeys(t *testing.T) {
 		t.Errorf("invalid ota pk. pk lenght:%d", len(pk))
 	}
 }
+
+func TestComputeOTAPrivateKeyWithPassphrase(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	owner, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wAddr, err := ks.GetWanAddress(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ota, err := genOTA(hexutil.Encode(wAddr[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otaWAddr := common.FromHex(ota)
+
+	if _, err := ks.ComputeOTAPrivateKeyWithPassphrase(owner, "bar", otaWAddr); err != ErrDecrypt {
+		t.Fatalf("wrong passphrase: have %v, want %v", err, ErrDecrypt)
+	}
+	if _, err := ks.ComputeOTAPrivateKeyWithPassphrase(other, "foo", otaWAddr); err != ErrOTANotOwned {
+		t.Fatalf("other account: have %v, want %v", err, ErrOTANotOwned)
+	}
+	otaKey, err := ks.ComputeOTAPrivateKeyWithPassphrase(owner, "foo", otaWAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The OTA key ring signs with decoys
+	decoy, _ := crypto.GenerateKey()
+	msg := owner.Address.Bytes()
+	pubs, image, w, q, err := crypto.RingSign(msg, otaKey.D, []*ecdsa.PublicKey{&otaKey.PublicKey, &decoy.PublicKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !crypto.VerifyRingSign(msg, pubs, image, w, q) {
+		t.Error("OTA ring signature doesn't verify")
+	}
+}
+
+func TestFindOTAOwner(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	if _, err := ks.NewAccount("foo"); err != nil {
+		t.Fatal(err)
+	}
+	owner, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.NewAccount("bar"); err != nil {
+		t.Fatal(err)
+	}
+	wAddr, err := ks.GetWanAddress(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ota, err := genOTA(hexutil.Encode(wAddr[:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otaWAddr := common.FromHex(ota)
+
+	found, otaKey, err := ks.FindOTAOwner("foo", otaWAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Address != owner.Address {
+		t.Errorf("owner mismatch: have %x, want %x", found.Address, owner.Address)
+	}
+	pub, _, _ := GeneratePKPairFromWAddress(otaWAddr)
+	if otaKey.X.Cmp(pub.X) != 0 || otaKey.Y.Cmp(pub.Y) != 0 {
+		t.Error("OTA key doesn't match the one-time address")
+	}
+	if _, _, err := ks.FindOTAOwner("bar", otaWAddr); err != ErrOTANoOwner {
+		t.Errorf("wrong passphrase: have %v, want %v", err, ErrOTANoOwner)
+	}
+	if _, _, err := ks.FindOTAOwner("foo", otaWAddr[1:]); err == nil {
+		t.Error("invalid one-time address accepted")
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/wanchain/go-wanchain/accounts"
+)
+
+// kmsEnvelopeVersion is the version of the envelope format of KMS encrypted
+// key files.
+const kmsEnvelopeVersion = 1
+
+// KeyManagementService encrypts and decrypts small secrets with a master key
+// held by a key management service outside of the node, such as AWS KMS or a
+// HashiCorp Vault transit engine.
+type KeyManagementService interface {
+	// Encrypt encrypts plaintext with the master key.
+	Encrypt(plaintext []byte) ([]byte, error)
+
+	// Decrypt decrypts a ciphertext returned by Encrypt.
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// kmsEnvelope is the content of a KMS encrypted key file. The key file is
+// encrypted with a random data key using AES-256-GCM, and only the data key is
+// sent to the key management service to be encrypted with the master key.
+type kmsEnvelope struct {
+	Version      int    `json:"version"`
+	EncryptedKey []byte `json:"encryptedKey"`
+	Nonce        []byte `json:"nonce"`
+	Ciphertext   []byte `json:"ciphertext"`
+}
+
+// EncryptKMS encrypts keyjson for storage with envelope encryption by kms.
+func EncryptKMS(kms KeyManagementService, keyjson []byte) ([]byte, error) {
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+	aead, err := newKMSCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	encryptedKey, err := kms.Encrypt(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(&kmsEnvelope{
+		Version:      kmsEnvelopeVersion,
+		EncryptedKey: encryptedKey,
+		Nonce:        nonce,
+		Ciphertext:   aead.Seal(nil, nonce, keyjson, nil),
+	})
+}
+
+// DecryptKMS decrypts a key file encrypted by EncryptKMS. Files which are no
+// envelope are decrypted by kms directly, as the key files encrypted by AWS
+// KMS before envelope encryption was introduced.
+func DecryptKMS(kms KeyManagementService, data []byte) ([]byte, error) {
+	var envelope kmsEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Version == 0 {
+		return kms.Decrypt(data)
+	}
+	if envelope.Version != kmsEnvelopeVersion {
+		return nil, errors.New("unsupported KMS envelope version")
+	}
+	dataKey, err := kms.Decrypt(envelope.EncryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newKMSCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(envelope.Nonce) != aead.NonceSize() {
+		return nil, errors.New("invalid KMS envelope nonce")
+	}
+	return aead.Open(nil, envelope.Nonce, envelope.Ciphertext, nil)
+}
+
+func newKMSCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptKMSFile encrypts the key file srcFile with kms into desFile.
+func EncryptKMSFile(kms KeyManagementService, srcFile, desFile string) error {
+	keyjson, err := ioutil.ReadFile(srcFile)
+	if err != nil {
+		return err
+	}
+	data, err := EncryptKMS(kms, keyjson)
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(desFile, data)
+}
+
+// DecryptKMSFile decrypts the KMS encrypted key file srcFile into desFile.
+func DecryptKMSFile(kms KeyManagementService, srcFile, desFile string) error {
+	data, err := ioutil.ReadFile(srcFile)
+	if err != nil {
+		return err
+	}
+	keyjson, err := DecryptKMS(kms, data)
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(desFile, keyjson)
+}
+
+// DecryptKMSKey decrypts the KMS encrypted key file of a, returning the key
+// json to unlock it with.
+func (ks *KeyStore) DecryptKMSKey(a accounts.Account, kms KeyManagementService) ([]byte, error) {
+	a, err := ks.Find(a)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(a.URL.Path)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptKMS(kms, data)
+}
+
+// UnlockKMS unlocks the account of a KMS encrypted key file indefinitely.
+func (ks *KeyStore) UnlockKMS(a accounts.Account, kms KeyManagementService, passphrase string) error {
+	keyjson, err := ks.DecryptKMSKey(a, kms)
+	if err != nil {
+		return err
+	}
+	return ks.UnlockMemKey(a, keyjson, passphrase)
+}
@@ -0,0 +1,143 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// testKMS is a key management service "encrypting" by xor with a fixed byte
+// and tagging the ciphertext, so that foreign ciphertexts are rejected.
+type testKMS struct {
+	calls int
+}
+
+var testKMSTag = []byte("test-kms:")
+
+func (s *testKMS) Encrypt(plaintext []byte) ([]byte, error) {
+	s.calls++
+	out := append([]byte{}, testKMSTag...)
+	for _, b := range plaintext {
+		out = append(out, b^0x5a)
+	}
+	return out, nil
+}
+
+func (s *testKMS) Decrypt(ciphertext []byte) ([]byte, error) {
+	s.calls++
+	if !bytes.HasPrefix(ciphertext, testKMSTag) {
+		return nil, errors.New("not a test KMS ciphertext")
+	}
+	out := make([]byte, 0, len(ciphertext)-len(testKMSTag))
+	for _, b := range ciphertext[len(testKMSTag):] {
+		out = append(out, b^0x5a)
+	}
+	return out, nil
+}
+
+func TestKMSEnvelope(t *testing.T) {
+	kms := new(testKMS)
+	keyjson := []byte(`{"address":"0102030405060708090a0b0c0d0e0f1011121314"}`)
+
+	data, err := EncryptKMS(kms, keyjson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, keyjson) {
+		t.Fatal("envelope contains the plaintext key file")
+	}
+	kms.calls = 0
+	have, err := DecryptKMS(kms, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(have, keyjson) {
+		t.Errorf("decrypted key mismatch: have %s, want %s", have, keyjson)
+	}
+	// Only the data key goes through the service
+	if kms.calls != 1 {
+		t.Errorf("service called %d times, want 1", kms.calls)
+	}
+	// Tampered ciphertexts must be rejected
+	data = bytes.Replace(data, []byte(`"ciphertext":"`), []byte(`"ciphertext":"AA`), 1)
+	if _, err := DecryptKMS(kms, data); err == nil {
+		t.Error("tampered envelope decrypted")
+	}
+}
+
+func TestKMSLegacyFormat(t *testing.T) {
+	kms := new(testKMS)
+	keyjson := []byte(`{"address":"0102030405060708090a0b0c0d0e0f1011121314"}`)
+
+	// Key files encrypted by AWS KMS before envelopes were the raw ciphertext
+	data, _ := kms.Encrypt(keyjson)
+	have, err := DecryptKMS(kms, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(have, keyjson) {
+		t.Errorf("decrypted key mismatch: have %s, want %s", have, keyjson)
+	}
+}
+
+func TestUnlockKMS(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	a, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kms := new(testKMS)
+	if err := EncryptKMSFile(kms, a.URL.Path, a.URL.Path+AwsKMSCiphertextFileExt); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(a.URL.Path); err != nil {
+		t.Fatal(err)
+	}
+	ks = NewKeyStore(dir, veryLightScryptN, veryLightScryptP)
+	if !ks.HasAddress(a.Address) {
+		t.Fatal("encrypted key file not found in the keystore")
+	}
+	if err := ks.UnlockKMS(a, kms, "bar"); err != ErrDecrypt {
+		t.Fatalf("unlock with wrong passphrase: have %v, want %v", err, ErrDecrypt)
+	}
+	if err := ks.UnlockKMS(a, kms, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.SignHash(a, testSigData); err != nil {
+		t.Fatal(err)
+	}
+
+	// Decrypting the file restores the original key file
+	src := a.URL.Path + AwsKMSCiphertextFileExt
+	if err := DecryptKMSFile(kms, src, a.URL.Path); err != nil {
+		t.Fatal(err)
+	}
+	keyjson, err := ioutil.ReadFile(a.URL.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DecryptKey(keyjson, "foo"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package mnemonic implements BIP-39 mnemonic sentences, encoding the entropy
+// of a hierarchical deterministic wallet as a list of English words.
+//
+// See https://github.com/bitcoin/bips/blob/master/bip-0039.mediawiki
+package mnemonic
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/wanchain/go-wanchain/common/math"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// DefaultEntropyBits is the entropy of generated mnemonics, 24 words.
+	DefaultEntropyBits = 256
+
+	seedIterations = 2048
+	seedLength     = 64
+)
+
+var (
+	ErrEntropyLength = errors.New("entropy must be 128 to 256 bits, a multiple of 32")
+	ErrWordCount     = errors.New("mnemonic must have 12, 15, 18, 21 or 24 words")
+	ErrChecksum      = errors.New("invalid mnemonic checksum")
+)
+
+var wordIndex = make(map[string]int, len(English))
+
+func init() {
+	for i, word := range English {
+		wordIndex[word] = i
+	}
+}
+
+// NewEntropy returns bits of random entropy for a new mnemonic.
+func NewEntropy(bits int) ([]byte, error) {
+	if err := checkEntropyBits(bits); err != nil {
+		return nil, err
+	}
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, err
+	}
+	return entropy, nil
+}
+
+func checkEntropyBits(bits int) error {
+	if bits < 128 || bits > 256 || bits%32 != 0 {
+		return ErrEntropyLength
+	}
+	return nil
+}
+
+// NewMnemonic encodes entropy as a mnemonic sentence: the entropy followed by
+// the first bits of its SHA-256 hash, split in groups of 11 bits indexing the
+// wordlist.
+func NewMnemonic(entropy []byte) (string, error) {
+	bits := len(entropy) * 8
+	if err := checkEntropyBits(bits); err != nil {
+		return "", err
+	}
+	csBits := uint(bits / 32)
+	hash := sha256.Sum256(entropy)
+
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, csBits)
+	data.Or(data, big.NewInt(int64(hash[0]>>(8-csBits))))
+
+	words := make([]string, (bits+int(csBits))/11)
+	mask := big.NewInt(2047)
+	for i := len(words) - 1; i >= 0; i-- {
+		words[i] = English[new(big.Int).And(data, mask).Int64()]
+		data.Rsh(data, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// MnemonicToEntropy decodes a mnemonic sentence, verifying its checksum.
+func MnemonicToEntropy(mnemonic string) ([]byte, error) {
+	words := strings.Fields(mnemonic)
+	if len(words) < 12 || len(words) > 24 || len(words)%3 != 0 {
+		return nil, ErrWordCount
+	}
+	data := new(big.Int)
+	for _, word := range words {
+		index, ok := wordIndex[strings.ToLower(word)]
+		if !ok {
+			return nil, fmt.Errorf("unknown mnemonic word %q", word)
+		}
+		data.Lsh(data, 11)
+		data.Or(data, big.NewInt(int64(index)))
+	}
+	csBits := uint(len(words) / 3)
+	checksum := byte(new(big.Int).And(data, big.NewInt(1<<csBits-1)).Int64())
+	data.Rsh(data, csBits)
+
+	entropy := math.PaddedBigBytes(data, len(words)*4/3)
+	if hash := sha256.Sum256(entropy); hash[0]>>(8-csBits) != checksum {
+		return nil, ErrChecksum
+	}
+	return entropy, nil
+}
+
+// Validate reports whether mnemonic is a well formed sentence with a valid
+// checksum.
+func Validate(mnemonic string) error {
+	_, err := MnemonicToEntropy(mnemonic)
+	return err
+}
+
+// NewSeed validates mnemonic and derives the 64 byte wallet seed from it and
+// the optional passphrase. Both are used as given: the English wordlist is
+// ASCII, and passphrases are not Unicode normalized.
+func NewSeed(mnemonic, passphrase string) ([]byte, error) {
+	if err := Validate(mnemonic); err != nil {
+		return nil, err
+	}
+	normalized := strings.ToLower(strings.Join(strings.Fields(mnemonic), " "))
+	return pbkdf2.Key([]byte(normalized), []byte("mnemonic"+passphrase), seedIterations, seedLength, sha512.New), nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package mnemonic
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Vectors from https://github.com/trezor/python-mnemonic/blob/master/vectors.json
+var mnemonicTests = []struct {
+	entropy  string
+	mnemonic string
+}{
+	{"00000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"},
+	{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank yellow"},
+	{"80808080808080808080808080808080", "letter advice cage absurd amount doctor acoustic avoid letter advice cage above"},
+	{"ffffffffffffffffffffffffffffffff", "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong"},
+	{"9e885d952ad362caeb4efe34a8e91bd2", "ozone drill grab fiber curtain grace pudding thank cruise elder eight picnic"},
+	{"c0ba5a8e914111210f2bd131f3d5e08d", "scheme spot photo card baby mountain device kick cradle pact join borrow"},
+	{"f30f8c1da665478f49b001d94c5fc452", "vessel ladder alter error federal sibling chat ability sun glass valve picture"},
+	{"77c2b00716cec7213839159e404db50d", "jelly better achieve collect unaware mountain thought cargo oxygen act hood bridge"},
+	{"0460ef47585604c5660618db2e6a7e7f", "afford alter spike radar gate glance object seek swamp infant panel yellow"},
+	{"18ab19a9f54a9274f03e5209a2ac8a91", "board flee heavy tunnel powder denial science ski answer betray cargo cat"},
+	{"b63a9c59a6e641f288ebc103017f1da9f8290b3da6bdef7b", "renew stay biology evidence goat welcome casual join adapt armor shuffle fault little machine walk stumble urge swap"},
+}
+
+func TestMnemonic(t *testing.T) {
+	for i, test := range mnemonicTests {
+		entropy, _ := hex.DecodeString(test.entropy)
+		mnemonic, err := NewMnemonic(entropy)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if mnemonic != test.mnemonic {
+			t.Errorf("test %d: mnemonic mismatch:\nhave %s\nwant %s", i, mnemonic, test.mnemonic)
+		}
+		decoded, err := MnemonicToEntropy(test.mnemonic)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if !bytes.Equal(decoded, entropy) {
+			t.Errorf("test %d: entropy mismatch: have %x, want %x", i, decoded, entropy)
+		}
+	}
+}
+
+func TestMnemonicRoundTrip(t *testing.T) {
+	for bits := 128; bits <= 256; bits += 32 {
+		entropy, err := NewEntropy(bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mnemonic, _ := NewMnemonic(entropy)
+		if words := len(strings.Fields(mnemonic)); words != bits*3/32 {
+			t.Errorf("%d bits: word count mismatch: have %d, want %d", bits, words, bits*3/32)
+		}
+		decoded, err := MnemonicToEntropy(mnemonic)
+		if err != nil || !bytes.Equal(decoded, entropy) {
+			t.Errorf("%d bits: round trip failed: %v", bits, err)
+		}
+	}
+	if _, err := NewEntropy(160 + 8); err != ErrEntropyLength {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrEntropyLength)
+	}
+}
+
+func TestMnemonicInvalid(t *testing.T) {
+	tests := []struct {
+		mnemonic string
+		err      string
+	}{
+		{"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon", ErrChecksum.Error()},
+		{"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about", ErrWordCount.Error()},
+		{"abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon wanchain", "unknown mnemonic word"},
+	}
+	for i, test := range tests {
+		if err := Validate(test.mnemonic); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %q", i, err, test.err)
+		}
+	}
+	// case and spacing don't matter
+	if err := Validate("  Legal winner thank year wave sausage worth useful legal winner THANK yellow\n"); err != nil {
+		t.Errorf("loosely formatted mnemonic rejected: %v", err)
+	}
+}
+
+func TestNewSeed(t *testing.T) {
+	tests := []struct {
+		mnemonic string
+		seed     string
+	}{
+		{mnemonicTests[0].mnemonic, "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04"},
+		{mnemonicTests[1].mnemonic, "2e8905819b8723fe2c1d161860e5ee1830318dbf49a83bd451cfb8440c28bd6fa457fe1296106559a3c80937a1c1069be3a3a5bd381ee6260e8d9739fce1f607"},
+		{mnemonicTests[3].mnemonic, "ac27495480225222079d7be181583751e86f571027b0497b5b5d11218e0a8a13332572917f0f8e5a589620c6f15b11c61dee327651a14c34e18231052e48c069"},
+	}
+	for i, test := range tests {
+		seed, err := NewSeed(test.mnemonic, "TREZOR")
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if hex.EncodeToString(seed) != test.seed {
+			t.Errorf("test %d: seed mismatch:\nhave %x\nwant %s", i, seed, test.seed)
+		}
+	}
+	if _, err := NewSeed("abandon abandon", ""); err != ErrWordCount {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrWordCount)
+	}
+}
@@ -304,19 +304,10 @@ func (w *ledgerDriver) ledgerSign(derivationPath []uint32, tx *types.Transaction
 	for i, component := range derivationPath {
 		binary.BigEndian.PutUint32(path[1+4*i:], component)
 	}
-	// Create the transaction RLP based on whether legacy or EIP155 signing was requeste
-	var (
-		txrlp []byte
-		err   error
-	)
-	if chainID == nil {
-		if txrlp, err = rlp.EncodeToBytes([]interface{}{tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data()}); err != nil {
-			return common.Address{}, nil, err
-		}
-	} else {
-		if txrlp, err = rlp.EncodeToBytes([]interface{}{tx.Txtype(), tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data(), chainID, big.NewInt(0), big.NewInt(0)}); err != nil {
-			return common.Address{}, nil, err
-		}
+	// Create the transaction RLP based on whether legacy or EIP155 signing was requested
+	txrlp, err := ledgerTxRLP(tx, chainID)
+	if err != nil {
+		return common.Address{}, nil, err
 	}
 	payload := append(path, txrlp...)
 
@@ -350,6 +341,7 @@ func (w *ledgerDriver) ledgerSign(derivationPath []uint32, tx *types.Transaction
 	var signer types.Signer
 	if chainID == nil {
 		signer = new(types.HomesteadSigner)
+		signature[64] = signature[64] - 27
 	} else {
 		signer = types.NewEIP155Signer(chainID)
 		signature[64] = signature[64] - byte(chainID.Uint64()*2+35)
@@ -365,6 +357,18 @@ func (w *ledgerDriver) ledgerSign(derivationPath []uint32, tx *types.Transaction
 	return sender, signed, nil
 }
 
+// ledgerTxRLP serializes the part of a Wanchain transaction the Ledger signs:
+// the txdata fields up to the payload, led by the transaction type, followed
+// by the chain ID and two zeroes for EIP155 signing. It matches the hash of
+// the Homestead and EIP155 signers.
+func ledgerTxRLP(tx *types.Transaction, chainID *big.Int) ([]byte, error) {
+	fields := []interface{}{tx.Txtype(), tx.Nonce(), tx.GasPrice(), tx.Gas(), tx.To(), tx.Value(), tx.Data()}
+	if chainID != nil {
+		fields = append(fields, chainID, uint(0), uint(0))
+	}
+	return rlp.EncodeToBytes(fields)
+}
+
 // ledgerExchange performs a data exchange with the Ledger wallet, sending it a
 // message and retrieving the response.
 //
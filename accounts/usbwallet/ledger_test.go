@@ -0,0 +1,207 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package usbwallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// ledgerMock emulates the Wanchain app of a Ledger behind its HID transport,
+// signing the transaction RLP it receives with a local key.
+type ledgerMock struct {
+	t   *testing.T
+	key *ecdsa.PrivateKey
+
+	apdu    []byte // APDU being received
+	length  int    // Total length of the APDU being received
+	path    []uint32
+	txrlp   []byte // Transaction RLP being signed
+	replies bytes.Buffer
+}
+
+func newLedgerMock(t *testing.T) *ledgerMock {
+	key, _ := crypto.GenerateKey()
+	return &ledgerMock{t: t, key: key}
+}
+
+func (m *ledgerMock) address() common.Address {
+	return crypto.PubkeyToAddress(m.key.PublicKey)
+}
+
+func (m *ledgerMock) Read(p []byte) (int, error) {
+	return m.replies.Read(p)
+}
+
+func (m *ledgerMock) Write(chunk []byte) (int, error) {
+	if chunk[0] != 0x01 || chunk[1] != 0x01 || chunk[2] != 0x05 {
+		m.t.Fatalf("invalid transport header %x", chunk[:5])
+	}
+	if binary.BigEndian.Uint16(chunk[3:5]) == 0 {
+		m.length = int(binary.BigEndian.Uint16(chunk[5:7]))
+		m.apdu = append(m.apdu[:0], chunk[7:]...)
+	} else {
+		m.apdu = append(m.apdu, chunk[5:]...)
+	}
+	if len(m.apdu) >= m.length {
+		m.reply(m.handle(m.apdu[:m.length]))
+	}
+	return len(chunk), nil
+}
+
+// handle executes an APDU command, returning the reply data.
+func (m *ledgerMock) handle(apdu []byte) []byte {
+	op, p1, data := ledgerOpcode(apdu[1]), ledgerParam1(apdu[2]), apdu[5:]
+	switch op {
+	case ledgerOpGetConfiguration:
+		return []byte{0x00, 1, 2, 0}
+
+	case ledgerOpRetrieveAddress:
+		m.path = m.parsePath(data)
+		pubkey := crypto.FromECDSAPub(&m.key.PublicKey)
+		hexaddr := []byte(hex.EncodeToString(m.address().Bytes()))
+		reply := append([]byte{byte(len(pubkey))}, pubkey...)
+		return append(append(reply, byte(len(hexaddr))), hexaddr...)
+
+	case ledgerOpSignTransaction:
+		if p1 == ledgerP1InitTransactionData {
+			m.path = m.parsePath(data)
+			m.txrlp = append([]byte(nil), data[1+4*int(data[0]):]...)
+		} else {
+			m.txrlp = append(m.txrlp, data...)
+		}
+		content, rest, err := rlp.SplitList(m.txrlp)
+		if err != nil || len(rest) != 0 {
+			return nil // more chunks to come
+		}
+		sig, err := crypto.Sign(crypto.Keccak256(m.txrlp), m.key)
+		if err != nil {
+			m.t.Fatal(err)
+		}
+		v := uint64(sig[64]) + 27
+		if fields, _ := rlp.CountValues(content); fields == 10 {
+			var tx struct {
+				Txtype, Nonce uint64
+				Price, Gas    *big.Int
+				To            *common.Address
+				Amount        *big.Int
+				Data          []byte
+				ChainID       *big.Int
+				Zero1, Zero2  uint
+			}
+			if err := rlp.DecodeBytes(m.txrlp, &tx); err != nil {
+				m.t.Fatal(err)
+			}
+			v = uint64(sig[64]) + 35 + 2*tx.ChainID.Uint64()
+		}
+		return append([]byte{byte(v)}, sig[:64]...)
+	}
+	m.t.Fatalf("unexpected ledger opcode %x", op)
+	return nil
+}
+
+func (m *ledgerMock) parsePath(data []byte) []uint32 {
+	path := make([]uint32, data[0])
+	for i := range path {
+		path[i] = binary.BigEndian.Uint32(data[1+4*i:])
+	}
+	return path
+}
+
+// reply streams a reply with a success status word back in 64 byte chunks.
+func (m *ledgerMock) reply(data []byte) {
+	payload := make([]byte, 2, len(data)+4)
+	binary.BigEndian.PutUint16(payload, uint16(len(data)+2))
+	payload = append(append(payload, data...), 0x90, 0x00)
+
+	for seq := 0; len(payload) > 0; seq++ {
+		chunk := make([]byte, 64)
+		copy(chunk, []byte{0x01, 0x01, 0x05})
+		binary.BigEndian.PutUint16(chunk[3:], uint16(seq))
+		payload = payload[copy(chunk[5:], payload):]
+		m.replies.Write(chunk)
+	}
+}
+
+func newTestLedger(t *testing.T) (*ledgerDriver, *ledgerMock) {
+	mock := newLedgerMock(t)
+	driver := newLedgerDriver(log.New()).(*ledgerDriver)
+	if err := driver.Open(mock, ""); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := driver.Status(); status != "Wanchain app v1.2.0 online" {
+		t.Fatalf("status mismatch: %s", status)
+	}
+	return driver, mock
+}
+
+// Tests that the Ledger signs the transaction type of Wanchain transactions,
+// with legacy and EIP155 signatures, streaming large payloads in chunks.
+func TestLedgerSignTx(t *testing.T) {
+	for _, chainID := range []*big.Int{nil, big.NewInt(6)} {
+		for _, txtype := range []uint64{types.NORMAL_TX, types.POS_TX} {
+			driver, mock := newTestLedger(t)
+
+			tx := types.NewTransaction(3, common.HexToAddress("0x01"), big.NewInt(1), big.NewInt(50000), big.NewInt(180000000000), bytes.Repeat([]byte{0xaa}, 600))
+			tx.SetTxtype(txtype)
+			sender, signed, err := driver.SignTx(accounts.DefaultBaseDerivationPath, tx, chainID)
+			if err != nil {
+				t.Fatalf("chain %v, type %d: %v", chainID, txtype, err)
+			}
+			if sender != mock.address() {
+				t.Errorf("chain %v, type %d: sender mismatch: have %x, want %x", chainID, txtype, sender, mock.address())
+			}
+			if signed.Txtype() != txtype || signed.Hash() == tx.Hash() {
+				t.Errorf("chain %v, type %d: signed tx type %d", chainID, txtype, signed.Txtype())
+			}
+			var signer types.Signer = types.HomesteadSigner{}
+			if chainID != nil {
+				signer = types.NewEIP155Signer(chainID)
+			}
+			if from, err := types.Sender(signer, signed); err != nil || from != mock.address() {
+				t.Errorf("chain %v, type %d: recovered sender %x: %v", chainID, txtype, from, err)
+			}
+			if !equalPath(mock.path, accounts.DefaultBaseDerivationPath) {
+				t.Errorf("chain %v, type %d: path mismatch: have %v", chainID, txtype, mock.path)
+			}
+		}
+	}
+}
+
+func equalPath(a []uint32, b accounts.DerivationPath) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
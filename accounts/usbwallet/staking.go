@@ -0,0 +1,159 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// This file contains the calls of the pos staking contract hardware wallets
+// sign, decoded for the user to review before confirming them on the device.
+
+package usbwallet
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/accounts/abi"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
+)
+
+// stakingAddress is the address of the pos staking precompiled contract. It is
+// vm.WanCscPrecompileAddr, which can't be imported as core/vm depends on this
+// package through the node package.
+var stakingAddress = common.BytesToAddress([]byte{218})
+
+// stakingDefinition is the part of the staking contract abi hardware wallets
+// sign calls of, matching the definition in core/vm.
+const stakingDefinition = `[
+	{"constant":false,"inputs":[{"name":"secPk","type":"bytes"},{"name":"bn256Pk","type":"bytes"},{"name":"lockEpochs","type":"uint256"},{"name":"feeRate","type":"uint256"}],"name":"stakeIn","outputs":[],"payable":true,"stateMutability":"payable","type":"function"},
+	{"constant":false,"inputs":[{"name":"delegateAddress","type":"address"}],"name":"delegateIn","outputs":[],"payable":true,"stateMutability":"payable","type":"function"},
+	{"constant":false,"inputs":[{"name":"delegateAddress","type":"address"}],"name":"delegateOut","outputs":[],"payable":false,"stateMutability":"nonpayable","type":"function"}
+]`
+
+var stakingABI abi.ABI
+
+func init() {
+	var err error
+	if stakingABI, err = abi.JSON(strings.NewReader(stakingDefinition)); err != nil {
+		panic(fmt.Sprintf("usbwallet: invalid staking abi: %v", err))
+	}
+}
+
+var (
+	// ErrNotStakingCall is returned when signing a staking transaction whose
+	// input is not a stakeIn, delegateIn or delegateOut call.
+	ErrNotStakingCall = errors.New("not a stakeIn, delegateIn or delegateOut call")
+
+	// errPrivacyTx is returned when a hardware wallet is asked to sign a privacy
+	// transaction, whose one-time address ring signature needs the private keys.
+	errPrivacyTx = errors.New("privacy transactions can't be signed by hardware wallets")
+)
+
+// StakingCall is a decoded call of the pos staking contract.
+type StakingCall struct {
+	Method string   // stakeIn, delegateIn or delegateOut
+	Value  *big.Int // Amount staked or delegated
+
+	SecPk      []byte   // stakeIn: validator secp256k1 public key
+	Bn256Pk    []byte   // stakeIn: validator bn256 public key
+	LockEpochs *big.Int // stakeIn: number of epochs the stake is locked
+	FeeRate    *big.Int // stakeIn: fee rate charged to delegators, in 1/10000
+
+	Validator common.Address // delegateIn, delegateOut: validator delegated to
+}
+
+// String implements fmt.Stringer, formatting the call for user review.
+func (c *StakingCall) String() string {
+	switch c.Method {
+	case "stakeIn":
+		return fmt.Sprintf("stakeIn(secPk=%s, bn256Pk=%s, lockEpochs=%v, feeRate=%v) value=%v",
+			hexutil.Encode(c.SecPk), hexutil.Encode(c.Bn256Pk), c.LockEpochs, c.FeeRate, c.Value)
+	default:
+		return fmt.Sprintf("%s(%s) value=%v", c.Method, c.Validator.Hex(), c.Value)
+	}
+}
+
+// PackStakeIn packs the input of a stakeIn call registering a validator.
+func PackStakeIn(secPk, bn256Pk []byte, lockEpochs, feeRate *big.Int) ([]byte, error) {
+	return stakingABI.Pack("stakeIn", secPk, bn256Pk, lockEpochs, feeRate)
+}
+
+// PackDelegateIn packs the input of a delegateIn call delegating to validator.
+func PackDelegateIn(validator common.Address) ([]byte, error) {
+	return stakingABI.Pack("delegateIn", validator)
+}
+
+// PackDelegateOut packs the input of a delegateOut call withdrawing the
+// delegation to validator.
+func PackDelegateOut(validator common.Address) ([]byte, error) {
+	return stakingABI.Pack("delegateOut", validator)
+}
+
+// DecodeStakingCall decodes tx as a stakeIn, delegateIn or delegateOut call of
+// the staking contract. It returns nil without error if tx is not sent to the
+// staking contract or calls another of its methods.
+func DecodeStakingCall(tx *types.Transaction) (*StakingCall, error) {
+	if to := tx.To(); to == nil || *to != stakingAddress || len(tx.Data()) < 4 {
+		return nil, nil
+	}
+	method, err := stakingABI.MethodById(tx.Data()[:4])
+	if err != nil {
+		return nil, nil
+	}
+	call := &StakingCall{Method: method.Name, Value: tx.Value()}
+	input := tx.Data()[4:]
+
+	switch method.Name {
+	case "stakeIn":
+		var params struct {
+			SecPk      []byte
+			Bn256Pk    []byte
+			LockEpochs *big.Int
+			FeeRate    *big.Int
+		}
+		if err := stakingABI.UnpackInput(&params, method.Name, input); err != nil {
+			return nil, fmt.Errorf("invalid stakeIn input: %v", err)
+		}
+		call.SecPk, call.Bn256Pk, call.LockEpochs, call.FeeRate = params.SecPk, params.Bn256Pk, params.LockEpochs, params.FeeRate
+	default:
+		if err := stakingABI.UnpackInput(&call.Validator, method.Name, input); err != nil {
+			return nil, fmt.Errorf("invalid %s input: %v", method.Name, err)
+		}
+	}
+	return call, nil
+}
+
+// SignStakingTx creates a transaction calling the staking contract with input,
+// packed by PackStakeIn, PackDelegateIn or PackDelegateOut, and signs it with
+// account of wallet. Hardware wallets log the decoded call for the user to
+// review while confirming it on the device.
+func SignStakingTx(wallet accounts.Wallet, account accounts.Account, nonce uint64, value, gasLimit, gasPrice *big.Int, input []byte, chainID *big.Int) (*types.Transaction, error) {
+	tx := types.NewTransaction(nonce, stakingAddress, value, gasLimit, gasPrice, input)
+	call, err := DecodeStakingCall(tx)
+	if err != nil {
+		return nil, err
+	}
+	if call == nil {
+		return nil, ErrNotStakingCall
+	}
+	if call.Method == "delegateOut" && value.Sign() != 0 {
+		return nil, errors.New("delegateOut doesn't accept value")
+	}
+	return wallet.SignTx(account, tx, chainID)
+}
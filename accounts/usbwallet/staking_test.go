@@ -0,0 +1,132 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package usbwallet
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/karalabe/hid"
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/log"
+)
+
+var testValidator = common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
+
+func TestStakingCallDecode(t *testing.T) {
+	secPk, bn256Pk := bytes.Repeat([]byte{4}, 65), bytes.Repeat([]byte{5}, 64)
+	stakeIn, err := PackStakeIn(secPk, bn256Pk, big.NewInt(10), big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	delegateIn, _ := PackDelegateIn(testValidator)
+	delegateOut, _ := PackDelegateOut(testValidator)
+
+	value := big.NewInt(1e18)
+	tests := []struct {
+		to    common.Address
+		input []byte
+		want  *StakingCall
+		err   bool
+	}{
+		{stakingAddress, stakeIn, &StakingCall{Method: "stakeIn", Value: value, SecPk: secPk, Bn256Pk: bn256Pk, LockEpochs: big.NewInt(10), FeeRate: big.NewInt(100)}, false},
+		{stakingAddress, delegateIn, &StakingCall{Method: "delegateIn", Value: value, Validator: testValidator}, false},
+		{stakingAddress, delegateOut, &StakingCall{Method: "delegateOut", Value: value, Validator: testValidator}, false},
+		{common.HexToAddress("0x01"), delegateIn, nil, false}, // other contract
+		{stakingAddress, []byte{1, 2, 3, 4}, nil, false},      // other method
+		{stakingAddress, stakeIn[:40], nil, true},             // truncated input
+	}
+	for i, test := range tests {
+		tx := types.NewTransaction(0, test.to, value, big.NewInt(200000), big.NewInt(1), test.input)
+		call, err := DecodeStakingCall(tx)
+		if test.err {
+			if err == nil {
+				t.Errorf("test %d: expected error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if (call == nil) != (test.want == nil) || (call != nil && call.String() != test.want.String()) {
+			t.Errorf("test %d: call mismatch: have %v, want %v", i, call, test.want)
+		}
+	}
+}
+
+// newTestWallet creates a wallet of a Ledger behind a mocked transport, with
+// its first account derived.
+func newTestWallet(t *testing.T) (*wallet, accounts.Account) {
+	driver, mock := newTestLedger(t)
+	w := &wallet{
+		hub:       new(Hub),
+		driver:    driver,
+		url:       &accounts.URL{Scheme: LedgerScheme, Path: "mock"},
+		device:    new(hid.Device),
+		paths:     make(map[common.Address]accounts.DerivationPath),
+		commsLock: make(chan struct{}, 1),
+		log:       log.New(),
+	}
+	w.commsLock <- struct{}{}
+	account, err := w.Derive(accounts.DefaultBaseDerivationPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Address != mock.address() {
+		t.Fatalf("account mismatch: have %x, want %x", account.Address, mock.address())
+	}
+	return w, account
+}
+
+func TestSignStakingTx(t *testing.T) {
+	w, account := newTestWallet(t)
+	chainID := big.NewInt(6)
+
+	input, _ := PackDelegateIn(testValidator)
+	tx, err := SignStakingTx(w, account, 1, big.NewInt(1e18), big.NewInt(200000), big.NewInt(180000000000), input, chainID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from, err := types.Sender(types.NewEIP155Signer(chainID), tx); err != nil || from != account.Address {
+		t.Fatalf("sender mismatch: have %x, want %x: %v", from, account.Address, err)
+	}
+	if *tx.To() != stakingAddress || tx.Txtype() != types.NORMAL_TX || !bytes.Equal(tx.Data(), input) {
+		t.Fatalf("staking tx mismatch: to %x, type %d", tx.To(), tx.Txtype())
+	}
+
+	input, _ = PackDelegateOut(testValidator)
+	if _, err := SignStakingTx(w, account, 2, big.NewInt(1), big.NewInt(200000), big.NewInt(1), input, chainID); err == nil {
+		t.Error("delegateOut with value signed")
+	}
+	if _, err := SignStakingTx(w, account, 2, new(big.Int), big.NewInt(200000), big.NewInt(1), []byte{1, 2, 3, 4}, chainID); err != ErrNotStakingCall {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrNotStakingCall)
+	}
+}
+
+func TestSignPrivacyTxRejected(t *testing.T) {
+	w, account := newTestWallet(t)
+
+	tx := types.NewTransaction(0, common.HexToAddress("0x01"), new(big.Int), big.NewInt(21000), big.NewInt(1), nil)
+	tx.SetTxtype(types.PRIVACY_TX)
+	if _, err := w.SignTx(account, tx, big.NewInt(6)); err != errPrivacyTx {
+		t.Fatalf("error mismatch: have %v, want %v", err, errPrivacyTx)
+	}
+}
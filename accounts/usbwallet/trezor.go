@@ -222,6 +222,7 @@ func (w *trezorDriver) trezorSign(derivationPath []uint32, tx *types.Transaction
 	var signer types.Signer
 	if chainID == nil {
 		signer = new(types.HomesteadSigner)
+		signature[64] = signature[64] - 27
 	} else {
 		signer = types.NewEIP155Signer(chainID)
 		signature[64] = signature[64] - byte(chainID.Uint64()*2+35)
@@ -0,0 +1,211 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package usbwallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/accounts/usbwallet/internal/trezor"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// trezorMock emulates a Trezor behind its HID transport, signing the
+// transactions it receives with a local key.
+type trezorMock struct {
+	t   *testing.T
+	key *ecdsa.PrivateKey
+
+	kind    uint16 // Type of the message being received
+	msg     []byte // Message being received
+	length  int    // Total length of the message being received
+	request *trezor.EthereumSignTx
+	data    []byte // Transaction payload being received
+	replies bytes.Buffer
+}
+
+func newTrezorMock(t *testing.T) *trezorMock {
+	key, _ := crypto.GenerateKey()
+	return &trezorMock{t: t, key: key}
+}
+
+func (m *trezorMock) address() common.Address {
+	return crypto.PubkeyToAddress(m.key.PublicKey)
+}
+
+func (m *trezorMock) Read(p []byte) (int, error) {
+	return m.replies.Read(p)
+}
+
+func (m *trezorMock) Write(chunk []byte) (int, error) {
+	if chunk[0] != 0x3f {
+		m.t.Fatalf("invalid report id %x", chunk[0])
+	}
+	if m.msg == nil {
+		if chunk[1] != 0x23 || chunk[2] != 0x23 {
+			m.t.Fatalf("invalid message header %x", chunk[1:3])
+		}
+		m.kind = binary.BigEndian.Uint16(chunk[3:5])
+		m.length = int(binary.BigEndian.Uint32(chunk[5:9]))
+		m.msg = append([]byte{}, chunk[9:]...)
+	} else {
+		m.msg = append(m.msg, chunk[1:]...)
+	}
+	if len(m.msg) >= m.length {
+		msg := m.msg[:m.length]
+		m.msg = nil
+		m.reply(m.handle(m.kind, msg))
+	}
+	return len(chunk), nil
+}
+
+// handle executes a request, returning the reply message.
+func (m *trezorMock) handle(kind uint16, msg []byte) proto.Message {
+	switch trezor.MessageType(kind) {
+	case trezor.MessageType_MessageType_Initialize:
+		major, minor, patch, label := uint32(1), uint32(6), uint32(0), "mock"
+		return &trezor.Features{MajorVersion: &major, MinorVersion: &minor, PatchVersion: &patch, Label: &label}
+
+	case trezor.MessageType_MessageType_Ping:
+		return new(trezor.Success)
+
+	case trezor.MessageType_MessageType_EthereumGetAddress:
+		return &trezor.EthereumAddress{Address: m.address().Bytes()}
+
+	case trezor.MessageType_MessageType_EthereumSignTx:
+		m.request = new(trezor.EthereumSignTx)
+		if err := proto.Unmarshal(msg, m.request); err != nil {
+			m.t.Fatal(err)
+		}
+		m.data = append([]byte{}, m.request.DataInitialChunk...)
+		return m.sign()
+
+	case trezor.MessageType_MessageType_EthereumTxAck:
+		ack := new(trezor.EthereumTxAck)
+		if err := proto.Unmarshal(msg, ack); err != nil {
+			m.t.Fatal(err)
+		}
+		m.data = append(m.data, ack.DataChunk...)
+		return m.sign()
+	}
+	m.t.Fatalf("unexpected trezor message %s", trezor.Name(kind))
+	return nil
+}
+
+// sign requests the next chunk of the transaction payload, or signs the
+// transaction once it's complete.
+func (m *trezorMock) sign() proto.Message {
+	if left := int(m.request.GetDataLength()) - len(m.data); left > 0 {
+		if left > 1024 {
+			left = 1024
+		}
+		length := uint32(left)
+		return &trezor.EthereumTxRequest{DataLength: &length}
+	}
+	var to *common.Address
+	if len(m.request.To) > 0 {
+		addr := common.BytesToAddress(m.request.To)
+		to = &addr
+	}
+	fields := []interface{}{
+		uint64(m.request.GetTxType()),
+		new(big.Int).SetBytes(m.request.Nonce),
+		new(big.Int).SetBytes(m.request.GasPrice),
+		new(big.Int).SetBytes(m.request.GasLimit),
+		to,
+		new(big.Int).SetBytes(m.request.Value),
+		m.data,
+	}
+	if m.request.ChainId != nil {
+		fields = append(fields, uint64(m.request.GetChainId()), uint(0), uint(0))
+	}
+	txrlp, err := rlp.EncodeToBytes(fields)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	sig, err := crypto.Sign(crypto.Keccak256(txrlp), m.key)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	v := uint32(sig[64]) + 27
+	if m.request.ChainId != nil {
+		v = uint32(sig[64]) + 35 + 2*m.request.GetChainId()
+	}
+	return &trezor.EthereumTxRequest{SignatureV: &v, SignatureR: sig[:32], SignatureS: sig[32:64]}
+}
+
+// reply streams a reply message back in 64 byte chunks.
+func (m *trezorMock) reply(msg proto.Message) {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	payload := make([]byte, 8+len(data))
+	copy(payload, []byte{0x23, 0x23})
+	binary.BigEndian.PutUint16(payload[2:], trezor.Type(msg))
+	binary.BigEndian.PutUint32(payload[4:], uint32(len(data)))
+	copy(payload[8:], data)
+
+	for len(payload) > 0 {
+		chunk := make([]byte, 64)
+		chunk[0] = 0x3f
+		payload = payload[copy(chunk[1:], payload):]
+		m.replies.Write(chunk)
+	}
+}
+
+// Tests that the Trezor signs the transaction type of Wanchain transactions,
+// with legacy and EIP155 signatures, streaming large payloads in chunks.
+func TestTrezorSignTx(t *testing.T) {
+	for _, chainID := range []*big.Int{nil, big.NewInt(6)} {
+		for _, txtype := range []uint64{types.NORMAL_TX, types.POS_TX} {
+			mock := newTrezorMock(t)
+			driver := newTrezorDriver(log.New()).(*trezorDriver)
+			if err := driver.Open(mock, ""); err != nil {
+				t.Fatal(err)
+			}
+			if addr, err := driver.Derive(accounts.DefaultBaseDerivationPath); err != nil || addr != mock.address() {
+				t.Fatalf("derived address mismatch: have %x, want %x: %v", addr, mock.address(), err)
+			}
+			tx := types.NewTransaction(3, common.HexToAddress("0x01"), big.NewInt(1), big.NewInt(50000), big.NewInt(180000000000), bytes.Repeat([]byte{0xaa}, 2500))
+			tx.SetTxtype(txtype)
+			sender, signed, err := driver.SignTx(accounts.DefaultBaseDerivationPath, tx, chainID)
+			if err != nil {
+				t.Fatalf("chain %v, type %d: %v", chainID, txtype, err)
+			}
+			if sender != mock.address() {
+				t.Errorf("chain %v, type %d: sender mismatch: have %x, want %x", chainID, txtype, sender, mock.address())
+			}
+			if signed.Txtype() != txtype || !bytes.Equal(signed.Data(), tx.Data()) {
+				t.Errorf("chain %v, type %d: signed tx mismatch", chainID, txtype)
+			}
+			if !equalPath(mock.request.AddressN, accounts.DefaultBaseDerivationPath) {
+				t.Errorf("chain %v, type %d: path mismatch: have %v", chainID, txtype, mock.request.AddressN)
+			}
+		}
+	}
+}
@@ -522,6 +522,15 @@ func (w *wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID
 	if !ok {
 		return nil, accounts.ErrUnknownAccount
 	}
+	if types.IsPrivacyTransaction(tx.Txtype()) {
+		return nil, errPrivacyTx
+	}
+	// Show staking calls decoded, the device only displays their raw input
+	if call, err := DecodeStakingCall(tx); err != nil {
+		return nil, err
+	} else if call != nil {
+		w.log.Info("Confirm staking call on the hardware wallet", "account", account.Address, "call", call)
+	}
 	// All infos gathered and metadata checks out, request signing
 	<-w.commsLock
 	defer func() { w.commsLock <- struct{}{} }()
@@ -2,10 +2,7 @@ package awskms
 
 import (
 	"errors"
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/wanchain/go-wanchain/kms"
 	"github.com/wanchain/go-wanchain/log"
 	"io/ioutil"
 	"os"
@@ -105,59 +102,38 @@ func DecryptFileToBuffer(srcFile, aKID, secretKey, region string) ([]byte, error
 	return plaintext, nil
 }
 
+// Encrypt encrypts text with the AWS KMS master key keyId.
+//
+// Deprecated: use kms.AWS, which keystore.EncryptKMSFile uses for envelope
+// encryption.
 func Encrypt(text, aKID, secretKey, region, keyId string) ([]byte, error) {
-	// Initialize a session
-	sess, err := session.NewSession(&aws.Config{
-		Region:      aws.String(region),
-		Credentials: credentials.NewStaticCredentials(aKID, secretKey, ""),
-	})
-
+	svc, err := kms.NewAWS(aKID, secretKey, region, keyId)
 	if err != nil {
 		log.Error("create kms session fail", "err", err)
 		return nil, err
 	}
-
-	// Create KMS service client
-	svc := kms.New(sess)
-
-	// Encrypt the data key
-	result, err := svc.Encrypt(&kms.EncryptInput{
-		KeyId:     aws.String(keyId),
-		Plaintext: []byte(text),
-	})
-
+	ciphertext, err := svc.Encrypt([]byte(text))
 	if err != nil {
 		log.Error("kms encrypt fail", "err", err)
 		return nil, err
 	}
-
-	return result.CiphertextBlob, nil
+	return ciphertext, nil
 }
 
+// Decrypt decrypts a ciphertext returned by Encrypt.
+//
+// Deprecated: use kms.AWS, which keystore.DecryptKMSFile uses for envelope
+// encryption.
 func Decrypt(text []byte, aKID, secretKey, region string) ([]byte, error) {
-	// Initialize a session
-	sess, err := session.NewSession(&aws.Config{
-		Region:      aws.String(region),
-		Credentials: credentials.NewStaticCredentials(aKID, secretKey, ""),
-	})
-
+	svc, err := kms.NewAWS(aKID, secretKey, region, "")
 	if err != nil {
 		log.Error("create kms session fail", "err", err)
 		return nil, err
 	}
-
-	// Create KMS service client
-	svc := kms.New(sess)
-
-	// Encrypted data
-	blob := []byte(text)
-
-	// Decrypt the data
-	result, err := svc.Decrypt(&kms.DecryptInput{CiphertextBlob: blob})
+	plaintext, err := svc.Decrypt(text)
 	if err != nil {
 		log.Error("kms decrypt fail", "err", err)
 		return nil, err
 	}
-
-	return result.Plaintext, nil
+	return plaintext, nil
 }
@@ -114,6 +114,26 @@ var (
 		Name:  "nostack",
 		Usage: "disable stack output",
 	}
+	WanchainFlag = cli.BoolFlag{
+		Name:  "wanchain",
+		Usage: "run with the Wanchain mainnet rules and POS context",
+	}
+	EpochFlag = cli.Uint64Flag{
+		Name:  "epoch",
+		Usage: "POS epoch the code runs in",
+	}
+	SlotFlag = cli.Uint64Flag{
+		Name:  "slot",
+		Usage: "POS slot the code runs in",
+	}
+	StakersFlag = cli.StringFlag{
+		Name:  "stakers",
+		Usage: "JSON file with staker records added to the prestate",
+	}
+	TxFlag = cli.StringFlag{
+		Name:  "tx",
+		Usage: "JSON file with a transaction to replay (as written by 'gwan transaction export')",
+	}
 )
 
 func init() {
@@ -138,6 +158,11 @@ func init() {
 		ReceiverFlag,
 		DisableMemoryFlag,
 		DisableStackFlag,
+		WanchainFlag,
+		EpochFlag,
+		SlotFlag,
+		StakersFlag,
+		TxFlag,
 	}
 	app.Commands = []cli.Command{
 		compileCommand,
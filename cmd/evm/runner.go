@@ -32,11 +32,13 @@ import (
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/core/vm/runtime"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
 	cli "gopkg.in/urfave/cli.v1"
 )
 
@@ -69,6 +71,23 @@ func readGenesis(genesisPath string) *core.Genesis {
 	return genesis
 }
 
+// readTransaction reads a transaction in the JSON format written by
+// 'gwan transaction export'.
+func readTransaction(txPath string) *types.Transaction {
+	data, err := ioutil.ReadFile(txPath)
+	if err != nil {
+		utils.Fatalf("Failed to read transaction file: %v", err)
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalJSON(data); err != nil {
+		utils.Fatalf("invalid transaction file: %v", err)
+	}
+	if types.IsPrivacyTransaction(tx.Txtype()) {
+		utils.Fatalf("Privacy transactions can not be replayed")
+	}
+	return tx
+}
+
 func runCmd(ctx *cli.Context) error {
 	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
 	glogger.Verbosity(log.Lvl(ctx.GlobalInt(VerbosityFlag.Name)))
@@ -83,6 +102,7 @@ func runCmd(ctx *cli.Context) error {
 		debugLogger *vm.StructLogger
 		statedb     *state.StateDB
 		chainConfig *params.ChainConfig
+		tx          *types.Transaction
 		sender      = common.StringToAddress("sender")
 		receiver    = common.StringToAddress("receiver")
 	)
@@ -102,14 +122,37 @@ func runCmd(ctx *cli.Context) error {
 		db, _ := ethdb.NewMemDatabase()
 		statedb, _ = state.New(common.Hash{}, state.NewDatabase(db))
 	}
+	if ctx.GlobalBool(WanchainFlag.Name) {
+		if chainConfig == nil {
+			chainConfig = params.WanchainChainConfig
+		}
+		// The POS helpers read the fork schedule from the global config.
+		posconfig.Cfg().ChainConfig = chainConfig
+	}
+	if ctx.GlobalString(StakersFlag.Name) != "" {
+		if err := loadStakers(statedb, ctx.GlobalString(StakersFlag.Name)); err != nil {
+			utils.Fatalf("Failed to load stakers: %v", err)
+		}
+	}
 	if ctx.GlobalString(SenderFlag.Name) != "" {
 		sender = common.HexToAddress(ctx.GlobalString(SenderFlag.Name))
 	}
-	statedb.CreateAccount(sender)
-
 	if ctx.GlobalString(ReceiverFlag.Name) != "" {
 		receiver = common.HexToAddress(ctx.GlobalString(ReceiverFlag.Name))
 	}
+	// The '--tx' flag overrides sender, receiver, input, value and gas
+	if ctx.GlobalString(TxFlag.Name) != "" {
+		tx = readTransaction(ctx.GlobalString(TxFlag.Name))
+		from, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), tx)
+		if err != nil {
+			utils.Fatalf("Failed to recover transaction sender: %v", err)
+		}
+		sender = from
+		if tx.To() != nil {
+			receiver = *tx.To()
+		}
+	}
+	statedb.CreateAccount(sender)
 
 	var (
 		code []byte
@@ -151,13 +194,24 @@ func runCmd(ctx *cli.Context) error {
 		code = common.Hex2Bytes(bin)
 	}
 
-	initialGas := ctx.GlobalUint64(GasFlag.Name)
+	var (
+		input      = common.Hex2Bytes(ctx.GlobalString(InputFlag.Name))
+		initialGas = ctx.GlobalUint64(GasFlag.Name)
+		gasPrice   = utils.GlobalBig(ctx, PriceFlag.Name)
+		value      = utils.GlobalBig(ctx, ValueFlag.Name)
+	)
+	if tx != nil {
+		input = tx.Data()
+		initialGas = tx.Gas().Uint64()
+		gasPrice = tx.GasPrice()
+		value = tx.Value()
+	}
 	runtimeConfig := runtime.Config{
 		Origin:   sender,
 		State:    statedb,
 		GasLimit: initialGas,
-		GasPrice: utils.GlobalBig(ctx, PriceFlag.Name),
-		Value:    utils.GlobalBig(ctx, ValueFlag.Name),
+		GasPrice: gasPrice,
+		Value:    value,
 		EVMConfig: vm.Config{
 			Tracer:             tracer,
 			Debug:              ctx.GlobalBool(DebugFlag.Name) || ctx.GlobalBool(MachineFlag.Name),
@@ -181,16 +235,19 @@ func runCmd(ctx *cli.Context) error {
 	if chainConfig != nil {
 		runtimeConfig.ChainConfig = chainConfig
 	}
+	if ctx.GlobalIsSet(EpochFlag.Name) || ctx.GlobalIsSet(SlotFlag.Name) {
+		runtime.SetEpochSlot(&runtimeConfig, ctx.GlobalUint64(EpochFlag.Name), ctx.GlobalUint64(SlotFlag.Name))
+	}
 	tstart := time.Now()
 	var leftOverGas uint64
-	if ctx.GlobalBool(CreateFlag.Name) {
-		input := append(code, common.Hex2Bytes(ctx.GlobalString(InputFlag.Name))...)
+	if ctx.GlobalBool(CreateFlag.Name) || (tx != nil && tx.To() == nil) {
+		input = append(code, input...)
 		ret, _, leftOverGas, err = runtime.Create(input, &runtimeConfig)
 	} else {
 		if len(code) > 0 {
 			statedb.SetCode(receiver, code)
 		}
-		ret, leftOverGas, err = runtime.Call(receiver, common.Hex2Bytes(ctx.GlobalString(InputFlag.Name)), &runtimeConfig)
+		ret, leftOverGas, err = runtime.Call(receiver, input, &runtimeConfig)
 	}
 	execTime := time.Since(tstart)
 
@@ -0,0 +1,121 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+)
+
+// stakerJSON is the JSON form of a vm.StakerInfo record read by --stakers.
+type stakerJSON struct {
+	Address        common.Address `json:"address"`
+	PubSec256      hexutil.Bytes  `json:"pubSec256"`
+	PubBn256       hexutil.Bytes  `json:"pubBn256"`
+	Amount         *hexutil.Big   `json:"amount"`
+	StakeAmount    *hexutil.Big   `json:"stakeAmount"`
+	LockEpochs     uint64         `json:"lockEpochs"`
+	NextLockEpochs uint64         `json:"nextLockEpochs"`
+	From           common.Address `json:"from"`
+	StakingEpoch   uint64         `json:"stakingEpoch"`
+	FeeRate        uint64         `json:"feeRate"`
+	Clients        []clientJSON   `json:"clients"`
+	Partners       []partnerJSON  `json:"partners"`
+}
+
+type clientJSON struct {
+	Address     common.Address `json:"address"`
+	Amount      *hexutil.Big   `json:"amount"`
+	StakeAmount *hexutil.Big   `json:"stakeAmount"`
+	QuitEpoch   uint64         `json:"quitEpoch"`
+}
+
+type partnerJSON struct {
+	Address      common.Address `json:"address"`
+	Amount       *hexutil.Big   `json:"amount"`
+	StakeAmount  *hexutil.Big   `json:"stakeAmount"`
+	Renewal      bool           `json:"renewal"`
+	LockEpochs   uint64         `json:"lockEpochs"`
+	StakingEpoch uint64         `json:"stakingEpoch"`
+}
+
+func (s *stakerJSON) toStakerInfo() *vm.StakerInfo {
+	info := &vm.StakerInfo{
+		Address:        s.Address,
+		PubSec256:      s.PubSec256,
+		PubBn256:       s.PubBn256,
+		Amount:         toBig(s.Amount),
+		StakeAmount:    toBig(s.StakeAmount),
+		LockEpochs:     s.LockEpochs,
+		NextLockEpochs: s.NextLockEpochs,
+		From:           s.From,
+		StakingEpoch:   s.StakingEpoch,
+		FeeRate:        s.FeeRate,
+	}
+	for _, c := range s.Clients {
+		info.Clients = append(info.Clients, vm.ClientInfo{
+			Address:     c.Address,
+			Amount:      toBig(c.Amount),
+			StakeAmount: toBig(c.StakeAmount),
+			QuitEpoch:   c.QuitEpoch,
+		})
+	}
+	for _, p := range s.Partners {
+		info.Partners = append(info.Partners, vm.PartnerInfo{
+			Address:      p.Address,
+			Amount:       toBig(p.Amount),
+			StakeAmount:  toBig(p.StakeAmount),
+			Renewal:      p.Renewal,
+			LockEpochs:   p.LockEpochs,
+			StakingEpoch: p.StakingEpoch,
+		})
+	}
+	return info
+}
+
+func toBig(b *hexutil.Big) *big.Int {
+	if b == nil {
+		return new(big.Int)
+	}
+	return b.ToInt()
+}
+
+// loadStakers reads a JSON list of staker records from path and stores them
+// in the staker list of statedb.
+func loadStakers(statedb *state.StateDB, path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var stakers []stakerJSON
+	if err := json.Unmarshal(data, &stakers); err != nil {
+		return err
+	}
+	for i := range stakers {
+		if err := vm.StoreStakerInfo(statedb, stakers[i].toStakerInfo()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -18,23 +18,40 @@ package main
 
 import (
 	"fmt"
-	"github.com/wanchain/go-wanchain/awskms"
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 	"io/ioutil"
+	"os"
 	"strings"
 
 	"github.com/wanchain/go-wanchain/accounts"
 	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/accounts/mnemonic"
 	"github.com/wanchain/go-wanchain/cmd/utils"
 	"github.com/wanchain/go-wanchain/console"
 	"github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/kms"
 	"github.com/wanchain/go-wanchain/log"
 	"gopkg.in/urfave/cli.v1"
 )
 
 var (
+	mnemonicFlag = cli.BoolFlag{
+		Name:  "mnemonic",
+		Usage: "Derive the account from a new BIP-39 mnemonic and print the mnemonic",
+	}
+	hdPathFlag = cli.StringFlag{
+		Name:  "hdpath",
+		Usage: "HD derivation path of the account key, the privacy key is derived from the same path with change component 1",
+		Value: accounts.DefaultBaseDerivationPath.String(),
+	}
+	hdCountFlag = cli.IntFlag{
+		Name:  "count",
+		Usage: "Number of consecutive accounts to restore, incrementing the last path component",
+		Value: 1,
+	}
+
 	walletCommand = cli.Command{
 		Name:      "wallet",
 		Usage:     "Manage Ethereum presale wallets",
@@ -115,6 +132,8 @@ Print a short summary of all accounts`,
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					mnemonicFlag,
+					hdPathFlag,
 				},
 				Description: `
     geth account new
@@ -129,6 +148,12 @@ For non-interactive use the passphrase can be specified with the --password flag
 
 Note, this is meant to be used for testing only, it is a bad idea to save your
 password to file or expose in any other way.
+
+With --mnemonic the keys are derived from a new 24 word BIP-39 mnemonic, which
+is printed once. Write it down: "gwan account restore" recreates the account
+from it. The account key is derived at --hdpath, m/44'/5718350'/0'/0/0 by
+default, the privacy key at the same path with change component 1, and the
+bn256 staking key from the privacy key.
 `,
 			},
 			{
@@ -191,6 +216,35 @@ Note:
 As you can directly copy your encrypted accounts to another ethereum instance,
 this import mechanism is not needed when you transfer an account between
 nodes.
+`,
+			},
+			{
+				Name:   "restore",
+				Usage:  "Restore accounts from a BIP-39 mnemonic",
+				Action: utils.MigrateFlags(accountRestore),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+					hdPathFlag,
+					hdCountFlag,
+				},
+				ArgsUsage: "[<mnemonicFile>]",
+				Description: `
+    gwan account restore [options] [<mnemonicfile>]
+
+Derives accounts from a BIP-39 mnemonic, read from <mnemonicfile> or prompted
+for, and imports them into the keystore. Prints the addresses.
+
+The first account is derived at --hdpath, m/44'/5718350'/0'/0/0 by default,
+and --count accounts are restored by incrementing its last component. The
+privacy key of each account is derived at the same path with change component
+1, e.g. m/44'/5718350'/0'/1/0.
+
+You are prompted for the optional BIP-39 passphrase of the mnemonic, unless
+the --password flag is given, in which case the mnemonic has no passphrase.
+Accounts already in the keystore are skipped.
 `,
 			},
 			{
@@ -206,36 +260,55 @@ Print public key of an address`,
 			},
 			{
 				Name:      "encrypt",
-				Usage:     "Encrypt an existing account with AWS KMS",
+				Usage:     "Encrypt an existing account with a key management service",
 				Action:    utils.MigrateFlags(accountEncrypt),
 				ArgsUsage: "<address>",
 				Flags: []cli.Flag{
 					utils.DataDirFlag,
 					utils.KeyStoreDirFlag,
+					utils.KMSProviderFlag,
+					utils.KMSVaultAddrFlag,
+					utils.KMSVaultMountFlag,
+					utils.KMSVaultKeyFlag,
+					utils.KMSLocalKeyFlag,
 				},
 				Description: `
-    gwan account encrypt <address>
+    gwan account encrypt [options] <address>
 
 Encrypt an existing account.
 
-The account will be encrypted by AWS KMS, and ciphertext will be saved into new file named as "<original-name>-cipher"
+The account will be encrypted by the key management service selected with
+--kms.provider, and ciphertext will be saved into new file named as "<original-name>-cipher"
+
+The keystore file is encrypted with a random data key, and only the data key is
+encrypted by the service (envelope encryption). Supported services are:
+
+    aws    AWS KMS, the credentials and key id are prompted for
+    vault  HashiCorp Vault transit engine key --kms.vault.key at --kms.vault.addr
+    local  master key in the file --kms.localkey, generated if missing
 `,
 			},
 			{
 				Name:      "decrypt",
-				Usage:     "Decrypt an existing AWS KMS encrypted account",
+				Usage:     "Decrypt an existing KMS encrypted account",
 				Action:    utils.MigrateFlags(accountDecrypt),
 				ArgsUsage: "<address>",
 				Flags: []cli.Flag{
 					utils.DataDirFlag,
 					utils.KeyStoreDirFlag,
+					utils.KMSProviderFlag,
+					utils.KMSVaultAddrFlag,
+					utils.KMSVaultMountFlag,
+					utils.KMSVaultKeyFlag,
+					utils.KMSLocalKeyFlag,
 				},
 				Description: `
-    gwan account decrypt <address>
+    gwan account decrypt [options] <address>
 
 Decrypt an existing account.
 
-The account will be decrypted by AWS KMS, and plaintext will be saved into new file named as "<original-name>"
+The account will be decrypted by the key management service selected with
+--kms.provider, and plaintext will be saved into new file named as "<original-name>"
 `,
 			},
 		},
@@ -283,41 +356,36 @@ func unlockAccount(ctx *cli.Context, ks *keystore.KeyStore, address string, i in
 	return accounts.Account{}, ""
 }
 
-func unlockAccountFromAwsKmsFile(ctx *cli.Context, ks *keystore.KeyStore, address string, i int, passwords []string) (accounts.Account, string) {
+// unlockAccountFromKMS decrypts the KMS encrypted key file of an account with
+// the key management service selected by the CLI flags, then tries unlocking
+// it a few times.
+func unlockAccountFromKMS(ctx *cli.Context, ks *keystore.KeyStore, address string, i int, passwords []string) (accounts.Account, string) {
 	account, err := utils.MakeAddress(ks, address)
 	if err != nil {
 		utils.Fatalf("Could not list accounts: %v", err)
 	}
 
-	a, err := ks.Find(account)
-	if err != nil {
-		utils.Fatalf("Could not find the account: %v", err)
-	}
-
-	var trials int
 	var keyjson []byte
-	for ; trials < 3; trials++ {
-		prompt := fmt.Sprintf("AWS KMS decrypting account %s | Attempt %d/%d", address, trials+1, 3)
-		keyNames := [3]string{"aKID", "secretKey", "region"}
-		kmsInfo, err := getAwsKmsSecretInfo(prompt, keyNames[:])
+	for trials := 0; trials < 3; trials++ {
+		prompt := fmt.Sprintf("KMS decrypting account %s | Attempt %d/%d", address, trials+1, 3)
+		service, err := makeKMS(ctx, prompt, false)
 		if err != nil {
-			utils.Fatalf("Failed to read input: %v", err)
+			utils.Fatalf("Failed to create key management service: %v", err)
 		}
-
-		keyjson, err = awskms.DecryptFileToBuffer(a.URL.Path, kmsInfo[0], kmsInfo[1], kmsInfo[2])
-		if err != nil {
-			fmt.Println("invalid AWS KMS info, decrypt keystore file fail: ", err)
-			continue
+		if keyjson, err = ks.DecryptKMSKey(account, service); err == nil {
+			break
+		}
+		fmt.Println("KMS decrypt keystore file fail: ", err)
+		// Only the AWS credentials are prompted for, retry with new ones
+		if ctx.GlobalString(utils.KMSProviderFlag.Name) != "aws" {
+			break
 		}
-
-		break
 	}
-
-	if trials == 3 || len(keyjson) == 0 {
-		utils.Fatalf("AWS KMS decrypt failed")
+	if len(keyjson) == 0 {
+		utils.Fatalf("KMS decrypt failed")
 	}
 
-	fmt.Println("AWS KMS decrypt successful")
+	fmt.Println("KMS decrypt successful")
 	for trials := 0; trials < 3; trials++ {
 		prompt := fmt.Sprintf("Unlocking account %s | Attempt %d/%d", address, trials+1, 3)
 		password := getPassPhrase(prompt, false, i, passwords)
@@ -399,6 +467,9 @@ func ambiguousAddrRecovery(ks *keystore.KeyStore, err *keystore.AmbiguousAddrErr
 
 // accountCreate creates a new account into the keystore defined by the CLI flags.
 func accountCreate(ctx *cli.Context) error {
+	if ctx.Bool(mnemonicFlag.Name) {
+		return accountCreateMnemonic(ctx)
+	}
 	stack, _ := makeConfigNode(ctx)
 	password := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
 
@@ -411,6 +482,131 @@ func accountCreate(ctx *cli.Context) error {
 	return nil
 }
 
+// accountCreateMnemonic creates a new account derived from a new mnemonic and
+// prints the mnemonic.
+func accountCreateMnemonic(ctx *cli.Context) error {
+	path := mnemonicPath(ctx)
+	entropy, err := mnemonic.NewEntropy(mnemonic.DefaultEntropyBits)
+	if err != nil {
+		utils.Fatalf("Failed to generate entropy: %v", err)
+	}
+	phrase, _ := mnemonic.NewMnemonic(entropy)
+
+	stack, _ := makeConfigNode(ctx)
+	passwords := utils.MakePasswordList(ctx)
+	seed := mnemonicSeed(phrase, true, passwords)
+	password := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, passwords)
+
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	account, err := importDerivedAccount(ks, seed, path, password)
+	if err != nil {
+		utils.Fatalf("Failed to create account: %v", err)
+	}
+	fmt.Printf("Address: {%s}\n", account.Address.Hex()[2:])
+	fmt.Printf("Path: %s\n", path)
+	fmt.Printf("Mnemonic: %s\n", phrase)
+	fmt.Println("Write the mnemonic down and keep it safe, it won't be shown again. Anyone who has it controls the account.")
+	return nil
+}
+
+// accountRestore derives accounts from a mnemonic into the keystore defined by
+// the CLI flags.
+func accountRestore(ctx *cli.Context) error {
+	path := mnemonicPath(ctx)
+	count := ctx.Int(hdCountFlag.Name)
+	if count < 1 {
+		utils.Fatalf("Invalid account count %d", count)
+	}
+	var phrase string
+	if file := ctx.Args().First(); file != "" {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			utils.Fatalf("Could not read mnemonic file: %v", err)
+		}
+		phrase = string(data)
+	} else {
+		input, err := console.Stdin.PromptPassword("Mnemonic: ")
+		if err != nil {
+			utils.Fatalf("Failed to read mnemonic: %v", err)
+		}
+		phrase = input
+	}
+	if err := mnemonic.Validate(phrase); err != nil {
+		utils.Fatalf("Invalid mnemonic: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	passwords := utils.MakePasswordList(ctx)
+	seed := mnemonicSeed(phrase, false, passwords)
+	password := getPassPhrase("Your restored accounts are locked with a password. Please give a password. Do not forget this password.", true, 0, passwords)
+
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	for i := 0; i < count; i++ {
+		account, err := importDerivedAccount(ks, seed, path, password)
+		if err != nil {
+			utils.Fatalf("Failed to restore account at %s: %v", path, err)
+		}
+		fmt.Printf("Address: {%s} %s\n", account.Address.Hex()[2:], path)
+		path[len(path)-1]++
+	}
+	return nil
+}
+
+// mnemonicPath returns the derivation path given with the --hdpath flag.
+func mnemonicPath(ctx *cli.Context) accounts.DerivationPath {
+	path, err := accounts.ParseDerivationPath(ctx.String(hdPathFlag.Name))
+	if err != nil {
+		utils.Fatalf("Invalid derivation path: %v", err)
+	}
+	if _, err := accounts.PrivacyKeyPath(path); err != nil {
+		utils.Fatalf("Invalid derivation path: %v", err)
+	}
+	return path
+}
+
+// mnemonicSeed derives the wallet seed of a mnemonic, prompting for its BIP-39
+// passphrase unless the account passwords are given in a file.
+func mnemonicSeed(phrase string, confirmation bool, passwords []string) []byte {
+	var passphrase string
+	if len(passwords) == 0 {
+		fmt.Println("The mnemonic can be protected by an optional BIP-39 passphrase, leave it empty for none.")
+		input, err := console.Stdin.PromptPassword("BIP-39 passphrase: ")
+		if err != nil {
+			utils.Fatalf("Failed to read BIP-39 passphrase: %v", err)
+		}
+		if confirmation && input != "" {
+			confirm, err := console.Stdin.PromptPassword("Repeat BIP-39 passphrase: ")
+			if err != nil {
+				utils.Fatalf("Failed to read BIP-39 passphrase confirmation: %v", err)
+			}
+			if input != confirm {
+				utils.Fatalf("BIP-39 passphrases do not match")
+			}
+		}
+		passphrase = input
+	}
+	seed, err := mnemonic.NewSeed(phrase, passphrase)
+	if err != nil {
+		utils.Fatalf("Invalid mnemonic: %v", err)
+	}
+	return seed
+}
+
+// importDerivedAccount derives the keys of the account at path from seed and
+// imports them into the keystore. Accounts already in the keystore are
+// returned as is.
+func importDerivedAccount(ks *keystore.KeyStore, seed []byte, path accounts.DerivationPath, password string) (accounts.Account, error) {
+	key, key2, err := accounts.DeriveKeyPair(seed, path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	if ks.HasAddress(address) {
+		fmt.Printf("Account {%s} already exists, skipping\n", address.Hex()[2:])
+		return accounts.Account{Address: address}, nil
+	}
+	return ks.ImportECDSA(key, key2, password)
+}
+
 // accountUpdate transitions an account from a previous format to the current
 // one, also providing the possibility to change the pass-phrase.
 func accountUpdate(ctx *cli.Context) error {
@@ -510,15 +706,14 @@ func showPublicKey(ctx *cli.Context) error {
 	return nil
 }
 
-// accountEncrypt encrypt an account using AWS KMS,
+// accountEncrypt encrypt an account using a key management service,
 // and save ciphertext into new file named as "<original-name>-cipher"
 func accountEncrypt(ctx *cli.Context) error {
 	if len(ctx.Args()) == 0 {
 		utils.Fatalf("No accounts specified to encrypt")
 	}
 
-	keyNames := [4]string{"aKID", "secretKey", "region", "keyId"}
-	keyVals, err := getAwsKmsSecretInfo("", keyNames[:])
+	service, err := makeKMS(ctx, "", true)
 	if err != nil {
 		return err
 	}
@@ -535,7 +730,7 @@ func accountEncrypt(ctx *cli.Context) error {
 		}
 
 		desFile := fa.URL.Path + keystore.AwsKMSCiphertextFileExt
-		err = awskms.EncryptFile(fa.URL.Path, desFile, keyVals[0], keyVals[1], keyVals[2], keyVals[3])
+		err = keystore.EncryptKMSFile(service, fa.URL.Path, desFile)
 		if err != nil {
 			return err
 		}
@@ -546,15 +741,14 @@ func accountEncrypt(ctx *cli.Context) error {
 	return nil
 }
 
-// accountDecrypt decrypt an account using AWS KMS,
+// accountDecrypt decrypt an account using a key management service,
 // and save ciphertext into new file named as "<original-name>-plain"
 func accountDecrypt(ctx *cli.Context) error {
 	if len(ctx.Args()) == 0 {
 		utils.Fatalf("No accounts specified to decrypt")
 	}
 
-	keyNames := [3]string{"aKID", "secretKey", "region"}
-	keyVals, err := getAwsKmsSecretInfo("", keyNames[:])
+	service, err := makeKMS(ctx, "", false)
 	if err != nil {
 		return err
 	}
@@ -578,7 +772,7 @@ func accountDecrypt(ctx *cli.Context) error {
 			desFile = fa.URL.Path + "-plain"
 		}
 
-		err = awskms.DecryptFile(fa.URL.Path, desFile, keyVals[0], keyVals[1], keyVals[2])
+		err = keystore.DecryptKMSFile(service, fa.URL.Path, desFile)
 		if err != nil {
 			return err
 		}
@@ -589,6 +783,52 @@ func accountDecrypt(ctx *cli.Context) error {
 	return nil
 }
 
+// makeKMS creates the key management service selected by the CLI flags,
+// prompting for the secrets it needs. The AWS master key id is only needed to
+// encrypt.
+func makeKMS(ctx *cli.Context, prompt string, encrypt bool) (keystore.KeyManagementService, error) {
+	switch provider := ctx.GlobalString(utils.KMSProviderFlag.Name); provider {
+	case "aws":
+		keyNames := []string{"aKID", "secretKey", "region"}
+		if encrypt {
+			keyNames = append(keyNames, "keyId")
+		}
+		keyVals, err := getAwsKmsSecretInfo(prompt, keyNames)
+		if err != nil {
+			return nil, err
+		}
+		keyVals = append(keyVals, "")
+		return kms.NewAWS(keyVals[0], keyVals[1], keyVals[2], keyVals[3])
+
+	case "vault":
+		addr, key := ctx.GlobalString(utils.KMSVaultAddrFlag.Name), ctx.GlobalString(utils.KMSVaultKeyFlag.Name)
+		if addr == "" || key == "" {
+			return nil, fmt.Errorf("--%s and --%s are required", utils.KMSVaultAddrFlag.Name, utils.KMSVaultKeyFlag.Name)
+		}
+		token := os.Getenv("VAULT_TOKEN")
+		if token == "" {
+			if prompt != "" {
+				fmt.Println(prompt)
+			}
+			var err error
+			if token, err = console.Stdin.PromptPassword("Vault token: "); err != nil {
+				return nil, err
+			}
+		}
+		return kms.NewVault(addr, ctx.GlobalString(utils.KMSVaultMountFlag.Name), key, token), nil
+
+	case "local":
+		file := ctx.GlobalString(utils.KMSLocalKeyFlag.Name)
+		if file == "" {
+			return nil, fmt.Errorf("--%s is required", utils.KMSLocalKeyFlag.Name)
+		}
+		return kms.NewLocal(file, encrypt)
+
+	default:
+		return nil, fmt.Errorf("unknown key management service %q", provider)
+	}
+}
+
 func getAwsKmsSecretInfo(notice string, items []string) ([]string, error) {
 	inputs := make([]string, len(items))
 	fmt.Println(notice)
@@ -18,6 +18,7 @@ package main
 
 import (
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -90,6 +91,41 @@ Fatal: Passphrases do not match
 `)
 }
 
+func TestAccountRestore(t *testing.T) {
+	datadir := tmpdir(t)
+	file := filepath.Join(datadir, "mnemonic.txt")
+	phrase := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about\n"
+	if err := ioutil.WriteFile(file, []byte(phrase), 0600); err != nil {
+		t.Fatal(err)
+	}
+	geth := runGeth(t, "account", "restore", "--datadir", datadir, "--lightkdf", "--count", "2", file)
+	defer geth.ExpectExit()
+	geth.Expect(`
+The mnemonic can be protected by an optional BIP-39 passphrase, leave it empty for none.
+!! Unsupported terminal, password will be echoed.
+BIP-39 passphrase: {{.InputLine ""}}
+Your restored accounts are locked with a password. Please give a password. Do not forget this password.
+Passphrase: {{.InputLine "foobar"}}
+Repeat passphrase: {{.InputLine "foobar"}}
+Address: {e5524b1E073bA7dAF47954f8692044d7cB24E0DD} m/44'/5718350'/0'/0/0
+Address: {75C9CC8a34020d7d9899127Dd45f3b66ceaBBECb} m/44'/5718350'/0'/0/1
+`)
+}
+
+func TestAccountNewMnemonic(t *testing.T) {
+	geth := runGeth(t, "account", "new", "--lightkdf", "--mnemonic")
+	defer geth.ExpectExit()
+	geth.Expect(`
+The mnemonic can be protected by an optional BIP-39 passphrase, leave it empty for none.
+!! Unsupported terminal, password will be echoed.
+BIP-39 passphrase: {{.InputLine ""}}
+Your new account is locked with a password. Please give a password. Do not forget this password.
+Passphrase: {{.InputLine "foobar"}}
+Repeat passphrase: {{.InputLine "foobar"}}
+`)
+	geth.ExpectRegexp(`Address: \{[0-9a-fA-F]{40}\}\nPath: m/44'/5718350'/0'/0/0\nMnemonic: ([a-z]+ ){23}[a-z]+\nWrite the mnemonic down.*\n`)
+}
+
 func TestAccountUpdate(t *testing.T) {
 	datadir := tmpDatadirWithKeystore(t)
 	geth := runGeth(t, "account", "update",
@@ -296,3 +332,42 @@ Fatal: None of the listed files could be unlocked.
 `)
 	geth.ExpectExit()
 }
+
+func TestAccountEncryptLocalKMS(t *testing.T) {
+	datadir := tmpDatadirWithKeystore(t)
+	keyfile := filepath.Join(datadir, "keystore", "UTC--2016-03-22T12-57-55.920751759Z--7ef5a6135f1fd6a02593eedc869c6d41d934aef8")
+	masterKey := filepath.Join(datadir, "master.key")
+	kmsFlags := []string{"--datadir", datadir, "--kms.provider", "local", "--kms.localkey", masterKey}
+
+	geth := runGeth(t, append([]string{"account", "encrypt"}, append(kmsFlags, "7ef5a6135f1fd6a02593eedc869c6d41d934aef8")...)...)
+	geth.ExpectRegexp(`begin encrypting\.\.\.
+encrypt account\( 7ef5a6135f1fd6a02593eedc869c6d41d934aef8 \) successfully into new keystore file :  .*-cipher
+`)
+	geth.ExpectExit()
+
+	// Replace the key file by the encrypted one and unlock it at startup
+	if err := os.Remove(keyfile); err != nil {
+		t.Fatal(err)
+	}
+	geth = runGeth(t, append([]string{"--kms", "--nat", "none", "--nodiscover", "--pluto",
+		"--unlock", "7ef5a6135f1fd6a02593eedc869c6d41d934aef8"}, append(kmsFlags, "js", "testdata/empty.js")...)...)
+	geth.Expect(`
+KMS decrypt successful
+Unlocking account 7ef5a6135f1fd6a02593eedc869c6d41d934aef8 | Attempt 1/3
+!! Unsupported terminal, password will be echoed.
+Passphrase: {{.InputLine "foobar"}}
+`)
+	geth.ExpectExit()
+	if !strings.Contains(geth.StderrText(), "Unlocked account") {
+		t.Errorf("stderr text does not contain %q", "Unlocked account")
+	}
+
+	geth = runGeth(t, append([]string{"account", "decrypt"}, append(kmsFlags, "7ef5a6135f1fd6a02593eedc869c6d41d934aef8")...)...)
+	geth.ExpectRegexp(`begin decrypting\.\.\.
+decrypt account\( 7ef5a6135f1fd6a02593eedc869c6d41d934aef8 \) successfully into new keystore file :  .*7ef5a6135f1fd6a02593eedc869c6d41d934aef8
+`)
+	geth.ExpectExit()
+	if _, err := os.Stat(keyfile); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -32,13 +32,13 @@ import (
 	"github.com/wanchain/go-wanchain/cmd/utils"
 	"github.com/wanchain/go-wanchain/contracts/release"
 	"github.com/wanchain/go-wanchain/eth"
+	"github.com/wanchain/go-wanchain/graphql"
 	"github.com/wanchain/go-wanchain/node"
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 
 	//"github.com/wanchain/go-wanchain/pos/posconfig"
 
-	"github.com/wanchain/go-wanchain/pos/posdb"
 	whisper "github.com/wanchain/go-wanchain/whisper/whisperv5"
 )
 
@@ -85,6 +85,7 @@ type gethConfig struct {
 	Shh      whisper.Config
 	Node     node.Config
 	Ethstats ethstatsConfig
+	GraphQL  graphql.Config
 }
 
 func loadConfig(file string, cfg *gethConfig) error {
@@ -117,9 +118,10 @@ func defaultNodeConfig() node.Config {
 func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	// Load defaults.
 	cfg := gethConfig{
-		Eth:  eth.DefaultConfig,
-		Shh:  whisper.DefaultConfig,
-		Node: defaultNodeConfig(),
+		Eth:     eth.DefaultConfig,
+		Shh:     whisper.DefaultConfig,
+		Node:    defaultNodeConfig(),
+		GraphQL: graphql.DefaultConfig,
 	}
 
 	// Load config file.
@@ -141,9 +143,8 @@ func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	}
 
 	utils.SetShhConfig(ctx, stack, &cfg.Shh)
+	utils.SetGraphQLConfig(ctx, &cfg.GraphQL)
 
-	//Init wanpos private db
-	posdb.DbInitAll(cfg.Node.DataDir)
 	posconfig.Init(&cfg.Node, cfg.Eth.NetworkId)
 
 	return stack, cfg
@@ -182,6 +183,11 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 		utils.RegisterEthStatsService(stack, cfg.Ethstats.URL)
 	}
 
+	// Add the GraphQL server if requested.
+	if ctx.GlobalBool(utils.GraphQLEnabledFlag.Name) {
+		utils.RegisterGraphQLService(stack, cfg.GraphQL)
+	}
+
 	// Add the release oracle service so it boots along with node.
 	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 		config := release.Config{
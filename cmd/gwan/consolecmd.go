@@ -17,6 +17,8 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"os/signal"
 	"strings"
@@ -48,7 +50,7 @@ See https://github.com/wanchain/go-wanchain/wiki/Javascipt-Console.`,
 		Name:      "attach",
 		Usage:     "Start an interactive JavaScript environment (connect to node)",
 		ArgsUsage: "[endpoint]",
-		Flags:     append(consoleFlags, utils.DataDirFlag),
+		Flags:     append(consoleFlags, utils.DataDirFlag, utils.RPCTokenFlag),
 		Category:  "CONSOLE COMMANDS",
 		Description: `
 The Geth console is an interactive shell for the JavaScript runtime environment
@@ -57,6 +59,26 @@ See https://github.com/wanchain/go-wanchain/wiki/Javascipt-Console.
 This command allows to open a console on a running geth node.`,
 	}
 
+	rpcTokenModulesFlag = cli.StringFlag{
+		Name:  "modules",
+		Usage: "Comma separated list of RPC modules the token grants (default = all enabled modules)",
+	}
+	rpcTokenTTLFlag = cli.DurationFlag{
+		Name:  "ttl",
+		Usage: "Time the token stays valid (default = never expires)",
+	}
+	rpcTokenCommand = cli.Command{
+		Action:   utils.MigrateFlags(makeRPCToken),
+		Name:     "rpctoken",
+		Usage:    "Issue a bearer token for the authenticated RPC endpoints",
+		Flags:    []cli.Flag{utils.DataDirFlag, utils.JWTSecretFlag, configFileFlag, rpcTokenModulesFlag, rpcTokenTTLFlag},
+		Category: "CONSOLE COMMANDS",
+		Description: `
+Signs a token with the JWT secret of the node, creating the secret if needed.
+The token is passed to "gwan attach --rpctoken" or sent by other clients in the
+Authorization header of their HTTP and websocket requests.`,
+	}
+
 	javascriptCommand = cli.Command{
 		Action:    utils.MigrateFlags(ephemeralConsole),
 		Name:      "js",
@@ -112,7 +134,11 @@ func localConsole(ctx *cli.Context) error {
 // console to it.
 func remoteConsole(ctx *cli.Context) error {
 	// Attach to a remotely running geth instance and start the JavaScript console
-	client, err := dialRPC(ctx.Args().First())
+	var options []rpc.ClientOption
+	if token := ctx.String(utils.RPCTokenFlag.Name); token != "" {
+		options = append(options, rpc.WithAuthToken(token))
+	}
+	client, err := dialRPC(ctx.Args().First(), options...)
 	if err != nil {
 		utils.Fatalf("Unable to attach to remote geth: %v", err)
 	}
@@ -144,7 +170,7 @@ func remoteConsole(ctx *cli.Context) error {
 // dialRPC returns a RPC client which connects to the given endpoint.
 // The check for empty endpoint implements the defaulting logic
 // for "geth attach" and "geth monitor" with no argument.
-func dialRPC(endpoint string) (*rpc.Client, error) {
+func dialRPC(endpoint string, options ...rpc.ClientOption) (*rpc.Client, error) {
 	if endpoint == "" {
 		endpoint = node.DefaultIPCEndpoint(clientIdentifier)
 	} else if strings.HasPrefix(endpoint, "rpc:") || strings.HasPrefix(endpoint, "ipc:") {
@@ -152,7 +178,37 @@ func dialRPC(endpoint string) (*rpc.Client, error) {
 		// these prefixes.
 		endpoint = endpoint[4:]
 	}
-	return rpc.Dial(endpoint)
+	return rpc.DialContext(context.Background(), endpoint, options...)
+}
+
+// makeRPCToken prints a bearer token signed with the JWT secret of the node.
+func makeRPCToken(ctx *cli.Context) error {
+	cfg := gethConfig{Node: defaultNodeConfig()}
+	if file := ctx.GlobalString(configFileFlag.Name); file != "" {
+		if err := loadConfig(file, &cfg); err != nil {
+			utils.Fatalf("%v", err)
+		}
+	}
+	utils.SetNodeConfig(ctx, &cfg.Node)
+
+	secret, err := cfg.Node.AuthSecret()
+	if err != nil {
+		utils.Fatalf("Failed to load the JWT secret: %v", err)
+	}
+	var modules []string
+	if list := ctx.String(rpcTokenModulesFlag.Name); list != "" {
+		for _, module := range strings.Split(list, ",") {
+			if module = strings.TrimSpace(module); module != "" {
+				modules = append(modules, module)
+			}
+		}
+	}
+	token, err := rpc.NewAuthToken(secret, modules, ctx.Duration(rpcTokenTTLFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to sign the token: %v", err)
+	}
+	fmt.Println(token)
+	return nil
 }
 
 // ephemeralConsole starts a new geth node, attaches an ephemeral JavaScript
@@ -31,7 +31,7 @@ import (
 )
 
 const (
-	ipcAPIs  = "admin:1.0 debug:1.0 eth:1.0 miner:1.0 net:1.0 personal:1.0 pos:1.0 rpc:1.0 shh:1.0 txpool:1.0 wan:1.0 web3:1.0"
+	ipcAPIs  = "admin:1.0 debug:1.0 eth:1.0 miner:1.0 net:1.0 personal:1.0 pos:1.0 rpc:1.0 shh:1.0 txpool:1.0 wan:1.0 wanexplorer:1.0 web3:1.0"
 	httpAPIs = "eth:1.0 net:1.0 pos:1.0 rpc:1.0 wan:1.0 web3:1.0"
 	wsAPIs = "eth:1.0 net:1.0 rpc:1.0 wan:1.0 web3:1.0"
 )
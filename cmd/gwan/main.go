@@ -106,6 +106,9 @@ var (
 		utils.DevModeFlag,
 		utils.TestnetFlag,
 		utils.FirstPos,
+		utils.OverrideApolloFlag,
+		utils.OverrideAugustFlag,
+		utils.OverrideMercuryFlag,
 		utils.DevInternalFlag,
 
 		utils.PlutoFlag,
@@ -127,6 +130,11 @@ var (
 		configFileFlag,
 
 		utils.AwsKmsFlag,
+		utils.KMSProviderFlag,
+		utils.KMSVaultAddrFlag,
+		utils.KMSVaultMountFlag,
+		utils.KMSVaultKeyFlag,
+		utils.KMSLocalKeyFlag,
 	}
 
 	rpcFlags = []cli.Flag{
@@ -139,6 +147,12 @@ var (
 		utils.WSPortFlag,
 		utils.WSApiFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.RPCAuthFlag,
+		utils.WSAuthFlag,
+		utils.JWTSecretFlag,
+		utils.GraphQLEnabledFlag,
+		utils.GraphQLMaxDepthFlag,
+		utils.GraphQLMaxCostFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 	}
@@ -181,6 +195,7 @@ func init() {
 		// See consolecmd.go:
 		consoleCommand,
 		attachCommand,
+		rpcTokenCommand,
 		javascriptCommand,
 		// See misccmd.go:
 		makecacheCommand,
@@ -259,8 +274,8 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 	unlocks := strings.Split(ctx.GlobalString(utils.UnlockedAccountFlag.Name), ",")
 	for i, account := range unlocks {
 		if trimmed := strings.TrimSpace(account); trimmed != "" {
-			if ctx.IsSet(utils.AwsKmsFlag.Name) {
-				unlockAccountFromAwsKmsFile(ctx, ks, trimmed, i, passwords)
+			if ctx.GlobalIsSet(utils.AwsKmsFlag.Name) {
+				unlockAccountFromKMS(ctx, ks, trimmed, i, passwords)
 			} else {
 				unlockAccount(ctx, ks, trimmed, i, passwords)
 			}
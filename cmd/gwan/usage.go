@@ -73,6 +73,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.NetworkIdFlag,
 			utils.TestnetFlag,
 			utils.FirstPos,
+			utils.OverrideApolloFlag,
+			utils.OverrideAugustFlag,
+			utils.OverrideMercuryFlag,
 			utils.DevInternalFlag,
 			utils.PlutoFlag,
 			utils.PlutoDevFlag,
@@ -124,6 +127,11 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.UnlockedAccountFlag,
 			utils.PasswordFileFlag,
 			utils.AwsKmsFlag,
+			utils.KMSProviderFlag,
+			utils.KMSVaultAddrFlag,
+			utils.KMSVaultMountFlag,
+			utils.KMSVaultKeyFlag,
+			utils.KMSLocalKeyFlag,
 		},
 	},
 	{
@@ -138,6 +146,12 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.WSPortFlag,
 			utils.WSApiFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.RPCAuthFlag,
+			utils.WSAuthFlag,
+			utils.JWTSecretFlag,
+			utils.GraphQLEnabledFlag,
+			utils.GraphQLMaxDepthFlag,
+			utils.GraphQLMaxCostFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
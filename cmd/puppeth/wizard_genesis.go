@@ -52,6 +52,9 @@ func (w *wizard) makeGenesis() {
 	fmt.Println("Which consensus engine to use? (default = clique)")
 	fmt.Println(" 1. Ethash - proof-of-work")
 	fmt.Println(" 2. Clique - proof-of-authority")
+	fmt.Println(" 3. Pluto - proof-of-stake")
+
+	var pos *posNetwork
 
 	choice := w.read()
 	switch {
@@ -98,6 +101,10 @@ func (w *wizard) makeGenesis() {
 			copy(genesis.ExtraData[32+i*common.AddressLength:], signer[:])
 		}
 
+	case choice == "3":
+		// In the case of pluto, generate the validators staking at genesis
+		pos = w.makePosGenesis(genesis)
+
 	default:
 		log.Crit("Invalid consensus engine choice", "choice", choice)
 	}
@@ -134,6 +141,13 @@ func (w *wizard) makeGenesis() {
 	}
 	genesis.ExtraData = append([]byte(extra), genesis.ExtraData[len(extra):]...)
 
+	// With the genesis complete, give the POS validators their node configs
+	if pos != nil {
+		if err := pos.write(genesis); err != nil {
+			log.Crit("Failed to write validator configs", "err", err)
+		}
+	}
+
 	// All done, store the genesis and flush to disk
 	w.conf.genesis = genesis
 }
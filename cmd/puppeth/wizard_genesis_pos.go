@@ -0,0 +1,206 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/crypto"
+	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/p2p/discover"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+// validatorScript is the shell script starting a POS validator node from its
+// own directory.
+var validatorScript = `#!/bin/sh
+cd "$(dirname "$0")"
+gwan --datadir . init ../genesis.json
+exec gwan --datadir . --networkid {{.NetworkID}} --port {{.Port}} --rpc --rpcport {{.RPCPort}} --nodiscover \
+	--etherbase {{.Address}} --unlock {{.Address}} --password password --mine --minerthreads 1
+`
+
+// posValidator is a validator generated for a new POS network.
+type posValidator struct {
+	dir     string            // Directory holding the datadir of the validator
+	key     *keystore.Key     // Staking key, also used to seal blocks
+	nodeKey *ecdsa.PrivateKey // P2P identity of the validator node
+	port    int               // P2P listening port of the validator node
+}
+
+// posNetwork collects the validators of a new POS network until the genesis
+// is complete and their node configs can be written.
+type posNetwork struct {
+	dir        string
+	passphrase string
+	validators []*posValidator
+}
+
+// makePosGenesis configures genesis to run the Pluto POS engine and generates
+// the keys of its validators, which are allocated as genesis stakers and make
+// up the epoch leader white list.
+func (w *wizard) makePosGenesis(genesis *core.Genesis) *posNetwork {
+	genesis.Difficulty = big.NewInt(1)
+	genesis.ExtraData = make([]byte, 32)
+	genesis.Config.ByzantiumBlock = big.NewInt(0)
+	genesis.Config.ApolloEpoch = big.NewInt(0)
+	genesis.Config.AugustEpoch = big.NewInt(0)
+	genesis.Config.MercuryEpoch = big.NewInt(0)
+	genesis.Config.Pluto = &params.PlutoConfig{
+		Period: params.PlutoChainConfig.Pluto.Period,
+		Epoch:  params.PlutoChainConfig.Pluto.Epoch,
+	}
+
+	fmt.Println()
+	fmt.Println("Which block should POS start at? (default = 1)")
+	genesis.Config.PosFirstBlock = big.NewInt(int64(w.readDefaultInt(1)))
+	if genesis.Config.PosFirstBlock.Sign() <= 0 {
+		log.Crit("POS has to start after the genesis block")
+	}
+	if genesis.Config.PosFirstBlock.Cmp(big.NewInt(1)) == 0 {
+		genesis.Config.IsPosActive = true
+	} else {
+		genesis.Config.Ethash = new(params.EthashConfig)
+	}
+
+	fmt.Println()
+	fmt.Println("How many validators should be generated? (default = 4)")
+	count := w.readDefaultInt(4)
+	if count <= 0 || count > len(posconfig.WhiteList) {
+		log.Crit("Invalid validator count", "count", count, "max", len(posconfig.WhiteList))
+	}
+
+	fmt.Println()
+	fmt.Println("How many Wan should each validator stake? (default = 100000)")
+	stake := new(big.Int).Mul(big.NewInt(int64(w.readDefaultInt(100000))), big.NewInt(params.Wan))
+	if stake.Sign() <= 0 {
+		log.Crit("Validators have to stake some Wan")
+	}
+
+	fmt.Println()
+	fmt.Println("Which P2P port should the first validator listen on? (default = 17717)")
+	port := w.readDefaultInt(17717)
+
+	network := &posNetwork{
+		dir: filepath.Join(filepath.Dir(w.conf.path), w.network+"-validators"),
+	}
+	fmt.Println()
+	fmt.Printf("Where should the validator configs be written? (default = %s)\n", network.dir)
+	network.dir = w.readDefaultString(network.dir)
+
+	fmt.Println()
+	fmt.Println("Which passphrase should protect the validator keys?")
+	network.passphrase = w.readPassword()
+
+	for i := 0; i < count; i++ {
+		validator, err := newPosValidator(filepath.Join(network.dir, fmt.Sprintf("validator%d", i)), network.passphrase, port+i)
+		if err != nil {
+			log.Crit("Failed to generate validator", "index", i, "err", err)
+		}
+		network.validators = append(network.validators, validator)
+
+		genesis.Alloc[validator.key.Address] = core.GenesisAccount{
+			Balance: new(big.Int).Lsh(big.NewInt(1), 256-7), // 2^256 / 128 (allow many pre-funds without balance overflows)
+			Staking: core.GenesisAccountStaking{
+				Amount:  stake,
+				S256pk:  crypto.FromECDSAPub(&validator.key.PrivateKey.PublicKey),
+				Bn256pk: new(bn256.G1).ScalarBaseMult(posconfig.GenerateD3byKey2(validator.key.PrivateKey2)).Marshal(),
+			},
+		}
+		genesis.Config.Pluto.WhiteList = append(genesis.Config.Pluto.WhiteList, hexutil.Encode(crypto.FromECDSAPub(&validator.key.PrivateKey.PublicKey)))
+		log.Info("Generated POS validator", "address", validator.key.Address)
+	}
+	return network
+}
+
+// newPosValidator generates the staking and node keys of a validator whose
+// datadir is dir.
+func newPosValidator(dir string, passphrase string, port int) (*posValidator, error) {
+	ks := keystore.NewKeyStore(filepath.Join(dir, "keystore"), keystore.StandardScryptN, keystore.StandardScryptP)
+	account, err := ks.NewAccount(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ks.GetKey(account, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	nodeKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	return &posValidator{dir: dir, key: key, nodeKey: nodeKey, port: port}, nil
+}
+
+// write stores genesis next to the validator datadirs and gives every
+// validator its node key, the static peer list of the network, its keystore
+// passphrase and a script starting the node.
+func (n *posNetwork) write(genesis *core.Genesis) error {
+	out, _ := json.MarshalIndent(genesis, "", "  ")
+	if err := ioutil.WriteFile(filepath.Join(n.dir, "genesis.json"), out, 0644); err != nil {
+		return err
+	}
+	peers := make([]string, len(n.validators))
+	for i, validator := range n.validators {
+		node := discover.NewNode(discover.PubkeyID(&validator.nodeKey.PublicKey), []byte{127, 0, 0, 1}, uint16(validator.port), uint16(validator.port))
+		peers[i] = node.String()
+	}
+	for i, validator := range n.validators {
+		instdir := filepath.Join(validator.dir, "gwan")
+		if err := os.MkdirAll(instdir, 0700); err != nil {
+			return err
+		}
+		if err := crypto.SaveECDSA(filepath.Join(instdir, "nodekey"), validator.nodeKey); err != nil {
+			return err
+		}
+		static := append(append([]string{}, peers[:i]...), peers[i+1:]...)
+		out, _ := json.MarshalIndent(static, "", "  ")
+		if err := ioutil.WriteFile(filepath.Join(instdir, "static-nodes.json"), out, 0644); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(validator.dir, "password"), []byte(n.passphrase), 0600); err != nil {
+			return err
+		}
+		script := new(bytes.Buffer)
+		template.Must(template.New("").Parse(validatorScript)).Execute(script, map[string]interface{}{
+			"NetworkID": genesis.Config.ChainId,
+			"Port":      validator.port,
+			"RPCPort":   8545 + i,
+			"Address":   common.ToHex(validator.key.Address[:]),
+		})
+		if err := ioutil.WriteFile(filepath.Join(validator.dir, "gwan.sh"), script.Bytes(), 0755); err != nil {
+			return err
+		}
+	}
+	log.Info("Wrote POS validator configs", "dir", n.dir)
+	return nil
+}
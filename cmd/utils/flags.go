@@ -44,6 +44,7 @@ import (
 	"github.com/wanchain/go-wanchain/eth/gasprice"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/ethstats"
+	"github.com/wanchain/go-wanchain/graphql"
 	"github.com/wanchain/go-wanchain/les"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/metrics"
@@ -134,6 +135,18 @@ var (
 		Name:  "firstPos",
 		Usage: "firstPos",
 	}
+	OverrideApolloFlag = cli.Uint64Flag{
+		Name:  "override.apollo",
+		Usage: "Manually specify the Apollo fork epoch, overriding the bundled setting",
+	}
+	OverrideAugustFlag = cli.Uint64Flag{
+		Name:  "override.august",
+		Usage: "Manually specify the August fork epoch, overriding the bundled setting",
+	}
+	OverrideMercuryFlag = cli.Uint64Flag{
+		Name:  "override.mercury",
+		Usage: "Manually specify the Mercury fork epoch, overriding the bundled setting",
+	}
 	TestnetFlag = cli.BoolFlag{
 		Name:  "testnet",
 		Usage: "Wan test network: pre-configured proof-of-work test network",
@@ -191,7 +204,7 @@ var (
 	defaultSyncMode = eth.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
+		Usage: `Blockchain sync mode ("fast", "full", "light" or "posfast")`,
 		Value: &defaultSyncMode,
 	}
 	NoStakingFlag = cli.BoolFlag{
@@ -423,6 +436,38 @@ var (
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	RPCAuthFlag = cli.BoolFlag{
+		Name:  "rpcauth",
+		Usage: "Require a JWT bearer token on the HTTP-RPC server",
+	}
+	WSAuthFlag = cli.BoolFlag{
+		Name:  "wsauth",
+		Usage: "Require a JWT bearer token on the WS-RPC server",
+	}
+	JWTSecretFlag = cli.StringFlag{
+		Name:  "jwtsecret",
+		Usage: "Path to the hex encoded secret the RPC tokens are signed with (default = inside the datadir)",
+		Value: "",
+	}
+	RPCTokenFlag = cli.StringFlag{
+		Name:  "rpctoken",
+		Usage: "JWT bearer token to authenticate to the HTTP or WS RPC endpoint with",
+		Value: "",
+	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL server on the HTTP-RPC endpoint, at /graphql",
+	}
+	GraphQLMaxDepthFlag = cli.IntFlag{
+		Name:  "graphql.maxdepth",
+		Usage: "Deepest nesting of fields a GraphQL query may select",
+		Value: graphql.DefaultConfig.MaxDepth,
+	}
+	GraphQLMaxCostFlag = cli.Int64Flag{
+		Name:  "graphql.maxcost",
+		Usage: "Most cost units a GraphQL query may spend, one unit roughly being a block read",
+		Value: graphql.DefaultConfig.MaxCost,
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -548,7 +593,29 @@ var (
 	}
 	AwsKmsFlag = cli.BoolFlag{
 		Name:  "kms",
-		Usage: "Enable AWS KMS encrypted keystore file",
+		Usage: "Enable KMS encrypted keystore file",
+	}
+	KMSProviderFlag = cli.StringFlag{
+		Name:  "kms.provider",
+		Usage: `Key management service of encrypted keystore files ("aws", "vault" or "local")`,
+		Value: "aws",
+	}
+	KMSVaultAddrFlag = cli.StringFlag{
+		Name:  "kms.vault.addr",
+		Usage: "Vault server address, the token is read from the VAULT_TOKEN environment variable or prompted for",
+	}
+	KMSVaultMountFlag = cli.StringFlag{
+		Name:  "kms.vault.mount",
+		Usage: "Mount path of the Vault transit secrets engine",
+		Value: "transit",
+	}
+	KMSVaultKeyFlag = cli.StringFlag{
+		Name:  "kms.vault.key",
+		Usage: "Name of the Vault transit key",
+	}
+	KMSLocalKeyFlag = cli.StringFlag{
+		Name:  "kms.localkey",
+		Usage: "Master key file of the local key management service, created by account encrypt if missing",
 	}
 )
 
@@ -718,6 +785,12 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(RPCApiFlag.Name) {
 		cfg.HTTPModules = splitAndTrim(ctx.GlobalString(RPCApiFlag.Name))
 	}
+	if ctx.GlobalBool(RPCAuthFlag.Name) {
+		cfg.HTTPAuth = true
+	}
+	if ctx.GlobalIsSet(JWTSecretFlag.Name) {
+		cfg.JWTSecret = ctx.GlobalString(JWTSecretFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -739,6 +812,9 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(WSApiFlag.Name) {
 		cfg.WSModules = splitAndTrim(ctx.GlobalString(WSApiFlag.Name))
 	}
+	if ctx.GlobalBool(WSAuthFlag.Name) {
+		cfg.WSAuth = true
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -991,6 +1067,16 @@ func SetShhConfig(ctx *cli.Context, stack *node.Node, cfg *whisper.Config) {
 	}
 }
 
+// SetGraphQLConfig applies GraphQL related command line flags to the config.
+func SetGraphQLConfig(ctx *cli.Context, cfg *graphql.Config) {
+	if ctx.GlobalIsSet(GraphQLMaxDepthFlag.Name) {
+		cfg.MaxDepth = ctx.GlobalInt(GraphQLMaxDepthFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLMaxCostFlag.Name) {
+		cfg.MaxCost = ctx.GlobalInt64(GraphQLMaxCostFlag.Name)
+	}
+}
+
 // SetEthConfig applies eth-related command line flags to the config.
 func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	// Avoid conflicting network flags
@@ -1047,6 +1133,15 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	if ctx.GlobalIsSet(OverrideApolloFlag.Name) {
+		cfg.OverrideApollo = new(big.Int).SetUint64(ctx.GlobalUint64(OverrideApolloFlag.Name))
+	}
+	if ctx.GlobalIsSet(OverrideAugustFlag.Name) {
+		cfg.OverrideAugust = new(big.Int).SetUint64(ctx.GlobalUint64(OverrideAugustFlag.Name))
+	}
+	if ctx.GlobalIsSet(OverrideMercuryFlag.Name) {
+		cfg.OverrideMercury = new(big.Int).SetUint64(ctx.GlobalUint64(OverrideMercuryFlag.Name))
+	}
 	if ctx.GlobalIsSet(FirstPos.Name) {
 		params.WanchainChainConfig.PosFirstBlock = new(big.Int).SetInt64(ctx.GlobalInt64(FirstPos.Name))
 	}
@@ -1128,6 +1223,24 @@ func RegisterShhService(stack *node.Node, cfg *whisper.Config) {
 	}
 }
 
+// RegisterGraphQLService adds the GraphQL server to the given node, resolving
+// queries against the full or light client service.
+func RegisterGraphQLService(stack *node.Node, cfg graphql.Config) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var ethServ *eth.Ethereum
+		if err := ctx.Service(&ethServ); err == nil {
+			return graphql.New(ethServ.ApiBackend, cfg)
+		}
+		var lesServ *les.LightEthereum
+		if err := ctx.Service(&lesServ); err != nil {
+			return nil, err
+		}
+		return graphql.New(lesServ.ApiBackend, cfg)
+	}); err != nil {
+		Fatalf("Failed to register the GraphQL service: %v", err)
+	}
+}
+
 // RegisterEthStatsService configures the Ethereum Stats daemon and adds it to
 // th egiven node.
 func RegisterEthStatsService(stack *node.Node, url string) {
@@ -1187,12 +1300,29 @@ func MakeGenesis(ctx *cli.Context) *core.Genesis {
 	return genesis
 }
 
+// makeNetworkId returns the network id selected by the command line flags,
+// as SetEthConfig does.
+func makeNetworkId(ctx *cli.Context) uint64 {
+	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
+		return ctx.GlobalUint64(NetworkIdFlag.Name)
+	}
+	switch {
+	case ctx.GlobalBool(TestnetFlag.Name):
+		return 3
+	case ctx.GlobalBool(DevInternalFlag.Name):
+		return 4
+	case ctx.GlobalBool(PlutoFlag.Name), ctx.GlobalIsSet(PlutoDevFlag.Name):
+		return 6
+	}
+	return eth.DefaultConfig.NetworkId
+}
+
 // MakeChain creates a chain manager from set command line flags.
 func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chainDb ethdb.Database) {
 	var err error
 	chainDb = MakeChainDatabase(ctx, stack)
 
-	config, _, err := core.SetupGenesisBlock(chainDb, MakeGenesis(ctx))
+	config, _, err := core.SetupGenesisBlockWithNetwork(chainDb, MakeGenesis(ctx), makeNetworkId(ctx))
 	if err != nil {
 		Fatalf("%v", err)
 	}
@@ -29,7 +29,6 @@ import (
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/math"
 	"github.com/wanchain/go-wanchain/consensus"
-	"github.com/wanchain/go-wanchain/pos/posconfig"
 	"github.com/wanchain/go-wanchain/pos/util"
 
 	//"github.com/wanchain/go-wanchain/consensus/misc"
@@ -396,7 +395,7 @@ func (ethash *Ethash) verifyHeader(chain consensus.ChainReader, header *types.He
 	}
 
 	epId, _ := util.CalEpochSlotID(parent.Time.Uint64())
-	if epId >= posconfig.ApolloEpochID {
+	if chain.Config().IsApollo(epId) {
 		params.GasLimitBoundDivisor = params.GasLimitBoundDivisorNew
 	}
 
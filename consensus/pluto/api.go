@@ -18,8 +18,10 @@ package pluto
 
 import (
 	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/consensus"
 	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/rpc"
 )
 
@@ -117,3 +119,13 @@ func (api *API) Discard(address common.Address) {
 
 	delete(api.pluto.proposals, address)
 }
+
+// GetLeaderCertificate returns the rlp encoded leader certificate of an epoch,
+// which lets a client check the headers of the epoch without the chain state.
+func (api *API) GetLeaderCertificate(epochID uint64) (hexutil.Bytes, error) {
+	cert, err := api.pluto.slotLeaderSelection().GetLeaderCertificate(epochID)
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(cert)
+}
@@ -47,6 +47,7 @@ import (
 	"github.com/wanchain/go-wanchain/params"
 	"github.com/wanchain/go-wanchain/pos/incentive"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"github.com/wanchain/go-wanchain/pos/slotleader"
 	posUtil "github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
@@ -142,6 +143,10 @@ var (
 	// on an instant chain (0 second period). It's important to refuse these as the
 	// block reward is zero, so an empty block just bloats the chain... fast.
 	errWaitTransactions = errors.New("waiting for transactions")
+
+	// errCertificateEpoch is returned if a header is checked against the leader
+	// certificate of another epoch.
+	errCertificateEpoch = errors.New("leader certificate of another epoch")
 )
 
 // SignerFn is a signer callback function to request a hash to be signed by a
@@ -213,6 +218,15 @@ func ecrecover(header *types.Header, sigcache *lru.ARCCache) (common.Address, er
 	return signer, nil
 }
 
+// sealSigners caches the signers recovered by Ecrecover.
+var sealSigners, _ = lru.NewARC(inmemorySignatures)
+
+// Ecrecover extracts the address of the slot leader that sealed header, for
+// callers checking pluto headers without an engine instance.
+func Ecrecover(header *types.Header) (common.Address, error) {
+	return ecrecover(header, sealSigners)
+}
+
 // Pluto is the proof-of-authority consensus engine proposed to support the
 // Ethereum testnet following the Ropsten attacks.
 type Pluto struct {
@@ -229,6 +243,8 @@ type Pluto struct {
 	lock   sync.RWMutex   // Protects the signer fields
 
 	key *keystore.Key // Unlocked key
+
+	posCtx *posctx.PosContext // POS services of the chain, package-level ones if nil
 }
 
 // New creates a Pluto proof-of-authority consensus engine with the initial
@@ -252,6 +268,53 @@ func New(config *params.PlutoConfig, db ethdb.Database) *Pluto {
 	}
 }
 
+// SetPosContext makes the engine use the POS services of ctx instead of the
+// package-level ones.
+func (c *Pluto) SetPosContext(ctx *posctx.PosContext) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.posCtx = ctx
+}
+
+func (c *Pluto) slotLeaderSelection() *slotleader.SLS {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	if c.posCtx != nil && c.posCtx.SLS != nil {
+		return c.posCtx.SLS
+	}
+	return slotleader.GetSlotLeaderSelection()
+}
+
+func (c *Pluto) posContext() *posctx.PosContext {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.posCtx
+}
+
+func (c *Pluto) firstEpochID() uint64 {
+	if ctx := c.posContext(); ctx != nil {
+		return ctx.Config.FirstEpochID()
+	}
+	return posconfig.FirstEpochId
+}
+
+func (c *Pluto) stakeOutRun(state *state.StateDB, epochID uint64) bool {
+	if ctx := c.posContext(); ctx != nil && ctx.Epocher != nil {
+		return ctx.Epocher.StakeOutRun(state, epochID)
+	}
+	return epochLeader.StakeOutRun(state, epochID)
+}
+
+func (c *Pluto) runIncentive(chain consensus.ChainReader, state *state.StateDB, epochID uint64) bool {
+	if ctx := c.posContext(); ctx != nil && ctx.Incentive != nil {
+		return incentive.RunWithHooks(ctx.Incentive, chain, state, epochID)
+	}
+	return incentive.Run(chain, state, epochID)
+}
+
 // Author implements consensus.Engine, returning the Ethereum address recovered
 // from the signature in the header's extra-data section.
 func (c *Pluto) Author(header *types.Header) (common.Address, error) {
@@ -551,7 +614,7 @@ func (c *Pluto) verifyProof(block *types.Block, header *types.Header, parents []
 
 	epochID, slotID := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
 
-	s := slotleader.GetSlotLeaderSelection()
+	s := c.slotLeaderSelection()
 
 	proof, proofMeg, err := s.GetInfoFromHeadExtra(epochID, header.Extra[:len(header.Extra)-extraSeal])
 
@@ -593,7 +656,7 @@ func (c *Pluto) verifySeal(chain consensus.ChainReader, header *types.Header, pa
 		return errors.New("epochId or slotid do not match")
 	}
 
-	s := slotleader.GetSlotLeaderSelection()
+	s := c.slotLeaderSelection()
 
 	if len(header.Extra) > 512 { // proof,proofmsg,sign
 		log.SyslogErr("Header extra info length is too long")
@@ -672,6 +735,42 @@ func (c *Pluto) verifySeal(chain consensus.ChainReader, header *types.Header, pa
 	return nil
 }
 
+// VerifyHeaderWithCertificate checks the seal and the slot leader proof of a
+// header against a leader certificate of its epoch, without reading any state.
+// The certificate must have been verified against a trusted anchor header.
+func (c *Pluto) VerifyHeaderWithCertificate(header *types.Header, cert *slotleader.LeaderCertificate) error {
+	if header.Number.Uint64() == 0 {
+		return errUnknownBlock
+	}
+	if header.Difficulty.Cmp(new(big.Int).SetUint64(math.MaxUint64)) > 0 {
+		return errInvalidDifficulty
+	}
+	epidTime, slIdTime := posUtil.CalEpochSlotID(header.Time.Uint64())
+	epochID, slotID := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
+	if epidTime != epochID || slIdTime != slotID {
+		return errInvalidDifficulty
+	}
+	if epochID != cert.EpochID {
+		return errCertificateEpoch
+	}
+	if len(header.Extra) > 512 || len(header.Extra) <= extraSeal {
+		return errUnauthorized
+	}
+
+	proof, proofMeg, err := slotleader.ParseHeadExtra(epochID, header.Extra[:len(header.Extra)-extraSeal])
+	if err != nil {
+		return err
+	}
+	signer, err := ecrecover(header, c.signatures)
+	if err != nil {
+		return err
+	}
+	if signer != crypto.PubkeyToAddress(*proofMeg[0]) {
+		return errUnauthorized
+	}
+	return cert.VerifySlotProof(slotID, proof, proofMeg)
+}
+
 // Prepare implements consensus.Engine, preparing all the consensus fields of the
 // header for running the transactions on top.
 func (c *Pluto) Prepare(chain consensus.ChainReader, header *types.Header, mining bool) error {
@@ -761,10 +860,11 @@ func (c *Pluto) Prepare(chain consensus.ChainReader, header *types.Header, minin
 // rewards given, and returns the final block.
 func (c *Pluto) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
 	epochID, slotID := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
-	if posconfig.FirstEpochId != 0 && epochID > posconfig.FirstEpochId+2 && epochID >= posconfig.IncentiveDelayEpochs && slotID > posconfig.IncentiveStartStage {
+	firstEpochID := c.firstEpochID()
+	if firstEpochID != 0 && epochID > firstEpochID+2 && epochID >= posconfig.IncentiveDelayEpochs && slotID > posconfig.IncentiveStartStage {
 		log.Debug("--------Incentive Start--------", "number", header.Number.String(), "epochID", epochID)
 		snap := state.Snapshot()
-		if !incentive.Run(chain, state, epochID-posconfig.IncentiveDelayEpochs) {
+		if !c.runIncentive(chain, state, epochID-posconfig.IncentiveDelayEpochs) {
 			log.SyslogAlert("********Incentive Failed********", "number", header.Number.String(), "epochID", epochID)
 			state.RevertToSnapshot(snap)
 		} else {
@@ -772,7 +872,7 @@ func (c *Pluto) Finalize(chain consensus.ChainReader, header *types.Header, stat
 		}
 
 		snap = state.Snapshot()
-		if !epochLeader.StakeOutRun(state, epochID) {
+		if !c.stakeOutRun(state, epochID) {
 			log.SyslogErr("Stake Out failed.")
 			state.RevertToSnapshot(snap)
 		}
@@ -835,7 +935,7 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 		return nil, nil
 	}
 	localPublicKey := hex.EncodeToString(crypto.FromECDSAPub(&c.key.PrivateKey.PublicKey))
-	leaderPub, err := slotleader.GetSlotLeaderSelection().GetSlotLeader(epochId, slotId)
+	leaderPub, err := c.slotLeaderSelection().GetSlotLeader(epochId, slotId)
 	if err != nil {
 		return nil, err
 	}
@@ -863,7 +963,7 @@ func (c *Pluto) Seal(chain consensus.ChainReader, block *types.Block, stop <-cha
 	header.Difficulty.SetUint64(epochSlotId)
 	header.Coinbase = signer
 
-	s := slotleader.GetSlotLeaderSelection()
+	s := c.slotLeaderSelection()
 	buf, err := s.PackSlotProof(epochId, slotId, key.PrivateKey)
 	if err != nil {
 		log.Warn("PackSlotProof failed in Seal", "epochID", epochId, "slotID", slotId, "error", err.Error())
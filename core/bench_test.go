@@ -111,7 +111,7 @@ func init() {
 func genTxRing(naccounts int) func(int, *BlockGen) {
 	from := 0
 	return func(i int, gen *BlockGen) {
-		gas := CalcGasLimit(gen.PrevBlock(i - 1))
+		gas := CalcGasLimit(params.TestChainConfig, gen.PrevBlock(i - 1))
 		for {
 			gas.Sub(gas, bigTxGas)
 			if gas.Cmp(bigTxGas) < 0 {
@@ -85,7 +85,8 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 		epIDOld, slotIDOld := util.CalEpochSlotID(parentBlockHeader.Time.Uint64())
 		flatSlotIdNew := epIDNew*posconfig.SlotCount + slotIDNew
 		flatSlotIdOld := epIDOld*posconfig.SlotCount + slotIDOld
-		if epIDNew > posconfig.ApolloEpochID {
+		// a block per slot is enforced from the epoch after Apollo
+		if epIDNew > 0 && v.config.IsApollo(epIDNew-1) {
 			if flatSlotIdNew <= flatSlotIdOld {
 				return fmt.Errorf("Invalid slot in chain.")
 			}
@@ -130,9 +131,9 @@ func (v *BlockValidator) ValidateState(block, parent *types.Block, statedb *stat
 // CalcGasLimit computes the gas limit of the next block after parent.
 // The result may be modified by the caller.
 // This is miner strategy, not consensus protocol.
-func CalcGasLimit(parent *types.Block) *big.Int {
+func CalcGasLimit(config *params.ChainConfig, parent *types.Block) *big.Int {
 	epId, _ := util.CalEpochSlotID(parent.Header().Time.Uint64())
-	if epId >= posconfig.ApolloEpochID {
+	if config.IsApollo(epId) {
 		params.GasLimitBoundDivisor = params.GasLimitBoundDivisorNew
 	}
 
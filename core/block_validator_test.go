@@ -229,7 +229,7 @@ func TestCalcGasLimit(t *testing.T) {
 
 	block := types.NewBlock(header, nil, nil, nil)
 
-	gas := CalcGasLimit(block)
+	gas := CalcGasLimit(params.TestChainConfig, block)
 
 	fmt.Println("gasLimitNew:", gas.Uint64())
 
@@ -239,7 +239,7 @@ func TestCalcGasLimit(t *testing.T) {
 
 		block = types.NewBlock(header, nil, nil, nil)
 
-		gas = CalcGasLimit(block)
+		gas = CalcGasLimit(params.TestChainConfig, block)
 
 		fmt.Println(i, "gasLimitNew:", gas.Uint64())
 
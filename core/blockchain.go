@@ -108,6 +108,7 @@ type BlockChain struct {
 	currentFastBlock *types.Block // Current head of the fast-sync chain (may be above the block chain!)
 
 	stateCache   state.Database // State database to reuse between imports (contains state cache)
+	otaIndex     *OTAIndex      // OTA storage index following the canonical head
 	bodyCache    *lru.Cache     // Cache for the most recent block bodies
 	bodyRLPCache *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
 	blockCache   *lru.Cache     // Cache for the most recent entire blocks
@@ -131,6 +132,7 @@ type BlockChain struct {
 	CurrentEpochId int64
 
 	slotValidator Validator
+	posCtx        vm.PosContext // POS services of the chain, package-level ones if nil
 
 	checkCQStartSlot uint64 //use this field to check restart status,the value will be 0:init restarting, bigger than 0:in restarting,minus:restart scucess
 	checkCQBlk       *types.Block
@@ -178,6 +180,12 @@ func NewBlockChain(chainDb ethdb.Database, config *params.ChainConfig, engine co
 		panic("failed to create chain quality cache")
 	}
 	bc.cqCache = c
+	bc.otaIndex = NewOTAIndex(bc.stateCache, func() common.Hash {
+		if head := bc.CurrentBlock(); head != nil {
+			return head.Root()
+		}
+		return common.Hash{}
+	})
 
 	if len(posEngines) > 0 {
 		bc.posEngine = posEngines[0]
@@ -242,6 +250,12 @@ func NewBlockChain(chainDb ethdb.Database, config *params.ChainConfig, engine co
 	bc.checkCQStartSlot = epid*posconfig.SlotCount + slid
 
 	go bc.update()
+	bc.wg.Add(1)
+	go func() {
+		defer bc.wg.Done()
+		bc.otaIndex.follow(bc.quit)
+	}()
+	bc.otaIndex.HeadChanged()
 	return bc, nil
 }
 
@@ -474,6 +488,11 @@ func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
 	return state.New(root, bc.stateCache)
 }
 
+// OTAIndex returns the OTA storage index kept alongside the canonical chain.
+func (bc *BlockChain) OTAIndex() *OTAIndex {
+	return bc.otaIndex
+}
+
 // Reset purges the entire blockchain, restoring it to its genesis state.
 func (bc *BlockChain) Reset() error {
 	return bc.ResetWithGenesisBlock(bc.genesisBlock)
@@ -553,6 +572,7 @@ func (bc *BlockChain) insert(block *types.Block) {
 		log.Crit("Failed to insert head block hash", "err", err)
 	}
 	bc.currentBlock = block
+	bc.otaIndex.HeadChanged()
 
 	// If the block is better than out head or is on a different chain, force update heads
 	if updateHeads {
@@ -963,7 +983,7 @@ func (bc *BlockChain) ChainQuality(epochid uint64, slotid uint64) (uint64, error
 	blkSlots := blkEpid*posconfig.SlotCount + blkSlid
 	expSlots := epochid*posconfig.SlotCount + slotid
 
-	if expSlots >= (blkSlots+posconfig.SlotSecurityParam) || (epochid == posconfig.FirstEpochId && slotid == 0) {
+	if expSlots >= (blkSlots+posconfig.SlotSecurityParam) || (epochid == bc.PosConfig().FirstEpochID() && slotid == 0) {
 		return 0, errors.New("wrong epoid or slotid")
 	}
 
@@ -1023,7 +1043,7 @@ func (bc *BlockChain) WriteBlockAndState(block *types.Block, receipts []*types.R
 
 
 
-	if bc.config.IsPosActive && epid > posconfig.FirstEpochId+1  {
+	if bc.config.IsPosActive && epid > bc.PosConfig().FirstEpochID()+1  {
 
 		//res, _ := bc.ChainRestartStatus()
 
@@ -1384,9 +1404,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		// Create a new statedb using the parent block and report an
 		// error if it fails.
 
-		if block.NumberU64() == posconfig.Pow2PosUpgradeBlockNumber {
+		if bc.config.PosFirstBlock != nil && block.NumberU64() == bc.config.PosFirstBlock.Uint64() {
 			epochId, _ := posUtil.CalEpSlbyTd(block.Difficulty().Uint64())
-			posconfig.FirstEpochId = epochId
+			bc.PosConfig().SetFirstEpochID(epochId)
 		}
 
 		var parent *types.Block
@@ -1399,6 +1419,10 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		if err != nil {
 			return i, events, coalescedLogs, err
 		}
+		// Verify the ring signatures of the block as a batch, skipping those
+		// verified by the pool already.
+		verifyRingSigns(types.MakeSigner(bc.config, block.Number()), block.Transactions())
+
 		// Process block using the parent state as reference point.
 		receipts, logs, usedGas, err := bc.processor.Process(block, state, bc.vmConfig)
 		if err != nil {
@@ -1601,7 +1625,7 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	go bc.reorgFeed.Send(ReorgEvent{epochId, slotid, uint64(len(oldChain))})
 
 	//if reorg length is bigger than k,do not let reorg happen
-	if posconfig.FirstEpochId != 0 && uint(newChainLen) > posconfig.Cfg().K {
+	if bc.PosConfig().FirstEpochID() != 0 && uint(newChainLen) > bc.PosConfig().K {
 		log.Error("Impossible reorg because reorg length is bigger than K setting", "reorg length", newChainLen, "old chain rollback lenght", len(oldChain))
 		return ErrSecurityViolated
 
@@ -1861,6 +1885,40 @@ func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscript
 }
 
 
+// SetPosContext makes the chain, its transactions and the EVMs running them
+// use the POS services of ctx instead of the package-level ones.
+func (bc *BlockChain) SetPosContext(ctx vm.PosContext) {
+	bc.posCtx = ctx
+}
+
+// PosContext returns the POS services set by SetPosContext, nil if none.
+func (bc *BlockChain) PosContext() vm.PosContext {
+	if bc == nil {
+		// ApplyTransaction may run without a chain, see the chain makers
+		return nil
+	}
+	return bc.posCtx
+}
+
+// PosConfig returns the POS configuration of the chain.
+func (bc *BlockChain) PosConfig() *posconfig.Config {
+	if bc.posCtx == nil {
+		return posconfig.Cfg()
+	}
+	return bc.posCtx.PosConfig()
+}
+
+func (bc *BlockChain) reorgDb() *posdb.Db {
+	if bc.posCtx != nil {
+		return bc.posCtx.LocalDb(posconfig.ReorgLocalDB)
+	}
+	reOrgDb := posdb.GetDbByName(posconfig.ReorgLocalDB)
+	if reOrgDb == nil {
+		reOrgDb = posdb.NewDb(posconfig.ReorgLocalDB)
+	}
+	return reOrgDb
+}
+
 func (bc *BlockChain) SetSlotValidator(validator Validator) {
 	bc.slotValidator = validator
 }
@@ -1974,10 +2032,7 @@ func (bc *BlockChain) checkRestarting(chain types.Blocks) ([]uint, error) {
 }
 func (bc *BlockChain) updateReOrg(epochId uint64, slotid uint64, length uint64) {
 
-	reOrgDb := posdb.GetDbByName(posconfig.ReorgLocalDB)
-	if reOrgDb == nil {
-		reOrgDb = posdb.NewDb(posconfig.ReorgLocalDB)
-	}
+	reOrgDb := bc.reorgDb()
 
 	numberBytes, _ := reOrgDb.Get(epochId, "reorgNumber")
 
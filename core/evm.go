@@ -35,6 +35,21 @@ type ChainContext interface {
 	GetHeader(common.Hash, uint64) *types.Header
 }
 
+// posChainContext is implemented by the chains running their own POS
+// services, see BlockChain.SetPosContext.
+type posChainContext interface {
+	PosContext() vm.PosContext
+}
+
+// posContextOf returns the POS services of chain, nil for the package-level
+// ones.
+func posContextOf(chain interface{}) vm.PosContext {
+	if c, ok := chain.(posChainContext); ok {
+		return c.PosContext()
+	}
+	return nil
+}
+
 // NewEVMContext creates a new context for use in the EVM.
 func NewEVMContext(msg Message, header *types.Header, chain ChainContext, author *common.Address) vm.Context {
 	// If we don't have an explicit author (i.e. not mining), extract from the header
@@ -55,6 +70,7 @@ func NewEVMContext(msg Message, header *types.Header, chain ChainContext, author
 		Difficulty:  new(big.Int).Set(header.Difficulty),
 		GasLimit:    new(big.Int).Set(header.GasLimit),
 		GasPrice:    new(big.Int).Set(msg.GasPrice()),
+		Pos:         posContextOf(chain),
 	}
 }
 
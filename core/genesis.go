@@ -24,6 +24,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"strings"
 
 	"github.com/wanchain/go-wanchain/common"
@@ -36,10 +37,13 @@ import (
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/rlp"
 )
 
 //go:generate gencodec -type Genesis -field-override genesisSpecMarshaling -out gen_genesis.go
+//go:generate gencodec -type GenesisStaker -field-override genesisStakerMarshaling -out gen_genesis_staker.go
+//go:generate gencodec -type GenesisDelegator -field-override genesisDelegatorMarshaling -out gen_genesis_delegator.go
 //go:generate gencodec -type GenesisAccount -field-override genesisAccountMarshaling -out gen_genesis_account.go
 
 var errGenesisNoConfig = errors.New("genesis has no chain configuration")
@@ -56,6 +60,7 @@ type Genesis struct {
 	Mixhash    common.Hash         `json:"mixHash"`
 	Coinbase   common.Address      `json:"coinbase"`
 	Alloc      GenesisAlloc        `json:"alloc"      gencodec:"required"`
+	Stakers    []GenesisStaker     `json:"stakers,omitempty"`
 
 	// These fields are used for consensus tests. Please don't use them
 	// in actual genesis blocks.
@@ -95,6 +100,26 @@ type GenesisAccount struct {
 	PrivateKey []byte                      `json:"secretKey,omitempty"` // for tests
 }
 
+// GenesisStaker is a staker registered in the genesis block. It is added by a
+// stakeIn call of the staking contract from From, followed by a delegateIn call
+// of each delegator, so the same rules apply as for stakes sent later on. The
+// staked amounts are minted for the calls.
+type GenesisStaker struct {
+	From       common.Address     `json:"from"       gencodec:"required"`
+	SecPk      []byte             `json:"secPk"      gencodec:"required"`
+	Bn256Pk    []byte             `json:"bn256Pk"    gencodec:"required"`
+	Amount     *big.Int           `json:"amount"     gencodec:"required"`
+	LockEpochs uint64             `json:"lockEpochs" gencodec:"required"`
+	FeeRate    uint64             `json:"feeRate"`
+	Delegators []GenesisDelegator `json:"delegators,omitempty"`
+}
+
+// GenesisDelegator is an account delegating its stake to a genesis staker.
+type GenesisDelegator struct {
+	Address common.Address `json:"address" gencodec:"required"`
+	Amount  *big.Int       `json:"amount"  gencodec:"required"`
+}
+
 // field type overrides for gencodec
 type genesisSpecMarshaling struct {
 	Nonce      math.HexOrDecimal64
@@ -107,6 +132,18 @@ type genesisSpecMarshaling struct {
 	Alloc      map[common.UnprefixedAddress]GenesisAccount
 }
 
+type genesisStakerMarshaling struct {
+	SecPk      hexutil.Bytes
+	Bn256Pk    hexutil.Bytes
+	Amount     *math.HexOrDecimal256
+	LockEpochs math.HexOrDecimal64
+	FeeRate    math.HexOrDecimal64
+}
+
+type genesisDelegatorMarshaling struct {
+	Amount *math.HexOrDecimal256
+}
+
 type genesisAccountMarshaling struct {
 	Code       hexutil.Bytes
 	Balance    *math.HexOrDecimal256
@@ -158,9 +195,19 @@ func (e *GenesisMismatchError) Error() string {
 // specify a fork block below the local head block). In case of a conflict, the
 // error is a *params.ConfigCompatError and the new, unwritten config is returned.
 //
-// The returned chain configuration is never nil.
+// The returned chain configuration is never nil. Missing POS fork epochs are
+// filled in as for a network without its own defaults, callers that know the
+// network id use SetupGenesisBlockWithNetwork.
 
 func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig, common.Hash, error) {
+	return SetupGenesisBlockWithNetwork(db, genesis, 0)
+}
+
+// SetupGenesisBlockWithNetwork is SetupGenesisBlock for the network with the
+// given id. Chain configurations of networks other than the bundled ones that
+// have no POS fork epochs get the epochs the network ran them at before they
+// became configurable, see params.DefaultEpochForks.
+func SetupGenesisBlockWithNetwork(db ethdb.Database, genesis *Genesis, networkId uint64) (*params.ChainConfig, common.Hash, error) {
 	if genesis != nil && genesis.Config == nil {
 		return params.AllProtocolChanges, common.Hash{}, errGenesisNoConfig
 	}
@@ -168,6 +215,9 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig
 	// Just commit the new block if there is no stored genesis block.
 	stored := GetCanonicalHash(db, 0)
 	if (stored == common.Hash{}) {
+		if genesis != nil {
+			genesis = genesis.withEpochForks(networkId)
+		}
 		if genesis == nil {
 			log.Info("Writing default main-net genesis block")
 			genesis = DefaultGenesisBlock()
@@ -179,12 +229,18 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig
 			log.Info("Writing custom genesis block")
 		}
 		block, err := genesis.Commit(db)
-		return genesis.Config, block.Hash(), err
+		if err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
+		return genesis.Config, block.Hash(), nil
 	}
 
 	// Check whether the genesis block is already written.
 	if genesis != nil {
-		block, _ := genesis.ToBlock()
+		block, _, err := genesis.toBlock()
+		if err != nil {
+			return genesis.Config, common.Hash{}, err
+		}
 		hash := block.Hash()
 		if hash != stored {
 			return genesis.Config, block.Hash(), &GenesisMismatchError{stored, hash}
@@ -192,6 +248,9 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig
 	}
 
 	// Get the existing chain configuration.
+	if genesis != nil {
+		genesis = genesis.withEpochForks(networkId)
+	}
 	newcfg := genesis.configOrDefault(stored)
 	storedcfg, err := GetChainConfig(db, stored)
 	if err != nil {
@@ -202,6 +261,17 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig
 		}
 		return newcfg, stored, err
 	}
+	// Chains initialised before the POS fork epochs were part of the chain
+	// configuration have none stored. Take them from the bundled configuration
+	// of the network, or the epochs it ran them at, so the forks still
+	// activate on those nodes.
+	if fillEpochForks(storedcfg, epochForkDefaults(stored, networkId)) {
+		log.Info("Upgrading stored chain config with POS fork epochs", "apollo", storedcfg.ApolloEpoch,
+			"august", storedcfg.AugustEpoch, "mercury", storedcfg.MercuryEpoch)
+		if err := WriteChainConfig(db, stored, storedcfg); err != nil {
+			return newcfg, stored, err
+		}
+	}
 	// Special case: don't change the existing config of a non-mainnet chain if no new
 	// config is supplied. These chains would get AllProtocolChanges (and a compat error)
 	// if we just continued here.
@@ -215,7 +285,10 @@ func SetupGenesisBlock(db ethdb.Database, genesis *Genesis) (*params.ChainConfig
 	if height == missingNumber {
 		return newcfg, stored, fmt.Errorf("missing block number for head header hash")
 	}
-	compatErr := storedcfg.CheckCompatible(newcfg, height)
+	compatErr := storedcfg.CheckCompatible(newcfg, height, blockEpoch(db, storedcfg, height))
+	if compatErr != nil && compatErr.Epoch {
+		compatErr.RewindTo = lastBlockOfEpoch(db, storedcfg, height, compatErr.RewindToEpoch)
+	}
 	if compatErr != nil && height != 0 && compatErr.RewindTo != 0 {
 		return newcfg, stored, compatErr
 	}
@@ -240,8 +313,97 @@ func (g *Genesis) configOrDefault(ghash common.Hash) *params.ChainConfig {
 	}
 }
 
-// ToBlock creates the block and state of a genesis specification.
+// bundledConfig returns the chain configuration shipped for a well known
+// network, or nil if the genesis hash is not one of them.
+func bundledConfig(ghash common.Hash) *params.ChainConfig {
+	switch ghash {
+	case params.MainnetGenesisHash:
+		return params.WanchainChainConfig
+	case params.TestnetGenesisHash:
+		return params.TestnetChainConfig
+	case params.InternalGenesisHash:
+		return params.InternalChainConfig
+	case params.PlutoGenesisHash:
+		return params.PlutoChainConfig
+	default:
+		return nil
+	}
+}
+
+// epochForkDefaults returns the configuration to take missing POS fork epochs
+// from for the chain with the given genesis hash and network id.
+func epochForkDefaults(ghash common.Hash, networkId uint64) *params.ChainConfig {
+	if bundled := bundledConfig(ghash); bundled != nil {
+		return bundled
+	}
+	return params.DefaultEpochForks(networkId)
+}
+
+// withEpochForks returns g, or a copy of it whose configuration has the
+// missing POS fork epochs filled in for a network other than the bundled ones.
+func (g *Genesis) withEpochForks(networkId uint64) *Genesis {
+	config := *g.Config
+	if !fillEpochForks(&config, params.DefaultEpochForks(networkId)) {
+		return g
+	}
+	genesis := *g
+	genesis.Config = &config
+	return &genesis
+}
+
+// fillEpochForks sets the POS fork epochs missing in cfg from bundled and
+// reports whether anything changed.
+func fillEpochForks(cfg, bundled *params.ChainConfig) bool {
+	changed := false
+	fill := func(dst **big.Int, src *big.Int) {
+		if *dst == nil && src != nil {
+			*dst = new(big.Int).Set(src)
+			changed = true
+		}
+	}
+	fill(&cfg.ApolloEpoch, bundled.ApolloEpoch)
+	fill(&cfg.AugustEpoch, bundled.AugustEpoch)
+	fill(&cfg.MercuryEpoch, bundled.MercuryEpoch)
+	return changed
+}
+
+// blockEpoch returns the POS epoch of the canonical block with the given
+// number, or zero for blocks before the POS upgrade.
+func blockEpoch(db DatabaseReader, config *params.ChainConfig, number uint64) uint64 {
+	if config.PosFirstBlock == nil || !config.IsPosBlockNumber(new(big.Int).SetUint64(number)) {
+		return 0
+	}
+	header := GetHeader(db, GetCanonicalHash(db, number), number)
+	if header == nil {
+		return 0
+	}
+	epochID, _ := util.GetEpochSlotIDFromDifficulty(header.Difficulty)
+	return epochID
+}
+
+// lastBlockOfEpoch returns the number of the last canonical block up to height
+// that belongs to epochID or an earlier epoch.
+func lastBlockOfEpoch(db DatabaseReader, config *params.ChainConfig, height uint64, epochID uint64) uint64 {
+	n := sort.Search(int(height)+1, func(i int) bool {
+		return blockEpoch(db, config, uint64(i)) > epochID
+	})
+	if n == 0 {
+		return 0
+	}
+	return uint64(n - 1)
+}
+
+// ToBlock creates the block and state of a genesis specification. It panics
+// if a genesis staker is rejected, Commit reports that as an error.
 func (g *Genesis) ToBlock() (*types.Block, *state.StateDB) {
+	block, statedb, err := g.toBlock()
+	if err != nil {
+		panic(err)
+	}
+	return block, statedb
+}
+
+func (g *Genesis) toBlock() (*types.Block, *state.StateDB, error) {
 	db, _ := ethdb.NewMemDatabase()
 	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
 	for addr, account := range g.Alloc {
@@ -282,6 +444,9 @@ func (g *Genesis) ToBlock() (*types.Block, *state.StateDB) {
 			statedb.SetState(addr, key, value)
 		}
 	}
+	if err := g.applyStakers(statedb); err != nil {
+		return nil, nil, err
+	}
 	root := statedb.IntermediateRoot(false)
 	head := &types.Header{
 		Number:     new(big.Int).SetUint64(g.Number),
@@ -302,13 +467,73 @@ func (g *Genesis) ToBlock() (*types.Block, *state.StateDB) {
 	if g.Difficulty == nil {
 		head.Difficulty = params.GenesisDifficulty
 	}
-	return types.NewBlock(head, nil, nil, nil), statedb
+	return types.NewBlock(head, nil, nil, nil), statedb, nil
+}
+
+// applyStakers registers the genesis stakers and their delegators through the
+// staking contract.
+func (g *Genesis) applyStakers(statedb *state.StateDB) error {
+	if len(g.Stakers) == 0 {
+		return nil
+	}
+	config := g.Config
+	if config == nil {
+		config = params.AllProtocolChanges
+	}
+	context := vm.Context{
+		CanTransfer: CanTransfer,
+		Transfer:    Transfer,
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		BlockNumber: new(big.Int).SetUint64(g.Number),
+		Time:        new(big.Int).SetUint64(g.Timestamp),
+		Difficulty:  new(big.Int),
+		GasLimit:    new(big.Int).SetUint64(g.GasLimit),
+		GasPrice:    new(big.Int),
+	}
+	evm := vm.NewEVM(context, statedb, config, vm.Config{})
+
+	// The staking contract doesn't charge gas.
+	call := func(from common.Address, input []byte, value *big.Int) error {
+		statedb.AddBalance(from, value)
+		_, _, err := evm.Call(vm.AccountRef(from), vm.WanCscPrecompileAddr, input, 0, value)
+		return err
+	}
+	for i, staker := range g.Stakers {
+		if staker.Amount == nil {
+			return fmt.Errorf("genesis staker %d: missing amount", i)
+		}
+		input, err := vm.PackStakeIn(staker.SecPk, staker.Bn256Pk, new(big.Int).SetUint64(staker.LockEpochs), new(big.Int).SetUint64(staker.FeeRate))
+		if err != nil {
+			return fmt.Errorf("genesis staker %d: %v", i, err)
+		}
+		if err := call(staker.From, input, staker.Amount); err != nil {
+			return fmt.Errorf("genesis staker %d: %v", i, err)
+		}
+
+		validator := crypto.PubkeyToAddress(*crypto.ToECDSAPub(staker.SecPk))
+		for j, delegator := range staker.Delegators {
+			if delegator.Amount == nil {
+				return fmt.Errorf("genesis staker %d delegator %d: missing amount", i, j)
+			}
+			input, err := vm.PackDelegateIn(validator)
+			if err != nil {
+				return err
+			}
+			if err := call(delegator.Address, input, delegator.Amount); err != nil {
+				return fmt.Errorf("genesis staker %d delegator %d: %v", i, j, err)
+			}
+		}
+	}
+	return nil
 }
 
 // Commit writes the block and state of a genesis specification to the database.
 // The block is committed as the canonical head block.
 func (g *Genesis) Commit(db ethdb.Database) (*types.Block, error) {
-	block, statedb := g.ToBlock()
+	block, statedb, err := g.toBlock()
+	if err != nil {
+		return nil, err
+	}
 	if block.Number().Sign() != 0 {
 		return nil, fmt.Errorf("can't commit genesis block with number > 0")
 	}
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"encoding/json"
 	"math/big"
 	"reflect"
 	"testing"
@@ -27,8 +28,11 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/rlp"
 )
 
 var (
@@ -85,6 +89,10 @@ func TestSetupGenesis(t *testing.T) {
 		oldcustomg = customg
 	)
 	oldcustomg.Config = &params.ChainConfig{ByzantiumBlock: big.NewInt(2)}
+	// The custom configs have no POS fork epochs, so they get the defaults.
+	forks := params.DefaultEpochForks(0)
+	customcfg := *customg.Config
+	customcfg.ApolloEpoch, customcfg.AugustEpoch, customcfg.MercuryEpoch = forks.ApolloEpoch, forks.AugustEpoch, forks.MercuryEpoch
 	tests := []struct {
 		name       string
 		fn         func(ethdb.Database) (*params.ChainConfig, common.Hash, error)
@@ -124,7 +132,7 @@ func TestSetupGenesis(t *testing.T) {
 				return SetupGenesisBlock(db, nil)
 			},
 			wantHash:   customghash,
-			wantConfig: customg.Config,
+			wantConfig: &customcfg,
 		},
 		{
 			name: "custom block in DB, genesis == testnet",
@@ -143,7 +151,7 @@ func TestSetupGenesis(t *testing.T) {
 				return SetupGenesisBlock(db, &customg)
 			},
 			wantHash:   customghash,
-			wantConfig: customg.Config,
+			wantConfig: &customcfg,
 		},
 
 		/*
@@ -196,3 +204,186 @@ func TestSetupGenesis(t *testing.T) {
 		}
 	}
 }
+
+func TestGenesisAccountStakingJSON(t *testing.T) {
+	account := GenesisAccount{
+		Balance: big.NewInt(1),
+		Staking: GenesisAccountStaking{
+			Amount:  big.NewInt(100000),
+			S256pk:  common.FromHex("0x04d7dffe5e06d2c7024d9bb93f675b8242e71901ee66a1bfe3fe5369324c0a75bf6f033dc4af65f5d0fe7072e98788fcfa670919b5bdc046f1ca91f28dff59db70"),
+			Bn256pk: common.FromHex("0x150b2b3230d6d6c8d1c133ec42d82f84add5e096c57665ff50ad071f6345cf45191fd8015cea72c4591ab3fd2ade12287c28a092ac0abf9ea19c13eb65fd4910"),
+		},
+	}
+	blob, err := account.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded GenesisAccount
+	if err := decoded.UnmarshalJSON(blob); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, account) {
+		t.Errorf("staking lost in JSON round trip:\nhave %+v\nwant %+v", decoded, account)
+	}
+}
+
+func TestGenesisStakers(t *testing.T) {
+	var (
+		secPk     = common.FromHex("0x04d7dffe5e06d2c7024d9bb93f675b8242e71901ee66a1bfe3fe5369324c0a75bf6f033dc4af65f5d0fe7072e98788fcfa670919b5bdc046f1ca91f28dff59db70")
+		bn256Pk   = common.FromHex("0x150b2b3230d6d6c8d1c133ec42d82f84add5e096c57665ff50ad071f6345cf45191fd8015cea72c4591ab3fd2ade12287c28a092ac0abf9ea19c13eb65fd4910")
+		from      = common.HexToAddress("0x01")
+		delegator = common.HexToAddress("0x02")
+		wan       = big.NewInt(params.Wan)
+	)
+	genesis := &Genesis{
+		Config:     params.TestChainConfig,
+		Difficulty: big.NewInt(1),
+		Alloc:      GenesisAlloc{},
+		Stakers: []GenesisStaker{{
+			From:       from,
+			SecPk:      secPk,
+			Bn256Pk:    bn256Pk,
+			Amount:     new(big.Int).Mul(big.NewInt(100000), wan),
+			LockEpochs: 10,
+			FeeRate:    100,
+			Delegators: []GenesisDelegator{{Address: delegator, Amount: new(big.Int).Mul(big.NewInt(1000), wan)}},
+		}},
+	}
+	blob, err := json.Marshal(genesis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Genesis
+	if err := json.Unmarshal(blob, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded.Stakers, genesis.Stakers) {
+		t.Errorf("stakers lost in JSON round trip:\nhave %+v\nwant %+v", decoded.Stakers, genesis.Stakers)
+	}
+
+	_, statedb := genesis.ToBlock()
+	secAddr := crypto.PubkeyToAddress(*crypto.ToECDSAPub(secPk))
+	raw, err := vm.GetInfo(statedb, vm.StakersInfoAddr, vm.GetStakeInKeyHash(secAddr))
+	if err != nil || raw == nil {
+		t.Fatalf("staker not stored: %v", err)
+	}
+	var info vm.StakerInfo
+	if err := rlp.DecodeBytes(raw, &info); err != nil {
+		t.Fatal(err)
+	}
+	if info.From != from || info.LockEpochs != 10 || info.FeeRate != 100 || info.Amount.Cmp(genesis.Stakers[0].Amount) != 0 {
+		t.Errorf("staker mismatch: %+v", info)
+	}
+	if len(info.Clients) != 1 || info.Clients[0].Address != delegator {
+		t.Errorf("delegator mismatch: %+v", info.Clients)
+	}
+	total := new(big.Int).Add(genesis.Stakers[0].Amount, genesis.Stakers[0].Delegators[0].Amount)
+	if balance := statedb.GetBalance(vm.WanCscPrecompileAddr); balance.Cmp(total) != 0 {
+		t.Errorf("staking contract balance mismatch: have %v, want %v", balance, total)
+	}
+
+	genesis.Stakers[0].LockEpochs = 1
+	db, _ := ethdb.NewMemDatabase()
+	if _, err := genesis.Commit(db); err == nil {
+		t.Error("expected error for invalid lock epochs")
+	}
+}
+
+func TestSetupGenesisInvalidStaker(t *testing.T) {
+	invalid := &Genesis{
+		Config:     params.TestChainConfig,
+		Difficulty: big.NewInt(1),
+		Alloc:      GenesisAlloc{},
+		Stakers: []GenesisStaker{{
+			From:       common.HexToAddress("0x01"),
+			SecPk:      make([]byte, 65),
+			Bn256Pk:    make([]byte, 64),
+			Amount:     big.NewInt(params.Wan),
+			LockEpochs: 1,
+		}},
+	}
+
+	// Fresh database, the genesis is committed.
+	db, _ := ethdb.NewMemDatabase()
+	if _, _, err := SetupGenesisBlock(db, invalid); err == nil {
+		t.Error("expected error for invalid staker on empty database")
+	}
+	if stored := GetCanonicalHash(db, 0); stored != (common.Hash{}) {
+		t.Errorf("invalid genesis written: %x", stored)
+	}
+
+	// Existing database, the genesis is compared against the stored one.
+	db, _ = ethdb.NewMemDatabase()
+	valid := &Genesis{Config: params.TestChainConfig, Difficulty: big.NewInt(1), Alloc: GenesisAlloc{}}
+	valid.MustCommit(db)
+	if _, _, err := SetupGenesisBlock(db, invalid); err == nil {
+		t.Error("expected error for invalid staker on initialized database")
+	}
+}
+
+func TestSetupGenesisUpgradeEpochForks(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	genesis := DefaultTestnetGenesisBlock().MustCommit(db)
+
+	// Config as written by releases without the POS fork epochs.
+	old := *params.TestnetChainConfig
+	old.ApolloEpoch, old.AugustEpoch, old.MercuryEpoch = nil, nil, nil
+	if err := WriteChainConfig(db, genesis.Hash(), &old); err != nil {
+		t.Fatal(err)
+	}
+
+	config, _, err := SetupGenesisBlock(db, nil)
+	if err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	stored, err := GetChainConfig(db, genesis.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cfg := range []*params.ChainConfig{config, stored} {
+		if cfg.ApolloEpoch.Uint64() != params.TestnetApolloEpoch ||
+			cfg.AugustEpoch.Uint64() != params.TestnetAugustEpoch ||
+			cfg.MercuryEpoch.Uint64() != params.TestnetMercuryEpoch {
+			t.Errorf("fork epochs not upgraded: apollo %v, august %v, mercury %v", cfg.ApolloEpoch, cfg.AugustEpoch, cfg.MercuryEpoch)
+		}
+	}
+}
+
+func TestSetupGenesisUpgradeCustomEpochForks(t *testing.T) {
+	tests := []struct {
+		networkId uint64
+		mercury   uint64
+	}{
+		{1, params.MainnetMercuryEpoch},
+		{6, 0},
+		{1234, params.TestnetMercuryEpoch},
+	}
+	for _, test := range tests {
+		// Private network config as written by releases without the POS
+		// fork epochs.
+		old := *params.TestChainConfig
+		old.ApolloEpoch, old.AugustEpoch, old.MercuryEpoch = nil, nil, nil
+		db, _ := ethdb.NewMemDatabase()
+		genesis := (&Genesis{Config: &old, Difficulty: big.NewInt(1), Alloc: GenesisAlloc{}}).MustCommit(db)
+
+		config, _, err := SetupGenesisBlockWithNetwork(db, nil, test.networkId)
+		if err != nil {
+			t.Fatalf("network %d: setup failed: %v", test.networkId, err)
+		}
+		stored, err := GetChainConfig(db, genesis.Hash())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, cfg := range []*params.ChainConfig{config, stored} {
+			if cfg.ApolloEpoch == nil || cfg.ApolloEpoch.Uint64() != params.MainnetApolloEpoch ||
+				cfg.AugustEpoch == nil || cfg.AugustEpoch.Uint64() != params.MainnetAugustEpoch ||
+				cfg.MercuryEpoch == nil || cfg.MercuryEpoch.Uint64() != test.mercury {
+				t.Errorf("network %d: fork epochs not upgraded: apollo %v, august %v, mercury %v",
+					test.networkId, cfg.ApolloEpoch, cfg.AugustEpoch, cfg.MercuryEpoch)
+			}
+		}
+		if !config.IsApollo(params.MainnetApolloEpoch) || config.IsApollo(params.MainnetApolloEpoch-1) {
+			t.Errorf("network %d: Apollo not active from epoch %d", test.networkId, params.MainnetApolloEpoch)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/trie"
+)
+
+// ErrOTAIndexUnavailable is returned by the OTA index when it can't answer for
+// the requested state, in which case callers should fall back to traversing
+// the OTA storage of that state.
+var ErrOTAIndexUnavailable = errors.New("ota index unavailable")
+
+var errMissingOTAPreimage = errors.New("missing ota storage key preimage")
+
+// otaDenomination is the list of OTAs sharing one balance. The list is kept
+// dense so that members can be picked by position; removals swap the last
+// element into the freed slot.
+type otaDenomination struct {
+	otas [][]byte
+	pos  map[common.Hash]int
+}
+
+func (d *otaDenomination) add(ax common.Hash, wanAddr []byte) {
+	if _, ok := d.pos[ax]; ok {
+		return
+	}
+	d.pos[ax] = len(d.otas)
+	d.otas = append(d.otas, wanAddr)
+}
+
+func (d *otaDenomination) remove(ax common.Hash) {
+	i, ok := d.pos[ax]
+	if !ok {
+		return
+	}
+	last := len(d.otas) - 1
+	if i != last {
+		d.otas[i] = d.otas[last]
+		moved, _ := vm.GetAXFromWanAddr(d.otas[i])
+		d.pos[common.BytesToHash(moved)] = i
+	}
+	d.otas = d.otas[:last]
+	delete(d.pos, ax)
+}
+
+// OTAIndex is an auxiliary, non-consensus index over the OTA storage of the
+// canonical head state. It keeps the OTAs of every denomination in a list with
+// per-denomination counters, together with the historical and spent OTA
+// totals, so that mix-set selection costs O(ringSize) and the unspent total is
+// O(1) instead of a walk over the whole storage.
+//
+// The chain calls HeadChanged whenever a block insertion or a reorg moves the
+// canonical head, and the index follows it in the background by diffing the
+// OTA storage tries of the indexed state against the head state, so imported
+// blocks add entries and reorgs drop the ones that are no longer part of the
+// chain. Queries for the head state catch up first if the index lags behind,
+// which also covers rewinds.
+//
+// Indexing relies on the preimages of the OTA storage keys. A fast synced node
+// lacks them for the state it downloaded, so its index can never be built: it
+// is disabled and every query reports ErrOTAIndexUnavailable, leaving callers
+// to traverse the OTA storage as they would without an index.
+type OTAIndex struct {
+	db     state.Database
+	head   func() common.Hash // state root of the canonical head
+	headCh chan struct{}      // wakes up the follow loop on head changes
+
+	mu       sync.Mutex
+	built    bool
+	disabled bool        // OTA storage key preimages are missing
+	root     common.Hash // state root the index reflects
+	balances map[common.Hash]*big.Int
+	denoms   map[common.Address]*otaDenomination
+	total    *big.Int // sum of all OTA balances ever bought
+	spent    *big.Int // sum of all OTA balances refunded
+	images   int      // number of spent OTA images
+	spentOTA map[common.Address]int
+	rnd      *rand.Rand
+}
+
+// NewOTAIndex creates an OTA index over the states of db, tracking the state
+// root returned by head.
+func NewOTAIndex(db state.Database, head func() common.Hash) *OTAIndex {
+	return &OTAIndex{
+		db:     db,
+		head:   head,
+		headCh: make(chan struct{}, 1),
+		rnd:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// HeadChanged tells the index the canonical head moved. It never blocks, the
+// index catches up in its follow loop.
+func (idx *OTAIndex) HeadChanged() {
+	select {
+	case idx.headCh <- struct{}{}:
+	default:
+	}
+}
+
+// follow keeps the index at the canonical head until quit is closed.
+func (idx *OTAIndex) follow(quit <-chan struct{}) {
+	for {
+		select {
+		case <-idx.headCh:
+			idx.mu.Lock()
+			if idx.head != nil {
+				idx.sync(idx.head())
+			}
+			idx.mu.Unlock()
+		case <-quit:
+			return
+		}
+	}
+}
+
+// SetRandSource replaces the source used for mix-set selection, making the
+// selected sets reproducible.
+func (idx *OTAIndex) SetRandSource(src rand.Source) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.rnd = rand.New(src)
+}
+
+// GetOTASet selects setNum OTAs of the same balance as the OTA identified by
+// otaAX from the state with the given root. The rules are those of
+// vm.GetOTASet: the set never contains otaAX itself nor duplicates, and the
+// denomination must hold more than setNum OTAs. Every such set is equally
+// likely to be returned.
+func (idx *OTAIndex) GetOTASet(root common.Hash, otaAX []byte, setNum int) ([][]byte, *big.Int, error) {
+	if len(otaAX) != common.HashLength {
+		return nil, nil, vm.ErrInvalidOTAAX
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.sync(root); err != nil {
+		return nil, nil, err
+	}
+
+	ax := common.BytesToHash(otaAX)
+	balance, ok := idx.balances[ax]
+	if !ok {
+		return nil, nil, errors.New("can't find ota address balance!")
+	}
+	d := idx.denoms[vm.OTABalance2ContractAddr(balance)]
+	if setNum >= len(d.otas) {
+		return nil, balance, errors.New("too more required ota number! balance:" + balance.String() +
+			", exist count:" + strconv.Itoa(len(d.otas)))
+	}
+
+	// Partial Fisher-Yates over the denomination with otaAX's own slot
+	// removed; swapped only records the positions that have been touched.
+	self := d.pos[ax]
+	candidates := len(d.otas) - 1
+	swapped := make(map[int]int, setNum)
+	otaSet := make([][]byte, 0, setNum)
+	for k := 0; k < setNum; k++ {
+		j := k + idx.rnd.Intn(candidates-k)
+		picked, ok := swapped[j]
+		if !ok {
+			picked = j
+		}
+		if moved, ok := swapped[k]; ok {
+			swapped[j] = moved
+		} else {
+			swapped[j] = k
+		}
+		if picked >= self {
+			picked++
+		}
+		otaSet = append(otaSet, common.CopyBytes(d.otas[picked]))
+	}
+	return otaSet, new(big.Int).Set(balance), nil
+}
+
+// OTACount returns the number of OTAs of the given balance in the state with
+// the given root.
+func (idx *OTAIndex) OTACount(root common.Hash, balance *big.Int) (int, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.sync(root); err != nil {
+		return 0, err
+	}
+	if d, ok := idx.denoms[vm.OTABalance2ContractAddr(balance)]; ok {
+		return len(d.otas), nil
+	}
+	return 0, nil
+}
+
+// OTAStats returns, for the state with the given root, the number of OTAs of
+// each of the given balances and how many of them are spent, together with the
+// total number of spent OTA images.
+func (idx *OTAIndex) OTAStats(root common.Hash, balances []*big.Int) (otas, spent []int, images int, err error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.sync(root); err != nil {
+		return nil, nil, 0, err
+	}
+	otas, spent = make([]int, len(balances)), make([]int, len(balances))
+	for i, balance := range balances {
+		mptAddr := vm.OTABalance2ContractAddr(balance)
+		if d, ok := idx.denoms[mptAddr]; ok {
+			otas[i] = len(d.otas)
+		}
+		spent[i] = idx.spentOTA[mptAddr]
+	}
+	return otas, spent, idx.images, nil
+}
+
+// UnspentBalance returns the total balance of the unspent OTAs in the state with
+// the given root, as vm.GetUnspendOTATotalBalance does.
+func (idx *OTAIndex) UnspentBalance(root common.Hash) (*big.Int, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if err := idx.sync(root); err != nil {
+		return nil, err
+	}
+	return new(big.Int).Sub(idx.total, idx.spent), nil
+}
+
+// sync brings the index up to the state with the given root. Only the state of
+// the canonical head is served, anything else reports ErrOTAIndexUnavailable.
+func (idx *OTAIndex) sync(root common.Hash) error {
+	if idx.disabled || idx.head == nil || idx.head() != root {
+		return ErrOTAIndexUnavailable
+	}
+	if idx.built && idx.root == root {
+		return nil
+	}
+	to, err := state.New(root, idx.db)
+	if err != nil {
+		return ErrOTAIndexUnavailable
+	}
+	if idx.built {
+		from, err := state.New(idx.root, idx.db)
+		if err == nil {
+			err = idx.update(from, to)
+		}
+		if err == nil {
+			idx.root = root
+			return nil
+		}
+		log.Warn("Failed to update OTA index, rebuilding", "from", idx.root, "to", root, "err", err)
+	}
+	if err := idx.rebuild(to); err != nil {
+		idx.built = false
+		if err == errMissingOTAPreimage {
+			// the preimages of a fast synced state never show up later
+			idx.disabled = true
+			log.Warn("OTA index disabled, falling back to storage traversal", "root", root, "err", err)
+		} else {
+			log.Warn("Failed to build OTA index", "root", root, "err", err)
+		}
+		return ErrOTAIndexUnavailable
+	}
+	idx.built, idx.root = true, root
+	return nil
+}
+
+// rebuild indexes the OTA storage of st from scratch.
+func (idx *OTAIndex) rebuild(st *state.StateDB) error {
+	idx.balances = make(map[common.Hash]*big.Int)
+	idx.denoms = make(map[common.Address]*otaDenomination)
+	idx.total, idx.spent = new(big.Int), new(big.Int)
+	idx.images, idx.spentOTA = 0, make(map[common.Address]int)
+
+	return idx.update(nil, st)
+}
+
+// update moves the index from the state from to the state to. Entries that
+// only exist in from are dropped before the ones that only exist in to are
+// added, so a changed entry ends up with its new value.
+func (idx *OTAIndex) update(from, to *state.StateDB) error {
+	if from != nil {
+		err := forEachNewStorage(to, from, vm.OTABalanceStorageAddr, func(ax common.Hash, value []byte) error {
+			idx.removeOTA(ax, value)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		err = forEachNewStorage(to, from, vm.OTAImageStorageAddr, func(_ common.Hash, value []byte) error {
+			idx.removeImage(value)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	err := forEachNewStorage(from, to, vm.OTABalanceStorageAddr, func(ax common.Hash, value []byte) error {
+		return idx.addOTA(to, ax, value)
+	})
+	if err != nil {
+		return err
+	}
+	return forEachNewStorage(from, to, vm.OTAImageStorageAddr, func(_ common.Hash, value []byte) error {
+		idx.addImage(value)
+		return nil
+	})
+}
+
+func (idx *OTAIndex) addOTA(st *state.StateDB, ax common.Hash, value []byte) error {
+	balance := new(big.Int).SetBytes(value)
+	if balance.Sign() == 0 {
+		return nil
+	}
+	mptAddr := vm.OTABalance2ContractAddr(balance)
+	wanAddr := st.GetStateByteArray(mptAddr, ax)
+	if len(wanAddr) != common.WAddressLength {
+		return errors.New(fmt.Sprint("invalid OTA address! balance:", balance, ", ota:", wanAddr))
+	}
+
+	d, ok := idx.denoms[mptAddr]
+	if !ok {
+		d = &otaDenomination{pos: make(map[common.Hash]int)}
+		idx.denoms[mptAddr] = d
+	}
+	d.add(ax, common.CopyBytes(wanAddr))
+	idx.balances[ax] = balance
+	idx.total.Add(idx.total, balance)
+	return nil
+}
+
+func (idx *OTAIndex) removeOTA(ax common.Hash, value []byte) {
+	balance := new(big.Int).SetBytes(value)
+	if balance.Sign() == 0 {
+		return
+	}
+	mptAddr := vm.OTABalance2ContractAddr(balance)
+	if d, ok := idx.denoms[mptAddr]; ok {
+		d.remove(ax)
+		if len(d.otas) == 0 {
+			delete(idx.denoms, mptAddr)
+		}
+	}
+	delete(idx.balances, ax)
+	idx.total.Sub(idx.total, balance)
+}
+
+func (idx *OTAIndex) addImage(value []byte) {
+	balance := new(big.Int).SetBytes(value)
+	idx.spent.Add(idx.spent, balance)
+	idx.spentOTA[vm.OTABalance2ContractAddr(balance)]++
+	idx.images++
+}
+
+func (idx *OTAIndex) removeImage(value []byte) {
+	balance := new(big.Int).SetBytes(value)
+	mptAddr := vm.OTABalance2ContractAddr(balance)
+	idx.spent.Sub(idx.spent, balance)
+	if idx.spentOTA[mptAddr]--; idx.spentOTA[mptAddr] <= 0 {
+		delete(idx.spentOTA, mptAddr)
+	}
+	idx.images--
+}
+
+// forEachNewStorage calls cb for every storage entry of addr that is present in
+// the state to but not, or with a different value, in the state from. A nil
+// from stands for an empty state.
+func forEachNewStorage(from, to *state.StateDB, addr common.Address, cb func(key common.Hash, value []byte) error) error {
+	if to == nil {
+		return nil
+	}
+	toTrie := to.StorageTrie(addr)
+	if toTrie == nil {
+		return nil
+	}
+	nodes := toTrie.NodeIterator(nil)
+	if from != nil {
+		if fromTrie := from.StorageTrie(addr); fromTrie != nil {
+			nodes, _ = trie.NewDifferenceIterator(fromTrie.NodeIterator(nil), nodes)
+		}
+	}
+
+	it := trie.NewIterator(nodes)
+	for it.Next() {
+		key := toTrie.GetKey(it.Key)
+		if key == nil {
+			return errMissingOTAPreimage
+		}
+		if err := cb(common.BytesToHash(key), it.Value); err != nil {
+			return err
+		}
+	}
+	return it.Err
+}
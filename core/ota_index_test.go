@@ -0,0 +1,297 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+)
+
+var (
+	otaTestBalance1 = new(big.Int).Mul(big.NewInt(10), big.NewInt(1e18))
+	otaTestBalance2 = new(big.Int).Mul(big.NewInt(20), big.NewInt(1e18))
+)
+
+func otaTestWanAddr(i int) []byte {
+	wanAddr := make([]byte, common.WAddressLength)
+	wanAddr[0] = 0x02
+	copy(wanAddr[1:], crypto.Keccak256(big.NewInt(int64(i)).Bytes()))
+	wanAddr[1+common.HashLength] = 0x03
+	copy(wanAddr[2+common.HashLength:], crypto.Keccak256([]byte{byte(i)}))
+	return wanAddr
+}
+
+// buildOTAStates commits a state holding ten OTAs of otaTestBalance1 and
+// three of otaTestBalance2, and a child state adding two more of
+// otaTestBalance1 and refunding one of them.
+func buildOTAStates(t *testing.T) (state.Database, common.Hash, common.Hash) {
+	db, _ := ethdb.NewMemDatabase()
+	return buildOTAStatesIn(t, db)
+}
+
+func buildOTAStatesIn(t *testing.T, db *ethdb.MemDatabase) (state.Database, common.Hash, common.Hash) {
+	sdb := state.NewDatabase(db)
+
+	st, _ := state.New(common.Hash{}, sdb)
+	for i := 0; i < 13; i++ {
+		balance := otaTestBalance1
+		if i >= 10 {
+			balance = otaTestBalance2
+		}
+		if _, err := vm.AddOTAIfNotExist(st, balance, otaTestWanAddr(i)); err != nil {
+			t.Fatalf("failed to add ota %d: %v", i, err)
+		}
+	}
+	root1, err := st.CommitTo(db, false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+
+	st, _ = state.New(root1, sdb)
+	for i := 13; i < 15; i++ {
+		if _, err := vm.AddOTAIfNotExist(st, otaTestBalance1, otaTestWanAddr(i)); err != nil {
+			t.Fatalf("failed to add ota %d: %v", i, err)
+		}
+	}
+	if err := vm.AddOTAImage(st, []byte("image"), otaTestBalance1.Bytes()); err != nil {
+		t.Fatalf("failed to add ota image: %v", err)
+	}
+	root2, err := st.CommitTo(db, false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	return sdb, root1, root2
+}
+
+func TestOTAIndexFollowsHead(t *testing.T) {
+	sdb, root1, root2 := buildOTAStates(t)
+
+	head := root1
+	idx := NewOTAIndex(sdb, func() common.Hash { return head })
+
+	check := func(root common.Hash, count1, count2, spent1 int, unspent *big.Int) {
+		if n, err := idx.OTACount(root, otaTestBalance1); err != nil || n != count1 {
+			t.Errorf("ota count mismatch: have %d (%v), want %d", n, err, count1)
+		}
+		if n, err := idx.OTACount(root, otaTestBalance2); err != nil || n != count2 {
+			t.Errorf("ota count mismatch: have %d (%v), want %d", n, err, count2)
+		}
+		if total, err := idx.UnspentBalance(root); err != nil || total.Cmp(unspent) != 0 {
+			t.Errorf("unspent balance mismatch: have %v (%v), want %v", total, err, unspent)
+		}
+		otas, spent, images, err := idx.OTAStats(root, []*big.Int{otaTestBalance1, otaTestBalance2})
+		if err != nil {
+			t.Fatalf("failed to get ota stats: %v", err)
+		}
+		if otas[0] != count1 || otas[1] != count2 {
+			t.Errorf("ota stats count mismatch: have %v, want [%d %d]", otas, count1, count2)
+		}
+		if spent[0] != spent1 || spent[1] != 0 || images != spent1 {
+			t.Errorf("ota stats spent mismatch: have %v with %d images, want [%d 0]", spent, images, spent1)
+		}
+	}
+	unspent1 := new(big.Int).Add(new(big.Int).Mul(otaTestBalance1, big.NewInt(10)), new(big.Int).Mul(otaTestBalance2, big.NewInt(3)))
+	unspent2 := new(big.Int).Add(unspent1, otaTestBalance1)
+
+	check(root1, 10, 3, 0, unspent1)
+	if _, err := idx.UnspentBalance(root2); err != ErrOTAIndexUnavailable {
+		t.Errorf("non-head state served: err %v", err)
+	}
+
+	// Import a block, then reorg back to its parent
+	head = root2
+	check(root2, 12, 3, 1, unspent2)
+	head = root1
+	check(root1, 10, 3, 0, unspent1)
+}
+
+func TestOTAIndexGetOTASet(t *testing.T) {
+	sdb, root, _ := buildOTAStates(t)
+
+	idx := NewOTAIndex(sdb, func() common.Hash { return root })
+	idx.SetRandSource(rand.NewSource(1))
+
+	self := otaTestWanAddr(3)
+	otaAX, _ := vm.GetAXFromWanAddr(self)
+	set, balance, err := idx.GetOTASet(root, otaAX, 9)
+	if err != nil {
+		t.Fatalf("failed to get ota set: %v", err)
+	}
+	if balance.Cmp(otaTestBalance1) != 0 {
+		t.Errorf("balance mismatch: have %v, want %v", balance, otaTestBalance1)
+	}
+	seen := make(map[string]bool)
+	for _, ota := range set {
+		if bytes.Equal(ota, self) {
+			t.Errorf("set contains the ota itself")
+		}
+		if seen[string(ota)] {
+			t.Errorf("set contains %x twice", ota)
+		}
+		seen[string(ota)] = true
+
+		ax, _ := vm.GetAXFromWanAddr(ota)
+		if b, _ := vm.GetOtaBalanceFromAX(mustState(t, sdb, root), ax); b.Cmp(otaTestBalance1) != 0 {
+			t.Errorf("set contains %x of balance %v", ota, b)
+		}
+	}
+	if len(seen) != 9 {
+		t.Errorf("set size mismatch: have %d, want 9", len(seen))
+	}
+	if _, _, err := idx.GetOTASet(root, otaAX, 10); err == nil {
+		t.Errorf("oversized set selected")
+	}
+
+	// The same source must select the same sets
+	other := NewOTAIndex(sdb, func() common.Hash { return root })
+	other.SetRandSource(rand.NewSource(2))
+	idx.SetRandSource(rand.NewSource(2))
+	for i := 0; i < 10; i++ {
+		a, _, _ := idx.GetOTASet(root, otaAX, 4)
+		b, _, _ := other.GetOTASet(root, otaAX, 4)
+		for j := range a {
+			if !bytes.Equal(a[j], b[j]) {
+				t.Fatalf("selection %d not reproducible", i)
+			}
+		}
+	}
+
+	// Every other ota of the denomination must be picked about equally often
+	counts := make(map[string]int)
+	for i := 0; i < 9000; i++ {
+		set, _, err := idx.GetOTASet(root, otaAX, 1)
+		if err != nil {
+			t.Fatalf("failed to get ota set: %v", err)
+		}
+		counts[string(set[0])]++
+	}
+	if len(counts) != 9 {
+		t.Fatalf("picked %d distinct otas, want 9", len(counts))
+	}
+	for ota, n := range counts {
+		if n < 800 || n > 1200 {
+			t.Errorf("ota %x picked %d times out of 9000", ota, n)
+		}
+	}
+}
+
+func mustState(t *testing.T, sdb state.Database, root common.Hash) *state.StateDB {
+	st, err := state.New(root, sdb)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	return st
+}
+
+// waitOTAIndex waits for the follow loop to bring idx to the state root.
+func waitOTAIndex(t *testing.T, idx *OTAIndex, root common.Hash) {
+	for i := 0; i < 200; i++ {
+		idx.mu.Lock()
+		done := idx.built && idx.root == root
+		idx.mu.Unlock()
+		if done {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("ota index did not follow the head to %x", root)
+}
+
+func TestOTAIndexFollowLoop(t *testing.T) {
+	sdb, root1, root2 := buildOTAStates(t)
+
+	var head atomic.Value
+	head.Store(root1)
+	idx := NewOTAIndex(sdb, func() common.Hash { return head.Load().(common.Hash) })
+
+	quit := make(chan struct{})
+	defer close(quit)
+	go idx.follow(quit)
+
+	// The index is built and moved along without any query
+	idx.HeadChanged()
+	waitOTAIndex(t, idx, root1)
+
+	head.Store(root2)
+	idx.HeadChanged()
+	waitOTAIndex(t, idx, root2)
+	if n, err := idx.OTACount(root2, otaTestBalance1); err != nil || n != 12 {
+		t.Errorf("ota count mismatch: have %d (%v), want 12", n, err)
+	}
+}
+
+func TestOTAIndexFollowsChainInsert(t *testing.T) {
+	bc, chainEnv := newTestBlockChain(true)
+	defer bc.Stop()
+
+	waitOTAIndex(t, bc.OTAIndex(), bc.CurrentBlock().Root())
+
+	blocks, _ := chainEnv.GenerateChain(bc.genesisBlock, 3, func(i int, block *BlockGen) {
+		block.SetCoinbase(common.Address{byte(i + 1)})
+	})
+	if _, err := bc.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitOTAIndex(t, bc.OTAIndex(), blocks[len(blocks)-1].Root())
+}
+
+// Tests that the index of a state without OTA storage key preimages, as left
+// by a fast sync, is disabled and callers fall back to traversing the storage.
+func TestOTAIndexMissingPreimages(t *testing.T) {
+	src, _ := ethdb.NewMemDatabase()
+	_, root, _ := buildOTAStatesIn(t, src)
+
+	// Copy the state without the preimages
+	db, _ := ethdb.NewMemDatabase()
+	for _, key := range src.Keys() {
+		if bytes.HasPrefix(key, []byte("secure-key-")) {
+			continue
+		}
+		value, _ := src.Get(key)
+		db.Put(key, value)
+	}
+	sdb := state.NewDatabase(db)
+
+	idx := NewOTAIndex(sdb, func() common.Hash { return root })
+	if _, err := idx.OTACount(root, otaTestBalance1); err != ErrOTAIndexUnavailable {
+		t.Fatalf("index built without preimages: err %v", err)
+	}
+	if !idx.disabled {
+		t.Fatal("index not disabled")
+	}
+	if _, err := idx.UnspentBalance(root); err != ErrOTAIndexUnavailable {
+		t.Fatalf("disabled index served: err %v", err)
+	}
+
+	// The storage traversal still selects sets
+	otaAX, _ := vm.GetAXFromWanAddr(otaTestWanAddr(3))
+	set, balance, err := vm.GetOTASet(mustState(t, sdb, root), otaAX, 4)
+	if err != nil || len(set) != 4 || balance.Cmp(otaTestBalance1) != 0 {
+		t.Fatalf("fallback selection failed: %d otas of %v, err %v", len(set), balance, err)
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package relay defines the account, storage and receipt proofs served by the
+// eth_getProof and eth_getReceiptProof RPCs, and checks them offline against a
+// trusted header, typically the stable header returned by eth_getStableHeader.
+package relay
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/rlp"
+	"github.com/wanchain/go-wanchain/trie"
+)
+
+var (
+	ErrHeaderMismatch   = errors.New("proof does not belong to the trusted header")
+	ErrAccountMismatch  = errors.New("proven account does not match the result")
+	ErrStorageMismatch  = errors.New("proven storage value does not match the result")
+	ErrReceiptMismatch  = errors.New("proven receipt does not match the result")
+	ErrReceiptNotProven = errors.New("receipt is absent from the receipt trie")
+)
+
+// StorageResult is the proof of a single storage slot. Value is the raw value
+// kept in the storage trie: the rlp encoded word for slots written by contracts,
+// the plain bytes for the byte arrays kept by the Wanchain precompiles.
+type StorageResult struct {
+	Key   common.Hash     `json:"key"`
+	Value hexutil.Bytes   `json:"value"`
+	Proof []hexutil.Bytes `json:"proof"`
+}
+
+// AccountResult is the proof of an account and some of its storage slots.
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []hexutil.Bytes `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// ReceiptResult is the proof of a transaction receipt in the receipt trie of
+// its block. Stable tells whether the block was stable when the proof was made.
+type ReceiptResult struct {
+	BlockHash        common.Hash     `json:"blockHash"`
+	BlockNumber      hexutil.Uint64  `json:"blockNumber"`
+	TransactionHash  common.Hash     `json:"transactionHash"`
+	TransactionIndex hexutil.Uint    `json:"transactionIndex"`
+	ReceiptsRoot     common.Hash     `json:"receiptsRoot"`
+	Receipt          hexutil.Bytes   `json:"receipt"` // consensus rlp encoding
+	Proof            []hexutil.Bytes `json:"proof"`
+	Stable           bool            `json:"stable"`
+}
+
+// ToProof converts raw trie nodes into their rpc representation.
+func ToProof(nodes []rlp.RawValue) []hexutil.Bytes {
+	proof := make([]hexutil.Bytes, len(nodes))
+	for i, node := range nodes {
+		proof[i] = hexutil.Bytes(node)
+	}
+	return proof
+}
+
+func fromProof(proof []hexutil.Bytes) []rlp.RawValue {
+	nodes := make([]rlp.RawValue, len(proof))
+	for i, node := range proof {
+		nodes[i] = rlp.RawValue(node)
+	}
+	return nodes
+}
+
+// VerifyHeader checks a header matches the hash of a block the caller trusts.
+func VerifyHeader(header *types.Header, trusted common.Hash) error {
+	if header == nil || header.Hash() != trusted {
+		return ErrHeaderMismatch
+	}
+	return nil
+}
+
+// VerifyAccount checks the account and storage proofs of res against the state
+// root of a trusted header. An absent account is proven with empty fields.
+func VerifyAccount(header *types.Header, res *AccountResult) error {
+	enc, err := trie.VerifyProof(header.Root, crypto.Keccak256(res.Address.Bytes()), fromProof(res.AccountProof))
+	if err != nil {
+		return err
+	}
+
+	account := state.Account{
+		Balance:  new(big.Int),
+		Root:     types.EmptyRootHash,
+		CodeHash: crypto.Keccak256(nil),
+	}
+	if enc != nil {
+		if err := rlp.DecodeBytes(enc, &account); err != nil {
+			return err
+		}
+	}
+	if res.Balance == nil || account.Balance.Cmp(res.Balance.ToInt()) != 0 || account.Nonce != uint64(res.Nonce) ||
+		account.Root != res.StorageHash || common.BytesToHash(account.CodeHash) != res.CodeHash {
+		return ErrAccountMismatch
+	}
+
+	for i := range res.StorageProof {
+		if err := VerifyStorage(res.StorageHash, &res.StorageProof[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VerifyStorage checks a storage proof against the storage root of an account,
+// which must have been proven with VerifyAccount.
+func VerifyStorage(storageRoot common.Hash, res *StorageResult) error {
+	if storageRoot == types.EmptyRootHash {
+		if len(res.Value) != 0 {
+			return ErrStorageMismatch
+		}
+		return nil
+	}
+	value, err := trie.VerifyProof(storageRoot, crypto.Keccak256(res.Key.Bytes()), fromProof(res.Proof))
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(value, res.Value) {
+		return ErrStorageMismatch
+	}
+	return nil
+}
+
+// StorageWord decodes the value of a storage slot written by a contract.
+func StorageWord(value []byte) (common.Hash, error) {
+	if len(value) == 0 {
+		return common.Hash{}, nil
+	}
+	_, content, _, err := rlp.Split(value)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(content), nil
+}
+
+// VerifyReceipt checks a receipt proof against the receipt root of a trusted
+// header and returns the proven receipt. The logs of the receipt are those the
+// transaction emitted, so proving the receipt proves its logs too.
+func VerifyReceipt(header *types.Header, res *ReceiptResult) (*types.Receipt, error) {
+	if header.Hash() != res.BlockHash || header.Number.Uint64() != uint64(res.BlockNumber) ||
+		header.ReceiptHash != res.ReceiptsRoot {
+		return nil, ErrHeaderMismatch
+	}
+	value, err := trie.VerifyProof(header.ReceiptHash, types.DeriveShaKey(int(res.TransactionIndex)), fromProof(res.Proof))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, ErrReceiptNotProven
+	}
+	if !bytes.Equal(value, res.Receipt) {
+		return nil, ErrReceiptMismatch
+	}
+
+	receipt := new(types.Receipt)
+	if err := rlp.DecodeBytes(value, receipt); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package relay
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+var (
+	testAddr   = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+	testKey    = common.HexToHash("0x01")
+	testRawKey = common.HexToHash("0x02")
+)
+
+func accountResult(t *testing.T, statedb *state.StateDB, addr common.Address, keys ...common.Hash) *AccountResult {
+	proof, err := statedb.GetProof(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := &AccountResult{
+		Address:      addr,
+		AccountProof: ToProof(proof),
+		Balance:      (*hexutil.Big)(statedb.GetBalance(addr)),
+		CodeHash:     crypto.Keccak256Hash(nil),
+		Nonce:        hexutil.Uint64(statedb.GetNonce(addr)),
+		StorageHash:  types.EmptyRootHash,
+	}
+	if statedb.Exist(addr) {
+		res.CodeHash = statedb.GetCodeHash(addr)
+		res.StorageHash = statedb.StorageTrie(addr).Hash()
+	}
+	for _, key := range keys {
+		proof, err := statedb.GetStorageProof(addr, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.StorageProof = append(res.StorageProof, StorageResult{
+			Key:   key,
+			Value: statedb.GetStateByteArray(addr, key),
+			Proof: ToProof(proof),
+		})
+	}
+	return res
+}
+
+func TestVerifyAccount(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	statedb.SetBalance(testAddr, big.NewInt(42))
+	statedb.SetNonce(testAddr, 7)
+	statedb.SetCode(testAddr, []byte{0x60, 0x00})
+	statedb.SetState(testAddr, testKey, common.HexToHash("0xbeef"))
+	statedb.SetStateByteArray(testAddr, testRawKey, []byte("precompile data"))
+	root, err := statedb.CommitTo(db, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := &types.Header{Number: big.NewInt(1), Root: root}
+
+	res := accountResult(t, statedb, testAddr, testKey, testRawKey, common.HexToHash("0x03"))
+	if err := VerifyAccount(header, res); err != nil {
+		t.Fatalf("valid account proof rejected: %v", err)
+	}
+	if word, err := StorageWord(res.StorageProof[0].Value); err != nil || word != common.HexToHash("0xbeef") {
+		t.Fatalf("storage word mismatch: have %x, %v", word, err)
+	}
+	if string(res.StorageProof[1].Value) != "precompile data" || len(res.StorageProof[2].Value) != 0 {
+		t.Fatalf("storage values mismatch: %v", res.StorageProof)
+	}
+
+	// absent accounts are proven too
+	if err := VerifyAccount(header, accountResult(t, statedb, common.HexToAddress("0xff"))); err != nil {
+		t.Fatalf("absent account proof rejected: %v", err)
+	}
+
+	res.Balance = (*hexutil.Big)(big.NewInt(43))
+	if err := VerifyAccount(header, res); err != ErrAccountMismatch {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrAccountMismatch)
+	}
+	res.Balance = (*hexutil.Big)(big.NewInt(42))
+	res.StorageProof[1].Value = []byte("forged data")
+	if err := VerifyAccount(header, res); err != ErrStorageMismatch {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrStorageMismatch)
+	}
+	res.StorageProof[1].Value = []byte("precompile data")
+	if err := VerifyAccount(&types.Header{Number: big.NewInt(1), Root: common.Hash{1}}, res); err == nil {
+		t.Fatal("proof accepted against another state root")
+	}
+}
+
+func TestVerifyReceipt(t *testing.T) {
+	receipts := make(types.Receipts, 3)
+	for i := range receipts {
+		receipts[i] = types.NewReceipt(nil, false, big.NewInt(int64(21000*(i+1))))
+		receipts[i].Logs = []*types.Log{{Address: testAddr, Data: []byte{byte(i)}}}
+		receipts[i].Bloom = types.CreateBloom(types.Receipts{receipts[i]})
+	}
+	header := &types.Header{Number: big.NewInt(10), ReceiptHash: types.DeriveSha(receipts)}
+
+	root, proof := types.DeriveShaProof(receipts, 1)
+	if root != header.ReceiptHash {
+		t.Fatalf("receipt root mismatch: have %x, want %x", root, header.ReceiptHash)
+	}
+	res := &ReceiptResult{
+		BlockHash:        header.Hash(),
+		BlockNumber:      10,
+		TransactionIndex: 1,
+		ReceiptsRoot:     root,
+		Receipt:          receipts.GetRlp(1),
+		Proof:            ToProof(proof),
+	}
+	receipt, err := VerifyReceipt(header, res)
+	if err != nil {
+		t.Fatalf("valid receipt proof rejected: %v", err)
+	}
+	if receipt.CumulativeGasUsed.Cmp(big.NewInt(42000)) != 0 || len(receipt.Logs) != 1 || receipt.Logs[0].Data[0] != 1 {
+		t.Fatalf("proven receipt mismatch: %v", receipt)
+	}
+
+	res.TransactionIndex = 2
+	if _, err := VerifyReceipt(header, res); err == nil {
+		t.Fatal("proof accepted for another index")
+	}
+	res.TransactionIndex = 1
+	res.Receipt, _ = rlp.EncodeToBytes(receipts[0])
+	if _, err := VerifyReceipt(header, res); err != ErrReceiptMismatch {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrReceiptMismatch)
+	}
+	res.Receipt = receipts.GetRlp(1)
+	if _, err := VerifyReceipt(&types.Header{Number: big.NewInt(11), ReceiptHash: root}, res); err != ErrHeaderMismatch {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrHeaderMismatch)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+// verifyRingSigns verifies the ring signatures carried by txs as a batch: those
+// of privacy transactions, and of the wancoin refunds called directly. The
+// valid ones are cached by transaction hash, and aren't verified again when the
+// transactions are validated or applied. Invalid or malformed signatures are
+// left for the regular validation to reject.
+func verifyRingSigns(signer types.Signer, txs types.Transactions) {
+	var (
+		sigs    []*crypto.RingSignature
+		pending []*types.Transaction
+		data    []string
+	)
+	for _, tx := range txs {
+		ringSignedData, ok := txRingSignedData(tx)
+		if !ok {
+			continue
+		}
+		from, err := types.Sender(signer, tx)
+		if err != nil || vm.RingSignVerified(tx.Hash(), from.Bytes(), ringSignedData) {
+			continue
+		}
+		sig, err := vm.DecodeRingSignature(from.Bytes(), ringSignedData)
+		if err != nil {
+			continue
+		}
+		sigs = append(sigs, sig)
+		pending = append(pending, tx)
+		data = append(data, ringSignedData)
+	}
+	if len(sigs) == 0 {
+		return
+	}
+	for i, valid := range crypto.BatchVerifyRingSign(sigs) {
+		if valid {
+			vm.CacheVerifiedRingSign(pending[i].Hash(), sigs[i].Message, data[i])
+		}
+	}
+}
+
+// txRingSignedData returns the ring signed data carried by tx, if any.
+func txRingSignedData(tx *types.Transaction) (string, bool) {
+	if types.IsPrivacyTransaction(tx.Txtype()) {
+		in := tx.Data()
+		if len(in) < 4 {
+			return "", false
+		}
+		var TxDataWithRing struct {
+			RingSignedData string
+			CxtCallParams  []byte
+		}
+		if err := utilAbi.Unpack(&TxDataWithRing, "combine", in[4:]); err != nil {
+			return "", false
+		}
+		return TxDataWithRing.RingSignedData, true
+	}
+	return vm.RefundRingSignedData(tx.To(), tx.Data())
+}
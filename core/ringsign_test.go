@@ -0,0 +1,110 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+// encodeTestRingSign encodes a ring signature as the ring signed data of
+// wancoin refunds.
+func encodeTestRingSign(pubs []*ecdsa.PublicKey, image *ecdsa.PublicKey, w, q []*big.Int) string {
+	var ps, ws, qs []string
+	for i := range pubs {
+		ps = append(ps, common.ToHex(crypto.FromECDSAPub(pubs[i])))
+		ws = append(ws, hexutil.EncodeBig(w[i]))
+		qs = append(qs, hexutil.EncodeBig(q[i]))
+	}
+	return strings.Join([]string{strings.Join(ps, "&"), common.ToHex(crypto.FromECDSAPub(image)), strings.Join(ws, "&"), strings.Join(qs, "&")}, "+")
+}
+
+// newTestRefund creates a refund of the wancoin of otaKey to the account key,
+// ring signed among decoys.
+func newTestRefund(t *testing.T, signer types.Signer, nonce uint64, key, otaKey *ecdsa.PrivateKey, decoys []*ecdsa.PrivateKey) (*types.Transaction, string) {
+	pubs := []*ecdsa.PublicKey{&otaKey.PublicKey}
+	for _, decoy := range decoys {
+		pubs = append(pubs, &decoy.PublicKey)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pubs, image, w, q, err := crypto.RingSign(from.Bytes(), otaKey.D, pubs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ringSignedData := encodeTestRingSign(pubs, image, w, q)
+	data, err := vm.PackRefundCoin(ringSignedData, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := types.SignTx(types.NewTransaction(nonce, vm.WanCoinPrecompileAddr, new(big.Int), big.NewInt(200000), big.NewInt(1), data), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tx, ringSignedData
+}
+
+func TestVerifyRingSigns(t *testing.T) {
+	var (
+		signer    = types.NewEIP155Signer(big.NewInt(1))
+		key, _    = crypto.GenerateKey()
+		other, _  = crypto.GenerateKey()
+		decoys    []*ecdsa.PrivateKey
+		from      = crypto.PubkeyToAddress(key.PublicKey)
+		otherFrom = crypto.PubkeyToAddress(other.PublicKey)
+	)
+	for i := 0; i < 3; i++ {
+		decoy, _ := crypto.GenerateKey()
+		decoys = append(decoys, decoy)
+	}
+	otaKey, _ := crypto.GenerateKey()
+	valid, validData := newTestRefund(t, signer, 0, key, otaKey, decoys)
+
+	// A refund ring signed for another account is invalid when sent by key
+	otaKey2, _ := crypto.GenerateKey()
+	forged, forgedData := newTestRefund(t, signer, 1, other, otaKey2, decoys)
+	forged, _ = types.SignTx(types.NewTransaction(1, vm.WanCoinPrecompileAddr, new(big.Int), big.NewInt(200000), big.NewInt(1), forged.Data()), signer, key)
+
+	plain, _ := types.SignTx(types.NewTransaction(2, common.Address{1}, big.NewInt(1), big.NewInt(21000), big.NewInt(1), nil), signer, key)
+
+	verifyRingSigns(signer, types.Transactions{valid, forged, plain})
+
+	if !vm.RingSignVerified(valid.Hash(), from.Bytes(), validData) {
+		t.Error("valid ring signature not cached")
+	}
+	if vm.RingSignVerified(forged.Hash(), from.Bytes(), forgedData) {
+		t.Error("invalid ring signature cached")
+	}
+	// The cache doesn't vouch for other messages or signatures of the tx
+	if vm.RingSignVerified(valid.Hash(), otherFrom.Bytes(), validData) {
+		t.Error("ring signature cached for another message")
+	}
+	if vm.RingSignVerified(valid.Hash(), from.Bytes(), forgedData) {
+		t.Error("another ring signature cached for the tx")
+	}
+	if vm.RingSignVerified(common.Hash{}, from.Bytes(), validData) {
+		t.Error("ring signature cached without tx hash")
+	}
+}
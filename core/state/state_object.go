@@ -201,6 +201,24 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 		return value
 	}
 	// Load from DB in case it is missing.
+	value = self.loadState(db, key)
+	if (value != common.Hash{}) {
+		self.cachedStorage[key] = value
+	}
+	return value
+}
+
+// GetCommittedState returns the value in account storage as of the last
+// finalised transaction, ignoring the modifications made since.
+func (self *stateObject) GetCommittedState(db Database, key common.Hash) common.Hash {
+	if _, dirty := self.dirtyStorage[key]; !dirty {
+		return self.GetState(db, key)
+	}
+	return self.loadState(db, key)
+}
+
+// loadState reads a value from the storage trie.
+func (self *stateObject) loadState(db Database, key common.Hash) (value common.Hash) {
 	enc, err := self.getTrie(db).TryGet(key[:])
 	if err != nil {
 		self.setError(err)
@@ -213,9 +231,6 @@ func (self *stateObject) GetState(db Database, key common.Hash) common.Hash {
 		}
 		value.SetBytes(content)
 	}
-	if (value != common.Hash{}) {
-		self.cachedStorage[key] = value
-	}
 	return value
 }
 
@@ -19,6 +19,7 @@
 package state
 
 import (
+	"errors"
 	"fmt"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
 	"github.com/wanchain/go-wanchain/pos/util"
@@ -38,6 +39,11 @@ const (
 	OTA_ADDR_LEN = 128
 )
 
+var (
+	errNoStorageTrie = errors.New("storage trie for requested address does not exist")
+	errTrieNoProof   = errors.New("trie does not support merkle proofs")
+)
+
 type revision struct {
 	id           int
 	journalIndex int
@@ -173,6 +179,16 @@ func (self *StateDB) AddRefund(gas *big.Int) {
 	self.refund.Add(self.refund, gas)
 }
 
+// SubRefund removes gas from the refund counter.
+// This method will panic if the refund counter goes below zero
+func (self *StateDB) SubRefund(gas *big.Int) {
+	self.journal = append(self.journal, refundChange{prev: new(big.Int).Set(self.refund)})
+	if gas.Cmp(self.refund) > 0 {
+		panic("Refund counter below zero")
+	}
+	self.refund = new(big.Int).Sub(self.refund, gas)
+}
+
 // Exist reports whether the given account address exists in the state.
 // Notably this also returns true for suicided accounts.
 func (self *StateDB) Exist(addr common.Address) bool {
@@ -243,6 +259,16 @@ func (self *StateDB) GetState(a common.Address, b common.Hash) common.Hash {
 	return common.Hash{}
 }
 
+// GetCommittedState retrieves a value from the given account's committed
+// storage trie, as it was before the running transaction.
+func (self *StateDB) GetCommittedState(a common.Address, b common.Hash) common.Hash {
+	stateObject := self.getStateObject(a)
+	if stateObject != nil {
+		return stateObject.GetCommittedState(self.db, b)
+	}
+	return common.Hash{}
+}
+
 func (self *StateDB) GetStateByteArray(a common.Address, b common.Hash) []byte {
 	stateObject := self.getStateObject(a)
 	if stateObject != nil {
@@ -262,6 +288,32 @@ func (self *StateDB) StorageTrie(a common.Address) Trie {
 	return cpy.updateTrie(self.db)
 }
 
+// GetProof returns the Merkle proof of the account of a in the state trie.
+func (self *StateDB) GetProof(a common.Address) ([]rlp.RawValue, error) {
+	return proveTrieKey(self.trie, a.Bytes())
+}
+
+// GetStorageProof returns the Merkle proof of the storage slot key of a in
+// the account's storage trie.
+func (self *StateDB) GetStorageProof(a common.Address, key common.Hash) ([]rlp.RawValue, error) {
+	tr := self.StorageTrie(a)
+	if tr == nil {
+		return nil, errNoStorageTrie
+	}
+	return proveTrieKey(tr, key.Bytes())
+}
+
+// proveTrieKey builds a proof with tries able to, like trie.SecureTrie.
+func proveTrieKey(tr Trie, key []byte) ([]rlp.RawValue, error) {
+	prover, ok := tr.(interface {
+		Prove(key []byte) []rlp.RawValue
+	})
+	if !ok {
+		return nil, errTrieNoProof
+	}
+	return prover.Prove(key), nil
+}
+
 func (self *StateDB) HasSuicided(addr common.Address) bool {
 	stateObject := self.getStateObject(addr)
 	if stateObject != nil {
@@ -471,7 +523,7 @@ func (db *StateDB) ForEachStorageByteArray(addr common.Address, cb func(key comm
 
 	epochid,_ := util.GetCurrentBlkEpochSlotID()
 
-	if epochid < posconfig.Cfg().MercuryEpochId {
+	if !posconfig.Cfg().IsMercury(epochid) {
 		db.ForEachStorageByteArrayBeforeFork(addr,cb)
 
 	} else {
@@ -256,7 +256,7 @@ func (st *StateTransition) TransitionDb() (ret []byte, requiredGas, usedGas *big
 	var stampTotalGas uint64
 	if types.IsPrivacyTransaction(st.msg.TxType()) {
 		pureCallData, totalUseableGas, evmUseableGas, err := PreProcessPrivacyTx(st.evm.StateDB,
-			sender.Address().Bytes(),
+			st.evm.TxHash, sender.Address().Bytes(),
 			st.data, st.gasPrice, st.value)
 		if err != nil {
 			return nil, nil, nil, false, err
@@ -364,6 +364,12 @@ func init() {
 	}
 }
 
+// PackPrivacyTxData packs the input of a privacy transaction: the ring
+// signature of the OTA paying the gas, combined with the contract call data.
+func PackPrivacyTxData(ringSignedData string, callData []byte) ([]byte, error) {
+	return utilAbi.Pack("combine", ringSignedData, callData)
+}
+
 type PrivacyTxInfo struct {
 	PublicKeys         []*ecdsa.PublicKey
 	KeyImage           *ecdsa.PublicKey
@@ -375,7 +381,7 @@ type PrivacyTxInfo struct {
 	GasLeftSubRingSign uint64
 }
 
-func FetchPrivacyTxInfo(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *big.Int) (info *PrivacyTxInfo, err error) {
+func FetchPrivacyTxInfo(stateDB vm.StateDB, txHash common.Hash, hashInput []byte, in []byte, gasPrice *big.Int) (info *PrivacyTxInfo, err error) {
 	if len(in) < 4 {
 		return nil, vm.ErrInvalidRingSigned
 	}
@@ -390,7 +396,7 @@ func FetchPrivacyTxInfo(stateDB vm.StateDB, hashInput []byte, in []byte, gasPric
 		return
 	}
 
-	ringSignInfo, err := vm.FetchRingSignInfo(stateDB, hashInput, TxDataWithRing.RingSignedData)
+	ringSignInfo, err := vm.FetchRingSignInfo(stateDB, txHash, hashInput, TxDataWithRing.RingSignedData)
 	if err != nil {
 		return
 	}
@@ -425,7 +431,7 @@ func FetchPrivacyTxInfo(stateDB vm.StateDB, hashInput []byte, in []byte, gasPric
 	return
 }
 
-func ValidPrivacyTx(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *big.Int,
+func ValidPrivacyTx(stateDB vm.StateDB, txHash common.Hash, hashInput []byte, in []byte, gasPrice *big.Int,
 	intrGas *big.Int, txValue *big.Int, gasLimit *big.Int) error {
 	if intrGas == nil || intrGas.BitLen() > 64 {
 		return vm.ErrOutOfGas
@@ -439,7 +445,7 @@ func ValidPrivacyTx(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *b
 		return vm.ErrInvalidGasPrice
 	}
 
-	info, err := FetchPrivacyTxInfo(stateDB, hashInput, in, gasPrice)
+	info, err := FetchPrivacyTxInfo(stateDB, txHash, hashInput, in, gasPrice)
 	if err != nil {
 		return err
 	}
@@ -463,12 +469,12 @@ func ValidPrivacyTx(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *b
 	return nil
 }
 
-func PreProcessPrivacyTx(stateDB vm.StateDB, hashInput []byte, in []byte, gasPrice *big.Int, txValue *big.Int) (callData []byte, totalUseableGas uint64, evmUseableGas uint64, err error) {
+func PreProcessPrivacyTx(stateDB vm.StateDB, txHash common.Hash, hashInput []byte, in []byte, gasPrice *big.Int, txValue *big.Int) (callData []byte, totalUseableGas uint64, evmUseableGas uint64, err error) {
 	if txValue.Sign() != 0 {
 		return nil, 0, 0, vm.ErrInvalidPrivacyValue
 	}
 
-	info, err := FetchPrivacyTxInfo(stateDB, hashInput, in, gasPrice)
+	info, err := FetchPrivacyTxInfo(stateDB, txHash, hashInput, in, gasPrice)
 	if err != nil {
 		return nil, 0, 0, err
 	}
@@ -329,7 +329,7 @@ func (l *txList) InvalidPrivacyTx(stateDB vm.StateDB, signer types.Signer, gasLi
 		}
 
 		intrGas := IntrinsicGas(tx.Data(), tx.To(), true)
-		err = ValidPrivacyTx(stateDB, from.Bytes(), tx.Data(), tx.GasPrice(), intrGas, tx.Value(), gasLimit)
+		err = ValidPrivacyTx(stateDB, tx.Hash(), from.Bytes(), tx.Data(), tx.GasPrice(), intrGas, tx.Value(), gasLimit)
 
 		return err != nil
 	})
@@ -352,7 +352,7 @@ func (l *txList) InvalidPrivacyTx(stateDB vm.StateDB, signer types.Signer, gasLi
 }
 
 // InvalidPosTx remove invalidate pos transactions
-func (l *txList) InvalidPosRBTx(stateDB vm.StateDB, signer types.Signer) types.Transactions {
+func (l *txList) InvalidPosRBTx(stateDB vm.StateDB, signer types.Signer, pos vm.PosContext) types.Transactions {
 	removed := l.txs.Filter(func(tx *types.Transaction) bool {
 		if !types.IsPosTransaction(tx.Txtype()) || (*tx.To()) != vm.GetRBAddress() {
 			return false
@@ -363,7 +363,7 @@ func (l *txList) InvalidPosRBTx(stateDB vm.StateDB, signer types.Signer) types.T
 			return true
 		}
 
-		err = vm.ValidPosRBTx(stateDB, from, tx.Data())
+		err = vm.ValidPosRBTx(stateDB, from, tx.Data(), pos)
 		return err != nil
 	})
 
@@ -607,7 +607,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) (*common.Addre
 		}
 
 	} else {
-		err := ValidPrivacyTx(pool.currentState, from.Bytes(), tx.Data(), tx.GasPrice(), intrGas, tx.Value(), pool.currentMaxGas)
+		err := ValidPrivacyTx(pool.currentState, tx.Hash(), from.Bytes(), tx.Data(), tx.GasPrice(), intrGas, tx.Value(), pool.currentMaxGas)
 		if err != nil {
 			return nil, err
 		}
@@ -616,7 +616,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) (*common.Addre
 	// Check precompile contracts transactions validation
 	if tx.To() != nil {
 		if p := vm.PrecompiledContractsByzantium[*tx.To()]; p != nil {
-			if err = p.ValidTx(pool.currentState, pool.signer, tx); err != nil {
+			if err = p.ValidTx(pool.currentState, pool.signer, tx, posContextOf(pool.chain)); err != nil {
 				return nil, err
 			}
 		}
@@ -832,6 +832,9 @@ func (pool *TxPool) addTx(tx *types.Transaction, local bool) error {
 
 // addTxs attempts to queue a batch of transactions if they are valid.
 func (pool *TxPool) addTxs(txs []*types.Transaction, local bool) error {
+	// Verify the ring signatures of the batch concurrently, outside of the lock
+	verifyRingSigns(pool.signer, txs)
+
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
@@ -961,7 +964,7 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) {
 		}
 
 		// Remove all invalid pos transactions
-		invalidPos := list.InvalidPosRBTx(pool.currentState, pool.signer)
+		invalidPos := list.InvalidPosRBTx(pool.currentState, pool.signer, posContextOf(pool.chain))
 		for _, tx := range invalidPos {
 			hash := tx.Hash()
 			log.Trace("Removed invalid pos transaction", "hash", hash)
@@ -1159,7 +1162,7 @@ func (pool *TxPool) demoteUnexecutables() {
 		}
 
 		// Remove all invalid pos transactions
-		invalidPos := list.InvalidPosRBTx(pool.currentState, pool.signer)
+		invalidPos := list.InvalidPosRBTx(pool.currentState, pool.signer, posContextOf(pool.chain))
 		for _, tx := range invalidPos {
 			hash := tx.Hash()
 			log.Trace("Removed invalid pos transaction", "hash", hash)
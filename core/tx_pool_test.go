@@ -1521,7 +1521,9 @@ func (CTStateDB) GetCode(common.Address) []byte
 func (CTStateDB) SetCode(common.Address, []byte)                                         {}
 func (CTStateDB) GetCodeSize(common.Address) int                                         { return 0 }
 func (CTStateDB) AddRefund(*big.Int)                                                     {}
+func (CTStateDB) SubRefund(*big.Int)                                                     {}
 func (CTStateDB) GetRefund() *big.Int                                                    { return nil }
+func (CTStateDB) GetCommittedState(common.Address, common.Hash) common.Hash              { return common.Hash{} }
 func (CTStateDB) GetState(common.Address, common.Hash) common.Hash                       { return common.Hash{} }
 func (CTStateDB) SetState(common.Address, common.Hash, common.Hash)                      {}
 func (CTStateDB) Suicide(common.Address) bool                                            { return false }
@@ -1599,7 +1601,7 @@ func TestStampVerifySuccess(t *testing.T) {
 
 	dbMockRetVal, _ = new(big.Int).SetString(WanStamp0dot1, 10)
 
-	_, _, _, err := PreProcessPrivacyTx(st.evm.StateDB, sender.Bytes(), st.data, st.gasPrice, common.Big0)
+	_, _, _, err := PreProcessPrivacyTx(st.evm.StateDB, common.Hash{}, sender.Bytes(), st.data, st.gasPrice, common.Big0)
 	if err != nil {
 		t.Error(err)
 		return
@@ -1624,7 +1626,7 @@ func TestStampVerifyFailWrongSender(t *testing.T) {
 
 	dbMockRetVal, _ = new(big.Int).SetString(WanStamp0dot1, 10)
 
-	_, _, _, err := PreProcessPrivacyTx(st.evm.StateDB, sender.Bytes(), st.data, st.gasPrice, common.Big0)
+	_, _, _, err := PreProcessPrivacyTx(st.evm.StateDB, common.Hash{}, sender.Bytes(), st.data, st.gasPrice, common.Big0)
 	if err == nil {
 		t.Error(err)
 		return
@@ -30,6 +30,23 @@ type DerivableList interface {
 }
 
 func DeriveSha(list DerivableList) common.Hash {
+	return deriveTrie(list).Hash()
+}
+
+// DeriveShaProof returns the root DeriveSha computes for list together with
+// the merkle proof of the element at index, keyed by DeriveShaKey(index).
+func DeriveShaProof(list DerivableList, index int) (common.Hash, []rlp.RawValue) {
+	trie := deriveTrie(list)
+	return trie.Hash(), trie.Prove(DeriveShaKey(index))
+}
+
+// DeriveShaKey returns the trie key of the element at index in a DeriveSha trie.
+func DeriveShaKey(index int) []byte {
+	key, _ := rlp.EncodeToBytes(uint(index))
+	return key
+}
+
+func deriveTrie(list DerivableList) *trie.Trie {
 	keybuf := new(bytes.Buffer)
 	trie := new(trie.Trie)
 	for i := 0; i < list.Len(); i++ {
@@ -37,5 +54,5 @@ func DeriveSha(list DerivableList) common.Hash {
 		rlp.Encode(keybuf, uint(i))
 		trie.Update(keybuf.Bytes(), list.GetRlp(i))
 	}
-	return trie.Hash()
+	return trie
 }
@@ -81,7 +81,7 @@ func (c *ecrecover) Run(input []byte, contract *Contract, evm *EVM) ([]byte, err
 	return common.LeftPadBytes(crypto.Keccak256(pubKey[1:])[12:], 32), nil
 }
 
-func (c *ecrecover) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *ecrecover) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction, pos PosContext) error {
 	return nil
 }
 
@@ -100,7 +100,7 @@ func (c *sha256hash) Run(input []byte, contract *Contract, evm *EVM) ([]byte, er
 	return h[:], nil
 }
 
-func (c *sha256hash) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *sha256hash) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction, pos PosContext) error {
 	return nil
 }
 
@@ -120,7 +120,7 @@ func (c *ripemd160hash) Run(input []byte, contract *Contract, evm *EVM) ([]byte,
 	return common.LeftPadBytes(ripemd.Sum(nil), 32), nil
 }
 
-func (c *ripemd160hash) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *ripemd160hash) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction, pos PosContext) error {
 	return nil
 }
 
@@ -138,7 +138,7 @@ func (c *dataCopy) Run(in []byte, contract *Contract, evm *EVM) ([]byte, error)
 	return in, nil
 }
 
-func (c *dataCopy) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *dataCopy) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction, pos PosContext) error {
 	return nil
 }
 
@@ -247,7 +247,7 @@ func (c *bigModExp) Run(input []byte, contract *Contract, evm *EVM) ([]byte, err
 	return common.LeftPadBytes(base.Exp(base, exp, mod).Bytes(), int(modLen)), nil
 }
 
-func (c *bigModExp) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *bigModExp) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction, pos PosContext) error {
 	return nil
 }
 
@@ -306,7 +306,7 @@ func (c *bn256Add) Run(input []byte, contract *Contract, evm *EVM) ([]byte, erro
 	return res.Marshal(), nil
 }
 
-func (c *bn256Add) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *bn256Add) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction, pos PosContext) error {
 	return nil
 }
 
@@ -328,7 +328,7 @@ func (c *bn256ScalarMul) Run(input []byte, contract *Contract, evm *EVM) ([]byte
 	return res.Marshal(), nil
 }
 
-func (c *bn256ScalarMul) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *bn256ScalarMul) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction, pos PosContext) error {
 	return nil
 }
 
@@ -380,7 +380,7 @@ func (c *bn256Pairing) Run(input []byte, contract *Contract, evm *EVM) ([]byte,
 	return false32Byte, nil
 }
 
-func (c *bn256Pairing) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *bn256Pairing) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction, pos PosContext) error {
 	return nil
 }
 
@@ -549,7 +549,7 @@ func (c *wanchainStampSC) Run(in []byte, contract *Contract, env *EVM) ([]byte,
 	return nil, errMethodId
 }
 
-func (c *wanchainStampSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *wanchainStampSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction, pos PosContext) error {
 	if stateDB == nil || signer == nil || tx == nil {
 		return errParameters
 	}
@@ -692,7 +692,7 @@ func (c *wanCoinSC) Run(in []byte, contract *Contract, evm *EVM) ([]byte, error)
 	return nil, errMethodId
 }
 
-func (c *wanCoinSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *wanCoinSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction, pos PosContext) error {
 	if stateDB == nil || signer == nil || tx == nil {
 		return errParameters
 	}
@@ -715,7 +715,7 @@ func (c *wanCoinSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tran
 			return err
 		}
 
-		_, _, err = c.ValidRefundReq(stateDB, payload[4:], from.Bytes())
+		_, _, err = c.ValidRefundReq(stateDB, tx.Hash(), payload[4:], from.Bytes())
 		return err
 	}
 
@@ -795,7 +795,7 @@ func (c *wanCoinSC) buyCoin(in []byte, contract *Contract, evm *EVM) ([]byte, er
 	}
 }
 
-func (c *wanCoinSC) ValidRefundReq(stateDB StateDB, payload []byte, from []byte) (image []byte, value *big.Int, err error) {
+func (c *wanCoinSC) ValidRefundReq(stateDB StateDB, txHash common.Hash, payload []byte, from []byte) (image []byte, value *big.Int, err error) {
 	if stateDB == nil || len(payload) == 0 || len(from) == 0 {
 		return nil, nil, errors.New("unknown error")
 	}
@@ -810,7 +810,7 @@ func (c *wanCoinSC) ValidRefundReq(stateDB StateDB, payload []byte, from []byte)
 		return nil, nil, errRefundCoin
 	}
 
-	ringSignInfo, err := FetchRingSignInfo(stateDB, from, RefundStruct.RingSignedData)
+	ringSignInfo, err := FetchRingSignInfo(stateDB, txHash, from, RefundStruct.RingSignedData)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -834,7 +834,7 @@ func (c *wanCoinSC) ValidRefundReq(stateDB StateDB, payload []byte, from []byte)
 }
 
 func (c *wanCoinSC) refund(all []byte, contract *Contract, evm *EVM) ([]byte, error) {
-	kix, value, err := c.ValidRefundReq(evm.StateDB, all, contract.CallerAddress.Bytes())
+	kix, value, err := c.ValidRefundReq(evm.StateDB, evm.TxHash, all, contract.CallerAddress.Bytes())
 	if err != nil {
 		fmt.Println("failed refund")
 		fmt.Println(evm.BlockNumber)
@@ -853,6 +853,72 @@ func (c *wanCoinSC) refund(all []byte, contract *Contract, evm *EVM) ([]byte, er
 	return []byte{1}, nil
 
 }
+// PackRefundCoin packs the input of a refundCoin call of the wancoin contract,
+// refunding value from the OTA ring signed in ringSignedData.
+func PackRefundCoin(ringSignedData string, value *big.Int) ([]byte, error) {
+	return coinAbi.Pack("refundCoin", ringSignedData, value)
+}
+
+// IsBuyCoinInput reports whether input is a buyCoinNote call of the wancoin contract.
+func IsBuyCoinInput(input []byte) bool {
+	if len(input) < 4 {
+		return false
+	}
+
+	var methodIdArr [4]byte
+	copy(methodIdArr[:], input[:4])
+	return methodIdArr == buyIdArr
+}
+
+// UnpackRefundCoin decodes the input of a refundCoin call of the wancoin contract
+// into the ring signed data and the refunded value.
+func UnpackRefundCoin(input []byte) (string, *big.Int, error) {
+	if len(input) < 4 {
+		return "", nil, errRefundCoin
+	}
+
+	var methodIdArr [4]byte
+	copy(methodIdArr[:], input[:4])
+	if methodIdArr != refundIdArr {
+		return "", nil, errRefundCoin
+	}
+
+	var RefundStruct struct {
+		RingSignedData string
+		Value          *big.Int
+	}
+	if err := coinAbi.Unpack(&RefundStruct, "refundCoin", input[4:]); err != nil {
+		return "", nil, errRefundCoin
+	}
+	return RefundStruct.RingSignedData, RefundStruct.Value, nil
+}
+
+// EncodeRingSignOut encodes a ring signature in the string format carried by
+// privacy transactions and wancoin refunds, see DecodeRingSignOut.
+func EncodeRingSignOut(publicKeys []*ecdsa.PublicKey, keyimage *ecdsa.PublicKey, Ws []*big.Int, Qs []*big.Int) string {
+	tmp := make([]string, 0, len(publicKeys))
+	for _, pk := range publicKeys {
+		tmp = append(tmp, common.ToHex(crypto.FromECDSAPub(pk)))
+	}
+	pkStr := strings.Join(tmp, "&")
+
+	k := common.ToHex(crypto.FromECDSAPub(keyimage))
+
+	wa := make([]string, 0, len(Ws))
+	for _, wi := range Ws {
+		wa = append(wa, hexutil.EncodeBig(wi))
+	}
+	wStr := strings.Join(wa, "&")
+
+	qa := make([]string, 0, len(Qs))
+	for _, qi := range Qs {
+		qa = append(qa, hexutil.EncodeBig(qi))
+	}
+	qStr := strings.Join(qa, "&")
+
+	return strings.Join([]string{pkStr, k, wStr, qStr}, "+")
+}
+
 func DecodeRingSignOut(s string) (error, []*ecdsa.PublicKey, *ecdsa.PublicKey, []*big.Int, []*big.Int) {
 	ss := strings.Split(s, "+")
 	if len(ss) < 4 {
@@ -918,7 +984,11 @@ type RingSignInfo struct {
 	OTABalance *big.Int
 }
 
-func FetchRingSignInfo(stateDB StateDB, hashInput []byte, ringSignedStr string) (info *RingSignInfo, err error) {
+// FetchRingSignInfo decodes and verifies the ring signature ringSignedStr of
+// hashInput, carried by the transaction txHash. Ring signatures verified for
+// the transaction already aren't verified again, a zero txHash disables the
+// cache.
+func FetchRingSignInfo(stateDB StateDB, txHash common.Hash, hashInput []byte, ringSignedStr string) (info *RingSignInfo, err error) {
 	if stateDB == nil || hashInput == nil {
 		return nil, errParameters
 	}
@@ -948,9 +1018,12 @@ func FetchRingSignInfo(stateDB StateDB, hashInput []byte, ringSignedStr string)
 
 	infoTmp.OTABalance = balanceGet
 
-	valid := crypto.VerifyRingSign(hashInput, infoTmp.PublicKeys, infoTmp.KeyImage, infoTmp.W_Random, infoTmp.Q_Random)
-	if !valid {
-		return nil, ErrInvalidRingSigned
+	if !RingSignVerified(txHash, hashInput, ringSignedStr) {
+		valid := crypto.VerifyRingSign(hashInput, infoTmp.PublicKeys, infoTmp.KeyImage, infoTmp.W_Random, infoTmp.Q_Random)
+		if !valid {
+			return nil, ErrInvalidRingSigned
+		}
+		CacheVerifiedRingSign(txHash, hashInput, ringSignedStr)
 	}
 
 	return infoTmp, nil
@@ -28,4 +28,6 @@ var (
 	ErrInvalidGasPrice          = errors.New("invalid gas price")
 	ErrInvalidPrivacyValue      = errors.New("invalid privacy transaction value")
 	ErrInvalidPosValue          = errors.New("invalid pos transaction value")
+
+	errSStoreSentry = errors.New("not enough gas for reentrancy sentry")
 )
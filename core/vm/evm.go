@@ -29,7 +29,7 @@ import (
 
 // emptyCodeHash is used by create to ensure deployment is disallowed to already
 // deployed contract addresses (relevant after the account abstraction).
-//var emptyCodeHash = crypto.Keccak256Hash(nil)
+var emptyCodeHash = crypto.Keccak256Hash(nil)
 
 type (
 	CanTransferFunc func(StateDB, common.Address, *big.Int) bool
@@ -69,6 +69,7 @@ type Context struct {
 	// Message information
 	Origin   common.Address // Provides information for ORIGIN
 	GasPrice *big.Int       // Provides information for GASPRICE
+	TxHash   common.Hash    // Hash of the transaction applied, zero for calls
 
 	// Block information
 	Coinbase    common.Address // Provides information for COINBASE
@@ -76,6 +77,9 @@ type Context struct {
 	BlockNumber *big.Int       // Provides information for NUMBER
 	Time        *big.Int       // Provides information for TIME
 	Difficulty  *big.Int       // Provides information for DIFFICULTY
+
+	// POS information
+	Pos PosContext // POS services of the chain, package-level ones if nil
 }
 
 // EVM is the Ethereum Virtual Machine base object and provides
@@ -313,6 +317,22 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 
 // Create creates a new contract using code as deployment code.
 func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	contractAddr = crypto.CreateAddress(caller.Address(), evm.StateDB.GetNonce(caller.Address()))
+	return evm.create(caller, code, crypto.Keccak256Hash(code), gas, value, contractAddr)
+}
+
+// Create2 creates a new contract using code as deployment code.
+//
+// The difference between Create2 with Create is Create2 uses sha3(0xff ++ msg.sender ++ salt ++ sha3(init_code))[12:]
+// instead of the usual sender-and-nonce-hash as the address where the contract is initialized at.
+func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *big.Int, salt *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	codeHash := crypto.Keccak256Hash(code)
+	contractAddr = crypto.CreateAddress2(caller.Address(), common.BigToHash(salt), codeHash[:])
+	return evm.create(caller, code, codeHash, gas, endowment, contractAddr)
+}
+
+// create creates a new contract at address using code as deployment code.
+func (evm *EVM) create(caller ContractRef, code []byte, codeHash common.Hash, gas uint64, value *big.Int, contractAddr common.Address) ([]byte, common.Address, uint64, error) {
 
 	// Depth check execution. Fail if we're trying to execute above the
 	// limit.
@@ -326,8 +346,7 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	nonce := evm.StateDB.GetNonce(caller.Address())
 	evm.StateDB.SetNonce(caller.Address(), nonce+1)
 
-	contractAddr = crypto.CreateAddress(caller.Address(), nonce)
-	if !evm.StateDB.Empty(contractAddr) {
+	if evm.isCollision(contractAddr) {
 		return nil, common.Address{}, 0, ErrContractAddressCollision
 	}
 	// Create a new account on the state
@@ -344,12 +363,12 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	// E The contract is a scoped evmironment for this execution context
 	// only.
 	contract := NewContract(caller, AccountRef(contractAddr), value, gas)
-	contract.SetCallCode(&contractAddr, crypto.Keccak256Hash(code), code)
+	contract.SetCallCode(&contractAddr, codeHash, code)
 
 	if evm.vmConfig.NoRecursion && evm.depth > 0 {
 		return nil, contractAddr, gas, nil
 	}
-	ret, err = run(evm, snapshot, contract, nil)
+	ret, err := run(evm, snapshot, contract, nil)
 	// check whether the max code size has been exceeded
 	maxCodeSizeExceeded := /*evm.ChainConfig().IsEIP158(evm.BlockNumber) &&*/ len(ret) > params.MaxCodeSize
 	// if the contract creation ran successfully and no errors were returned
@@ -381,6 +400,17 @@ func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.I
 	return ret, contractAddr, contract.Gas, err
 }
 
+// isCollision reports whether a contract can't be deployed at address. Since
+// Constantinople, only accounts with a nonce or code collide, so that funds
+// can be sent ahead to a CREATE2 address.
+func (evm *EVM) isCollision(address common.Address) bool {
+	if !evm.chainRules.IsConstantinople {
+		return !evm.StateDB.Empty(address)
+	}
+	codeHash := evm.StateDB.GetCodeHash(address)
+	return evm.StateDB.GetNonce(address) != 0 || (codeHash != (common.Hash{}) && codeHash != emptyCodeHash)
+}
+
 // ChainConfig returns the evmironment's chain configuration
 func (evm *EVM) ChainConfig() *params.ChainConfig { return evm.chainConfig }
 
@@ -122,21 +122,117 @@ func gasSStore(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, m
 		y, x = stack.Back(1), stack.Back(0)
 		val  = evm.StateDB.GetState(contract.Address(), common.BigToHash(x))
 	)
-	// This checks for 3 scenario's and calculates gas accordingly
-	// 1. From a zero-value address to a non-zero value         (NEW VALUE)
-	// 2. From a non-zero value address to a zero-value address (DELETE)
-	// 3. From a non-zero to a non-zero                         (CHANGE)
-	if common.EmptyHash(val) && !common.EmptyHash(common.BigToHash(y)) {
-		// 0 => non 0
-		return params.SstoreSetGas, nil
-	} else if !common.EmptyHash(val) && common.EmptyHash(common.BigToHash(y)) {
-		evm.StateDB.AddRefund(new(big.Int).SetUint64(params.SstoreRefundGas))
+	// The legacy gas metering only takes into consideration the current state.
+	// Constantinople introduced net gas metering (EIP-1283), which Petersburg
+	// removed again.
+	if !evm.chainRules.IsConstantinople || evm.chainRules.IsPetersburg {
+		// This checks for 3 scenario's and calculates gas accordingly
+		// 1. From a zero-value address to a non-zero value         (NEW VALUE)
+		// 2. From a non-zero value address to a zero-value address (DELETE)
+		// 3. From a non-zero to a non-zero                         (CHANGE)
+		if common.EmptyHash(val) && !common.EmptyHash(common.BigToHash(y)) {
+			// 0 => non 0
+			return params.SstoreSetGas, nil
+		} else if !common.EmptyHash(val) && common.EmptyHash(common.BigToHash(y)) {
+			evm.StateDB.AddRefund(new(big.Int).SetUint64(params.SstoreRefundGas))
+
+			return params.SstoreClearGas, nil
+		} else {
+			// non 0 => non 0 (or 0 => 0)
+			return params.SstoreResetGas, nil
+		}
+	}
+	// The new gas metering is based on net gas costs (EIP-1283):
+	//
+	// 1. If current value equals new value (this is a no-op), 200 gas is deducted.
+	// 2. If current value does not equal new value
+	//   2.1. If original value equals current value (this storage slot has not been changed by the current execution context)
+	//     2.1.1. If original value is 0, 20000 gas is deducted.
+	// 	   2.1.2. Otherwise, 5000 gas is deducted. If new value is 0, add 15000 gas to refund counter.
+	// 	2.2. If original value does not equal current value (this storage slot is dirty), 200 gas is deducted. Apply both of the following clauses.
+	// 	  2.2.1. If original value is not 0
+	//       2.2.1.1. If current value is 0 (also means that new value is not 0), remove 15000 gas from refund counter. We can prove that refund counter will never go below 0.
+	//       2.2.1.2. If new value is 0 (also means that current value is not 0), add 15000 gas to refund counter.
+	// 	  2.2.2. If original value equals new value (this storage slot is reset)
+	//       2.2.2.1. If original value is 0, add 19800 gas to refund counter.
+	// 	     2.2.2.2. Otherwise, add 4800 gas to refund counter.
+	return netSStoreGas(evm, contract, x, y, val, netSStoreGasEIP1283)
+}
 
-		return params.SstoreClearGas, nil
-	} else {
-		// non 0 => non 0 (or 0 => 0)
-		return params.SstoreResetGas, nil
+// netSStoreCosts holds the costs and refunds of a net gas metering scheme.
+type netSStoreCosts struct {
+	noop, init, clean, dirty                   uint64
+	clearRefund, resetRefund, resetClearRefund uint64
+}
+
+var (
+	netSStoreGasEIP1283 = netSStoreCosts{
+		noop:             params.NetSstoreNoopGas,
+		init:             params.NetSstoreInitGas,
+		clean:            params.NetSstoreCleanGas,
+		dirty:            params.NetSstoreDirtyGas,
+		clearRefund:      params.NetSstoreClearRefund,
+		resetRefund:      params.NetSstoreResetRefund,
+		resetClearRefund: params.NetSstoreResetClearRefund,
+	}
+	netSStoreGasEIP2200 = netSStoreCosts{
+		noop:             params.SstoreNoopGasEIP2200,
+		init:             params.SstoreInitGasEIP2200,
+		clean:            params.SstoreCleanGasEIP2200,
+		dirty:            params.SstoreDirtyGasEIP2200,
+		clearRefund:      params.SstoreClearRefundEIP2200,
+		resetRefund:      params.SstoreCleanRefundEIP2200,
+		resetClearRefund: params.SstoreInitRefundEIP2200,
 	}
+)
+
+// netSStoreGas calculates the net gas cost of storing y at slot x, whose
+// current value is current, and updates the refund counter accordingly.
+func netSStoreGas(evm *EVM, contract *Contract, x, y *big.Int, current common.Hash, costs netSStoreCosts) (uint64, error) {
+	value := common.BigToHash(y)
+	if current == value { // noop (1)
+		return costs.noop, nil
+	}
+	original := evm.StateDB.GetCommittedState(contract.Address(), common.BigToHash(x))
+	if original == current {
+		if original == (common.Hash{}) { // create slot (2.1.1)
+			return costs.init, nil
+		}
+		if value == (common.Hash{}) { // delete slot (2.1.2b)
+			evm.StateDB.AddRefund(new(big.Int).SetUint64(costs.clearRefund))
+		}
+		return costs.clean, nil // write existing slot (2.1.2)
+	}
+	if original != (common.Hash{}) {
+		if current == (common.Hash{}) { // recreate slot (2.2.1.1)
+			evm.StateDB.SubRefund(new(big.Int).SetUint64(costs.clearRefund))
+		} else if value == (common.Hash{}) { // delete slot (2.2.1.2)
+			evm.StateDB.AddRefund(new(big.Int).SetUint64(costs.clearRefund))
+		}
+	}
+	if original == value {
+		if original == (common.Hash{}) { // reset to original inexistent slot (2.2.2.1)
+			evm.StateDB.AddRefund(new(big.Int).SetUint64(costs.resetClearRefund))
+		} else { // reset to original existing slot (2.2.2.2)
+			evm.StateDB.AddRefund(new(big.Int).SetUint64(costs.resetRefund))
+		}
+	}
+	return costs.dirty, nil // dirty update (2.2)
+}
+
+// gasSStoreEIP2200 implements the Istanbul net gas metering (EIP-2200). It is
+// EIP-1283 with repriced costs, and it fails when the gas left is within the
+// call stipend so that calls with the stipend can't change the state.
+func gasSStoreEIP2200(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	// If we fail the minimum gas availability invariant, fail (0)
+	if contract.Gas <= params.SstoreSentryGasEIP2200 {
+		return 0, errSStoreSentry
+	}
+	var (
+		y, x    = stack.Back(1), stack.Back(0)
+		current = evm.StateDB.GetState(contract.Address(), common.BigToHash(x))
+	)
+	return netSStoreGas(evm, contract, x, y, current, netSStoreGasEIP2200)
 }
 
 func makeGasLog(n uint64) gasFunc {
@@ -291,6 +387,28 @@ func gasCreate(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, m
 	return gas, nil
 }
 
+func gasCreate2(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	var overflow bool
+	gas, err := memoryGasCost(mem, memorySize)
+	if err != nil {
+		return 0, err
+	}
+	if gas, overflow = math.SafeAdd(gas, params.Create2Gas); overflow {
+		return 0, errGasUintOverflow
+	}
+	wordGas, overflow := bigUint64(stack.Back(2))
+	if overflow {
+		return 0, errGasUintOverflow
+	}
+	if wordGas, overflow = math.SafeMul(toWordSize(wordGas), params.Sha3WordGas); overflow {
+		return 0, errGasUintOverflow
+	}
+	if gas, overflow = math.SafeAdd(gas, wordGas); overflow {
+		return 0, errGasUintOverflow
+	}
+	return gas, nil
+}
+
 func gasBalance(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 	return gt.Balance, nil
 }
@@ -299,6 +417,10 @@ func gasExtCodeSize(gt params.GasTable, evm *EVM, contract *Contract, stack *Sta
 	return gt.ExtcodeSize, nil
 }
 
+func gasExtCodeHash(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	return gt.ExtcodeHash, nil
+}
+
 func gasSLoad(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
 	return gt.SLoad, nil
 }
@@ -70,6 +70,11 @@ func NewInterpreter(evm *EVM, cfg Config) *Interpreter {
 	// we'll set the default jump table.
 	if !cfg.JumpTable[STOP].valid {
 		switch {
+		case evm.chainRules.IsIstanbul:
+			cfg.JumpTable = istanbulInstructionSet
+
+		case evm.chainRules.IsConstantinople:
+			cfg.JumpTable = constantinopleInstructionSet
 
 		//case evm.ChainConfig().IsByzantium(evm.BlockNumber):
 		//	cfg.JumpTable = byzantiumInstructionSet
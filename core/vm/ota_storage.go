@@ -223,6 +223,44 @@ func GetUnspendOTATotalBalance(statedb StateDB) (*big.Int, error) {
 	return totalOTABalance.Sub(totalOTABalance, totalSpendedOTABalance), nil
 }
 
+// GetOTACount returns the number of OTAs of the given balance, spent or not.
+func GetOTACount(statedb StateDB, balance *big.Int) (int, error) {
+	if statedb == nil || balance == nil {
+		return 0, ErrUnknown
+	}
+
+	count := 0
+	statedb.ForEachStorageByteArray(OTABalance2ContractAddr(balance), func(key common.Hash, value []byte) bool {
+		if len(value) != 0 {
+			count++
+		}
+		return true
+	})
+
+	return count, nil
+}
+
+// GetOTAImageCount returns the number of spent ota images, in total and grouped by
+// the ota storage address of the spent balance.
+func GetOTAImageCount(statedb StateDB) (int, map[common.Address]int, error) {
+	if statedb == nil {
+		return 0, nil, ErrUnknown
+	}
+
+	total, spent := 0, make(map[common.Address]int)
+	statedb.ForEachStorageByteArray(otaImageStorageAddr, func(key common.Hash, value []byte) bool {
+		if len(value) == 0 {
+			return true
+		}
+
+		total++
+		spent[OTABalance2ContractAddr(new(big.Int).SetBytes(value))]++
+		return true
+	})
+
+	return total, spent, nil
+}
+
 // setOTA storage ota info, include balance and WanAddr. Overwrite if ota exist already.
 func setOTA(statedb StateDB, balance *big.Int, otaWanAddr []byte) error {
 	if statedb == nil || balance == nil {
@@ -1605,3 +1605,77 @@ func TestAddOTAImage(t *testing.T) {
 		t.Errorf("err:%s", err.Error())
 	}
 }
+
+func TestGetOTACount(t *testing.T) {
+	var (
+		db, _      = ethdb.NewMemDatabase()
+		statedb, _ = state.New(common.Hash{}, state.NewDatabase(db))
+
+		balance1 = big.NewInt(10)
+		balance2 = big.NewInt(20)
+	)
+
+	for i, otaShortAddr := range otaShortAddrs {
+		balance := balance1
+		if i%3 == 0 {
+			balance = balance2
+		}
+		if _, err := AddOTAIfNotExist(statedb, balance, common.FromHex(otaShortAddr)); err != nil {
+			t.Fatalf("err:%s", err.Error())
+		}
+	}
+	for i := 0; i < 2; i++ {
+		otaImage := crypto.Keccak256(common.FromHex(otaShortAddrs[i]))
+		if err := AddOTAImage(statedb, otaImage, balance2.Bytes()); err != nil {
+			t.Fatalf("err:%s", err.Error())
+		}
+	}
+
+	if count, err := GetOTACount(statedb, balance1); err != nil || count != 6 {
+		t.Errorf("balance1 ota count:%d, err:%v, expect:6", count, err)
+	}
+	if count, err := GetOTACount(statedb, balance2); err != nil || count != 3 {
+		t.Errorf("balance2 ota count:%d, err:%v, expect:3", count, err)
+	}
+	if count, err := GetOTACount(statedb, big.NewInt(30)); err != nil || count != 0 {
+		t.Errorf("unused balance ota count:%d, err:%v, expect:0", count, err)
+	}
+
+	total, spent, err := GetOTAImageCount(statedb)
+	if err != nil {
+		t.Fatalf("err:%s", err.Error())
+	}
+	if total != 2 || spent[OTABalance2ContractAddr(balance2)] != 2 || spent[OTABalance2ContractAddr(balance1)] != 0 {
+		t.Errorf("image count:%d, spent:%v, expect 2 images of balance2", total, spent)
+	}
+}
+
+func TestUnpackRefundCoin(t *testing.T) {
+	value := big.NewInt(10)
+	input, err := PackRefundCoin("ringsigned", value)
+	if err != nil {
+		t.Fatalf("err:%s", err.Error())
+	}
+	if IsBuyCoinInput(input) {
+		t.Errorf("refund input taken for buyCoinNote")
+	}
+
+	ringSignedData, valueGet, err := UnpackRefundCoin(input)
+	if err != nil {
+		t.Fatalf("err:%s", err.Error())
+	}
+	if ringSignedData != "ringsigned" || valueGet.Cmp(value) != 0 {
+		t.Errorf("unpacked ringSignedData:%s, value:%v", ringSignedData, valueGet)
+	}
+
+	buyInput, err := coinAbi.Pack("buyCoinNote", "0x00", value)
+	if err != nil {
+		t.Fatalf("err:%s", err.Error())
+	}
+	if !IsBuyCoinInput(buyInput) {
+		t.Errorf("buyCoinNote input not recognised")
+	}
+	if _, _, err := UnpackRefundCoin(buyInput); err == nil {
+		t.Errorf("buyCoinNote input unpacked as refund")
+	}
+}
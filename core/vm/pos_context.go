@@ -0,0 +1,40 @@
+package vm
+
+import (
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+// PosContext gives the POS precompiled contracts the POS services of the
+// chain they run for. A nil PosContext stands for the package-level services.
+type PosContext interface {
+	// PosConfig returns the POS configuration of the chain.
+	PosConfig() *posconfig.Config
+	// SelectLead returns the epoch leader selection of the chain, nil before
+	// the switch to POS.
+	SelectLead() util.SelectLead
+	// LocalDb returns the local database called name of the chain.
+	LocalDb(name string) *posdb.Db
+}
+
+func posConfig(pos PosContext) *posconfig.Config {
+	if pos == nil {
+		return posconfig.Cfg()
+	}
+	return pos.PosConfig()
+}
+
+func posSelectLead(pos PosContext) util.SelectLead {
+	if pos == nil {
+		return util.GetEpocherInst()
+	}
+	return pos.SelectLead()
+}
+
+func posLocalDb(pos PosContext) *posdb.Db {
+	if pos == nil {
+		return posdb.GetDb()
+	}
+	return pos.LocalDb(posconfig.PosLocalDB)
+}
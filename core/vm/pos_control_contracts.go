@@ -117,7 +117,7 @@ func (p *PosControl) Run(input []byte, contract *Contract, evm *EVM) ([]byte, er
 	return nil, errMethodId
 }
 
-func (p *PosControl) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (p *PosControl) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction, pos PosContext) error {
 	input := tx.Data()
 	if len(input) < 4 {
 		return errors.New("parameter is too short")
@@ -8,7 +8,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/wanchain/go-wanchain/pos/posconfig"
 
 	"github.com/wanchain/go-wanchain/accounts/abi"
 	"github.com/wanchain/go-wanchain/common"
@@ -613,7 +612,7 @@ func (p *PosStaking) Run(input []byte, contract *Contract, evm *EVM) ([]byte, er
 	return nil, errMethodId
 }
 
-func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction, pos PosContext) error {
 	input := tx.Data()
 	if len(input) < 4 {
 		return errors.New("parameter is too short")
@@ -627,7 +626,7 @@ func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tra
 
 	if methodId == stakeRegisterId {
 		eidNow, _ := util.CalEpochSlotID(uint64(time.Now().Unix()))
-		if eidNow < posconfig.ApolloEpochID {
+		if !posConfig(pos).IsApollo(eidNow) {
 			return  errors.New("stakeRegister haven't enabled.")
 		}
 		_, err := p.stakeRegisterParseAndValid(input[4:])
@@ -673,7 +672,7 @@ func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tra
 		return nil
 	} else if methodId == stakeUpdateFeeRateId {
 		eidNow, _ := util.CalEpochSlotID(uint64(time.Now().Unix()))
-		if eidNow < posconfig.ApolloEpochID {
+		if !posConfig(pos).IsApollo(eidNow) {
 			return  errors.New("stakeUpdateFeeRateId haven't enabled.")
 		}
 		_, err := p.updateFeeRateParseAndValid(input[4:])
@@ -687,12 +686,28 @@ func (p *PosStaking) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Tra
 }
 
 func (p *PosStaking) saveStakeInfo(evm *EVM, stakerInfo *StakerInfo) error {
+	return StoreStakerInfo(evm.StateDB, stakerInfo)
+}
+
+// PackStakeIn packs the input of a stakeIn call to the staking contract.
+func PackStakeIn(secPk, bn256Pk []byte, lockEpochs, feeRate *big.Int) ([]byte, error) {
+	return cscAbi.Pack("stakeIn", secPk, bn256Pk, lockEpochs, feeRate)
+}
+
+// PackDelegateIn packs the input of a delegateIn call to the staking contract.
+func PackDelegateIn(validator common.Address) ([]byte, error) {
+	return cscAbi.Pack("delegateIn", validator)
+}
+
+// StoreStakerInfo writes stakerInfo into the staker list the way the staking
+// contract does, so state can be prepared outside of a transaction.
+func StoreStakerInfo(stateDB StateDB, stakerInfo *StakerInfo) error {
 	infoBytes, err := rlp.EncodeToBytes(stakerInfo)
 	if err != nil {
 		return err
 	}
 	key := GetStakeInKeyHash(stakerInfo.Address)
-	res := StoreInfo(evm.StateDB, StakersInfoAddr, key, infoBytes)
+	res := StoreInfo(stateDB, StakersInfoAddr, key, infoBytes)
 	if res != nil {
 		return res
 	}
@@ -783,7 +798,7 @@ func (p *PosStaking) PartnerIn(payload []byte, contract *Contract, evm *EVM) ([]
 	}
 
 	eidNow, _ := util.CalEpochSlotID(evm.Time.Uint64())
-	if eidNow >= posconfig.ApolloEpochID &&  eidNow < posconfig.AugustEpochID{
+	if evm.ChainConfig().IsApollo(eidNow) && !evm.ChainConfig().IsAugust(eidNow) {
 		if contract.Value().Cmp(minPartnerIn) < 0 {
 			return nil, errors.New("min wan amount should >= 10000")
 		}
@@ -825,7 +840,7 @@ func (p *PosStaking) PartnerIn(payload []byte, contract *Contract, evm *EVM) ([]
 			return nil, errors.New("Too many partners")
 		}
 
-		if eidNow >= posconfig.ApolloEpochID {
+		if evm.ChainConfig().IsApollo(eidNow) {
 			if contract.Value().Cmp(minPartnerIn) < 0 {
 				return nil, errors.New("min wan amount should >= 10000")
 			}
@@ -837,7 +852,7 @@ func (p *PosStaking) PartnerIn(payload []byte, contract *Contract, evm *EVM) ([]
 			StakingEpoch: eidNow + JoinDelay,
 			LockEpochs:   uint64(realLockEpoch),
 		}
-		if posconfig.FirstEpochId == 0 {
+		if posConfig(evm.Context.Pos).FirstEpochID() == 0 {
 			partner.StakingEpoch = 0
 		}
 		partner.StakeAmount = big.NewInt(0).Mul(partner.Amount, big.NewInt(int64(weight)))
@@ -989,7 +1004,7 @@ func (p *PosStaking) doStakeIn(contract *Contract, evm *EVM, info StakeInParam)
 		From:         contract.CallerAddress,
 		StakingEpoch: eidNow + JoinDelay,
 	}
-	if posconfig.FirstEpochId == 0 {
+	if posConfig(evm.Context.Pos).FirstEpochID() == 0 {
 		stakerInfo.StakingEpoch = 0
 	}
 	stakerInfo.StakeAmount = big.NewInt(0).Mul(stakerInfo.Amount, big.NewInt(int64(weight)))
@@ -1379,7 +1394,7 @@ func (p *PosStaking) stakeRegisterLog(contract *Contract, evm *EVM, info *Staker
 
 func (p *PosStaking) stakeInLog(contract *Contract, evm *EVM, info *StakerInfo) error {
 	eid, _ := util.CalEpochSlotID(evm.Time.Uint64())
-	if eid < posconfig.ApolloEpochID {
+	if !evm.ChainConfig().IsApollo(eid) {
 		params := make([]common.Hash, 5)
 		params[0] = common.BytesToHash(contract.Caller().Bytes())
 		params[1] = common.BigToHash(contract.Value())
@@ -1405,7 +1420,7 @@ func (p *PosStaking) stakeInLog(contract *Contract, evm *EVM, info *StakerInfo)
 
 func (p *PosStaking) stakeAppendLog(contract *Contract, evm *EVM, validator common.Address) error {
 	eid, _ := util.CalEpochSlotID(evm.Time.Uint64())
-	if eid < posconfig.ApolloEpochID {
+	if !evm.ChainConfig().IsApollo(eid) {
 		params := make([]common.Hash, 3)
 		params[0] = common.BytesToHash(contract.Caller().Bytes())
 		params[1] = common.BigToHash(contract.Value())
@@ -1427,7 +1442,7 @@ func (p *PosStaking) stakeAppendLog(contract *Contract, evm *EVM, validator comm
 
 func (p *PosStaking) stakeUpdateLog(contract *Contract, evm *EVM, info *StakerInfo) error {
 	eid, _ := util.CalEpochSlotID(evm.Time.Uint64())
-	if eid < posconfig.ApolloEpochID {
+	if !evm.ChainConfig().IsApollo(eid) {
 		params := make([]common.Hash, 3)
 		params[0] = common.BytesToHash(contract.Caller().Bytes())
 		params[1] = common.BigToHash(new(big.Int).SetUint64(info.NextLockEpochs))
@@ -1449,7 +1464,7 @@ func (p *PosStaking) stakeUpdateLog(contract *Contract, evm *EVM, info *StakerIn
 
 func (p *PosStaking) delegateInLog(contract *Contract, evm *EVM, validator common.Address) error {
 	eid, _ := util.CalEpochSlotID(evm.Time.Uint64())
-	if eid < posconfig.ApolloEpochID {
+	if !evm.ChainConfig().IsApollo(eid) {
 		params := make([]common.Hash, 3)
 		params[0] = common.BytesToHash(contract.Caller().Bytes())
 		params[1] = common.BigToHash(contract.Value())
@@ -1472,7 +1487,7 @@ func (p *PosStaking) delegateInLog(contract *Contract, evm *EVM, validator commo
 
 func (p *PosStaking) delegateOutLog(contract *Contract, evm *EVM, validator common.Address) error {
 	eid, _ := util.CalEpochSlotID(evm.Time.Uint64())
-	if eid < posconfig.ApolloEpochID {
+	if !evm.ChainConfig().IsApollo(eid) {
 		params := make([]common.Hash, 2)
 		params[0] = common.BytesToHash(contract.Caller().Bytes())
 		params[1] = validator.Hash()
@@ -1492,7 +1507,7 @@ func (p *PosStaking) delegateOutLog(contract *Contract, evm *EVM, validator comm
 
 func (p *PosStaking) stakeUpdateFeeRateLog(contract *Contract, evm *EVM, feeInfo *UpdateFeeRate) error {
 	eid, _ := util.CalEpochSlotID(evm.Time.Uint64())
-	if eid >= posconfig.ApolloEpochID {
+	if evm.ChainConfig().IsApollo(eid) {
 		// event stakeUpdateFeeRate(address indexed sender, address indexed posAddress, uint indexed feeRate);
 		params := make([]common.Hash, 3)
 		params[0] = common.BytesToHash(contract.Caller().Bytes())
@@ -1511,7 +1526,7 @@ func (p *PosStaking) stakeUpdateFeeRateLog(contract *Contract, evm *EVM, feeInfo
 
 func (p *PosStaking) partnerInLog(contract *Contract, evm *EVM, addr *common.Address, renew bool) error {
 	eid, _ := util.CalEpochSlotID(evm.Time.Uint64())
-	if eid >= posconfig.ApolloEpochID {
+	if evm.ChainConfig().IsApollo(eid) {
 		// event partnerIn(address indexed sender, address indexed posAddress, uint indexed v, bool renewal);
 		params := make([]common.Hash, 3)
 		params[0] = common.BytesToHash(contract.Caller().Bytes())
@@ -0,0 +1,226 @@
+package vm
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"sync"
+
+	"github.com/wanchain/go-wanchain/accounts/abi"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+type precompileAbi struct {
+	name string
+	abi  *abi.ABI
+}
+
+// precompileAbis holds the ABI of the Wanchain precompiled contracts that are
+// called with ABI encoded input.
+var precompileAbis = map[common.Address]precompileAbi{
+	wanCoinPrecompileAddr:      {"wanCoin", &coinAbi},
+	wanStampPrecompileAddr:     {"wanStamp", &stampAbi},
+	WanCscPrecompileAddr:       {"staking", &cscAbi},
+	PosControlPrecompileAddr:   {"posControl", &posControlAbi},
+	slotLeaderPrecompileAddr:   {"slotLeader", &slotLeaderAbi},
+	randomBeaconPrecompileAddr: {"randomBeacon", &rbSCAbi},
+}
+
+// maxRegisteredAbis is the number of contracts whose ABI can be registered.
+const maxRegisteredAbis = 1024
+
+var (
+	registeredAbisMu sync.RWMutex
+	registeredAbis   = make(map[common.Address]*abi.ABI)
+
+	errPrecompileAbi = errors.New("can't replace the ABI of a precompiled contract")
+	errTooManyAbis   = errors.New("too many registered contract ABIs")
+)
+
+// RegisterABI registers the ABI of the contract at addr, so the logs it emits
+// can be decoded by DecodeLog. The registration isn't persisted. Up to
+// maxRegisteredAbis contracts can be registered, replacing the ABI of a
+// registered contract is always possible.
+func RegisterABI(addr common.Address, contractAbi abi.ABI) error {
+	if _, ok := precompileAbis[addr]; ok {
+		return errPrecompileAbi
+	}
+	registeredAbisMu.Lock()
+	defer registeredAbisMu.Unlock()
+	if _, ok := registeredAbis[addr]; !ok && len(registeredAbis) >= maxRegisteredAbis {
+		return errTooManyAbis
+	}
+	registeredAbis[addr] = &contractAbi
+	return nil
+}
+
+// PrecompileCall is the decoded input of a call to a precompiled contract.
+// Byte slices and big integers in Args are converted to their hexutil types.
+type PrecompileCall struct {
+	Contract string
+	Method   string
+	Args     map[string]interface{}
+}
+
+// IsAbiPrecompile reports whether addr is a precompiled contract whose calls
+// DecodePrecompileCall can decode.
+func IsAbiPrecompile(addr common.Address) bool {
+	_, ok := precompileAbis[addr]
+	return ok
+}
+
+// DecodePrecompileCall decodes the input of a call to one of the Wanchain
+// precompiled contracts. It returns nil if to is not such a contract.
+func DecodePrecompileCall(to common.Address, input []byte) (*PrecompileCall, error) {
+	contract, ok := precompileAbis[to]
+	if !ok {
+		return nil, nil
+	}
+	method, err := contract.abi.MethodById(input)
+	if err != nil {
+		return nil, err
+	}
+	values, err := method.Inputs.UnpackValues(input[4:])
+	if err != nil {
+		return nil, err
+	}
+	inputs := method.Inputs.NonIndexed()
+	if len(values) != len(inputs) {
+		return nil, errors.New("wrong number of arguments")
+	}
+	call := &PrecompileCall{
+		Contract: contract.name,
+		Method:   method.Name,
+		Args:     make(map[string]interface{}, len(values)),
+	}
+	for i, value := range values {
+		call.Args[inputs[i].Name] = abiValue(value)
+	}
+	return call, nil
+}
+
+// DecodedLog is a log decoded by the ABI of the contract that emitted it.
+// Byte slices and big integers in Args are converted to their hexutil types,
+// indexed arguments of a dynamic type are given as their topic hash.
+type DecodedLog struct {
+	Contract string
+	Event    string
+	Args     map[string]interface{}
+}
+
+// DecodeLog decodes a log of a precompiled contract or of a contract whose ABI
+// got registered by RegisterABI. It returns nil if the log can't be matched to
+// an event.
+//
+// The staking contract logged the method signature and no data before the
+// Apollo fork, such logs only get their Event set to the method name.
+func DecodeLog(log *types.Log) (*DecodedLog, error) {
+	// The random beacon contract logs the epoch and its random number
+	// without an event signature.
+	if log.Address == randomBeaconPrecompileAddr && len(log.Topics) == 2 {
+		return &DecodedLog{
+			Contract: "randomBeacon",
+			Event:    "random",
+			Args: map[string]interface{}{
+				"epochId": hexutil.Uint64(log.Topics[0].Big().Uint64()),
+				"random":  (*hexutil.Big)(log.Topics[1].Big()),
+			},
+		}, nil
+	}
+
+	name, contractAbi := lookupLogAbi(log.Address)
+	if contractAbi == nil || len(log.Topics) == 0 {
+		return nil, nil
+	}
+	for _, event := range contractAbi.Events {
+		if event.Anonymous || event.Id() != log.Topics[0] {
+			continue
+		}
+		args, err := decodeEventArgs(event, log)
+		if err != nil {
+			return nil, err
+		}
+		return &DecodedLog{Contract: name, Event: event.Name, Args: args}, nil
+	}
+	if log.Address == WanCscPrecompileAddr {
+		for _, method := range contractAbi.Methods {
+			if crypto.Keccak256Hash([]byte(method.Sig())) == log.Topics[0] {
+				return &DecodedLog{Contract: name, Event: method.Name}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func lookupLogAbi(addr common.Address) (string, *abi.ABI) {
+	if contract, ok := precompileAbis[addr]; ok {
+		return contract.name, contract.abi
+	}
+	registeredAbisMu.RLock()
+	defer registeredAbisMu.RUnlock()
+	return "", registeredAbis[addr]
+}
+
+func decodeEventArgs(event abi.Event, log *types.Log) (map[string]interface{}, error) {
+	args := make(map[string]interface{}, len(event.Inputs))
+
+	topics := log.Topics[1:]
+	for _, input := range event.Inputs {
+		if !input.Indexed {
+			continue
+		}
+		if len(topics) == 0 {
+			return nil, errors.New("too few topics for event " + event.Name)
+		}
+		topic := topics[0]
+		topics = topics[1:]
+
+		switch input.Type.T {
+		case abi.IntTy, abi.UintTy, abi.BoolTy, abi.AddressTy, abi.FixedBytesTy:
+			word := abi.Arguments{{Name: input.Name, Type: input.Type}}
+			values, err := word.UnpackValues(topic.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			args[input.Name] = abiValue(values[0])
+		default:
+			args[input.Name] = topic
+		}
+	}
+
+	nonIndexed := event.Inputs.NonIndexed()
+	if len(nonIndexed) == 0 {
+		return args, nil
+	}
+	values, err := nonIndexed.UnpackValues(log.Data)
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		args[nonIndexed[i].Name] = abiValue(value)
+	}
+	return args, nil
+}
+
+// abiValue converts an unpacked ABI value to the type it's served as by RPC.
+func abiValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case common.Address, common.Hash:
+		return v
+	case []byte:
+		return hexutil.Bytes(v)
+	case *big.Int:
+		return (*hexutil.Big)(v)
+	}
+	// Fixed size byte arrays like bytes32.
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.Uint8 {
+		b := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(b), rv)
+		return hexutil.Bytes(b)
+	}
+	return value
+}
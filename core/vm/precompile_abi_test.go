@@ -0,0 +1,152 @@
+package vm
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/accounts/abi"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+func TestDecodePrecompileCall(t *testing.T) {
+	validator := common.HexToAddress("0x2d0e7c0813a51d3bd1d08246af2a8a7a57d8922e")
+	input, err := PackDelegateIn(validator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, err := DecodePrecompileCall(WanCscPrecompileAddr, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if call.Contract != "staking" || call.Method != "delegateIn" {
+		t.Fatalf("wrong call decoded: %s.%s", call.Contract, call.Method)
+	}
+	if addr, ok := call.Args["delegateAddress"].(common.Address); !ok || addr != validator {
+		t.Errorf("wrong delegateAddress: %v", call.Args["delegateAddress"])
+	}
+
+	input, err = PackStakeIn(make([]byte, 65), make([]byte, 64), big.NewInt(10), big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	call, err = DecodePrecompileCall(WanCscPrecompileAddr, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock, ok := call.Args["lockEpochs"].(*hexutil.Big); !ok || lock.ToInt().Int64() != 10 {
+		t.Errorf("wrong lockEpochs: %v", call.Args["lockEpochs"])
+	}
+
+	if _, err := DecodePrecompileCall(WanCscPrecompileAddr, []byte{1, 2, 3, 4}); err == nil {
+		t.Error("expected error for unknown method")
+	}
+	if call, err := DecodePrecompileCall(common.HexToAddress("0x1234"), input); call != nil || err != nil {
+		t.Errorf("decoded call to a normal account: %v, %v", call, err)
+	}
+}
+
+func TestDecodeLog(t *testing.T) {
+	sender := common.HexToAddress("0x01")
+	validator := common.HexToAddress("0x02")
+
+	// event stakeIn(address indexed sender, address indexed posAddress, uint indexed v, uint feeRate, uint lockEpoch);
+	data := append(common.BigToHash(big.NewInt(100)).Bytes(), common.BigToHash(big.NewInt(10)).Bytes()...)
+	log := &types.Log{
+		Address: WanCscPrecompileAddr,
+		Topics:  []common.Hash{cscAbi.Events["stakeIn"].Id(), sender.Hash(), validator.Hash(), common.BigToHash(big.NewInt(5000))},
+		Data:    data,
+	}
+	decoded, err := DecodeLog(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded == nil || decoded.Contract != "staking" || decoded.Event != "stakeIn" {
+		t.Fatalf("wrong event decoded: %+v", decoded)
+	}
+	if decoded.Args["sender"] != sender || decoded.Args["posAddress"] != validator {
+		t.Errorf("wrong indexed arguments: %v", decoded.Args)
+	}
+	if v, ok := decoded.Args["v"].(*hexutil.Big); !ok || v.ToInt().Int64() != 5000 {
+		t.Errorf("wrong v: %v", decoded.Args["v"])
+	}
+	if lock, ok := decoded.Args["lockEpoch"].(*hexutil.Big); !ok || lock.ToInt().Int64() != 10 {
+		t.Errorf("wrong lockEpoch: %v", decoded.Args["lockEpoch"])
+	}
+
+	// Before Apollo the method signature was logged instead.
+	log = &types.Log{
+		Address: WanCscPrecompileAddr,
+		Topics:  []common.Hash{crypto.Keccak256Hash([]byte(cscAbi.Methods["delegateOut"].Sig())), sender.Hash(), validator.Hash()},
+	}
+	if decoded, err := DecodeLog(log); err != nil || decoded == nil || decoded.Event != "delegateOut" {
+		t.Errorf("legacy log not decoded: %+v, %v", decoded, err)
+	}
+
+	log = &types.Log{
+		Address: randomBeaconPrecompileAddr,
+		Topics:  []common.Hash{common.BigToHash(big.NewInt(18000)), common.BigToHash(big.NewInt(42))},
+	}
+	if decoded, err := DecodeLog(log); err != nil || decoded == nil || decoded.Args["epochId"] != hexutil.Uint64(18000) {
+		t.Errorf("random log not decoded: %+v, %v", decoded, err)
+	}
+
+	// Logs of other contracts are decoded once their ABI is registered.
+	contract := common.HexToAddress("0x1234")
+	transfer, err := abi.JSON(strings.NewReader(`[{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"value","type":"uint256"}]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log = &types.Log{
+		Address: contract,
+		Topics:  []common.Hash{transfer.Events["Transfer"].Id(), sender.Hash(), validator.Hash()},
+		Data:    common.BigToHash(big.NewInt(7)).Bytes(),
+	}
+	if decoded, err := DecodeLog(log); decoded != nil || err != nil {
+		t.Errorf("decoded log of unknown contract: %+v, %v", decoded, err)
+	}
+	if err := RegisterABI(WanCscPrecompileAddr, transfer); err == nil {
+		t.Error("replaced the ABI of a precompiled contract")
+	}
+	if err := RegisterABI(contract, transfer); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		registeredAbisMu.Lock()
+		delete(registeredAbis, contract)
+		registeredAbisMu.Unlock()
+	}()
+	decoded, err = DecodeLog(log)
+	if err != nil || decoded == nil || decoded.Event != "Transfer" || decoded.Args["to"] != validator {
+		t.Errorf("registered log not decoded: %+v, %v", decoded, err)
+	}
+}
+
+func TestRegisterABILimit(t *testing.T) {
+	registeredAbisMu.Lock()
+	saved := registeredAbis
+	registeredAbis = make(map[common.Address]*abi.ABI)
+	registeredAbisMu.Unlock()
+	defer func() {
+		registeredAbisMu.Lock()
+		registeredAbis = saved
+		registeredAbisMu.Unlock()
+	}()
+
+	var contractAbi abi.ABI
+	for i := 0; i < maxRegisteredAbis; i++ {
+		if err := RegisterABI(common.BigToAddress(big.NewInt(int64(0x10000+i))), contractAbi); err != nil {
+			t.Fatalf("registration %d failed: %v", i, err)
+		}
+	}
+	if err := RegisterABI(common.HexToAddress("0x1234"), contractAbi); err != errTooManyAbis {
+		t.Fatalf("error mismatch: have %v, want %v", err, errTooManyAbis)
+	}
+	// a registered contract can still get its ABI replaced
+	if err := RegisterABI(common.BigToAddress(big.NewInt(0x10000)), contractAbi); err != nil {
+		t.Fatalf("replacing a registered ABI failed: %v", err)
+	}
+}
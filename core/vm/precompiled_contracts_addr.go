@@ -70,6 +70,9 @@ var (
 	// TODO: remove one?
 	RandomBeaconPrecompileAddr = randomBeaconPrecompileAddr
 	SlotLeaderPrecompileAddr   = slotLeaderPrecompileAddr
+	WanCoinPrecompileAddr      = wanCoinPrecompileAddr
+	OTABalanceStorageAddr      = otaBalanceStorageAddr
+	OTAImageStorageAddr        = otaImageStorageAddr
 )
 
 // PrecompiledContract is the basic interface for native Go contracts. The implementation
@@ -78,7 +81,7 @@ var (
 type PrecompiledContract interface {
 	RequiredGas(input []byte) uint64                                // RequiredPrice calculates the contract gas use
 	Run(input []byte, contract *Contract, evm *EVM) ([]byte, error) // Run runs the precompiled contract
-	ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error
+	ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction, pos PosContext) error
 }
 
 // PrecompiledContractsHomestead contains the default set of pre-compiled Ethereum
@@ -238,7 +238,7 @@ func (c *RandomBeaconContract) Run(input []byte, contract *Contract, evm *EVM) (
 		return c.sigShare(input[4:], contract, evm)
 	} else {
 		epochId,_ :=util.CalEpochSlotID(evm.Time.Uint64())
-		if epochId >= posconfig.Cfg().MercuryEpochId {
+		if evm.ChainConfig().IsMercury(epochId) {
 			if methodId == getEpochIdId {
 				return c.getEpochId(input[4:], contract, evm)
 			} else if methodId == getRandomNumberByEpochIdId {
@@ -264,7 +264,7 @@ func (c *RandomBeaconContract) getEpochId(payload []byte, contract *Contract, ev
 func (c *RandomBeaconContract) getRandomNumberByEpochId(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
 	epochId := new(big.Int).SetBytes(getData(payload, 0, 32)).Uint64()
 
-	r := GetStateR(evm.StateDB, epochId)
+	r := GetStateRWithConfig(posConfig(evm.Context.Pos), evm.StateDB, epochId)
 
 	if r == nil {
 		r = big.NewInt(0)
@@ -278,7 +278,7 @@ func (c *RandomBeaconContract) getRandomNumberByTimestamp(payload []byte, contra
 
 	epochId,_ := posutil.CalEpochSlotID(timestamp)
 
-	r := GetStateR(evm.StateDB, epochId)
+	r := GetStateRWithConfig(posConfig(evm.Context.Pos), evm.StateDB, epochId)
 
 	if r == nil {
 		r = big.NewInt(0)
@@ -287,8 +287,8 @@ func (c *RandomBeaconContract) getRandomNumberByTimestamp(payload []byte, contra
 	return common.LeftPadBytes(r.Bytes(), 32), nil
 }
 
-func (c *RandomBeaconContract) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
-	if posconfig.FirstEpochId == 0 {
+func (c *RandomBeaconContract) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction, pos PosContext) error {
+	if posConfig(pos).FirstEpochID() == 0 {
 		return  errParameters
 	}
 	if stateDB == nil || signer == nil || tx == nil {
@@ -305,10 +305,10 @@ func (c *RandomBeaconContract) ValidTx(stateDB StateDB, signer types.Signer, tx
 		return err
 	}
 
-	return ValidPosRBTx(stateDB, from, payload)
+	return ValidPosRBTx(stateDB, from, payload, pos)
 }
-func getRBProposerGroup(eid uint64)([]bn256.G1,error){
-	ep := util.GetEpocherInst()
+func getRBProposerGroup(pos PosContext, eid uint64)([]bn256.G1,error){
+	ep := posSelectLead(pos)
 	if ep == nil {
 		return nil,  errors.New("GetEpocherInst() == nil")
 	}
@@ -322,19 +322,23 @@ func getRBProposerGroup(eid uint64)([]bn256.G1,error){
 //
 // params or gas check functions
 //
-func ValidPosRBTx(stateDB StateDB, from common.Address, payload []byte) error {
+func ValidPosRBTx(stateDB StateDB, from common.Address, payload []byte, pos PosContext) error {
 	log.Debug("ValidPosRBTx")
 	var methodId [4]byte
 	copy(methodId[:], payload[:4])
 
+	now := uint64(time.Now().Unix())
 	if methodId == dkg1Id {
-		_, err := validDkg1(stateDB, uint64(time.Now().Unix()), from, payload[4:])
+		_, err := validDkg1(pos, stateDB, now, from, payload[4:])
+		notifyRBReject(pos, RbDkg1Stage, from, payload[4:], now, err)
 		return err
 	} else if methodId == dkg2Id {
-		_, err := validDkg2(stateDB, uint64(time.Now().Unix()), from, payload[4:])
+		_, err := validDkg2(pos, stateDB, now, from, payload[4:])
+		notifyRBReject(pos, RbDkg2Stage, from, payload[4:], now, err)
 		return err
 	} else if methodId == sigShareId {
-		_, _, _, err := validSigShare(stateDB, uint64(time.Now().Unix()), from, payload[4:])
+		_, _, _, err := validSigShare(pos, stateDB, now, from, payload[4:])
+		notifyRBReject(pos, RbSignStage, from, payload[4:], now, err)
 		return err
 	} else {
 		return errParameters
@@ -352,7 +356,7 @@ func ValidPosRBTx(stateDB StateDB, from common.Address, payload []byte) error {
 // 'caller' is the caller of DKG1. It should be set as Contract.CallerAddress
 // when called by precompiled contract. And should be set as tx's sender when
 // called by tx pool.
-func validDkg1(stateDB StateDB, time uint64, caller common.Address,
+func validDkg1(pos PosContext, stateDB StateDB, time uint64, caller common.Address,
 	payload []byte) (*RbDKG1FlatTxPayload, error) {
 
 	var dkg1FlatParam RbDKG1FlatTxPayload
@@ -369,7 +373,7 @@ func validDkg1(stateDB StateDB, time uint64, caller common.Address,
 	eid := dkg1Param.EpochId
 	pid := dkg1Param.ProposerId
 
-	pks, err := getRBProposerGroupVar(eid)
+	pks, err := getRBProposerGroupVar(pos, eid)
 	if err != nil {
 		return nil, err
 	}
@@ -379,7 +383,7 @@ func validDkg1(stateDB StateDB, time uint64, caller common.Address,
 	}
 
 	// 2. ProposerId: weather in the random commit
-	if !isInRandomGroupVar(pks, eid, pid, caller) {
+	if !isInRandomGroupVar(pos, pks, eid, pid, caller) {
 		return nil, logError(errors.New("invalid proposer, proposerId " + strconv.FormatUint(uint64(pid), 10)))
 	}
 
@@ -415,7 +419,7 @@ func validDkg1(stateDB StateDB, time uint64, caller common.Address,
 	return &dkg1FlatParam, nil
 }
 
-func validDkg2(stateDB StateDB, time uint64, caller common.Address,
+func validDkg2(pos PosContext, stateDB StateDB, time uint64, caller common.Address,
 	payload []byte) (*RbDKG2FlatTxPayload, error) {
 
 	var dkg2FlatParam RbDKG2FlatTxPayload
@@ -432,7 +436,7 @@ func validDkg2(stateDB StateDB, time uint64, caller common.Address,
 	eid := dkg2Param.EpochId
 	pid := dkg2Param.ProposerId
 
-	pks, err := getRBProposerGroupVar(eid)
+	pks, err := getRBProposerGroupVar(pos, eid)
 	if err != nil {
 		return nil, err
 	}
@@ -443,7 +447,7 @@ func validDkg2(stateDB StateDB, time uint64, caller common.Address,
 	}
 
 	// 2. ProposerId: weather in the random commit
-	if !isInRandomGroupVar(pks, eid, pid, caller) {
+	if !isInRandomGroupVar(pos, pks, eid, pid, caller) {
 		return nil, logError(errors.New("error proposerId " + strconv.FormatUint(uint64(pid), 10)))
 	}
 
@@ -476,7 +480,7 @@ func validDkg2(stateDB StateDB, time uint64, caller common.Address,
 	return &dkg2FlatParam, nil
 }
 
-func validSigShare(stateDB StateDB, time uint64, caller common.Address,
+func validSigShare(pos PosContext, stateDB StateDB, time uint64, caller common.Address,
 	payload []byte) (*RbSIGTxPayload, []bn256.G1, []RbCijDataCollector, error) {
 
 	var sigShareParam RbSIGTxPayload
@@ -488,7 +492,7 @@ func validSigShare(stateDB StateDB, time uint64, caller common.Address,
 	eid := sigShareParam.EpochId
 	pid := sigShareParam.ProposerId
 
-	pks, err := getRBProposerGroupVar(eid)
+	pks, err := getRBProposerGroupVar(pos, eid)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -499,12 +503,12 @@ func validSigShare(stateDB StateDB, time uint64, caller common.Address,
 	}
 
 	// 2. ProposerId: weather in the random commit
-	if !isInRandomGroupVar(pks, eid, pid, caller) {
+	if !isInRandomGroupVar(pos, pks, eid, pid, caller) {
 		return nil, nil, nil, logError(errors.New(" error proposerId " + strconv.FormatUint(uint64(pid), 10)))
 	}
 
 	// 3. Verification
-	M, err := getRBMVar(stateDB, eid)
+	M, err := getRBMVar(posConfig(pos), stateDB, eid)
 	if err != nil {
 		return nil, nil, nil, logError(buildError("getRBM error", eid, pid))
 	}
@@ -573,22 +577,33 @@ func GetRBRKeyHash(epochId uint64) *common.Hash {
 
 // get r of one epoch, if not exist return r in epoch 0
 func GetR(db StateDB, epochId uint64) *big.Int {
-	if epochId == posconfig.FirstEpochId {
-		return GetStateR(db, posconfig.FirstEpochId)
+	return GetRWithConfig(posconfig.Cfg(), db, epochId)
+}
+
+// GetRWithConfig is GetR for the chain configured by cfg.
+func GetRWithConfig(cfg *posconfig.Config, db StateDB, epochId uint64) *big.Int {
+	firstEpochId := cfg.FirstEpochID()
+	if epochId == firstEpochId {
+		return GetStateRWithConfig(cfg, db, firstEpochId)
 	}
-	r := GetStateR(db, epochId)
+	r := GetStateRWithConfig(cfg, db, epochId)
 	if r == nil {
-		if epochId > posconfig.FirstEpochId+2 {
+		if epochId > firstEpochId+2 {
 			log.SyslogWarning("***Can not found random r just use the first epoch R", "epochId", epochId)
 		}
-		r = GetStateR(db, posconfig.FirstEpochId)
+		r = GetStateRWithConfig(cfg, db, firstEpochId)
 	}
 	return r
 }
 
 // get r of one epoch
 func GetStateR(db StateDB, epochId uint64) *big.Int {
-	if epochId == posconfig.FirstEpochId {
+	return GetStateRWithConfig(posconfig.Cfg(), db, epochId)
+}
+
+// GetStateRWithConfig is GetStateR for the chain configured by cfg.
+func GetStateRWithConfig(cfg *posconfig.Config, db StateDB, epochId uint64) *big.Int {
+	if epochId == cfg.FirstEpochID() {
 		return new(big.Int).SetBytes(crypto.Keccak256(big.NewInt(1).Bytes()))
 	}
 	hash := GetRBRKeyHash(epochId)
@@ -619,8 +634,13 @@ func GetSig(db StateDB, epochId uint64, proposerId uint32) (*RbSIGTxPayload, err
 
 // get M
 func GetRBM(db StateDB, epochId uint64) ([]byte, error) {
+	return GetRBMWithConfig(posconfig.Cfg(), db, epochId)
+}
+
+// GetRBMWithConfig is GetRBM for the chain configured by cfg.
+func GetRBMWithConfig(cfg *posconfig.Config, db StateDB, epochId uint64) ([]byte, error) {
 	epochIdBigInt := big.NewInt(int64(epochId + 1))
-	preRandom := GetR(db, epochId)
+	preRandom := GetRWithConfig(cfg, db, epochId)
 
 	buf := epochIdBigInt.Bytes()
 	buf = append(buf, preRandom.Bytes()...)
@@ -932,11 +952,11 @@ func isValidEpochStage(epochId uint64, stage int, time uint64) bool {
 	return true
 }
 
-func isInRandomGroup(pks []bn256.G1, epochId uint64, proposerId uint32, address common.Address) bool {
+func isInRandomGroup(pos PosContext, pks []bn256.G1, epochId uint64, proposerId uint32, address common.Address) bool {
 	if len(pks) <= int(proposerId) || int(proposerId)<0 {
 		return false
 	}
-	ep := util.GetEpocherInst()
+	ep := posSelectLead(pos)
 	if ep == nil {
 		return false
 	}
@@ -980,19 +1000,48 @@ func setSignorsNum(epochId uint64, num uint32, evm *EVM) {
 //
 // variables for mock
 //
-var getRBMVar = GetRBM
+var getRBMVar = GetRBMWithConfig
 var isValidEpochStageVar = isValidEpochStage
 var isInRandomGroupVar = isInRandomGroup
 var getRBProposerGroupVar = getRBProposerGroup
 
+// RBRejectHook, when set, is told about the random beacon transactions
+// rejected by the tx pool or the contract. stage is the stage the transaction
+// was sent for and time the time it was checked against.
+var RBRejectHook func(stage int, epochId uint64, proposerId uint32, time uint64, err error)
+
+// notifyRBReject passes the rejection of a random beacon payload to the hook,
+// if the payload tells its epoch and proposer and was sent by that proposer.
+// Anyone can send a payload naming another proposer, such rejections say
+// nothing about the proposer.
+func notifyRBReject(pos PosContext, stage int, from common.Address, payload []byte, time uint64, err error) {
+	if err == nil || RBRejectHook == nil {
+		return
+	}
+	var head struct {
+		EpochId    uint64
+		ProposerId uint32
+		Rest       []rlp.RawValue `rlp:"tail"`
+	}
+	if rlp.DecodeBytes(payload, &head) != nil {
+		return
+	}
+	pks, perr := getRBProposerGroupVar(pos, head.EpochId)
+	if perr != nil || !isInRandomGroupVar(pos, pks, head.EpochId, head.ProposerId, from) {
+		return
+	}
+	RBRejectHook(stage, head.EpochId, head.ProposerId, time, err)
+}
+
 //
 // contract abi methods
 //
 // dkg1: happens in 0~2k-1 slots, send the commits to chain
 func (c *RandomBeaconContract) dkg1(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
 	log.Debug("dkg1")
-	dkg1FlatParam, err := validDkg1(evm.StateDB, evm.Time.Uint64(), contract.CallerAddress, payload)
+	dkg1FlatParam, err := validDkg1(evm.Context.Pos, evm.StateDB, evm.Time.Uint64(), contract.CallerAddress, payload)
 	if err != nil {
+		notifyRBReject(evm.Context.Pos, RbDkg1Stage, contract.CallerAddress, payload, evm.Time.Uint64(), err)
 		return nil, err
 	}
 
@@ -1014,8 +1063,9 @@ func (c *RandomBeaconContract) dkg1(payload []byte, contract *Contract, evm *EVM
 // dkg2: happens in 5k~7k-1 slots, send the proof, enShare to chain
 func (c *RandomBeaconContract) dkg2(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
 	log.Debug("dkg2")
-	dkg2FlatParam, err := validDkg2(evm.StateDB, evm.Time.Uint64(), contract.CallerAddress, payload)
+	dkg2FlatParam, err := validDkg2(evm.Context.Pos, evm.StateDB, evm.Time.Uint64(), contract.CallerAddress, payload)
 	if err != nil {
+		notifyRBReject(evm.Context.Pos, RbDkg2Stage, contract.CallerAddress, payload, evm.Time.Uint64(), err)
 		return nil, err
 	}
 
@@ -1037,8 +1087,9 @@ func (c *RandomBeaconContract) dkg2(payload []byte, contract *Contract, evm *EVM
 // sigShare: sign, happens in 8k~10k-1 slots, generate R if enough signers
 func (c *RandomBeaconContract) sigShare(payload []byte, contract *Contract, evm *EVM) ([]byte, error) {
 	log.Debug("sigShare")
-	sigShareParam, pks, dkgData, err := validSigShare(evm.StateDB, evm.Time.Uint64(), contract.CallerAddress, payload)
+	sigShareParam, pks, dkgData, err := validSigShare(evm.Context.Pos, evm.StateDB, evm.Time.Uint64(), contract.CallerAddress, payload)
 	if err != nil {
+		notifyRBReject(evm.Context.Pos, RbSignStage, contract.CallerAddress, payload, evm.Time.Uint64(), err)
 		return nil, err
 	}
 
@@ -1054,7 +1105,7 @@ func (c *RandomBeaconContract) sigShare(payload []byte, contract *Contract, evm
 	sigNum := getSignorsNum(eid, evm) + 1
 	setSignorsNum(eid, sigNum, evm)
 	if uint(sigNum) >= posconfig.Cfg().RBThres {
-		r, err := computeRandom(evm.StateDB, eid, dkgData, pks)
+		r, err := computeRandom(posConfig(evm.Context.Pos), evm.StateDB, eid, dkgData, pks)
 		if r != nil && err == nil {
 			hashR := GetRBRKeyHash(eid + 1)
 			evm.StateDB.SetStateByteArray(randomBeaconPrecompileAddr, *hashR, r.Bytes())
@@ -1077,8 +1128,8 @@ func (c *RandomBeaconContract) sigShare(payload []byte, contract *Contract, evm
 // calc random
 //
 // compute random[epochId+1] by data of epoch[epochId]
-func computeRandom(stateDB StateDB, epochId uint64, dkgData []RbCijDataCollector, pks []bn256.G1) (*big.Int, error) {
-	randomInt := GetStateR(stateDB, epochId+1)
+func computeRandom(cfg *posconfig.Config, stateDB StateDB, epochId uint64, dkgData []RbCijDataCollector, pks []bn256.G1) (*big.Int, error) {
+	randomInt := GetStateRWithConfig(cfg, stateDB, epochId+1)
 	if randomInt != nil && randomInt.Cmp(big.NewInt(0)) != 0 {
 		return randomInt, errors.New("random exist already")
 	}
@@ -1130,7 +1181,7 @@ func computeRandom(stateDB StateDB, epochId uint64, dkgData []RbCijDataCollector
 	gPub := rbselection.LagrangePub(c, xAll, int(posconfig.Cfg().PolymDegree))
 
 	// mG
-	mBuf, err := getRBMVar(stateDB, epochId)
+	mBuf, err := getRBMVar(cfg, stateDB, epochId)
 	if err != nil {
 		return nil, logError(err)
 	}
@@ -3,6 +3,7 @@ package vm
 import (
 	"bytes"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core/state"
@@ -40,7 +41,9 @@ func (CTStateDB) GetCode(common.Address) []byte
 func (CTStateDB) SetCode(common.Address, []byte)                                         {}
 func (CTStateDB) GetCodeSize(common.Address) int                                         { return 0 }
 func (CTStateDB) AddRefund(*big.Int)                                                     {}
+func (CTStateDB) SubRefund(*big.Int)                                                     {}
 func (CTStateDB) GetRefund() *big.Int                                                    { return nil }
+func (CTStateDB) GetCommittedState(common.Address, common.Hash) common.Hash              { return common.Hash{} }
 func (CTStateDB) GetState(common.Address, common.Hash) common.Hash                       { return common.Hash{} }
 func (CTStateDB) SetState(common.Address, common.Hash, common.Hash)                      {}
 func (CTStateDB) Suicide(common.Address) bool                                            { return false }
@@ -207,7 +210,7 @@ func prepareSig(Prikey []big.Int, enshare [][]*bn256.G1) ([]*bn256.G1)  {
 		}
 	}
 
-	M, err := getRBMVar(statedb, rbepochId)
+	M, err := getRBMVar(posconfig.Cfg(), statedb, rbepochId)
 	if err != nil {
 		fmt.Printf("get rbm error id:%v\n", rbepochId)
 	}
@@ -221,12 +224,12 @@ func prepareSig(Prikey []big.Int, enshare [][]*bn256.G1) ([]*bn256.G1)  {
 	return gSigShare
 }
 
-func getRBProposerGroupMock(epochId uint64) ([]bn256.G1,error){
+func getRBProposerGroupMock(_ PosContext, epochId uint64) ([]bn256.G1,error){
 	return rbgroupdb[epochId],nil
 }
 
 
-func getRBMMock(_ StateDB, epochId uint64) ([]byte, error) {
+func getRBMMock(_ *posconfig.Config, _ StateDB, epochId uint64) ([]byte, error) {
 	nextEpochId := big.NewInt(int64(epochId + 1))
 
 	preRandom, exisit := rbranddb[epochId]
@@ -249,7 +252,7 @@ func getRBMMock(_ StateDB, epochId uint64) ([]byte, error) {
 func isValidEpochStageMock(_ uint64, _ int, _ uint64) bool {
 	return true
 }
-func isInRandomGroupMock(_ []bn256.G1, _ uint64, _ uint32, _ common.Address) bool {
+func isInRandomGroupMock(_ PosContext, _ []bn256.G1, _ uint64, _ uint32, _ common.Address) bool {
 	return true
 }
 
@@ -420,7 +423,7 @@ func TestValidPosTx(t *testing.T) {
 		payloadBytes, _ := rlp.EncodeToBytes(dkg1)
 		payload := buildDkg1(payloadBytes)
 
-		err := ValidPosRBTx(evm.StateDB, contract.CallerAddress, payload)
+		err := ValidPosRBTx(evm.StateDB, contract.CallerAddress, payload, nil)
 		if err != nil {
 			t.Error("verify pos tx fail. err:", err)
 		}
@@ -442,7 +445,7 @@ func TestValidPosTx(t *testing.T) {
 		payloadBytes, _ := rlp.EncodeToBytes(dkg1)
 		payload := buildDkg2(payloadBytes)
 
-		err := ValidPosRBTx(evm.StateDB, contract.CallerAddress, payload)
+		err := ValidPosRBTx(evm.StateDB, contract.CallerAddress, payload, nil)
 		if err != nil {
 			t.Error("verify pos tx fail. err:", err)
 		}
@@ -464,7 +467,7 @@ func TestValidPosTx(t *testing.T) {
 		payloadBytes, _ := rlp.EncodeToBytes(sigShareParam)
 		payload := buildSig(payloadBytes)
 
-		err := ValidPosRBTx(evm.StateDB, contract.CallerAddress, payload)
+		err := ValidPosRBTx(evm.StateDB, contract.CallerAddress, payload, nil)
 		if err != nil {
 			t.Error("verify pos tx fail. err:", err)
 		}
@@ -508,3 +511,29 @@ func TestGetRBStage(t *testing.T) {
 		}
 	}
 }
+
+func TestNotifyRBRejectSender(t *testing.T) {
+	proposer := common.HexToAddress("0x01")
+	defer func(hook func(int, uint64, uint32, uint64, error), inGroup func(PosContext, []bn256.G1, uint64, uint32, common.Address) bool) {
+		RBRejectHook, isInRandomGroupVar = hook, inGroup
+	}(RBRejectHook, isInRandomGroupVar)
+
+	isInRandomGroupVar = func(_ PosContext, _ []bn256.G1, _ uint64, proposerId uint32, address common.Address) bool {
+		return proposerId == 7 && address == proposer
+	}
+	var notified []uint32
+	RBRejectHook = func(_ int, _ uint64, proposerId uint32, _ uint64, _ error) {
+		notified = append(notified, proposerId)
+	}
+
+	payload, _ := rlp.EncodeToBytes(&RbSIGTxPayload{EpochId: 3, ProposerId: 7, GSignShare: new(bn256.G1).ScalarBaseMult(big.NewInt(1))})
+	// a rejected payload naming proposer 7 but sent by someone else
+	notifyRBReject(nil, RbSignStage, common.HexToAddress("0x02"), payload, 0, errors.New("rejected"))
+	if len(notified) != 0 {
+		t.Fatalf("rejection of a foreign sender reported: %v", notified)
+	}
+	notifyRBReject(nil, RbSignStage, proposer, payload, 0, errors.New("rejected"))
+	if len(notified) != 1 || notified[0] != 7 {
+		t.Fatalf("rejection of the proposer not reported: %v", notified)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"github.com/hashicorp/golang-lru"
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/crypto"
+)
+
+// ringSignCacheSize is the number of verified ring signatures remembered,
+// about the privacy transactions of a full pool.
+const ringSignCacheSize = 8192
+
+// ringSignCache maps the hash of a transaction to the digest of the ring
+// signature it carries, once found valid. The ring signatures verified when
+// transactions are admitted to the pool, or as a batch before a block is
+// processed, aren't verified again when the transactions are applied.
+//
+// The digest covers the signed message along with the signature, so that the
+// ring signature of another call in the same transaction, or of a different
+// caller, isn't taken as verified.
+var ringSignCache, _ = lru.New(ringSignCacheSize)
+
+func ringSignDigest(hashInput []byte, ringSignedStr string) common.Hash {
+	return crypto.Keccak256Hash(hashInput, []byte(ringSignedStr))
+}
+
+// CacheVerifiedRingSign records that the ring signature ringSignedStr of
+// hashInput, carried by the transaction txHash, is valid.
+func CacheVerifiedRingSign(txHash common.Hash, hashInput []byte, ringSignedStr string) {
+	if txHash != (common.Hash{}) {
+		ringSignCache.Add(txHash, ringSignDigest(hashInput, ringSignedStr))
+	}
+}
+
+// RingSignVerified reports whether the ring signature ringSignedStr of
+// hashInput was verified for the transaction txHash already.
+func RingSignVerified(txHash common.Hash, hashInput []byte, ringSignedStr string) bool {
+	if txHash == (common.Hash{}) {
+		return false
+	}
+	digest, ok := ringSignCache.Get(txHash)
+	return ok && digest.(common.Hash) == ringSignDigest(hashInput, ringSignedStr)
+}
+
+// DecodeRingSignature decodes the ring signature ringSignedStr of hashInput
+// for verification, without checking its OTAs against the state.
+func DecodeRingSignature(hashInput []byte, ringSignedStr string) (*crypto.RingSignature, error) {
+	err, publicKeys, keyImage, w, q := DecodeRingSignOut(ringSignedStr)
+	if err != nil {
+		return nil, err
+	}
+	return &crypto.RingSignature{
+		Message:    hashInput,
+		PublicKeys: publicKeys,
+		KeyImage:   keyImage,
+		W:          w,
+		Q:          q,
+	}, nil
+}
+
+// RefundRingSignedData returns the ring signed data of input, if it is a
+// refundCoin call of the wancoin contract at to.
+func RefundRingSignedData(to *common.Address, input []byte) (string, bool) {
+	if to == nil || *to != wanCoinPrecompileAddr {
+		return "", false
+	}
+	ringSignedData, _, err := UnpackRefundCoin(input)
+	if err != nil {
+		return "", false
+	}
+	return ringSignedData, true
+}
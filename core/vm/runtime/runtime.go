@@ -27,6 +27,7 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
 )
 
 // Config is a basic type specifying certain configuration flags for running
@@ -88,6 +89,14 @@ func setDefaults(cfg *Config) {
 	}
 }
 
+// SetEpochSlot sets the block time and difficulty of cfg to those of a POS
+// block sealed in slot slotID of epoch epochID. The staking, slot leader and
+// random beacon contracts derive the current epoch from them.
+func SetEpochSlot(cfg *Config, epochID, slotID uint64) {
+	cfg.Time = new(big.Int).SetUint64((epochID*posconfig.SlotCount + slotID) * posconfig.SlotTime)
+	cfg.Difficulty = new(big.Int).SetUint64(epochID<<32 + slotID<<8 + 1)
+}
+
 // Execute executes the code using the input as call data during the execution.
 // It returns the EVM's return value, the new state and an error if it failed.
 //
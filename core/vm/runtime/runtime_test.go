@@ -26,6 +26,8 @@ import (
 	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/util"
 )
 
 func TestDefaults(t *testing.T) {
@@ -93,6 +95,42 @@ func TestExecute(t *testing.T) {
 	}
 }
 
+func TestForkOpcodes(t *testing.T) {
+	byzantium := &params.ChainConfig{ChainId: big.NewInt(3), ByzantiumBlock: big.NewInt(0)}
+	constantinople := &params.ChainConfig{ChainId: big.NewInt(3), ByzantiumBlock: big.NewInt(0), ConstantinopleBlock: big.NewInt(0)}
+	istanbul := &params.ChainConfig{ChainId: big.NewInt(3), ByzantiumBlock: big.NewInt(0), ConstantinopleBlock: big.NewInt(0), IstanbulBlock: big.NewInt(0)}
+
+	tests := []struct {
+		code   []byte
+		config *params.ChainConfig
+		want   int64 // -1 for an invalid opcode
+	}{
+		{[]byte{byte(vm.PUSH1), 3, byte(vm.PUSH1), 2, byte(vm.SHL)}, byzantium, -1},
+		{[]byte{byte(vm.PUSH1), 3, byte(vm.PUSH1), 2, byte(vm.SHL)}, constantinople, 12},
+		{[]byte{byte(vm.PUSH1), 12, byte(vm.PUSH1), 2, byte(vm.SHR)}, constantinople, 3},
+		{[]byte{byte(vm.CHAINID)}, constantinople, -1},
+		{[]byte{byte(vm.CHAINID)}, istanbul, 3},
+		{[]byte{byte(vm.SELFBALANCE)}, istanbul, 0},
+	}
+	for i, tt := range tests {
+		code := append(tt.code, byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.RETURN))
+		ret, _, err := Execute(code, nil, &Config{ChainConfig: tt.config})
+		if tt.want < 0 {
+			if err == nil {
+				t.Errorf("test %d: expected invalid opcode error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: didn't expect error: %v", i, err)
+			continue
+		}
+		if num := new(big.Int).SetBytes(ret); num.Int64() != tt.want {
+			t.Errorf("test %d: expected %d, got %v", i, tt.want, num)
+		}
+	}
+}
+
 func TestCall(t *testing.T) {
 	db, _ := ethdb.NewMemDatabase()
 	state, _ := state.New(common.Hash{}, state.NewDatabase(db))
@@ -149,3 +187,61 @@ func BenchmarkCall(b *testing.B) {
 		}
 	}
 }
+
+func TestPosStakingCall(t *testing.T) {
+	definition := `[{"constant":false,"inputs":[{"name":"addr","type":"address"},{"name":"lockEpochs","type":"uint256"}],"name":"stakeUpdate","outputs":[],"payable":false,"type":"function"}]`
+	stakingAbi, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		sender = common.HexToAddress("0x1111")
+		staker = common.HexToAddress("0x2222")
+	)
+	input, err := stakingAbi.Pack("stakeUpdate", staker, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tt := range []struct {
+		epoch uint64
+		fail  bool
+	}{
+		{epoch: 105, fail: false},
+		{epoch: 108, fail: true}, // within the last vm.UpdateDelay epochs of the lock
+	} {
+		db, _ := ethdb.NewMemDatabase()
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+		info := &vm.StakerInfo{
+			Address:      staker,
+			From:         sender,
+			Amount:       big.NewInt(1),
+			StakeAmount:  big.NewInt(1),
+			LockEpochs:   10,
+			StakingEpoch: 100,
+		}
+		if err := vm.StoreStakerInfo(statedb, info); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg := &Config{State: statedb, Origin: sender}
+		SetEpochSlot(cfg, tt.epoch, 3)
+		_, _, err := Call(vm.WanCscPrecompileAddr, input, cfg)
+		if (err != nil) != tt.fail {
+			t.Errorf("test %d: error mismatch: have %v, want failure %v", i, err, tt.fail)
+		}
+	}
+}
+
+func TestSetEpochSlot(t *testing.T) {
+	cfg := new(Config)
+	SetEpochSlot(cfg, 18000, 1234)
+
+	if epoch, slot := util.CalEpochSlotID(cfg.Time.Uint64()); epoch != 18000 || slot != 1234 {
+		t.Errorf("time mismatch: have epoch %d slot %d", epoch, slot)
+	}
+	if epoch, slot := util.GetEpochSlotIDFromDifficulty(cfg.Difficulty); epoch != 18000 || slot != 1234 {
+		t.Errorf("difficulty mismatch: have epoch %d slot %d", epoch, slot)
+	}
+}
@@ -13,7 +13,6 @@ import (
 	"github.com/wanchain/go-wanchain/rlp"
 
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/pos/util"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 
@@ -137,7 +136,7 @@ func (c *slotLeaderSC) Run(in []byte, contract *Contract, evm *EVM) ([]byte, err
 
 	if methodId == stgOneIdArr {
 		vldReset := validStg1Reset(evm.StateDB, from, in[:], evm.Time.Uint64())
-		vldService := validStg1Service(from, in[:])
+		vldService := validStg1Service(evm.Context.Pos, from, in[:])
 
 		if !(vldReset && vldService) {
 			return nil, errors.New("ValidTx stg1")
@@ -145,7 +144,7 @@ func (c *slotLeaderSC) Run(in []byte, contract *Contract, evm *EVM) ([]byte, err
 		return handleStgOne(in[:], contract, evm) //Do not use [4:] because it has do it in function
 	} else if methodId == stgTwoIdArr {
 		vldReset := validStg2Reset(evm.StateDB, from, in[:], evm.Time.Uint64())
-		vldService := validStg2Service(evm.StateDB, from, in[:])
+		vldService := validStg2Service(evm.Context.Pos, evm.StateDB, from, in[:])
 
 		if !(vldReset && vldService) {
 			return nil, errors.New("ValidTx stg2")
@@ -158,9 +157,9 @@ func (c *slotLeaderSC) Run(in []byte, contract *Contract, evm *EVM) ([]byte, err
 	return nil, errMethodId
 }
 
-func (c *slotLeaderSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction) error {
+func (c *slotLeaderSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.Transaction, pos PosContext) error {
 
-	if posconfig.FirstEpochId == 0 {
+	if posConfig(pos).FirstEpochID() == 0 {
 		log.SyslogErr("slotLeaderSC:ValidTx", "", ErrPowRcvPosTrans.Error())
 		return ErrPowRcvPosTrans
 	}
@@ -180,7 +179,7 @@ func (c *slotLeaderSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.T
 
 	if methodId == stgOneIdArr {
 		vldReset := validStg1Reset(stateDB, from, payload, uint64(time.Now().Unix()))
-		vldService := validStg1Service(from, payload)
+		vldService := validStg1Service(pos, from, payload)
 
 		if vldReset && vldService {
 			return nil
@@ -189,7 +188,7 @@ func (c *slotLeaderSC) ValidTx(stateDB StateDB, signer types.Signer, tx *types.T
 		}
 	} else if methodId == stgTwoIdArr {
 		vldReset := validStg2Reset(stateDB, from, payload, uint64(time.Now().Unix()))
-		vldService := validStg2Service(stateDB, from, payload)
+		vldService := validStg2Service(pos, stateDB, from, payload)
 
 		if vldReset && vldService {
 			return nil
@@ -273,14 +272,14 @@ func validStg2Reset(stateDB StateDB, from common.Address, payload []byte, time u
 	return true
 }
 
-func validStg1Service(from common.Address, payload []byte) bool {
+func validStg1Service(pos PosContext, from common.Address, payload []byte) bool {
 	epochIDBuf, selfIndexBuf, err := RlpGetStage1IDFromTx(payload[:])
 	if err != nil {
 		log.Error("validStg1Service failed")
 		return false
 	}
 
-	if !InEpochLeadersOrNotByAddress(convert.BytesToUint64(epochIDBuf), convert.BytesToUint64(selfIndexBuf), from) {
+	if !InEpochLeadersOrNotByAddress(pos, convert.BytesToUint64(epochIDBuf), convert.BytesToUint64(selfIndexBuf), from) {
 		log.SyslogErr(ErrIllegalSender.Error())
 		return false
 	}
@@ -293,14 +292,14 @@ func validStg1Service(from common.Address, payload []byte) bool {
 	return true
 }
 
-func validStg2Service(stateDB StateDB, from common.Address, payload []byte) bool {
+func validStg2Service(pos PosContext, stateDB StateDB, from common.Address, payload []byte) bool {
 	epochID, selfIndex, _, alphaPkis, proofs, err := RlpUnpackStage2DataForTx(payload[:])
 	if err != nil {
 		log.Error("validTxStg2:RlpUnpackStage2DataForTx failed")
 		return false
 	}
 
-	if !InEpochLeadersOrNotByAddress(epochID, selfIndex, from) {
+	if !InEpochLeadersOrNotByAddress(pos, epochID, selfIndex, from) {
 		log.SyslogErr("validTxStg2:InEpochLeadersOrNotByAddress failed")
 		return false
 	}
@@ -331,7 +330,7 @@ func validStg2Service(stateDB StateDB, from common.Address, payload []byte) bool
 	}
 	//Dleq
 
-	ep := util.GetEpocherInst()
+	ep := posSelectLead(pos)
 	if ep == nil {
 		log.Error(ErrEpochID.Error())
 		return false
@@ -371,7 +370,7 @@ func handleStgOne(in []byte, contract *Contract, evm *EVM) ([]byte, error) {
 		return nil, err
 	}
 
-	addSlotScCallTimes(convert.BytesToUint64(epochIDBuf))
+	addSlotScCallTimes(evm.Context.Pos, convert.BytesToUint64(epochIDBuf))
 
 	log.Debug(fmt.Sprintf("handleStgOne save data addr:%s, key:%s, data len:%d", slotLeaderPrecompileAddr.Hex(),
 		keyHash.Hex(), len(in)))
@@ -397,7 +396,7 @@ func handleStgTwo(in []byte, contract *Contract, evm *EVM) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	addSlotScCallTimes(convert.BytesToUint64(epochIDBuf))
+	addSlotScCallTimes(evm.Context.Pos, convert.BytesToUint64(epochIDBuf))
 
 	log.Debug(fmt.Sprintf("handleStgTwo save data addr:%s, key:%s, data len:%d", slotLeaderPrecompileAddr.Hex(),
 		keyHash.Hex(), len(in)))
@@ -428,8 +427,8 @@ func GetSlotLeaderScAbiString() string {
 }
 
 // GetSlotScCallTimes can get this precompile contract called times
-func GetSlotScCallTimes(epochID uint64) uint64 {
-	buf, err := posdb.GetDb().Get(epochID, scCallTimes)
+func GetSlotScCallTimes(pos PosContext, epochID uint64) uint64 {
+	buf, err := posLocalDb(pos).Get(epochID, scCallTimes)
 	if err != nil {
 		return 0
 	} else {
@@ -480,8 +479,8 @@ func PackStage1Data(input []byte, abiString string) ([]byte, error) {
 	return outBuf, err
 }
 
-func InEpochLeadersOrNotByAddress(epochID uint64, selfIndex uint64, senderAddress common.Address) bool {
-	ep := util.GetEpocherInst()
+func InEpochLeadersOrNotByAddress(pos PosContext, epochID uint64, selfIndex uint64, senderAddress common.Address) bool {
+	ep := posSelectLead(pos)
 	if ep == nil {
 		return false
 	}
@@ -739,8 +738,9 @@ func getSlotLeaderStageKeyHash(epochID, selfIndex []byte, slotLeaderStage string
 	return crypto.Keccak256Hash(keyBuf.Bytes())
 }
 
-func addSlotScCallTimes(epochID uint64) error {
-	buf, err := posdb.GetDb().Get(epochID, scCallTimes)
+func addSlotScCallTimes(pos PosContext, epochID uint64) error {
+	db := posLocalDb(pos)
+	buf, err := db.Get(epochID, scCallTimes)
 	times := uint64(0)
 	if err != nil {
 		if err.Error() != "leveldb: not found" {
@@ -753,7 +753,7 @@ func addSlotScCallTimes(epochID uint64) error {
 
 	times++
 
-	posdb.GetDb().Put(epochID, scCallTimes, convert.Uint64ToBytes(times))
+	db.Put(epochID, scCallTimes, convert.Uint64ToBytes(times))
 	return nil
 }
 
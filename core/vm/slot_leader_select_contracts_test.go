@@ -208,7 +208,7 @@ func TestAddSlotScCallTimes(t *testing.T) {
 	epochID := uint64(0)
 	loopCount := 10
 	for i := 0; i < loopCount; i++ {
-		addSlotScCallTimes(epochID)
+		addSlotScCallTimes(nil, epochID)
 	}
 
 	if intByte, _ := posdb.GetDb().Get(epochID, scCallTimes); convert.BytesToUint64(intByte[:]) != uint64(loopCount) {
@@ -83,6 +83,12 @@ func CreateAddress(b common.Address, nonce uint64) common.Address {
 	return common.BytesToAddress(Keccak256(data)[12:])
 }
 
+// CreateAddress2 creates an ethereum address given the address bytes, initial
+// contract code hash and a salt.
+func CreateAddress2(b common.Address, salt [32]byte, inithash []byte) common.Address {
+	return common.BytesToAddress(Keccak256([]byte{0xff}, b.Bytes(), salt[:], inithash)[12:])
+}
+
 // ToECDSA creates a private key with the given D value.
 func ToECDSA(d []byte) (*ecdsa.PrivateKey, error) {
 	return toECDSA(d, true)
@@ -511,21 +517,11 @@ func RingSign(M []byte, x *big.Int, PublicKeys []*ecdsa.PublicKey) ([]*ecdsa.Pub
 // VerifyRingSign verifies the validity of ring signature
 // Pengbo added, Shi,TeemoGuo revised
 func VerifyRingSign(M []byte, PublicKeys []*ecdsa.PublicKey, I *ecdsa.PublicKey, c []*big.Int, r []*big.Int) bool {
-	if M == nil || PublicKeys == nil || I == nil || c == nil || r == nil {
-		return false
-	}
-
-	if len(PublicKeys) == 0 || len(PublicKeys) != len(c) || len(PublicKeys) != len(r) {
+	if !ringSignWellFormed(M, PublicKeys, I, c, r) {
 		return false
 	}
 
 	n := len(PublicKeys)
-	for i := 0; i < n; i++ {
-		if PublicKeys[i] == nil || PublicKeys[i].X == nil || PublicKeys[i].Y == nil ||
-			c[i] == nil || r[i] == nil {
-			return false
-		}
-	}
 
 	log.Debug("M info", "R", 0, "M", common.ToHex(M))
 	for i := 0; i < n; i++ {
@@ -541,6 +537,46 @@ func VerifyRingSign(M []byte, PublicKeys []*ecdsa.PublicKey, I *ecdsa.PublicKey,
 		log.Debug("r info", "i", i, "r", common.ToHex(r[i].Bytes()))
 	}
 
+	hashPoints := make([]*ecdsa.PublicKey, n)
+	for i := 0; i < n; i++ {
+		hashPoints[i] = hashPoint(PublicKeys[i])
+	}
+	return verifyRingSign(M, PublicKeys, I, c, r, hashPoints)
+}
+
+// ringSignWellFormed checks that no part of a ring signature is missing, and
+// that it has as many c and r as public keys.
+func ringSignWellFormed(M []byte, PublicKeys []*ecdsa.PublicKey, I *ecdsa.PublicKey, c []*big.Int, r []*big.Int) bool {
+	if M == nil || PublicKeys == nil || I == nil || c == nil || r == nil {
+		return false
+	}
+
+	if len(PublicKeys) == 0 || len(PublicKeys) != len(c) || len(PublicKeys) != len(r) {
+		return false
+	}
+
+	for i := 0; i < len(PublicKeys); i++ {
+		if PublicKeys[i] == nil || PublicKeys[i].X == nil || PublicKeys[i].Y == nil ||
+			c[i] == nil || r[i] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// hashPoint calculates Hash(P) = [Keccak256(P)]P, the point the key image and
+// the Ri of ring signatures are computed from.
+func hashPoint(pub *ecdsa.PublicKey) *ecdsa.PublicKey {
+	hp := new(ecdsa.PublicKey)
+	hp.X, hp.Y = S256().ScalarMult(pub.X, pub.Y, Keccak256(FromECDSAPub(pub)))
+	hp.Curve = S256()
+	return hp
+}
+
+// verifyRingSign verifies a ring signature of validated length with the hash
+// points of its public keys calculated already.
+func verifyRingSign(M []byte, PublicKeys []*ecdsa.PublicKey, I *ecdsa.PublicKey, c []*big.Int, r []*big.Int, hashPoints []*ecdsa.PublicKey) bool {
+	n := len(PublicKeys)
 	SumC := new(big.Int).SetInt64(0)
 	Lpub := new(ecdsa.PublicKey)
 	d := sha3.NewKeccak256()
@@ -563,13 +599,12 @@ func VerifyRingSign(M []byte, PublicKeys []*ecdsa.PublicKey, I *ecdsa.PublicKey,
 		SumC.Add(SumC, c[i])
 		SumC.Mod(SumC, secp256k1_N)
 		d.Write(FromECDSAPub(Lpub))
-		log.Debug("LPublicKeys", "i", i, "Lpub", common.ToHex(FromECDSAPub(Lpub)))
 	}
 
 	Rpub := new(ecdsa.PublicKey)
 	for i := 0; i < n; i++ {
-		Rpub = xScalarHashP(r[i].Bytes(), PublicKeys[i]) //[qi]HashPi
-		if Rpub == nil || Rpub.X == nil || Rpub.Y == nil {
+		Rpub.X, Rpub.Y = S256().ScalarMult(hashPoints[i].X, hashPoints[i].Y, r[i].Bytes()) //[qi]HashPi
+		if Rpub.X == nil || Rpub.Y == nil {
 			return false
 		}
 
@@ -580,18 +615,12 @@ func VerifyRingSign(M []byte, PublicKeys []*ecdsa.PublicKey, I *ecdsa.PublicKey,
 		}
 
 		Rpub.X, Rpub.Y = S256().Add(Rpub.X, Rpub.Y, Ppub.X, Ppub.Y) //[qi]HashPi+[wi]I
-		log.Debug("RPublicKeys", "i", i, "Rpub", common.ToHex(FromECDSAPub(Rpub)))
 
 		d.Write(FromECDSAPub(Rpub))
 	}
 
 	hash := new(big.Int).SetBytes(d.Sum(nil)) //hash(m,Li,Ri)
-	log.Debug("hash info", "i", 0, "hash", common.ToHex(hash.Bytes()))
-
 	hash.Mod(hash, secp256k1_N)
-	log.Debug("hash info", "i", 2, "hash", common.ToHex(hash.Bytes()))
-	log.Debug("SumC info", "i", 3, "SumC", common.ToHex(SumC.Bytes()))
-
 	return hash.Cmp(SumC) == 0
 }
 
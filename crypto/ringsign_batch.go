@@ -0,0 +1,113 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// RingSignature is a ring signature of a message, as returned by RingSign.
+type RingSignature struct {
+	Message    []byte
+	PublicKeys []*ecdsa.PublicKey
+	KeyImage   *ecdsa.PublicKey
+	W          []*big.Int
+	Q          []*big.Int
+}
+
+// BatchVerifyRingSign verifies the ring signatures sigs, returning whether each
+// of them is valid, as VerifyRingSign would.
+//
+// The decoys of rings are drawn from the OTAs of the same balance, so the same
+// public keys appear in many signatures: the hash points of the distinct keys
+// are computed once for the batch. Both the hash points and the signatures are
+// computed concurrently.
+func BatchVerifyRingSign(sigs []*RingSignature) []bool {
+	valid := make([]bool, len(sigs))
+
+	// Collect the distinct public keys of the well formed signatures
+	var (
+		index = make(map[string]int)
+		keys  []*ecdsa.PublicKey
+		rings = make([][]int, len(sigs))
+	)
+	for i, sig := range sigs {
+		if sig == nil || !ringSignWellFormed(sig.Message, sig.PublicKeys, sig.KeyImage, sig.W, sig.Q) {
+			continue
+		}
+		rings[i] = make([]int, len(sig.PublicKeys))
+		for j, pub := range sig.PublicKeys {
+			id := string(FromECDSAPub(pub))
+			k, ok := index[id]
+			if !ok {
+				k = len(keys)
+				index[id] = k
+				keys = append(keys, pub)
+			}
+			rings[i][j] = k
+		}
+	}
+	hashPoints := make([]*ecdsa.PublicKey, len(keys))
+	parallelize(len(keys), func(k int) {
+		hashPoints[k] = hashPoint(keys[k])
+	})
+
+	parallelize(len(sigs), func(i int) {
+		if rings[i] == nil {
+			return
+		}
+		sig := sigs[i]
+		points := make([]*ecdsa.PublicKey, len(rings[i]))
+		for j, k := range rings[i] {
+			points[j] = hashPoints[k]
+		}
+		valid[i] = verifyRingSign(sig.Message, sig.PublicKeys, sig.KeyImage, sig.W, sig.Q, points)
+	})
+	return valid
+}
+
+// parallelize calls fn for 0 <= i < n on all CPUs, returning when all calls
+// are done.
+func parallelize(n int, fn func(i int)) {
+	workers := runtime.NumCPU()
+	if workers > n {
+		workers = n
+	}
+	var (
+		next int32 = -1
+		wg   sync.WaitGroup
+	)
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt32(&next, 1))
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,155 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+var benchRingSizes = []int{1, 2, 4, 8, 16}
+
+// newTestKeys generates n keys.
+func newTestKeys(n int) []*ecdsa.PrivateKey {
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		keys[i], _ = GenerateKey()
+	}
+	return keys
+}
+
+// newTestRingSign ring signs msg with key, hidden among the public keys of
+// decoys.
+func newTestRingSign(msg []byte, key *ecdsa.PrivateKey, decoys []*ecdsa.PrivateKey) (*RingSignature, error) {
+	pubs := []*ecdsa.PublicKey{&key.PublicKey}
+	for _, decoy := range decoys {
+		pubs = append(pubs, &decoy.PublicKey)
+	}
+	pubs, image, w, q, err := RingSign(msg, key.D, pubs)
+	if err != nil {
+		return nil, err
+	}
+	return &RingSignature{Message: msg, PublicKeys: pubs, KeyImage: image, W: w, Q: q}, nil
+}
+
+func TestBatchVerifyRingSign(t *testing.T) {
+	decoys := newTestKeys(8)
+
+	var sigs []*RingSignature
+	for i := 0; i < 16; i++ {
+		sig, err := newTestRingSign([]byte(fmt.Sprintf("message %d", i)), newTestKeys(1)[0], decoys[:i%len(decoys)])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigs = append(sigs, sig)
+	}
+	want := make([]bool, len(sigs))
+	for i := range want {
+		want[i] = true
+	}
+	// Break some of them
+	sigs[1].Message = []byte("other message")
+	sigs[3].Q[0] = new(big.Int).Add(sigs[3].Q[0], big.NewInt(1))
+	sigs[5].W = sigs[5].W[1:]
+	sigs[7] = nil
+	sigs[9].KeyImage = sigs[10].KeyImage
+	for _, i := range []int{1, 3, 5, 7, 9} {
+		want[i] = false
+	}
+
+	have := BatchVerifyRingSign(sigs)
+	for i, sig := range sigs {
+		if have[i] != want[i] {
+			t.Errorf("signature %d: have valid %v, want %v", i, have[i], want[i])
+		}
+		if sig != nil && VerifyRingSign(sig.Message, sig.PublicKeys, sig.KeyImage, sig.W, sig.Q) != have[i] {
+			t.Errorf("signature %d: batch and single verification differ", i)
+		}
+	}
+	if len(BatchVerifyRingSign(nil)) != 0 {
+		t.Error("empty batch verified")
+	}
+}
+
+func BenchmarkRingSign(b *testing.B) {
+	msg := []byte("benchmark")
+	for _, size := range benchRingSizes {
+		b.Run(fmt.Sprintf("ring-%d", size), func(b *testing.B) {
+			keys := newTestKeys(size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := newTestRingSign(msg, keys[0], keys[1:]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkVerifyRingSign(b *testing.B) {
+	msg := []byte("benchmark")
+	for _, size := range benchRingSizes {
+		b.Run(fmt.Sprintf("ring-%d", size), func(b *testing.B) {
+			keys := newTestKeys(size)
+			sig, err := newTestRingSign(msg, keys[0], keys[1:])
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if !VerifyRingSign(sig.Message, sig.PublicKeys, sig.KeyImage, sig.W, sig.Q) {
+					b.Fatal("invalid signature")
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkBatchVerifyRingSign verifies batches of 32 signatures, the decoys
+// of which are drawn from 32 keys, as those of OTAs of the same balance.
+func BenchmarkBatchVerifyRingSign(b *testing.B) {
+	const batch = 32
+	msg := []byte("benchmark")
+	decoys := newTestKeys(batch)
+	for _, size := range benchRingSizes {
+		b.Run(fmt.Sprintf("ring-%d", size), func(b *testing.B) {
+			sigs := make([]*RingSignature, batch)
+			for i := range sigs {
+				var ring []*ecdsa.PrivateKey
+				for j := 0; j < size-1; j++ {
+					ring = append(ring, decoys[(i+j)%len(decoys)])
+				}
+				sig, err := newTestRingSign(msg, newTestKeys(1)[0], ring)
+				if err != nil {
+					b.Fatal(err)
+				}
+				sigs[i] = sig
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j, valid := range BatchVerifyRingSign(sigs) {
+					if !valid {
+						b.Fatalf("invalid signature %d", j)
+					}
+				}
+			}
+		})
+	}
+}
@@ -50,6 +50,10 @@ func (b *EthApiBackend) CurrentBlock() *types.Block {
 	return b.eth.blockchain.CurrentBlock()
 }
 
+func (b *EthApiBackend) OTAIndex() *core.OTAIndex {
+	return b.eth.blockchain.OTAIndex()
+}
+
 func (b *EthApiBackend) SetHead(number uint64) {
 	b.eth.protocolManager.downloader.Cancel()
 	b.eth.blockchain.SetHead(number)
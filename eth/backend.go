@@ -49,6 +49,8 @@ import (
 	"github.com/wanchain/go-wanchain/node"
 	"github.com/wanchain/go-wanchain/p2p"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	"github.com/wanchain/go-wanchain/rlp"
 	"github.com/wanchain/go-wanchain/rpc"
 )
@@ -80,6 +82,7 @@ type Ethereum struct {
 	eventMux       *event.TypeMux
 	engine         consensus.Engine
 	accountManager *accounts.Manager
+	posCtx         *posctx.PosContext // POS services of this chain
 
 	bloomRequests chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer  *core.ChainIndexer             // Bloom indexer operating during block imports
@@ -114,12 +117,35 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		return nil, err
 	}
 	stopDbUpgrade := upgradeDeduplicateData(chainDb)
-	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlock(chainDb, config.Genesis)
+	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlockWithNetwork(chainDb, config.Genesis, config.NetworkId)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
 	}
+	chainConfig = overridePosForks(chainConfig, config)
+	if chainConfig.Pluto != nil && len(chainConfig.Pluto.WhiteList) > 0 {
+		if err := posconfig.SetWhiteList(chainConfig.Pluto.WhiteList); err != nil {
+			return nil, err
+		}
+	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
+	// Every chain runs its POS services on its own copy of the configuration,
+	// with its local databases in the instance directory.
+	posCfg := *posconfig.Cfg()
+	posCfg.ChainConfig = chainConfig
+	posCtx, err := posctx.New(&posCfg, ctx.ResolvePath(""))
+	if err != nil {
+		return nil, err
+	}
+	// Release the POS databases unless the service is handed out
+	started := false
+	defer func() {
+		if !started {
+			posCtx.Close()
+		}
+	}()
+	posCtx.Activate()
 	posEngine := pluto.New(chainConfig.Pluto, chainDb)
+	posEngine.SetPosContext(posCtx)
 
 	eth := &Ethereum{
 		config:         config,
@@ -127,6 +153,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		chainConfig:    chainConfig,
 		eventMux:       ctx.EventMux,
 		accountManager: ctx.AccountManager,
+		posCtx:         posCtx,
 		engine:         CreateConsensusEngine(ctx, config, chainConfig, chainDb),
 		shutdownChan:   make(chan bool),
 		stopDbUpgrade:  stopDbUpgrade,
@@ -161,6 +188,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
+	eth.blockchain.SetPosContext(posCtx)
 	//eth.blockchain.RegisterSwitchEngine(eth)
 	eth.blockchain.PrependRegisterSwitchEngine(eth)
 	// Rewind the chain in case of an incompatible config upgrade.
@@ -199,9 +227,30 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		miner.PosInit(eth)
 		chainConfig.SetPosActive()
 	}
+	started = true
 	return eth, nil
 }
 
+// overridePosForks applies the POS fork epoch overrides in config. The
+// returned config is a copy, so neither the stored nor the bundled chain
+// configurations are modified.
+func overridePosForks(chainConfig *params.ChainConfig, config *Config) *params.ChainConfig {
+	if config.OverrideApollo == nil && config.OverrideAugust == nil && config.OverrideMercury == nil {
+		return chainConfig
+	}
+	cfg := *chainConfig
+	if config.OverrideApollo != nil {
+		cfg.ApolloEpoch = config.OverrideApollo
+	}
+	if config.OverrideAugust != nil {
+		cfg.AugustEpoch = config.OverrideAugust
+	}
+	if config.OverrideMercury != nil {
+		cfg.MercuryEpoch = config.OverrideMercury
+	}
+	return &cfg
+}
+
 func makeExtraData(extra []byte) []byte {
 	if len(extra) == 0 {
 		// create default extradata
@@ -266,7 +315,7 @@ func (s *Ethereum) APIs() []rpc.API {
 
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
-	apis = append(apis, posapi.APIs(s.BlockChain(), s.ApiBackend)...)
+	apis = append(apis, posapi.APIs(s.BlockChain(), s.ApiBackend, s.posCtx)...)
 
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
@@ -407,6 +456,7 @@ func (s *Ethereum) TxPool() *core.TxPool               { return s.txPool }
 func (s *Ethereum) EventMux() *event.TypeMux           { return s.eventMux }
 func (s *Ethereum) Engine() consensus.Engine           { return s.engine }
 func (s *Ethereum) ChainDb() ethdb.Database            { return s.chainDb }
+func (s *Ethereum) PosContext() *posctx.PosContext     { return s.posCtx }
 func (s *Ethereum) IsListening() bool                  { return true } // Always listening
 func (s *Ethereum) EthVersion() int                    { return int(s.protocolManager.SubProtocols[0].Version) }
 func (s *Ethereum) NetVersion() uint64                 { return s.networkId }
@@ -462,6 +512,7 @@ func (s *Ethereum) Stop() error {
 	s.miner.Stop()
 	s.eventMux.Stop()
 
+	s.posCtx.Close()
 	s.chainDb.Close()
 	close(s.shutdownChan)
 
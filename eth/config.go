@@ -113,6 +113,11 @@ type Config struct {
 	PowFake   bool   `toml:"-"`
 	PowTest   bool   `toml:"-"`
 	PowShared bool   `toml:"-"`
+
+	// POS fork epoch overrides, used to test fork transitions on a stored chain
+	OverrideApollo  *big.Int `toml:",omitempty"`
+	OverrideAugust  *big.Int `toml:",omitempty"`
+	OverrideMercury *big.Int `toml:",omitempty"`
 }
 
 type configMarshaling struct {
@@ -124,6 +124,9 @@ type Downloader struct {
 	// Callbacks
 	dropPeer peerDropFn // Drops a peer for misbehaving
 
+	pivotHook     func(*types.Header) error // Called once the fast sync pivot is committed
+	pivotHookLock sync.RWMutex              // Lock protecting the pivot hook
+
 	// Status
 	synchroniseMock func(id string, hash common.Hash) error // Replacement for synchronise during testing
 	synchronising   int32
@@ -1633,7 +1636,25 @@ func (d *Downloader) commitPivotBlock(result *fetchResult) error {
 	if _, err := d.blockchain.InsertReceiptChain([]*types.Block{b}, []types.Receipts{result.Receipts}); err != nil {
 		return err
 	}
-	return d.blockchain.FastSyncCommitHead(b.Hash())
+	if err := d.blockchain.FastSyncCommitHead(b.Hash()); err != nil {
+		return err
+	}
+	d.pivotHookLock.RLock()
+	hook := d.pivotHook
+	d.pivotHookLock.RUnlock()
+	if hook != nil {
+		return hook(b.Header())
+	}
+	return nil
+}
+
+// SetPivotHook sets the function called once the fast sync pivot block and its
+// state are committed, before any block on top of it is imported. An error
+// aborts the sync. A nil hook removes it.
+func (d *Downloader) SetPivotHook(hook func(pivot *types.Header) error) {
+	d.pivotHookLock.Lock()
+	d.pivotHook = hook
+	d.pivotHookLock.Unlock()
 }
 
 // DeliverHeaders injects a new batch of block headers received from a remote
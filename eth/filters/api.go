@@ -28,6 +28,7 @@ import (
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/event"
 	"github.com/wanchain/go-wanchain/rpc"
@@ -251,7 +252,11 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 			select {
 			case logs := <-matchedLogs:
 				for _, log := range logs {
-					notifier.Notify(rpcSub.ID, &log)
+					if crit.Decoded {
+						notifier.Notify(rpcSub.ID, newDecodedLog(log))
+					} else {
+						notifier.Notify(rpcSub.ID, &log)
+					}
 				}
 			case <-rpcSub.Err(): // client send an unsubscribe request
 				logsSub.Unsubscribe()
@@ -272,6 +277,10 @@ type FilterCriteria struct {
 	ToBlock   *big.Int
 	Addresses []common.Address
 	Topics    [][]common.Hash
+
+	// Decoded requests the logs of precompiled contracts and of contracts
+	// registered by admin_registerABI to be returned with their event decoded.
+	Decoded bool
 }
 
 // NewFilter creates a new filter and returns the filter id. It can be
@@ -322,7 +331,7 @@ func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 // GetLogs returns logs matching the given argument that are stored within the state.
 //
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_getlogs
-func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
+func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) (interface{}, error) {
 	// Convert the RPC block numbers into internal representations
 	if crit.FromBlock == nil {
 		crit.FromBlock = big.NewInt(rpc.LatestBlockNumber.Int64())
@@ -337,7 +346,7 @@ func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([
 	if err != nil {
 		return nil, err
 	}
-	return returnLogs(logs), err
+	return formatLogs(logs, crit.Decoded), err
 }
 
 // UninstallFilter removes the filter with the given filter id.
@@ -361,7 +370,7 @@ func (api *PublicFilterAPI) UninstallFilter(id rpc.ID) bool {
 // If the filter could not be found an empty array of logs is returned.
 //
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_getfilterlogs
-func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*types.Log, error) {
+func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) (interface{}, error) {
 	api.filtersMu.Lock()
 	f, found := api.filters[id]
 	api.filtersMu.Unlock()
@@ -385,7 +394,7 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 	if err != nil {
 		return nil, err
 	}
-	return returnLogs(logs), nil
+	return formatLogs(logs, f.crit.Decoded), nil
 }
 
 // GetFilterChanges returns the logs for the filter with the given id since
@@ -415,7 +424,7 @@ func (api *PublicFilterAPI) GetFilterChanges(id rpc.ID) (interface{}, error) {
 		case LogsSubscription:
 			logs := f.logs
 			f.logs = nil
-			return returnLogs(logs), nil
+			return formatLogs(logs, f.crit.Decoded), nil
 		}
 	}
 
@@ -440,6 +449,54 @@ func returnLogs(logs []*types.Log) []*types.Log {
 	return logs
 }
 
+// formatLogs is a helper that returns the logs as returnLogs does, with their
+// events decoded if requested.
+func formatLogs(logs []*types.Log, decoded bool) interface{} {
+	logs = returnLogs(logs)
+	if !decoded {
+		return logs
+	}
+	ret := make([]*decodedLog, len(logs))
+	for i, log := range logs {
+		ret[i] = newDecodedLog(log)
+	}
+	return ret
+}
+
+// decodedLog is a log together with its event decoded by the ABI of the
+// contract that emitted it. It's marshalled as the log with an extra
+// "decoded" field, left out if the log couldn't be decoded.
+type decodedLog struct {
+	log   *types.Log
+	event *vm.DecodedLog
+}
+
+func newDecodedLog(log *types.Log) *decodedLog {
+	event, _ := vm.DecodeLog(log)
+	return &decodedLog{log: log, event: event}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (l *decodedLog) MarshalJSON() ([]byte, error) {
+	enc, err := json.Marshal(l.log)
+	if err != nil || l.event == nil {
+		return enc, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(enc, &fields); err != nil {
+		return nil, err
+	}
+	fields["decoded"], err = json.Marshal(struct {
+		Contract string                 `json:"contract,omitempty"`
+		Event    string                 `json:"event"`
+		Args     map[string]interface{} `json:"args,omitempty"`
+	}{l.event.Contract, l.event.Event, l.event.Args})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
 // UnmarshalJSON sets *args fields with given data.
 func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 	type input struct {
@@ -447,12 +504,14 @@ func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 		ToBlock   *rpc.BlockNumber `json:"toBlock"`
 		Addresses interface{}      `json:"address"`
 		Topics    []interface{}    `json:"topics"`
+		Decoded   bool             `json:"decoded"`
 	}
 
 	var raw input
 	if err := json.Unmarshal(data, &raw); err != nil {
 		return err
 	}
+	args.Decoded = raw.Decoded
 
 	if raw.From != nil {
 		args.FromBlock = big.NewInt(raw.From.Int64())
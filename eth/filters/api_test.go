@@ -19,9 +19,12 @@ package filters
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"testing"
 
 	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/rpc"
 )
 
@@ -183,3 +186,39 @@ func TestUnmarshalJSONNewFilterArgs(t *testing.T) {
 		t.Fatalf("expected 0 topics, got %d topics", len(test7.Topics[2]))
 	}
 }
+
+func TestDecodedLogs(t *testing.T) {
+	var crit FilterCriteria
+	if err := json.Unmarshal([]byte(`{"decoded": true}`), &crit); err != nil {
+		t.Fatal(err)
+	}
+	if !crit.Decoded {
+		t.Fatal("decoded flag not parsed")
+	}
+
+	logs := []*types.Log{{
+		Address: vm.RandomBeaconPrecompileAddr,
+		Topics:  []common.Hash{common.BigToHash(big.NewInt(18000)), common.BigToHash(big.NewInt(42))},
+	}, {
+		Address: common.HexToAddress("0x1234"),
+		Topics:  []common.Hash{common.HexToHash("0x01")},
+	}}
+	if _, ok := formatLogs(logs, false).([]*types.Log); !ok {
+		t.Error("logs changed without the decoded flag")
+	}
+	enc, err := json.Marshal(formatLogs(logs, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dec []map[string]interface{}
+	if err := json.Unmarshal(enc, &dec); err != nil {
+		t.Fatal(err)
+	}
+	decoded, ok := dec[0]["decoded"].(map[string]interface{})
+	if !ok || decoded["event"] != "random" || dec[0]["address"] == nil {
+		t.Errorf("wrong decoded log: %s", enc)
+	}
+	if _, ok := dec[1]["decoded"]; ok || dec[1]["address"] == nil {
+		t.Errorf("log of unknown contract decoded: %s", enc)
+	}
+}
@@ -36,10 +36,13 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
-		DocRoot                 string `toml:"-"`
-		PowFake                 bool   `toml:"-"`
-		PowTest                 bool   `toml:"-"`
-		PowShared               bool   `toml:"-"`
+		DocRoot                 string   `toml:"-"`
+		PowFake                 bool     `toml:"-"`
+		PowTest                 bool     `toml:"-"`
+		PowShared               bool     `toml:"-"`
+		OverrideApollo          *big.Int `toml:",omitempty"`
+		OverrideAugust          *big.Int `toml:",omitempty"`
+		OverrideMercury         *big.Int `toml:",omitempty"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
@@ -67,6 +70,9 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.PowFake = c.PowFake
 	enc.PowTest = c.PowTest
 	enc.PowShared = c.PowShared
+	enc.OverrideApollo = c.OverrideApollo
+	enc.OverrideAugust = c.OverrideAugust
+	enc.OverrideMercury = c.OverrideMercury
 	return &enc, nil
 }
 
@@ -94,10 +100,13 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
-		DocRoot                 *string `toml:"-"`
-		PowFake                 *bool   `toml:"-"`
-		PowTest                 *bool   `toml:"-"`
-		PowShared               *bool   `toml:"-"`
+		DocRoot                 *string  `toml:"-"`
+		PowFake                 *bool    `toml:"-"`
+		PowTest                 *bool    `toml:"-"`
+		PowShared               *bool    `toml:"-"`
+		OverrideApollo          *big.Int `toml:",omitempty"`
+		OverrideAugust          *big.Int `toml:",omitempty"`
+		OverrideMercury         *big.Int `toml:",omitempty"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -178,5 +187,14 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.PowShared != nil {
 		c.PowShared = *dec.PowShared
 	}
+	if dec.OverrideApollo != nil {
+		c.OverrideApollo = dec.OverrideApollo
+	}
+	if dec.OverrideAugust != nil {
+		c.OverrideAugust = dec.OverrideAugust
+	}
+	if dec.OverrideMercury != nil {
+		c.OverrideMercury = dec.OverrideMercury
+	}
 	return nil
 }
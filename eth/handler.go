@@ -39,6 +39,7 @@ import (
 	"github.com/wanchain/go-wanchain/p2p"
 	"github.com/wanchain/go-wanchain/p2p/discover"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/sidedata"
 	"github.com/wanchain/go-wanchain/rlp"
 )
 
@@ -67,6 +68,7 @@ type ProtocolManager struct {
 	networkId uint64
 
 	fastSync  uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
+	posSync   uint32 // Flag whether the POS side data is fetched before fast syncing
 	acceptTxs uint32 // Flag whether we're considered synchronised (enables transaction processing)
 
 	txpool      txPool
@@ -92,6 +94,10 @@ type ProtocolManager struct {
 	quitSync    chan struct{}
 	noMorePeers chan struct{}
 
+	// channel and flag for the POS side data sync
+	posSideDataCh  chan posSideDataPacket
+	posSideSyncing int32
+
 	// wait group is used for graceful shutdowns during downloading
 	// and processing
 	wg sync.WaitGroup
@@ -113,6 +119,13 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		noMorePeers: make(chan struct{}),
 		txsyncCh:    make(chan *txsync),
 		quitSync:    make(chan struct{}),
+
+		posSideDataCh: make(chan posSideDataPacket),
+	}
+	// POS fast sync is a fast sync preceded by the side data download
+	posSync := mode == downloader.PosFastSync
+	if posSync {
+		mode = downloader.FastSync
 	}
 	// Figure out whether to allow fast sync or not
 	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
@@ -121,6 +134,9 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 	}
 	if mode == downloader.FastSync {
 		manager.fastSync = uint32(1)
+		if posSync {
+			manager.posSync = uint32(1)
+		}
 	}
 	// Initiate a sub-protocol for every implemented version we can handle
 	manager.SubProtocols = make([]p2p.Protocol, 0, len(ProtocolVersions))
@@ -747,6 +763,24 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			log.Debug("Failed to deliver header td", "err", err)
 		}
 
+	case p.version >= eth64 && msg.Code == GetPosSideDataMsg:
+		var query getPosSideDataData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		return p.SendPosSideData(pm.collectPosSideData(query.From, query.Amount))
+
+	case p.version >= eth64 && msg.Code == PosSideDataMsg:
+		var data []*sidedata.EpochSideData
+		if err := msg.Decode(&data); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		select {
+		case pm.posSideDataCh <- posSideDataPacket{peer: p.id, data: data}:
+		default:
+			log.Debug("Unrequested pos side data", "peer", p.id, "count", len(data))
+		}
+
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
 	}
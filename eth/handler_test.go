@@ -21,6 +21,7 @@ import (
 	"math/big"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core"
@@ -31,6 +32,7 @@ import (
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/p2p"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/sidedata"
 )
 
 var bigTxGas = new(big.Int).SetUint64(params.TxGas)
@@ -43,8 +45,8 @@ func TestProtocolCompatibility(t *testing.T) {
 		mode       downloader.SyncMode
 		compatible bool
 	}{
-		{61, downloader.FullSync, true}, {62, downloader.FullSync, true}, {63, downloader.FullSync, true},
-		{61, downloader.FastSync, false}, {62, downloader.FastSync, false}, {63, downloader.FastSync, true},
+		{61, downloader.FullSync, true}, {62, downloader.FullSync, true}, {63, downloader.FullSync, true}, {64, downloader.FullSync, true},
+		{61, downloader.FastSync, false}, {62, downloader.FastSync, false}, {63, downloader.FastSync, true}, {64, downloader.FastSync, true},
 	}
 	// Make sure anything we screw up is restored
 	backup := ProtocolVersions
@@ -430,3 +432,46 @@ func testGetReceipt(t *testing.T, protocol int) {
 		t.Errorf("receipts mismatch: %v", err)
 	}
 }
+
+// Tests that the POS side data is only served over eth64, an older peer
+// sending the request is dropped like for any unknown message.
+func TestGetPosSideData(t *testing.T) {
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 4, nil, nil)
+
+	peer, _ := newTestPeer("peer", eth64, pm, true)
+	p2p.Send(peer.app, GetPosSideDataMsg, &getPosSideDataData{From: 0, Amount: 1})
+	if err := p2p.ExpectMsg(peer.app, PosSideDataMsg, []*sidedata.EpochSideData{}); err != nil {
+		t.Errorf("side data mismatch: %v", err)
+	}
+	peer.close()
+
+	peer, errc := newTestPeer("old", eth63, pm, true)
+	defer peer.close()
+	p2p.Send(peer.app, GetPosSideDataMsg, &getPosSideDataData{From: 0, Amount: 1})
+	select {
+	case err := <-errc:
+		if err == nil {
+			t.Error("side data request over eth63 accepted")
+		}
+	case <-time.After(time.Second):
+		t.Error("peer not dropped after a side data request over eth63")
+	}
+}
+
+// Tests that the peer set picks the best peer speaking a protocol version.
+func TestBestPeerFrom(t *testing.T) {
+	pm := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	old, _ := newTestPeer("old", eth63, pm, true)
+	defer old.close()
+	current, _ := newTestPeer("current", eth64, pm, true)
+	defer current.close()
+
+	old.peer.SetHead(common.Hash{1}, big.NewInt(1000))
+	current.peer.SetHead(common.Hash{2}, big.NewInt(10))
+	if best := pm.peers.BestPeer(); best == nil || best.id != old.peer.id {
+		t.Errorf("best peer mismatch: have %v, want %v", best, old.peer)
+	}
+	if best := pm.peers.BestPeerFrom(eth64); best == nil || best.id != current.peer.id {
+		t.Errorf("best eth64 peer mismatch: have %v, want %v", best, current.peer)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/p2p"
+	"github.com/wanchain/go-wanchain/pos/sidedata"
 	"github.com/wanchain/go-wanchain/rlp"
 	"gopkg.in/fatih/set.v0"
 )
@@ -197,6 +198,11 @@ func (p *peer) SendBlockHeaderTd(header *types.Header, td *big.Int) error {
 	return p2p.Send(p.rw, BlockHeaderTdMsg, []interface{}{header, td})
 }
 
+// SendPosSideData sends a batch of epoch POS side data to the remote peer.
+func (p *peer) SendPosSideData(data []*sidedata.EpochSideData) error {
+	return p2p.Send(p.rw, PosSideDataMsg, data)
+}
+
 // SendBlockBodies sends a batch of block contents to the remote peer.
 func (p *peer) SendBlockBodies(bodies []*blockBody) error {
 	return p2p.Send(p.rw, BlockBodiesMsg, blockBodiesData(bodies))
@@ -227,6 +233,13 @@ func (p *peer) RequestOneHeader(hash common.Hash) error {
 	return p2p.Send(p.rw, GetBlockHeadersMsg, &getBlockHeadersData{Origin: hashOrNumber{Hash: hash}, Amount: uint64(1), Skip: uint64(0), Reverse: false})
 }
 
+// RequestPosSideData fetches the POS side data of a batch of epochs, starting
+// at the given epoch.
+func (p *peer) RequestPosSideData(from uint64, amount int) error {
+	p.Log().Debug("Fetching batch of pos side data", "count", amount, "from", from)
+	return p2p.Send(p.rw, GetPosSideDataMsg, &getPosSideDataData{From: from, Amount: uint64(amount)})
+}
+
 // RequestHeadersByHash fetches a batch of blocks' headers corresponding to the
 // specified header query, based on the hash of an origin block.
 func (p *peer) RequestHeadersByHash(origin common.Hash, amount int, skip int, reverse bool) error {
@@ -476,6 +489,12 @@ func (ps *peerSet) PeersList() []*peer {
 }
 // BestPeer retrieves the known peer with the currently highest total difficulty.
 func (ps *peerSet) BestPeer() *peer {
+	return ps.BestPeerFrom(0)
+}
+
+// BestPeerFrom retrieves the known peer speaking at least the given protocol
+// version with the currently highest total difficulty.
+func (ps *peerSet) BestPeerFrom(version int) *peer {
 	ps.lock.RLock()
 	defer ps.lock.RUnlock()
 
@@ -484,6 +503,9 @@ func (ps *peerSet) BestPeer() *peer {
 		bestTd   *big.Int
 	)
 	for _, p := range ps.peers {
+		if p.version < version {
+			continue
+		}
 		if _, td := p.Head(); bestPeer == nil || td.Cmp(bestTd) > 0 {
 			bestPeer, bestTd = p, td
 		}
@@ -0,0 +1,149 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/sidedata"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+const (
+	posSideDataFetch   = 64               // Amount of epochs to be fetched per side data request
+	posSideDataTimeout = 10 * time.Second // Time allowance for a peer to answer a side data request
+)
+
+var (
+	errPosSideDataTimeout = errors.New("pos side data request timed out")
+	errPosSideDataOrder   = errors.New("pos side data delivered out of order")
+	errPosSideDataBusy    = errors.New("pos side data sync already running")
+	errPosSideDataQuit    = errors.New("pos side data sync terminated")
+)
+
+// posSideDataPacket is a batch of epoch side data delivered by a peer.
+type posSideDataPacket struct {
+	peer string
+	data []*sidedata.EpochSideData
+}
+
+// collectPosSideData gathers the side data of at most amount epochs starting
+// at from. Epochs without any block are skipped and the epoch in progress is
+// never served, since its block and stake out records are not final yet.
+func (pm *ProtocolManager) collectPosSideData(from uint64, amount uint64) []*sidedata.EpochSideData {
+	data := make([]*sidedata.EpochSideData, 0)
+	firstEpochID := pm.blockchain.PosConfig().FirstEpochID()
+	if firstEpochID == 0 {
+		return data
+	}
+	if amount > posSideDataFetch {
+		amount = posSideDataFetch
+	}
+	if from < firstEpochID {
+		from = firstEpochID
+	}
+
+	curEpoch, _ := util.GetEpochSlotIDFromDifficulty(pm.blockchain.CurrentHeader().Difficulty)
+	for epochID := from; epochID < curEpoch && uint64(len(data)) < amount; epochID++ {
+		d, err := sidedata.Export(pm.blockchain, epochID)
+		if err != nil {
+			log.Debug("Failed to export pos side data", "epochID", epochID, "err", err)
+			break
+		}
+		if d != nil {
+			data = append(data, d)
+		}
+	}
+	return data
+}
+
+// syncPosSideData downloads the POS side data of every finished epoch known
+// to the peer. Nothing is stored yet: the data is staged until the headers it
+// refers to have been downloaded, see commitPosSideData.
+func (pm *ProtocolManager) syncPosSideData(p *peer) (*sidedata.Staging, error) {
+	if !atomic.CompareAndSwapInt32(&pm.posSideSyncing, 0, 1) {
+		return nil, errPosSideDataBusy
+	}
+	defer atomic.StoreInt32(&pm.posSideSyncing, 0)
+
+	var (
+		from    uint64
+		staging = sidedata.NewStaging()
+	)
+	for {
+		if err := p.RequestPosSideData(from, posSideDataFetch); err != nil {
+			return nil, err
+		}
+		data, err := pm.waitPosSideData(p.id)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			log.Info("Pos side data downloaded", "peer", p.id, "epochs", staging.Len())
+			return staging, nil
+		}
+		for _, d := range data {
+			if d.EpochID < from {
+				return nil, errPosSideDataOrder
+			}
+			if err := staging.Add(d); err != nil {
+				return nil, err
+			}
+			from = d.EpochID + 1
+		}
+	}
+}
+
+// commitPosSideData imports the staged side data whose epoch block is at or
+// below number, checked against the local canonical chain and the state of
+// the current block, which must be at least number.
+func (pm *ProtocolManager) commitPosSideData(staging *sidedata.Staging, number uint64) error {
+	stateDb, err := pm.blockchain.State()
+	if err != nil {
+		return err
+	}
+	committed, err := staging.Commit(pm.blockchain, stateDb, number)
+	if committed > 0 {
+		log.Info("Pos side data imported", "epochs", committed, "block", number)
+	}
+	return err
+}
+
+// waitPosSideData blocks until the given peer answers a side data request.
+func (pm *ProtocolManager) waitPosSideData(id string) ([]*sidedata.EpochSideData, error) {
+	timeout := time.NewTimer(posSideDataTimeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case packet := <-pm.posSideDataCh:
+			if packet.peer != id {
+				log.Debug("Pos side data from unexpected peer", "peer", packet.peer)
+				continue
+			}
+			return packet.data, nil
+		case <-timeout.C:
+			return nil, errPosSideDataTimeout
+		case <-pm.quitSync:
+			return nil, errPosSideDataQuit
+		}
+	}
+}
@@ -21,7 +21,7 @@ import (
 	"sync/atomic"
 	"time"
 
-	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/sidedata"
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/core/types"
@@ -164,6 +164,11 @@ func (pm *ProtocolManager) syncer() {
 
 // synchronise tries to sync up our local block chain with a remote peer.
 func (pm *ProtocolManager) synchronise(peer *peer) {
+	// A posfast sync starts with the POS side data, which only the peers
+	// speaking eth64 serve
+	if peer != nil && peer.version < eth64 && atomic.LoadUint32(&pm.posSync) == 1 {
+		peer = pm.peers.BestPeerFrom(eth64)
+	}
 	// Short circuit if no peers are available
 	if peer == nil {
 		return
@@ -191,17 +196,49 @@ func (pm *ProtocolManager) synchronise(peer *peer) {
 		mode = downloader.FastSync
 	}
 
-	if posconfig.FirstEpochId != 0 {
+	if pm.blockchain.PosConfig().FirstEpochID() != 0 {
 		mode = downloader.FullSync
 	}
+	// The POS side data must be in place before the blocks after the pivot
+	// are fully imported, so fetch it first when it was requested. It is only
+	// stored once the pivot, and the headers below it, are committed.
+	var staging *sidedata.Staging
+	if mode == downloader.FastSync && atomic.LoadUint32(&pm.posSync) == 1 {
+		var err error
+		if staging, err = pm.syncPosSideData(peer); err != nil {
+			log.Warn("Pos side data sync failed", "peer", peer.id, "err", err)
+			if err != errPosSideDataBusy && err != errPosSideDataQuit {
+				pm.removePeer(peer.id)
+			}
+			return
+		}
+		pm.downloader.SetPivotHook(func(pivot *types.Header) error {
+			return pm.commitPosSideData(staging, pivot.Number.Uint64())
+		})
+		defer pm.downloader.SetPivotHook(nil)
+	}
 	// Run the sync cycle, and disable fast sync if we've went past the pivot block
 	err := pm.downloader.Synchronise(peer.id, pHead, pTd, mode)
 
+	// Import the side data of the epochs after the pivot, or drop the peer if
+	// what it sent does not belong to the chain we just synced
+	if staging != nil {
+		if cerr := pm.commitPosSideData(staging, pm.blockchain.CurrentBlock().NumberU64()); cerr != nil {
+			log.Error("Pos side data does not match synced chain", "peer", peer.id, "err", cerr)
+			pm.removePeer(peer.id)
+			return
+		}
+		if staging.Len() > 0 {
+			log.Warn("Discarded pos side data beyond the synced chain", "peer", peer.id, "epochs", staging.Len())
+		}
+	}
+
 	if atomic.LoadUint32(&pm.fastSync) == 1 {
 		// Disable fast sync if we indeed have something in our chain
 		if pm.blockchain.CurrentBlock().NumberU64() > 0 {
 			log.Info("Fast sync complete, auto disabling")
 			atomic.StoreUint32(&pm.fastSync, 0)
+			atomic.StoreUint32(&pm.posSync, 0)
 		}
 	}
 	if err != nil {
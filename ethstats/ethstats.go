@@ -137,7 +137,7 @@ func (s *Service) initApi() {
 		return
 	}
 
-	apis := posapi.APIs(s.eth.BlockChain(), s.eth.ApiBackend)
+	apis := posapi.APIs(s.eth.BlockChain(), s.eth.ApiBackend, s.eth.PosContext())
 	api, ok := apis[0].Service.(*posapi.PosApi)
 	if !ok {
 		log.Error("create posapi instance fail")
@@ -1336,7 +1336,7 @@ func (s *Service) getSelfActivity(api *posapi.PosApi, epochId uint64) (uint64, u
 		return minedBlks, elActivity, rnpActivity
 	}
 
-	selfAddr := posconfig.Cfg().GetMinerAddr()
+	selfAddr := s.eth.PosContext().Config.GetMinerAddr()
 	if (selfAddr == common.Address{}) {
 		return minedBlks, elActivity, rnpActivity
 	}
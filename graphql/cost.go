@@ -0,0 +1,67 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// The costs charged to a query's budget, in units of a block header read.
+const (
+	costHeader      = 1
+	costBlock       = 1
+	costState       = 1
+	costTransaction = 1
+	costLog         = 1
+	costLogBlock    = 1
+	costStaker      = 1
+	costReceipts    = 5
+	costIncentives  = 5
+	costStakers     = 20
+	costActivity    = 50
+)
+
+type budgetKey struct{}
+
+// budget is the cost a query may still spend. Fields are resolved in parallel,
+// so it's updated atomically.
+type budget struct {
+	max       int64
+	remaining int64
+}
+
+// withBudget returns a context charging the resolvers run with it against a
+// budget of max.
+func withBudget(ctx context.Context, max int64) context.Context {
+	return context.WithValue(ctx, budgetKey{}, &budget{max: max, remaining: max})
+}
+
+// charge charges cost to the budget of the query, failing once the budget is
+// exceeded. Queries without budget aren't limited.
+func charge(ctx context.Context, cost int64) error {
+	b, ok := ctx.Value(budgetKey{}).(*budget)
+	if !ok || cost == 0 {
+		return nil
+	}
+	if atomic.AddInt64(&b.remaining, -cost) < 0 {
+		return fmt.Errorf("query exceeds the cost limit of %d", b.max)
+	}
+	return nil
+}
@@ -0,0 +1,1064 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package graphql provides a GraphQL interface to Wanchain node data.
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/internal/ethapi"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/incentive"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+const (
+	// maxBlockRange is the most blocks returned by one blocks query.
+	maxBlockRange = 100
+	// maxLogRange is the most blocks searched by one logs query.
+	maxLogRange = 1000
+)
+
+var (
+	errBlockRange   = fmt.Errorf("block range must not exceed %d blocks", maxBlockRange)
+	errLogRange     = fmt.Errorf("log filter range must not exceed %d blocks", maxLogRange)
+	errBlockUnknown = errors.New("block not found")
+)
+
+// Long is a 64 bit integer, accepted as a number or as a decimal or hex string.
+type Long int64
+
+// ImplementsGraphQLType returns true if Long implements the provided GraphQL type.
+func (b Long) ImplementsGraphQLType(name string) bool { return name == "Long" }
+
+// UnmarshalGraphQL unmarshals the provided GraphQL query data.
+func (b *Long) UnmarshalGraphQL(input interface{}) error {
+	switch input := input.(type) {
+	case string:
+		value, err := strconv.ParseInt(input, 0, 64)
+		if err != nil {
+			return err
+		}
+		*b = Long(value)
+	case int32:
+		*b = Long(input)
+	case int64:
+		*b = Long(input)
+	case float64:
+		*b = Long(input)
+	default:
+		return fmt.Errorf("unexpected type %T for Long", input)
+	}
+	return nil
+}
+
+// Account represents a Wanchain account at a particular block.
+type Account struct {
+	backend     ethapi.Backend
+	address     common.Address
+	blockNumber rpc.BlockNumber
+}
+
+func (a *Account) getState(ctx context.Context) (*state.StateDB, error) {
+	if err := charge(ctx, costState); err != nil {
+		return nil, err
+	}
+	statedb, _, err := a.backend.StateAndHeaderByNumber(ctx, a.blockNumber)
+	if err == nil && statedb == nil {
+		err = errBlockUnknown
+	}
+	return statedb, err
+}
+
+func (a *Account) Address(ctx context.Context) common.Address {
+	return a.address
+}
+
+func (a *Account) Balance(ctx context.Context) (hexutil.Big, error) {
+	statedb, err := a.getState(ctx)
+	if err != nil {
+		return hexutil.Big{}, err
+	}
+	return hexutil.Big(*statedb.GetBalance(a.address)), nil
+}
+
+func (a *Account) TransactionCount(ctx context.Context) (Long, error) {
+	statedb, err := a.getState(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return Long(statedb.GetNonce(a.address)), nil
+}
+
+func (a *Account) Code(ctx context.Context) (hexutil.Bytes, error) {
+	statedb, err := a.getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return hexutil.Bytes(statedb.GetCode(a.address)), nil
+}
+
+func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash }) (common.Hash, error) {
+	statedb, err := a.getState(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return statedb.GetState(a.address, args.Slot), nil
+}
+
+// Log represents an individual log message. All arguments are mandatory.
+type Log struct {
+	backend     ethapi.Backend
+	transaction *Transaction
+	log         *types.Log
+}
+
+func (l *Log) Transaction(ctx context.Context) *Transaction {
+	return l.transaction
+}
+
+func (l *Log) Account(ctx context.Context) *Account {
+	return &Account{
+		backend:     l.backend,
+		address:     l.log.Address,
+		blockNumber: rpc.BlockNumber(l.log.BlockNumber),
+	}
+}
+
+func (l *Log) Index(ctx context.Context) int32 {
+	return int32(l.log.Index)
+}
+
+func (l *Log) Topics(ctx context.Context) []common.Hash {
+	return l.log.Topics
+}
+
+func (l *Log) Data(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(l.log.Data)
+}
+
+// Transaction represents a Wanchain transaction. The block is nil for pending
+// transactions.
+type Transaction struct {
+	backend ethapi.Backend
+	tx      *types.Transaction
+	block   *types.Block
+	index   uint64
+}
+
+func (t *Transaction) Hash(ctx context.Context) common.Hash {
+	return t.tx.Hash()
+}
+
+func (t *Transaction) TxType(ctx context.Context) Long {
+	return Long(t.tx.Txtype())
+}
+
+func (t *Transaction) Nonce(ctx context.Context) Long {
+	return Long(t.tx.Nonce())
+}
+
+func (t *Transaction) Index(ctx context.Context) *int32 {
+	if t.block == nil {
+		return nil
+	}
+	index := int32(t.index)
+	return &index
+}
+
+func (t *Transaction) From(ctx context.Context) (*Account, error) {
+	var signer types.Signer = types.FrontierSigner{}
+	if t.tx.Protected() {
+		signer = types.NewEIP155Signer(t.tx.ChainId())
+	}
+	from, err := types.Sender(signer, t.tx)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{backend: t.backend, address: from, blockNumber: t.blockNumber()}, nil
+}
+
+func (t *Transaction) To(ctx context.Context) *Account {
+	to := t.tx.To()
+	if to == nil {
+		return nil
+	}
+	return &Account{backend: t.backend, address: *to, blockNumber: t.blockNumber()}
+}
+
+func (t *Transaction) Value(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*t.tx.Value())
+}
+
+func (t *Transaction) GasPrice(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*t.tx.GasPrice())
+}
+
+func (t *Transaction) Gas(ctx context.Context) Long {
+	return Long(t.tx.Gas().Int64())
+}
+
+func (t *Transaction) InputData(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(t.tx.Data())
+}
+
+func (t *Transaction) Block(ctx context.Context) *Block {
+	if t.block == nil {
+		return nil
+	}
+	return &Block{backend: t.backend, block: t.block}
+}
+
+func (t *Transaction) Receipt(ctx context.Context) (*Receipt, error) {
+	if t.block == nil {
+		return nil, nil
+	}
+	if err := charge(ctx, costReceipts); err != nil {
+		return nil, err
+	}
+	receipts, err := t.backend.GetReceipts(ctx, t.block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if t.index >= uint64(len(receipts)) {
+		return nil, nil
+	}
+	return &Receipt{transaction: t, receipt: receipts[t.index]}, nil
+}
+
+// blockNumber returns the block whose state the accounts of the transaction
+// are resolved at, the latest one for pending transactions.
+func (t *Transaction) blockNumber() rpc.BlockNumber {
+	if t.block == nil {
+		return rpc.LatestBlockNumber
+	}
+	return rpc.BlockNumber(t.block.NumberU64())
+}
+
+// Receipt represents the receipt of a mined transaction.
+type Receipt struct {
+	transaction *Transaction
+	receipt     *types.Receipt
+}
+
+func (r *Receipt) Status(ctx context.Context) *Long {
+	if len(r.receipt.PostState) > 0 {
+		return nil
+	}
+	status := Long(r.receipt.Status)
+	return &status
+}
+
+func (r *Receipt) Root(ctx context.Context) *common.Hash {
+	if len(r.receipt.PostState) == 0 {
+		return nil
+	}
+	root := common.BytesToHash(r.receipt.PostState)
+	return &root
+}
+
+func (r *Receipt) GasUsed(ctx context.Context) Long {
+	return Long(r.receipt.GasUsed.Int64())
+}
+
+func (r *Receipt) CumulativeGasUsed(ctx context.Context) Long {
+	return Long(r.receipt.CumulativeGasUsed.Int64())
+}
+
+func (r *Receipt) CreatedContract(ctx context.Context) *Account {
+	if r.receipt.ContractAddress == (common.Address{}) {
+		return nil
+	}
+	return &Account{
+		backend:     r.transaction.backend,
+		address:     r.receipt.ContractAddress,
+		blockNumber: r.transaction.blockNumber(),
+	}
+}
+
+func (r *Receipt) LogsBloom(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(r.receipt.Bloom.Bytes())
+}
+
+func (r *Receipt) Logs(ctx context.Context) ([]*Log, error) {
+	if err := charge(ctx, int64(len(r.receipt.Logs))*costLog); err != nil {
+		return nil, err
+	}
+	ret := make([]*Log, 0, len(r.receipt.Logs))
+	for _, log := range r.receipt.Logs {
+		ret = append(ret, &Log{backend: r.transaction.backend, transaction: r.transaction, log: log})
+	}
+	return ret, nil
+}
+
+// Block represents a Wanchain block.
+type Block struct {
+	backend ethapi.Backend
+	block   *types.Block
+}
+
+func (b *Block) Number(ctx context.Context) Long {
+	return Long(b.block.NumberU64())
+}
+
+func (b *Block) Hash(ctx context.Context) common.Hash {
+	return b.block.Hash()
+}
+
+func (b *Block) Parent(ctx context.Context) (*Block, error) {
+	if b.block.NumberU64() == 0 {
+		return nil, nil
+	}
+	return blockByHash(ctx, b.backend, b.block.ParentHash())
+}
+
+func (b *Block) TransactionsRoot(ctx context.Context) common.Hash {
+	return b.block.TxHash()
+}
+
+func (b *Block) StateRoot(ctx context.Context) common.Hash {
+	return b.block.Root()
+}
+
+func (b *Block) ReceiptsRoot(ctx context.Context) common.Hash {
+	return b.block.ReceiptHash()
+}
+
+func (b *Block) Miner(ctx context.Context) *Account {
+	return &Account{
+		backend:     b.backend,
+		address:     b.block.Coinbase(),
+		blockNumber: rpc.BlockNumber(b.block.NumberU64()),
+	}
+}
+
+func (b *Block) ExtraData(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(b.block.Extra())
+}
+
+func (b *Block) GasLimit(ctx context.Context) Long {
+	return Long(b.block.GasLimit().Int64())
+}
+
+func (b *Block) GasUsed(ctx context.Context) Long {
+	return Long(b.block.GasUsed().Int64())
+}
+
+func (b *Block) Timestamp(ctx context.Context) Long {
+	return Long(b.block.Time().Int64())
+}
+
+func (b *Block) LogsBloom(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(b.block.Bloom().Bytes())
+}
+
+func (b *Block) Difficulty(ctx context.Context) hexutil.Big {
+	return hexutil.Big(*b.block.Difficulty())
+}
+
+func (b *Block) TotalDifficulty(ctx context.Context) (hexutil.Big, error) {
+	td := b.backend.GetTd(b.block.Hash())
+	if td == nil {
+		return hexutil.Big{}, fmt.Errorf("total difficulty not found %x", b.block.Hash())
+	}
+	return hexutil.Big(*td), nil
+}
+
+func (b *Block) EpochID(ctx context.Context) *Long {
+	if !isPosBlock(b.backend, b.block) {
+		return nil
+	}
+	epochID, _ := util.GetEpochSlotIDFromDifficulty(b.block.Difficulty())
+	id := Long(epochID)
+	return &id
+}
+
+func (b *Block) SlotID(ctx context.Context) *Long {
+	if !isPosBlock(b.backend, b.block) {
+		return nil
+	}
+	_, slotID := util.GetEpochSlotIDFromDifficulty(b.block.Difficulty())
+	id := Long(slotID)
+	return &id
+}
+
+func (b *Block) Epoch(ctx context.Context) *Epoch {
+	id := b.EpochID(ctx)
+	if id == nil {
+		return nil
+	}
+	return &Epoch{backend: b.backend, id: uint64(*id)}
+}
+
+func (b *Block) TransactionCount(ctx context.Context) int32 {
+	return int32(len(b.block.Transactions()))
+}
+
+func (b *Block) Transactions(ctx context.Context) ([]*Transaction, error) {
+	txs := b.block.Transactions()
+	if err := charge(ctx, int64(len(txs))*costTransaction); err != nil {
+		return nil, err
+	}
+	ret := make([]*Transaction, 0, len(txs))
+	for i, tx := range txs {
+		ret = append(ret, &Transaction{backend: b.backend, tx: tx, block: b.block, index: uint64(i)})
+	}
+	return ret, nil
+}
+
+func (b *Block) TransactionAt(ctx context.Context, args struct{ Index int32 }) (*Transaction, error) {
+	txs := b.block.Transactions()
+	if args.Index < 0 || int(args.Index) >= len(txs) {
+		return nil, nil
+	}
+	if err := charge(ctx, costTransaction); err != nil {
+		return nil, err
+	}
+	return &Transaction{backend: b.backend, tx: txs[args.Index], block: b.block, index: uint64(args.Index)}, nil
+}
+
+// BlockFilterCriteria encapsulates criteria passed to a `logs` accessor inside
+// a block.
+type BlockFilterCriteria struct {
+	Addresses *[]common.Address // restricts matches to events created by specific contracts
+	Topics    *[][]common.Hash  // restricts matches to events with the given topic prefix
+}
+
+func (b *Block) Logs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) ([]*Log, error) {
+	addresses, topics := filterArgs(args.Filter.Addresses, args.Filter.Topics)
+	return blockLogs(ctx, b.backend, b.block, addresses, topics)
+}
+
+func (b *Block) Account(ctx context.Context, args struct{ Address common.Address }) *Account {
+	return &Account{
+		backend:     b.backend,
+		address:     args.Address,
+		blockNumber: rpc.BlockNumber(b.block.NumberU64()),
+	}
+}
+
+func blockByHash(ctx context.Context, backend ethapi.Backend, hash common.Hash) (*Block, error) {
+	if err := charge(ctx, costBlock); err != nil {
+		return nil, err
+	}
+	block, err := backend.GetBlock(ctx, hash)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	return &Block{backend: backend, block: block}, nil
+}
+
+func blockByNumber(ctx context.Context, backend ethapi.Backend, number rpc.BlockNumber) (*Block, error) {
+	if err := charge(ctx, costBlock); err != nil {
+		return nil, err
+	}
+	block, err := backend.BlockByNumber(ctx, number)
+	if block == nil || err != nil {
+		return nil, err
+	}
+	return &Block{backend: backend, block: block}, nil
+}
+
+// blockLogs returns the logs of block matching the filter.
+func blockLogs(ctx context.Context, backend ethapi.Backend, block *types.Block, addresses []common.Address, topics [][]common.Hash) ([]*Log, error) {
+	if !bloomMatches(block.Bloom(), addresses, topics) {
+		return nil, nil
+	}
+	if err := charge(ctx, costReceipts); err != nil {
+		return nil, err
+	}
+	receipts, err := backend.GetReceipts(ctx, block.Hash())
+	if err != nil {
+		return nil, err
+	}
+	var ret []*Log
+	for i, receipt := range receipts {
+		if i >= len(block.Transactions()) {
+			break
+		}
+		tx := &Transaction{backend: backend, tx: block.Transactions()[i], block: block, index: uint64(i)}
+		for _, log := range receipt.Logs {
+			if !logMatches(log, addresses, topics) {
+				continue
+			}
+			if err := charge(ctx, costLog); err != nil {
+				return nil, err
+			}
+			ret = append(ret, &Log{backend: backend, transaction: tx, log: log})
+		}
+	}
+	return ret, nil
+}
+
+func filterArgs(addresses *[]common.Address, topics *[][]common.Hash) ([]common.Address, [][]common.Hash) {
+	var (
+		addrs []common.Address
+		tpcs  [][]common.Hash
+	)
+	if addresses != nil {
+		addrs = *addresses
+	}
+	if topics != nil {
+		tpcs = *topics
+	}
+	return addrs, tpcs
+}
+
+// bloomMatches reports whether a block with the given bloom may contain logs
+// matching the filter.
+func bloomMatches(bloom types.Bloom, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		var included bool
+		for _, addr := range addresses {
+			if types.BloomLookup(bloom, addr) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, sub := range topics {
+		if len(sub) == 0 {
+			continue
+		}
+		var included bool
+		for _, topic := range sub {
+			if types.BloomLookup(bloom, topic) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
+
+// logMatches reports whether log was emitted by one of addresses and matches
+// the topics, an empty topic position matching any topic.
+func logMatches(log *types.Log, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		var included bool
+		for _, addr := range addresses {
+			if log.Address == addr {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	if len(topics) > len(log.Topics) {
+		return false
+	}
+	for i, sub := range topics {
+		if len(sub) == 0 {
+			continue
+		}
+		var included bool
+		for _, topic := range sub {
+			if log.Topics[i] == topic {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
+
+// Delegator is a client delegating to a staker.
+type Delegator struct {
+	info vm.ClientInfo
+}
+
+func (d *Delegator) Address(ctx context.Context) common.Address { return d.info.Address }
+func (d *Delegator) Amount(ctx context.Context) hexutil.Big     { return bigOrZero(d.info.Amount) }
+func (d *Delegator) StakeAmount(ctx context.Context) hexutil.Big {
+	return bigOrZero(d.info.StakeAmount)
+}
+func (d *Delegator) QuitEpoch(ctx context.Context) Long { return Long(d.info.QuitEpoch) }
+
+// Partner is an account staking together with a staker.
+type Partner struct {
+	info vm.PartnerInfo
+}
+
+func (p *Partner) Address(ctx context.Context) common.Address  { return p.info.Address }
+func (p *Partner) Amount(ctx context.Context) hexutil.Big      { return bigOrZero(p.info.Amount) }
+func (p *Partner) StakeAmount(ctx context.Context) hexutil.Big { return bigOrZero(p.info.StakeAmount) }
+func (p *Partner) Renewal(ctx context.Context) bool            { return p.info.Renewal }
+func (p *Partner) LockEpochs(ctx context.Context) Long         { return Long(p.info.LockEpochs) }
+func (p *Partner) StakingEpoch(ctx context.Context) Long       { return Long(p.info.StakingEpoch) }
+
+// Staker is a validator registered in the staking contract.
+type Staker struct {
+	info vm.StakerInfo
+}
+
+func (s *Staker) Address(ctx context.Context) common.Address  { return s.info.Address }
+func (s *Staker) From(ctx context.Context) common.Address     { return s.info.From }
+func (s *Staker) PubSec256(ctx context.Context) hexutil.Bytes { return hexutil.Bytes(s.info.PubSec256) }
+func (s *Staker) PubBn256(ctx context.Context) hexutil.Bytes  { return hexutil.Bytes(s.info.PubBn256) }
+func (s *Staker) Amount(ctx context.Context) hexutil.Big      { return bigOrZero(s.info.Amount) }
+func (s *Staker) StakeAmount(ctx context.Context) hexutil.Big { return bigOrZero(s.info.StakeAmount) }
+func (s *Staker) LockEpochs(ctx context.Context) Long         { return Long(s.info.LockEpochs) }
+func (s *Staker) NextLockEpochs(ctx context.Context) Long     { return Long(s.info.NextLockEpochs) }
+func (s *Staker) StakingEpoch(ctx context.Context) Long       { return Long(s.info.StakingEpoch) }
+func (s *Staker) FeeRate(ctx context.Context) Long            { return Long(s.info.FeeRate) }
+
+func (s *Staker) Delegators(ctx context.Context) []*Delegator {
+	ret := make([]*Delegator, len(s.info.Clients))
+	for i := range s.info.Clients {
+		ret[i] = &Delegator{s.info.Clients[i]}
+	}
+	return ret
+}
+
+func (s *Staker) Partners(ctx context.Context) []*Partner {
+	ret := make([]*Partner, len(s.info.Partners))
+	for i := range s.info.Partners {
+		ret[i] = &Partner{s.info.Partners[i]}
+	}
+	return ret
+}
+
+// stakers returns the validators registered at the given block.
+func stakers(ctx context.Context, backend ethapi.Backend, number rpc.BlockNumber) ([]vm.StakerInfo, error) {
+	if err := charge(ctx, costStakers); err != nil {
+		return nil, err
+	}
+	statedb, _, err := backend.StateAndHeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if statedb == nil {
+		return nil, errBlockUnknown
+	}
+	snap := vm.GetStakersSnap(statedb)
+	if err := charge(ctx, int64(len(snap))*costStaker); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// DelegatorIncentive is the incentive paid to a delegator for an epoch.
+type DelegatorIncentive struct {
+	info vm.ClientIncentive
+}
+
+func (d *DelegatorIncentive) Address(ctx context.Context) common.Address { return d.info.WalletAddr }
+func (d *DelegatorIncentive) Incentive(ctx context.Context) hexutil.Big {
+	return bigOrZero(d.info.Incentive)
+}
+
+// ValidatorIncentive is the incentive paid to a validator and its delegators
+// for an epoch. The first entry is the validator's own.
+type ValidatorIncentive struct {
+	infos []vm.ClientIncentive
+}
+
+func (v *ValidatorIncentive) Address(ctx context.Context) common.Address {
+	return v.infos[0].ValidatorAddr
+}
+
+func (v *ValidatorIncentive) WalletAddress(ctx context.Context) common.Address {
+	return v.infos[0].WalletAddr
+}
+
+func (v *ValidatorIncentive) Incentive(ctx context.Context) hexutil.Big {
+	return bigOrZero(v.infos[0].Incentive)
+}
+
+func (v *ValidatorIncentive) Delegators(ctx context.Context) []*DelegatorIncentive {
+	ret := make([]*DelegatorIncentive, 0, len(v.infos)-1)
+	for _, info := range v.infos[1:] {
+		ret = append(ret, &DelegatorIncentive{info})
+	}
+	return ret
+}
+
+// LeaderActivity tells whether an epoch leader or random proposer took part in
+// its protocol.
+type LeaderActivity struct {
+	address common.Address
+	active  bool
+}
+
+func (l *LeaderActivity) Address(ctx context.Context) common.Address { return l.address }
+func (l *LeaderActivity) Active(ctx context.Context) bool            { return l.active }
+
+// SlotLeaderActivity is the number of blocks a slot leader produced.
+type SlotLeaderActivity struct {
+	address common.Address
+	blocks  int
+}
+
+func (s *SlotLeaderActivity) Address(ctx context.Context) common.Address { return s.address }
+func (s *SlotLeaderActivity) Blocks(ctx context.Context) int32           { return int32(s.blocks) }
+
+// Activity is the participation of the POS leaders in an epoch.
+type Activity struct {
+	epochLeaders    []*LeaderActivity
+	randomProposers []*LeaderActivity
+	slotLeaders     []*SlotLeaderActivity
+	slotActivity    float64
+	controlBlocks   int
+}
+
+func (a *Activity) EpochLeaders(ctx context.Context) []*LeaderActivity    { return a.epochLeaders }
+func (a *Activity) RandomProposers(ctx context.Context) []*LeaderActivity { return a.randomProposers }
+func (a *Activity) SlotLeaders(ctx context.Context) []*SlotLeaderActivity { return a.slotLeaders }
+func (a *Activity) SlotActivity(ctx context.Context) float64              { return a.slotActivity }
+func (a *Activity) ControlBlocks(ctx context.Context) int32               { return int32(a.controlBlocks) }
+
+func leaderActivity(addrs []common.Address, activity []int) []*LeaderActivity {
+	ret := make([]*LeaderActivity, 0, len(addrs))
+	for i, addr := range addrs {
+		ret = append(ret, &LeaderActivity{address: addr, active: i < len(activity) && activity[i] != 0})
+	}
+	return ret
+}
+
+// Epoch represents a POS epoch.
+type Epoch struct {
+	backend ethapi.Backend
+	id      uint64
+}
+
+func (e *Epoch) ID(ctx context.Context) Long {
+	return Long(e.id)
+}
+
+func (e *Epoch) Incentive(ctx context.Context) *hexutil.Big {
+	total, err := incentive.GetEpochIncentive(e.id)
+	if err != nil || total == nil {
+		return nil
+	}
+	return (*hexutil.Big)(total)
+}
+
+func (e *Epoch) Incentives(ctx context.Context) ([]*ValidatorIncentive, error) {
+	if err := charge(ctx, costIncentives); err != nil {
+		return nil, err
+	}
+	// Epochs that weren't paid yet have no details stored.
+	details, err := incentive.GetEpochPayDetail(e.id)
+	if err != nil {
+		return []*ValidatorIncentive{}, nil
+	}
+	ret := make([]*ValidatorIncentive, 0, len(details))
+	for _, infos := range details {
+		if len(infos) == 0 {
+			continue
+		}
+		ret = append(ret, &ValidatorIncentive{infos})
+	}
+	return ret, nil
+}
+
+func (e *Epoch) Activity(ctx context.Context) (*Activity, error) {
+	if err := charge(ctx, costActivity); err != nil {
+		return nil, err
+	}
+	statedb, _, err := e.backend.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if statedb == nil {
+		return nil, errBlockUnknown
+	}
+	ret := new(Activity)
+	ret.epochLeaders = leaderActivity(incentive.GetEpochLeaderActivity(statedb, e.id))
+	ret.randomProposers = leaderActivity(incentive.GetEpochRBLeaderActivity(statedb, e.id))
+
+	chain := &chainReader{ctx: ctx, backend: e.backend}
+	leaders, blocks, slotActivity, ctrlCount := incentive.GetSlotLeaderActivity(chain, e.id)
+	if chain.err != nil {
+		return nil, chain.err
+	}
+	ret.slotLeaders = make([]*SlotLeaderActivity, 0, len(leaders))
+	for i, leader := range leaders {
+		ret.slotLeaders = append(ret.slotLeaders, &SlotLeaderActivity{address: leader, blocks: blocks[i]})
+	}
+	ret.slotActivity, ret.controlBlocks = slotActivity, ctrlCount
+	return ret, nil
+}
+
+// chainReader serves the headers of the backend to the incentive package. The
+// headers it reads are charged to the query, once the budget is exceeded it
+// returns no more headers.
+type chainReader struct {
+	ctx     context.Context
+	backend ethapi.Backend
+	err     error
+}
+
+func (c *chainReader) Config() *params.ChainConfig {
+	return c.backend.ChainConfig()
+}
+
+func (c *chainReader) CurrentHeader() *types.Header {
+	return c.backend.CurrentBlock().Header()
+}
+
+func (c *chainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.GetHeaderByHash(hash)
+}
+
+func (c *chainReader) GetHeaderByNumber(number uint64) *types.Header {
+	if c.charge() {
+		return nil
+	}
+	header, _ := c.backend.HeaderByNumber(c.ctx, rpc.BlockNumber(number))
+	return header
+}
+
+func (c *chainReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	if block := c.GetBlock(hash, 0); block != nil {
+		return block.Header()
+	}
+	return nil
+}
+
+func (c *chainReader) GetBlock(hash common.Hash, number uint64) *types.Block {
+	if c.charge() {
+		return nil
+	}
+	block, _ := c.backend.GetBlock(c.ctx, hash)
+	return block
+}
+
+// charge charges a header read, reporting whether the budget is exceeded.
+func (c *chainReader) charge() bool {
+	if c.err == nil {
+		c.err = charge(c.ctx, costHeader)
+	}
+	return c.err != nil
+}
+
+// Resolver is the top-level object in the GraphQL hierarchy.
+type Resolver struct {
+	backend ethapi.Backend
+}
+
+func (r *Resolver) Block(ctx context.Context, args struct {
+	Number *Long
+	Hash   *common.Hash
+}) (*Block, error) {
+	switch {
+	case args.Number != nil && args.Hash != nil:
+		return nil, errors.New("only one of number or hash must be specified")
+	case args.Hash != nil:
+		return blockByHash(ctx, r.backend, *args.Hash)
+	case args.Number != nil:
+		return blockByNumber(ctx, r.backend, rpc.BlockNumber(*args.Number))
+	}
+	return blockByNumber(ctx, r.backend, rpc.LatestBlockNumber)
+}
+
+func (r *Resolver) Blocks(ctx context.Context, args struct {
+	From Long
+	To   *Long
+}) ([]*Block, error) {
+	head := r.backend.CurrentBlock().NumberU64()
+	from, to := uint64(args.From), head
+	if args.To != nil {
+		to = uint64(*args.To)
+	}
+	if to < from {
+		return []*Block{}, nil
+	}
+	if to-from >= maxBlockRange {
+		return nil, errBlockRange
+	}
+	if to > head {
+		to = head
+	}
+	ret := make([]*Block, 0, to-from+1)
+	for number := from; number <= to; number++ {
+		block, err := blockByNumber(ctx, r.backend, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			break
+		}
+		ret = append(ret, block)
+	}
+	return ret, nil
+}
+
+func (r *Resolver) Transaction(ctx context.Context, args struct{ Hash common.Hash }) (*Transaction, error) {
+	if err := charge(ctx, costTransaction); err != nil {
+		return nil, err
+	}
+	// Try to return an already finalized transaction
+	if tx, blockHash, _, index := core.GetTransaction(r.backend.ChainDb(), args.Hash); tx != nil {
+		block, err := r.backend.GetBlock(ctx, blockHash)
+		if err != nil {
+			return nil, err
+		}
+		return &Transaction{backend: r.backend, tx: tx, block: block, index: index}, nil
+	}
+	// No finalized transaction, try to retrieve it from the pool
+	if tx := r.backend.GetPoolTransaction(args.Hash); tx != nil {
+		return &Transaction{backend: r.backend, tx: tx}, nil
+	}
+	return nil, nil
+}
+
+// FilterCriteria encapsulates the arguments to `logs` on the root resolver object.
+type FilterCriteria struct {
+	FromBlock *Long             // beginning of the queried range, nil means latest block
+	ToBlock   *Long             // end of the range, nil means latest block
+	Addresses *[]common.Address // restricts matches to events created by specific contracts
+	Topics    *[][]common.Hash  // restricts matches to events with the given topic prefix
+}
+
+func (r *Resolver) Logs(ctx context.Context, args struct{ Filter FilterCriteria }) ([]*Log, error) {
+	head := r.backend.CurrentBlock().NumberU64()
+	from, to := head, head
+	if args.Filter.FromBlock != nil {
+		from = uint64(*args.Filter.FromBlock)
+	}
+	if args.Filter.ToBlock != nil {
+		to = uint64(*args.Filter.ToBlock)
+	}
+	if to < from {
+		return []*Log{}, nil
+	}
+	if to-from >= maxLogRange {
+		return nil, errLogRange
+	}
+	if to > head {
+		to = head
+	}
+	addresses, topics := filterArgs(args.Filter.Addresses, args.Filter.Topics)
+
+	ret := []*Log{}
+	for number := from; number <= to; number++ {
+		if err := charge(ctx, costLogBlock); err != nil {
+			return nil, err
+		}
+		header, err := r.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			break
+		}
+		if !bloomMatches(header.Bloom, addresses, topics) {
+			continue
+		}
+		block, err := r.backend.GetBlock(ctx, header.Hash())
+		if block == nil || err != nil {
+			return nil, err
+		}
+		logs, err := blockLogs(ctx, r.backend, block, addresses, topics)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, logs...)
+	}
+	return ret, nil
+}
+
+func (r *Resolver) Account(ctx context.Context, args struct {
+	Address     common.Address
+	BlockNumber *Long
+}) *Account {
+	return &Account{backend: r.backend, address: args.Address, blockNumber: blockNumberArg(args.BlockNumber)}
+}
+
+func (r *Resolver) Epoch(ctx context.Context, args struct{ ID *Long }) *Epoch {
+	if args.ID != nil {
+		return &Epoch{backend: r.backend, id: uint64(*args.ID)}
+	}
+	head := r.backend.CurrentBlock()
+	if !isPosBlock(r.backend, head) {
+		return nil
+	}
+	epochID, _ := util.GetEpochSlotIDFromDifficulty(head.Difficulty())
+	return &Epoch{backend: r.backend, id: epochID}
+}
+
+func (r *Resolver) Stakers(ctx context.Context, args struct{ BlockNumber *Long }) ([]*Staker, error) {
+	snap, err := stakers(ctx, r.backend, blockNumberArg(args.BlockNumber))
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*Staker, len(snap))
+	for i := range snap {
+		ret[i] = &Staker{snap[i]}
+	}
+	return ret, nil
+}
+
+func (r *Resolver) Staker(ctx context.Context, args struct {
+	Address     common.Address
+	BlockNumber *Long
+}) (*Staker, error) {
+	snap, err := stakers(ctx, r.backend, blockNumberArg(args.BlockNumber))
+	if err != nil {
+		return nil, err
+	}
+	for i := range snap {
+		if snap[i].Address == args.Address {
+			return &Staker{snap[i]}, nil
+		}
+	}
+	return nil, nil
+}
+
+// isPosBlock reports whether block was produced by the POS consensus.
+func isPosBlock(backend ethapi.Backend, block *types.Block) bool {
+	first := backend.ChainConfig().PosFirstBlock
+	return first != nil && block.Number().Cmp(first) >= 0
+}
+
+func blockNumberArg(number *Long) rpc.BlockNumber {
+	if number == nil {
+		return rpc.LatestBlockNumber
+	}
+	return rpc.BlockNumber(*number)
+}
+
+func bigOrZero(value *big.Int) hexutil.Big {
+	if value == nil {
+		return hexutil.Big{}
+	}
+	return hexutil.Big(*value)
+}
@@ -0,0 +1,228 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/consensus/ethash"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/internal/ethapi"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// testBackend resolves the queries against a local chain, leaving the methods
+// the resolvers don't use unimplemented.
+type testBackend struct {
+	ethapi.Backend
+	db    ethdb.Database
+	chain *core.BlockChain
+}
+
+func (b *testBackend) ChainDb() ethdb.Database                           { return b.db }
+func (b *testBackend) ChainConfig() *params.ChainConfig                  { return b.chain.Config() }
+func (b *testBackend) CurrentBlock() *types.Block                        { return b.chain.CurrentBlock() }
+func (b *testBackend) GetTd(hash common.Hash) *big.Int                   { return b.chain.GetTdByHash(hash) }
+func (b *testBackend) GetPoolTransaction(common.Hash) *types.Transaction { return nil }
+
+func (b *testBackend) blockNumber(number rpc.BlockNumber) uint64 {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return b.chain.CurrentBlock().NumberU64()
+	}
+	return uint64(number)
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	return b.chain.GetHeaderByNumber(b.blockNumber(number)), nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	return b.chain.GetBlockByNumber(b.blockNumber(number)), nil
+}
+
+func (b *testBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	header := b.chain.GetHeaderByNumber(b.blockNumber(number))
+	if header == nil {
+		return nil, nil, nil
+	}
+	statedb, err := b.chain.StateAt(header.Root)
+	return statedb, header, err
+}
+
+func (b *testBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	return b.chain.GetBlockByHash(hash), nil
+}
+
+func (b *testBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	return core.GetBlockReceipts(b.db, hash, core.GetBlockNumber(b.db, hash)), nil
+}
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testTopic   = common.HexToHash("0x01020304")
+	testBalance = new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Wan))
+)
+
+// newTestBackend creates a chain of 5 blocks, the second one creating a
+// contract that emits a log with testTopic.
+func newTestBackend(t *testing.T) (*testBackend, *types.Transaction) {
+	db, _ := ethdb.NewMemDatabase()
+	gspec := core.DefaultPPOWTestingGenesisBlock()
+	gspec.Alloc = core.GenesisAlloc{testAddr: {Balance: testBalance}}
+	genesis := gspec.MustCommit(db)
+
+	engine := ethash.NewFaker(db)
+	chain, err := core.NewBlockChain(db, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := core.NewChainEnv(gspec.Config, gspec, engine, chain, db)
+
+	// PUSH32 testTopic PUSH1 0 PUSH1 0 LOG1 STOP
+	code := append(append([]byte{byte(vm.PUSH32)}, testTopic.Bytes()...), byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.LOG1), byte(vm.STOP))
+	signer := types.NewEIP155Signer(gspec.Config.ChainId)
+	tx, err := types.SignTx(types.NewContractCreation(0, new(big.Int), big.NewInt(100000), nil, code), signer, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, _ := env.GenerateChain(genesis, 5, func(i int, gen *core.BlockGen) {
+		if i == 1 {
+			gen.AddTx(tx)
+		}
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatal(err)
+	}
+	return &testBackend{db: db, chain: chain}, tx
+}
+
+type testResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func query(t *testing.T, service *Service, query string) *testResponse {
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req := httptest.NewRequest("POST", "/graphql", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	service.HTTPHandlers()["/graphql"].ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status mismatch: have %d, want %d", rec.Code, http.StatusOK)
+	}
+	resp := new(testResponse)
+	if err := json.Unmarshal(rec.Body.Bytes(), resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestGraphQLQueries(t *testing.T) {
+	backend, tx := newTestBackend(t)
+	defer backend.chain.Stop()
+	service, err := New(backend, DefaultConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contract := crypto.CreateAddress(testAddr, 0)
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{
+			query: `{ block(number: 2) { number parent { number } transactionCount epochId } }`,
+			want:  `{"block":{"number":2,"parent":{"number":1},"transactionCount":1,"epochId":null}}`,
+		},
+		{
+			query: `{ blocks(from: 3) { number } }`,
+			want:  `{"blocks":[{"number":3},{"number":4},{"number":5}]}`,
+		},
+		{
+			query: `{ transaction(hash: "` + tx.Hash().Hex() + `") { txType index from { address } block { number } receipt { status createdContract { address } logs { topics } } } }`,
+			want:  `{"transaction":{"txType":1,"index":0,"from":{"address":"` + strings.ToLower(testAddr.Hex()) + `"},"block":{"number":2},"receipt":{"status":1,"createdContract":{"address":"` + strings.ToLower(contract.Hex()) + `"},"logs":[{"topics":["` + testTopic.Hex() + `"]}]}}}`,
+		},
+		{
+			query: `{ logs(filter: {fromBlock: 0, topics: [["` + testTopic.Hex() + `"]]}) { account { address } transaction { hash } } }`,
+			want:  `{"logs":[{"account":{"address":"` + strings.ToLower(contract.Hex()) + `"},"transaction":{"hash":"` + tx.Hash().Hex() + `"}}]}`,
+		},
+		{
+			query: `{ account(address: "` + testAddr.Hex() + `", blockNumber: 1) { transactionCount } }`,
+			want:  `{"account":{"transactionCount":0}}`,
+		},
+		{
+			query: `{ stakers { address } }`,
+			want:  `{"stakers":[]}`,
+		},
+	}
+	for i, test := range tests {
+		resp := query(t, service, test.query)
+		if len(resp.Errors) != 0 {
+			t.Errorf("test %d: query failed: %v", i, resp.Errors)
+			continue
+		}
+		have, _ := json.Marshal(resp.Data)
+		if string(have) != test.want {
+			t.Errorf("test %d: result mismatch:\nhave %s\nwant %s", i, have, test.want)
+		}
+	}
+}
+
+func TestGraphQLLimits(t *testing.T) {
+	backend, _ := newTestBackend(t)
+	defer backend.chain.Stop()
+	service, err := New(backend, Config{MaxDepth: 3, MaxCost: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{query: `{ blocks(from: 0) { number } }`, err: "cost limit"},
+		{query: `{ block { parent { parent { parent { number } } } } }`, err: "depth"},
+		{query: `{ blocks(from: 0, to: 1000) { number } }`, err: "must not exceed"},
+		{query: `{ logs(filter: {fromBlock: 0}) { index } }`, err: "cost limit"},
+	}
+	for i, test := range tests {
+		resp := query(t, service, test.query)
+		if len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, test.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %q", i, resp.Errors, test.err)
+		}
+	}
+	if resp := query(t, service, `{ block(number: 1) { number } }`); len(resp.Errors) != 0 {
+		t.Errorf("query within limits failed: %v", resp.Errors)
+	}
+}
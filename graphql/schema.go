@@ -0,0 +1,329 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+const schema string = `
+    # Bytes32 is a 32 byte binary string, represented as 0x-prefixed hexadecimal.
+    scalar Bytes32
+    # Address is a 20 byte Wanchain address, represented as 0x-prefixed hexadecimal.
+    scalar Address
+    # Bytes is an arbitrary length binary string, represented as 0x-prefixed hexadecimal.
+    # An empty byte string is represented as '0x'.
+    scalar Bytes
+    # BigInt is a large integer. Input is accepted as either a JSON number or as a string.
+    # Strings may be either decimal or 0x-prefixed hexadecimal. Output values are all
+    # 0x-prefixed hexadecimal.
+    scalar BigInt
+    # Long is a 64 bit unsigned integer. Input is accepted as either a JSON number or
+    # as a string. Strings may be either decimal or 0x-prefixed hexadecimal.
+    scalar Long
+
+    schema {
+        query: Query
+    }
+
+    # Account is a Wanchain account at a particular block.
+    type Account {
+        # Address is the address owning the account.
+        address: Address!
+        # Balance is the balance of the account, in wei.
+        balance: BigInt!
+        # TransactionCount is the number of transactions sent from this account,
+        # or in the case of a contract, the number of contracts created. Otherwise
+        # known as the nonce.
+        transactionCount: Long!
+        # Code contains the smart contract code for this account, if the account
+        # is a (non-self-destructed) contract.
+        code: Bytes!
+        # Storage provides access to the storage of a contract account, indexed
+        # by its 32 byte slot identifier.
+        storage(slot: Bytes32!): Bytes32!
+    }
+
+    # Log is a Wanchain event log.
+    type Log {
+        # Index is the index of this log in the block.
+        index: Int!
+        # Account is the account which generated this log - this will always
+        # be a contract account.
+        account: Account!
+        # Topics is a list of 0-4 indexed topics for the log.
+        topics: [Bytes32!]!
+        # Data is unindexed data for this log.
+        data: Bytes!
+        # Transaction is the transaction that generated this log entry.
+        transaction: Transaction!
+    }
+
+    # Receipt is the outcome of a transaction included in a block.
+    type Receipt {
+        # Status is the return status of the transaction, 1 if it succeeded and
+        # 0 if it failed. It's null for receipts storing a post state root.
+        status: Long
+        # Root is the post state root of receipts from before Byzantium.
+        root: Bytes32
+        # GasUsed is the amount of gas used by the transaction.
+        gasUsed: Long!
+        # CumulativeGasUsed is the total gas used in the block up to and
+        # including this transaction.
+        cumulativeGasUsed: Long!
+        # CreatedContract is the account that was created by a contract creation
+        # transaction, null otherwise.
+        createdContract: Account
+        # LogsBloom is the bloom filter of the logs of the transaction.
+        logsBloom: Bytes!
+        # Logs is the list of logs emitted by the transaction.
+        logs: [Log!]!
+    }
+
+    # Transaction is a Wanchain transaction.
+    type Transaction {
+        # Hash is the hash of this transaction.
+        hash: Bytes32!
+        # TxType is the Wanchain transaction type: 1 for normal, 6 for privacy
+        # and 7 for POS protocol transactions.
+        txType: Long!
+        # Nonce is the nonce of the account this transaction was generated with.
+        nonce: Long!
+        # Index is the index of this transaction in the parent block. This will
+        # be null if the transaction has not yet been mined.
+        index: Int
+        # From is the account that sent this transaction.
+        from: Account!
+        # To is the account the transaction was sent to. This is null for
+        # contract creating transactions.
+        to: Account
+        # Value is the value, in wei, sent along with this transaction.
+        value: BigInt!
+        # GasPrice is the price offered to miners for gas, in wei per unit.
+        gasPrice: BigInt!
+        # Gas is the maximum amount of gas this transaction can consume.
+        gas: Long!
+        # InputData is the data supplied to the target of the transaction.
+        inputData: Bytes!
+        # Block is the block this transaction was mined in. This will be null if
+        # the transaction has not yet been mined.
+        block: Block
+        # Receipt is the outcome of the transaction. This will be null if the
+        # transaction has not yet been mined.
+        receipt: Receipt
+    }
+
+    # BlockFilterCriteria encapsulates log filter criteria for a filter applied
+    # to a single block.
+    input BlockFilterCriteria {
+        # Addresses is list of addresses that are of interest. If this list is
+        # empty, results will not be filtered by address.
+        addresses: [Address!]
+        # Topics list restricts matches to particular event topics. Each event has a list
+        # of topics. Topics matches a prefix of that list. An empty element array matches any
+        # topic. Non-empty elements represent an alternative that matches any of the
+        # contained topics.
+        topics: [[Bytes32!]!]
+    }
+
+    # Block is a Wanchain block.
+    type Block {
+        # Number is the number of this block, starting at 0 for the genesis block.
+        number: Long!
+        # Hash is the block hash of this block.
+        hash: Bytes32!
+        # Parent is the parent block of this block.
+        parent: Block
+        # TransactionsRoot is the keccak256 hash of the root of the trie of
+        # transactions in this block.
+        transactionsRoot: Bytes32!
+        # StateRoot is the keccak256 hash of the state trie after this block was
+        # processed.
+        stateRoot: Bytes32!
+        # ReceiptsRoot is the keccak256 hash of the trie of transaction receipts
+        # in this block.
+        receiptsRoot: Bytes32!
+        # Miner is the account that mined this block, the slot leader of POS
+        # blocks.
+        miner: Account!
+        # ExtraData is an arbitrary data field supplied by the miner.
+        extraData: Bytes!
+        # GasLimit is the maximum amount of gas that was available to
+        # transactions in this block.
+        gasLimit: Long!
+        # GasUsed is the amount of gas that was used executing transactions in
+        # this block.
+        gasUsed: Long!
+        # Timestamp is the unix timestamp at which this block was mined.
+        timestamp: Long!
+        # LogsBloom is a bloom filter that can be used to check if a block may
+        # contain log entries matching a filter.
+        logsBloom: Bytes!
+        # Difficulty is a measure of the difficulty of mining this block. POS
+        # blocks encode their epoch and slot in it.
+        difficulty: BigInt!
+        # TotalDifficulty is the sum of all difficulty values up to and including
+        # this block.
+        totalDifficulty: BigInt!
+        # EpochId is the POS epoch of this block, null for POW blocks.
+        epochId: Long
+        # SlotId is the POS slot of this block, null for POW blocks.
+        slotId: Long
+        # Epoch is the POS epoch of this block, null for POW blocks.
+        epoch: Epoch
+        # TransactionCount is the number of transactions in this block.
+        transactionCount: Int!
+        # Transactions is a list of transactions associated with this block.
+        transactions: [Transaction!]!
+        # TransactionAt returns the transaction at the specified index. If the
+        # transaction is not available for this block, returns null.
+        transactionAt(index: Int!): Transaction
+        # Logs returns a filtered set of logs from this block.
+        logs(filter: BlockFilterCriteria!): [Log!]!
+        # Account fetches a Wanchain account at the current block's state.
+        account(address: Address!): Account!
+    }
+
+    # FilterCriteria encapsulates log filter criteria for searching log entries.
+    input FilterCriteria {
+        # FromBlock is the block at which to start searching, inclusive. Defaults
+        # to the latest block if not supplied.
+        fromBlock: Long
+        # ToBlock is the block at which to stop searching, inclusive. Defaults
+        # to the latest block if not supplied.
+        toBlock: Long
+        # Addresses is a list of addresses that are of interest. If this list is
+        # empty, results will not be filtered by address.
+        addresses: [Address!]
+        # Topics list restricts matches to particular event topics. Each event has a list
+        # of topics. Topics matches a prefix of that list. An empty element array matches any
+        # topic. Non-empty elements represent an alternative that matches any of the
+        # contained topics.
+        topics: [[Bytes32!]!]
+    }
+
+    # Delegator is a client delegating to a staker.
+    type Delegator {
+        address: Address!
+        amount: BigInt!
+        stakeAmount: BigInt!
+        # QuitEpoch is the epoch the delegation ends, 0 while it's active.
+        quitEpoch: Long!
+    }
+
+    # Partner is an account staking together with a staker.
+    type Partner {
+        address: Address!
+        amount: BigInt!
+        stakeAmount: BigInt!
+        renewal: Boolean!
+        lockEpochs: Long!
+        stakingEpoch: Long!
+    }
+
+    # Staker is a POS validator as registered in the staking contract.
+    type Staker {
+        # Address is the validator address, derived from its secp256k1 key.
+        address: Address!
+        # From is the account that registered the validator.
+        from: Address!
+        pubSec256: Bytes!
+        pubBn256: Bytes!
+        amount: BigInt!
+        stakeAmount: BigInt!
+        lockEpochs: Long!
+        nextLockEpochs: Long!
+        # StakingEpoch is the first epoch the validator may be selected in.
+        stakingEpoch: Long!
+        feeRate: Long!
+        delegators: [Delegator!]!
+        partners: [Partner!]!
+    }
+
+    # DelegatorIncentive is the incentive paid to a delegator for an epoch.
+    type DelegatorIncentive {
+        address: Address!
+        incentive: BigInt!
+    }
+
+    # ValidatorIncentive is the incentive paid to a validator and its
+    # delegators for an epoch.
+    type ValidatorIncentive {
+        address: Address!
+        walletAddress: Address!
+        incentive: BigInt!
+        delegators: [DelegatorIncentive!]!
+    }
+
+    # LeaderActivity tells whether an epoch leader or random proposer took part
+    # in its protocol.
+    type LeaderActivity {
+        address: Address!
+        active: Boolean!
+    }
+
+    # SlotLeaderActivity is the number of blocks a slot leader produced.
+    type SlotLeaderActivity {
+        address: Address!
+        blocks: Int!
+    }
+
+    # Activity is the participation of the POS leaders in an epoch.
+    type Activity {
+        epochLeaders: [LeaderActivity!]!
+        randomProposers: [LeaderActivity!]!
+        slotLeaders: [SlotLeaderActivity!]!
+        # SlotActivity is the share of the slots that got a block.
+        slotActivity: Float!
+        # ControlBlocks is the number of blocks produced by whitelisted leaders.
+        controlBlocks: Int!
+    }
+
+    # Epoch is a POS epoch.
+    type Epoch {
+        id: Long!
+        # Incentive is the total incentive paid for the epoch, null until it's
+        # paid.
+        incentive: BigInt
+        # Incentives is the incentive paid to each validator and its delegators.
+        incentives: [ValidatorIncentive!]!
+        # Activity is the participation of the epoch leaders, random proposers
+        # and slot leaders, as known at the latest block.
+        activity: Activity!
+    }
+
+    type Query {
+        # Block fetches a Wanchain block by number or by hash. If neither is
+        # supplied, the most recent known block is returned.
+        block(number: Long, hash: Bytes32): Block
+        # Blocks returns all the blocks between two numbers, inclusive. If
+        # to is not supplied, it defaults to the most recent known block.
+        blocks(from: Long!, to: Long): [Block!]!
+        # Transaction returns a transaction specified by its hash.
+        transaction(hash: Bytes32!): Transaction
+        # Logs returns log entries matching the provided filter.
+        logs(filter: FilterCriteria!): [Log!]!
+        # Account fetches a Wanchain account at the given block, the most
+        # recent known block if not supplied.
+        account(address: Address!, blockNumber: Long): Account!
+        # Epoch returns a POS epoch, the current one if id is not supplied.
+        epoch(id: Long): Epoch
+        # Stakers returns the validators registered at the given block, the
+        # most recent known block if not supplied.
+        stakers(blockNumber: Long): [Staker!]!
+        # Staker returns a validator registered at the given block, the most
+        # recent known block if not supplied.
+        staker(address: Address!, blockNumber: Long): Staker
+    }
+`
@@ -0,0 +1,126 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/wanchain/go-wanchain/internal/ethapi"
+	"github.com/wanchain/go-wanchain/p2p"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// maxRequestSize is the largest query document accepted, in bytes.
+const maxRequestSize = 1024 * 128
+
+// Config contains the limits of the GraphQL service.
+type Config struct {
+	MaxDepth int   // Deepest nesting of fields a query may select
+	MaxCost  int64 // Most cost units a query may spend resolving its fields
+}
+
+// DefaultConfig contains the default limits of the GraphQL service.
+var DefaultConfig = Config{
+	MaxDepth: 10,
+	MaxCost:  10000,
+}
+
+// Service serves GraphQL queries on the "/graphql" path of the node's HTTP
+// endpoint.
+type Service struct {
+	handler *handler
+}
+
+// New constructs a new GraphQL service resolving queries against backend.
+func New(backend ethapi.Backend, config Config) (*Service, error) {
+	s, err := graphql.ParseSchema(schema, &Resolver{backend}, graphql.MaxDepth(config.MaxDepth))
+	if err != nil {
+		return nil, err
+	}
+	return &Service{&handler{schema: s, maxCost: config.MaxCost}}, nil
+}
+
+// Protocols returns the list of protocols exported by this service.
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+// APIs returns the list of APIs exported by this service.
+func (s *Service) APIs() []rpc.API { return nil }
+
+// HTTPHandlers returns the GraphQL handler, mounted on the HTTP endpoint.
+func (s *Service) HTTPHandlers() map[string]http.Handler {
+	return map[string]http.Handler{"/graphql": s.handler}
+}
+
+// Start is called after all services have been constructed and the networking
+// layer was also initialized to spawn any goroutines required by the service.
+func (s *Service) Start(server *p2p.Server) error { return nil }
+
+// Stop terminates all goroutines belonging to the service, blocking until they
+// are all terminated.
+func (s *Service) Stop() error { return nil }
+
+// handler executes the queries posted as JSON or given in the URL of GET
+// requests, as described on https://graphql.org/learn/serving-over-http.
+type handler struct {
+	schema  *graphql.Schema
+	maxCost int64
+}
+
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		req.Query, req.OperationName = query.Get("query"), query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if len(req.Query) > maxRequestSize {
+		http.Error(w, "query too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	response := h.schema.Exec(withBudget(r.Context(), h.maxCost), req.Query, req.OperationName, req.Variables)
+	body, err := json.Marshal(response)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
@@ -22,6 +22,7 @@ import (
 	"context"
 	"crypto/ecdsa"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -36,6 +37,7 @@ import (
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/wanchain/go-wanchain/accounts"
+	"github.com/wanchain/go-wanchain/accounts/abi"
 	"github.com/wanchain/go-wanchain/accounts/keystore"
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/common/hexutil"
@@ -71,6 +73,7 @@ var (
 	ErrInvalidOTAMixNum                 = errors.New("Invalid required OTA mix address number")
 	ErrInvalidInput                     = errors.New("Invalid input")
 	ErrInvalidOTAImage                  = errors.New("Invalid OTA image")
+	ErrInvalidOTAStatsRange             = errors.New("Invalid OTA stats block range")
 )
 
 // PublicEthereumAPI provides an API to access Ethereum related information.
@@ -485,6 +488,104 @@ func (s *PrivateAccountAPI) SendPrivacyCxtTransaction(ctx context.Context, args
 	return submitTransaction(ctx, s.b, signed)
 }
 
+// SendPrivateRefund refunds the wancoin of the one-time address otaAddr to the
+// keystore account it was generated for. The owning account is found among the
+// accounts whose keys decrypt with passphrase, and the OTA private key derived
+// from its keys signs the refund in a ring of ringSize public keys, the OTA and
+// ringSize-1 decoys of the same balance. The refund transaction is sent from
+// the account and signed with the same passphrase, so no private key leaves
+// the node.
+func (s *PrivateAccountAPI) SendPrivateRefund(ctx context.Context, otaAddr string, ringSize int, passphrase string) (common.Hash, error) {
+	if ringSize < 2 {
+		return common.Hash{}, ErrInvalidOTAMixNum
+	}
+	if uint64(ringSize-1) > params.GetOTAMixSetMaxSize {
+		return common.Hash{}, ErrReqTooManyOTAMix
+	}
+	otaWAddr, err := hexutil.Decode(otaAddr)
+	if err != nil || len(otaWAddr) != common.WAddressLength {
+		return common.Hash{}, ErrInvalidOTAAddr
+	}
+
+	// Find the owning account and derive the OTA key from its keys
+	ks := fetchKeystore(s.am)
+	account, otaKey, err := ks.FindOTAOwner(passphrase, otaWAddr)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	otaPriv := common.LeftPadBytes(otaKey.D.Bytes(), 32)
+	defer func() {
+		for i := range otaPriv {
+			otaPriv[i] = 0
+		}
+		otaKey.D.SetInt64(0)
+	}()
+
+	// Select the decoys and ring sign the refunding account address
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return common.Hash{}, err
+	}
+	otaAX, err := vm.GetAXFromWanAddr(otaWAddr)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	mixSet, balance, err := getOTASet(s.b, state, header, otaAX, ringSize-1)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	mixWanAddrs := make([]string, 0, len(mixSet))
+	for _, mix := range mixSet {
+		mixWanAddrs = append(mixWanAddrs, common.ToHex(mix))
+	}
+	ringSignData, err := genRingSignData(account.Address.Bytes(), otaPriv, &otaKey.PublicKey, mixWanAddrs)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	err, _, keyImage, _, _ := vm.DecodeRingSignOut(ringSignData)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if used, _, err := vm.CheckOTAImageExist(state, crypto.FromECDSAPub(keyImage)); err != nil {
+		return common.Hash{}, err
+	} else if used {
+		return common.Hash{}, vm.ErrOTAReused
+	}
+
+	// Assemble the refund call, paying the intrinsic and ring verification gas
+	data, err := vm.PackRefundCoin(ringSignData, balance)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	to := vm.WanCoinPrecompileAddr
+	gas := core.IntrinsicGas(data, &to, true)
+	gas.Add(gas, new(big.Int).SetUint64(vm.PrecompiledContractsByzantium[to].RequiredGas(data)))
+
+	args := SendTxArgs{
+		From: account.Address,
+		To:   &to,
+		Gas:  (*hexutil.Big)(gas),
+		Data: data,
+	}
+	s.nonceLock.LockAddr(args.From)
+	defer s.nonceLock.UnlockAddr(args.From)
+
+	if err := args.setDefaults(ctx, s.b); err != nil {
+		return common.Hash{}, err
+	}
+	tx := args.toTransaction()
+
+	var chainID *big.Int
+	if config := s.b.ChainConfig(); config != nil {
+		chainID = config.ChainId
+	}
+	signed, err := ks.SignTxWithPassphrase(account, passphrase, tx, chainID)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return submitTransaction(ctx, s.b, signed)
+}
+
 // GenRingSignData generate ring sign data
 func (s *PrivateAccountAPI) GenRingSignData(ctx context.Context, hashMsg string, privateKey string, mixWanAdresses string) (string, error) {
 	if !hexutil.Has0xPrefix(privateKey) {
@@ -518,6 +619,43 @@ func (s *PrivateAccountAPI) GenRingSignData(ctx context.Context, hashMsg string,
 	return genRingSignData(hmsg, privKey, &ecdsaPrivateKey.PublicKey, wanAddresses)
 }
 
+// getOTASet selects the OTA mix set from the backend's OTA index when it covers
+// the given state, and by traversing the OTA storage of the state otherwise.
+func getOTASet(b Backend, statedb vm.StateDB, header *types.Header, otaAX []byte, setNum int) ([][]byte, *big.Int, error) {
+	if idx := b.OTAIndex(); idx != nil {
+		otaSet, balance, err := idx.GetOTASet(header.Root, otaAX, setNum)
+		if err != core.ErrOTAIndexUnavailable {
+			return otaSet, balance, err
+		}
+	}
+	return vm.GetOTASet(statedb, otaAX, setNum)
+}
+
+// getOTAStats counts the OTAs of the given balances and how many of them are
+// spent, from the backend's OTA index when it covers the given state and by
+// traversing the OTA storage of the state otherwise.
+func getOTAStats(b Backend, statedb vm.StateDB, header *types.Header, balances []*big.Int) ([]int, []int, int, error) {
+	if idx := b.OTAIndex(); idx != nil {
+		otas, spent, images, err := idx.OTAStats(header.Root, balances)
+		if err != core.ErrOTAIndexUnavailable {
+			return otas, spent, images, err
+		}
+	}
+
+	images, spentOTA, err := vm.GetOTAImageCount(statedb)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	otas, spent := make([]int, len(balances)), make([]int, len(balances))
+	for i, balance := range balances {
+		if otas[i], err = vm.GetOTACount(statedb, balance); err != nil {
+			return nil, nil, 0, err
+		}
+		spent[i] = spentOTA[vm.OTABalance2ContractAddr(balance)]
+	}
+	return otas, spent, images, nil
+}
+
 func genRingSignData(hashMsg []byte, privateKey []byte, actualPub *ecdsa.PublicKey, mixWanAdress []string) (string, error) {
 	otaPrivD := new(big.Int).SetBytes(privateKey)
 
@@ -549,31 +687,7 @@ func genRingSignData(hashMsg []byte, privateKey []byte, actualPub *ecdsa.PublicK
 		return "", err
 	}
 
-	return encodeRingSignOut(retPublicKeys, keyImage, w_random, q_random)
-}
-
-//  encode all ring sign out data to a string
-func encodeRingSignOut(publicKeys []*ecdsa.PublicKey, keyimage *ecdsa.PublicKey, Ws []*big.Int, Qs []*big.Int) (string, error) {
-	tmp := make([]string, 0)
-	for _, pk := range publicKeys {
-		tmp = append(tmp, common.ToHex(crypto.FromECDSAPub(pk)))
-	}
-
-	pkStr := strings.Join(tmp, "&")
-	k := common.ToHex(crypto.FromECDSAPub(keyimage))
-	wa := make([]string, 0)
-	for _, wi := range Ws {
-		wa = append(wa, hexutil.EncodeBig(wi))
-	}
-
-	wStr := strings.Join(wa, "&")
-	qa := make([]string, 0)
-	for _, qi := range Qs {
-		qa = append(qa, hexutil.EncodeBig(qi))
-	}
-	qStr := strings.Join(qa, "&")
-	outs := strings.Join([]string{pkStr, k, wStr, qStr}, "+")
-	return outs, nil
+	return vm.EncodeRingSignOut(retPublicKeys, keyImage, w_random, q_random), nil
 }
 
 // signHash is a helper function that calculates a hash for the given message that can be
@@ -710,6 +824,115 @@ func (s *PublicBlockChainAPI) GetSupportStampOTABalances(ctx context.Context) []
 	return vm.GetSupportStampOTABalances()
 }
 
+// OTADenominationStats describes the anonymity set of one wancoin denomination.
+type OTADenominationStats struct {
+	Balance *hexutil.Big   `json:"balance"`
+	OTAs    hexutil.Uint64 `json:"otas"`
+	Spent   hexutil.Uint64 `json:"spent"`
+	Unspent hexutil.Uint64 `json:"unspent"`
+	Warning string         `json:"warning,omitempty"`
+}
+
+// OTAStats summarises the privacy pool at a block, and the buyCoin and refund
+// transactions of the blocks up to it.
+type OTAStats struct {
+	BlockNumber   hexutil.Uint64          `json:"blockNumber"`
+	Denominations []*OTADenominationStats `json:"denominations"`
+	SpentImages   hexutil.Uint64          `json:"spentImages"`
+	FromBlock     hexutil.Uint64          `json:"fromBlock"`
+	BuyCoinCount  hexutil.Uint64          `json:"buyCoinCount"`
+	BuyCoinVolume *hexutil.Big            `json:"buyCoinVolume"`
+	RefundCount   hexutil.Uint64          `json:"refundCount"`
+	RefundVolume  *hexutil.Big            `json:"refundVolume"`
+}
+
+// GetOTAStats reports for every wancoin denomination how many OTAs exist at the
+// given block and how many of them are spent, warning about the denominations
+// whose unspent anonymity set is smaller than params.OTAAnonymitySetMinSize.
+// It also sums the successful buyCoin and refund transactions of the last
+// blockRange blocks up to the given one, params.OTAStatsBlockRange by default.
+func (s *PublicBlockChainAPI) GetOTAStats(ctx context.Context, blockNr rpc.BlockNumber, blockRange *uint64) (*OTAStats, error) {
+	span := params.OTAStatsBlockRange
+	if blockRange != nil {
+		span = *blockRange
+	}
+	if span == 0 || span > params.OTAStatsMaxBlockRange {
+		return nil, ErrInvalidOTAStatsRange
+	}
+
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+
+	balances := vm.GetSupportWanCoinOTABalances()
+	otas, spent, images, err := getOTAStats(s.b, state, header, balances)
+	if err != nil {
+		return nil, err
+	}
+
+	number := header.Number.Uint64()
+	stats := &OTAStats{
+		BlockNumber:   hexutil.Uint64(number),
+		Denominations: make([]*OTADenominationStats, 0, len(balances)),
+		SpentImages:   hexutil.Uint64(images),
+		BuyCoinVolume: (*hexutil.Big)(new(big.Int)),
+		RefundVolume:  (*hexutil.Big)(new(big.Int)),
+	}
+	for i, balance := range balances {
+		unspent := 0
+		if otas[i] > spent[i] {
+			unspent = otas[i] - spent[i]
+		}
+		denom := &OTADenominationStats{
+			Balance: (*hexutil.Big)(balance),
+			OTAs:    hexutil.Uint64(otas[i]),
+			Spent:   hexutil.Uint64(spent[i]),
+			Unspent: hexutil.Uint64(unspent),
+		}
+		if uint64(unspent) < params.OTAAnonymitySetMinSize {
+			denom.Warning = fmt.Sprintf("only %d unspent OTAs, anonymity set is below %d", unspent, params.OTAAnonymitySetMinSize)
+		}
+		stats.Denominations = append(stats.Denominations, denom)
+	}
+
+	// Sum the coin contract transactions of the block range
+	if number >= span {
+		stats.FromBlock = hexutil.Uint64(number + 1 - span)
+	}
+	for n := uint64(stats.FromBlock); n <= number; n++ {
+		nr := rpc.BlockNumber(n)
+		if n == number {
+			nr = blockNr
+		}
+		block, err := s.b.BlockByNumber(ctx, nr)
+		if block == nil || err != nil {
+			return nil, err
+		}
+		// Pending blocks have no receipts, their transactions aren't counted
+		receipts, err := s.b.GetReceipts(ctx, block.Hash())
+		if err != nil {
+			return nil, err
+		}
+		for i, tx := range block.Transactions() {
+			if tx.To() == nil || *tx.To() != vm.WanCoinPrecompileAddr {
+				continue
+			}
+			if i >= len(receipts) || receipts[i].Status != types.ReceiptStatusSuccessful {
+				continue
+			}
+			if vm.IsBuyCoinInput(tx.Data()) {
+				stats.BuyCoinCount++
+				stats.BuyCoinVolume.ToInt().Add(stats.BuyCoinVolume.ToInt(), tx.Value())
+			} else if _, value, err := vm.UnpackRefundCoin(tx.Data()); err == nil {
+				stats.RefundCount++
+				stats.RefundVolume.ToInt().Add(stats.RefundVolume.ToInt(), value)
+			}
+		}
+	}
+	return stats, nil
+}
+
 // GetBlockByNumber returns the requested block. When blockNr is -1 the chain head is returned. When fullTx is true all
 // transactions in the block are returned in full detail, otherwise only the transaction hash is returned.
 func (s *PublicBlockChainAPI) GetBlockByNumber(ctx context.Context, blockNr rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
@@ -1446,7 +1669,7 @@ func (s *PublicTransactionPoolAPI) GetOTAMixSet(ctx context.Context, otaAddr str
 		return []string{}, ErrInvalidOTAAddr
 	}
 
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.BlockNumber(-1))
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, rpc.BlockNumber(-1))
 	if state == nil || err != nil {
 		return nil, err
 	}
@@ -1456,7 +1679,7 @@ func (s *PublicTransactionPoolAPI) GetOTAMixSet(ctx context.Context, otaAddr str
 		otaAX, _ = vm.GetAXFromWanAddr(orgOtaAddr)
 	}
 
-	otaByteSet, _, err := vm.GetOTASet(state, otaAX, setLen)
+	otaByteSet, _, err := getOTASet(s.b, state, header, otaAX, setLen)
 	if err != nil {
 		return nil, err
 	}
@@ -1765,6 +1988,31 @@ func (api *PrivateDebugAPI) SetHead(number hexutil.Uint64) {
 	api.b.SetHead(uint64(number))
 }
 
+// PrivateAdminAPI is the collection of Wanchain APIs changing the node's
+// settings, exposed over the private admin endpoint.
+type PrivateAdminAPI struct{}
+
+// NewPrivateAdminAPI creates a new API definition for the private admin methods.
+func NewPrivateAdminAPI() *PrivateAdminAPI {
+	return &PrivateAdminAPI{}
+}
+
+// RegisterABI registers the ABI of the contract at address, so its logs can be
+// fetched with their events decoded by setting "decoded" in the filter
+// criteria. The ABI is given as JSON or as a string holding it, and is kept
+// until the node stops.
+func (api *PrivateAdminAPI) RegisterABI(address common.Address, abiJSON json.RawMessage) error {
+	var definition string
+	if err := json.Unmarshal(abiJSON, &definition); err != nil {
+		definition = string(abiJSON)
+	}
+	contractAbi, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		return err
+	}
+	return vm.RegisterABI(address, contractAbi)
+}
+
 // PublicNetAPI offers network related RPC methods
 type PublicNetAPI struct {
 	net            *p2p.Server
@@ -1851,11 +2099,16 @@ func (args *SendTxArgs) toOTATransaction() *types.Transaction {
 }
 
 func (s *PrivateAccountAPI) GetOTABalance(ctx context.Context, blockNr rpc.BlockNumber) (*big.Int, error) {
-	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
 		return nil, err
 	}
 
+	if idx := s.b.OTAIndex(); idx != nil {
+		if otaB, err := idx.UnspentBalance(header.Root); err != core.ErrOTAIndexUnavailable {
+			return otaB, err
+		}
+	}
 	otaB, err := vm.GetUnspendOTATotalBalance(state)
 	if err != nil {
 		return common.Big0, err
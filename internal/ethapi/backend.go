@@ -68,6 +68,9 @@ type Backend interface {
 
 	ChainConfig() *params.ChainConfig
 	CurrentBlock() *types.Block
+	// OTAIndex returns the OTA storage index of the canonical head, or nil if
+	// the backend doesn't keep one.
+	OTAIndex() *core.OTAIndex
 
 
 }
@@ -124,6 +127,11 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPrivateAccountAPI(apiBackend, nonceLock),
 			Public:    false,
+		}, {
+			Namespace: "admin",
+			Version:   "1.0",
+			Service:   NewPrivateAdminAPI(),
+			Public:    false,
 		},
 	}
 }
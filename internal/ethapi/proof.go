@@ -0,0 +1,142 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/relay"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/pos/cfm"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+var (
+	errNoStableBlock = errors.New("stable block is not known yet")
+	errReceiptsRoot  = errors.New("local receipts do not match the block receipt root")
+)
+
+// GetProof returns the merkle proof of an account and of the given storage
+// keys at the given block, in the format of eth_getProof.
+func (s *PublicBlockChainAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*relay.AccountResult, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	accountProof, err := state.GetProof(address)
+	if err != nil {
+		return nil, err
+	}
+
+	// absent accounts are proven with the fields of an empty account
+	codeHash := crypto.Keccak256Hash(nil)
+	if state.Exist(address) {
+		codeHash = state.GetCodeHash(address)
+	}
+	storageHash := types.EmptyRootHash
+	if storageTrie := state.StorageTrie(address); storageTrie != nil {
+		storageHash = storageTrie.Hash()
+	}
+	storageProof := make([]relay.StorageResult, len(storageKeys))
+	for i, key := range storageKeys {
+		storageProof[i].Key = common.HexToHash(key)
+		storageProof[i].Proof = []hexutil.Bytes{}
+		if storageHash == types.EmptyRootHash {
+			continue
+		}
+		proof, err := state.GetStorageProof(address, storageProof[i].Key)
+		if err != nil {
+			return nil, err
+		}
+		storageProof[i].Value = state.GetStateByteArray(address, storageProof[i].Key)
+		storageProof[i].Proof = relay.ToProof(proof)
+	}
+
+	return &relay.AccountResult{
+		Address:      address,
+		AccountProof: relay.ToProof(accountProof),
+		Balance:      (*hexutil.Big)(state.GetBalance(address)),
+		CodeHash:     codeHash,
+		Nonce:        hexutil.Uint64(state.GetNonce(address)),
+		StorageHash:  storageHash,
+		StorageProof: storageProof,
+	}, state.Error()
+}
+
+// GetReceiptProof returns the merkle proof of the receipt of a transaction in
+// the receipt trie of its block, and whether the block is stable yet.
+func (s *PublicBlockChainAPI) GetReceiptProof(ctx context.Context, hash common.Hash) (*relay.ReceiptResult, error) {
+	tx, blockHash, blockNumber, index := core.GetTransaction(s.b.ChainDb(), hash)
+	if tx == nil {
+		return nil, nil
+	}
+	header, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(blockNumber))
+	if header == nil || err != nil {
+		return nil, err
+	}
+	if header.Hash() != blockHash {
+		// the transaction lookup raced with a reorg
+		return nil, nil
+	}
+	receipts, err := s.b.GetReceipts(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if index >= uint64(len(receipts)) {
+		return nil, errReceiptsRoot
+	}
+	root, proof := types.DeriveShaProof(receipts, int(index))
+	if root != header.ReceiptHash {
+		return nil, errReceiptsRoot
+	}
+
+	stable, err := stableBlockNumber()
+	return &relay.ReceiptResult{
+		BlockHash:        blockHash,
+		BlockNumber:      hexutil.Uint64(blockNumber),
+		TransactionHash:  hash,
+		TransactionIndex: hexutil.Uint(index),
+		ReceiptsRoot:     root,
+		Receipt:          receipts.GetRlp(int(index)),
+		Proof:            relay.ToProof(proof),
+		Stable:           err == nil && blockNumber <= stable,
+	}, nil
+}
+
+// GetStableHeader returns the header of the latest stable block, whose state
+// and receipt roots the relay proofs can be checked against.
+func (s *PublicBlockChainAPI) GetStableHeader(ctx context.Context) (*types.Header, error) {
+	number, err := stableBlockNumber()
+	if err != nil {
+		return nil, err
+	}
+	return s.b.HeaderByNumber(ctx, rpc.BlockNumber(number))
+}
+
+func stableBlockNumber() (uint64, error) {
+	c := cfm.GetCFM()
+	if c == nil {
+		return 0, errNoStableBlock
+	}
+	return c.GetMaxStableBlkNumber(), nil
+}
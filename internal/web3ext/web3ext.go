@@ -18,19 +18,21 @@
 package web3ext
 
 var Modules = map[string]string{
-	"admin":      Admin_JS,
-	"chequebook": Chequebook_JS,
-	"clique":     Clique_JS,
-	"pos":        Pos_JS,
-	"debug":      Debug_JS,
-	"eth":        Eth_JS,
-	"miner":      Miner_JS,
-	"net":        Net_JS,
-	"personal":   Personal_JS,
-	"rpc":        RPC_JS,
-	"shh":        Shh_JS,
-	"swarmfs":    SWARMFS_JS,
-	"txpool":     TxPool_JS,
+	"admin":       Admin_JS,
+	"chequebook":  Chequebook_JS,
+	"clique":      Clique_JS,
+	"pos":         Pos_JS,
+	"debug":       Debug_JS,
+	"eth":         Eth_JS,
+	"miner":       Miner_JS,
+	"net":         Net_JS,
+	"personal":    Personal_JS,
+	"rpc":         RPC_JS,
+	"shh":         Shh_JS,
+	"swarmfs":     SWARMFS_JS,
+	"txpool":      TxPool_JS,
+	"wan":         Wan_JS,
+	"wanexplorer": Wanexplorer_JS,
 }
 
 const Chequebook_JS = `
@@ -194,6 +196,11 @@ web3._extend({
 			call: 'pos_getRbSignatureCount',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'getRBFailureReport',
+			call: 'pos_getRBFailureReport',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'getChainQuality',
 			call: 'pos_getChainQuality',
@@ -351,6 +358,11 @@ web3._extend({
 			call: 'pos_getMaxStableBlkNumber',
 			params: 0
 		}),
+		new web3._extend.Method({
+			name: 'getProtocolTxStatus',
+			call: 'pos_getProtocolTxStatus',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'calProbability',
 			call: 'pos_calProbability',
@@ -459,6 +471,12 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'registerABI',
+			call: 'admin_registerABI',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -706,6 +724,22 @@ web3._extend({
 			params: 2,
 			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.utils.toHex]
 		}),
+		new web3._extend.Method({
+			name: 'getProof',
+			call: 'eth_getProof',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getReceiptProof',
+			call: 'eth_getReceiptProof',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getStableHeader',
+			call: 'eth_getStableHeader',
+			params: 0
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -807,6 +841,11 @@ web3._extend({
 			call: 'personal_deriveAccount',
 			params: 3
 		}),
+		new web3._extend.Method({
+			name: 'sendPrivateRefund',
+			call: 'personal_sendPrivateRefund',
+			params: 3
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -900,3 +939,46 @@ web3._extend({
 	]
 });
 `
+
+const Wan_JS = `
+web3._extend({
+	property: 'wan',
+	methods: [
+		new web3._extend.Method({
+			name: 'getOTAStats',
+			call: 'wan_getOTAStats',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
+	]
+});
+`
+
+const Wanexplorer_JS = `
+web3._extend({
+	property: 'wanexplorer',
+	methods: [
+		new web3._extend.Method({
+			name: 'getBlock',
+			call: 'wanexplorer_getBlock',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getBlocks',
+			call: 'wanexplorer_getBlocks',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'getEpoch',
+			call: 'wanexplorer_getEpoch',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getEpochs',
+			call: 'wanexplorer_getEpochs',
+			params: 2
+		}),
+	]
+});
+`
@@ -0,0 +1,76 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package kms implements the key management services keystore files can be
+// encrypted with: AWS KMS, the transit secrets engine of HashiCorp Vault or any
+// server implementing its HTTP API, and a master key in a local file.
+package kms
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awskms "github.com/aws/aws-sdk-go/service/kms"
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+)
+
+var (
+	_ keystore.KeyManagementService = (*AWS)(nil)
+	_ keystore.KeyManagementService = (*Vault)(nil)
+	_ keystore.KeyManagementService = (*Local)(nil)
+)
+
+// AWS is a key management service backed by an AWS KMS customer master key.
+type AWS struct {
+	client *awskms.KMS
+	keyID  string
+}
+
+// NewAWS creates an AWS KMS client with static credentials. The master key
+// keyID is only needed to encrypt, AWS KMS finds it from the ciphertext when
+// decrypting.
+func NewAWS(accessKeyID, secretKey, region, keyID string) (*AWS, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String(region),
+		Credentials: credentials.NewStaticCredentials(accessKeyID, secretKey, ""),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &AWS{client: awskms.New(sess), keyID: keyID}, nil
+}
+
+// Encrypt implements keystore.KeyManagementService.
+func (s *AWS) Encrypt(plaintext []byte) ([]byte, error) {
+	result, err := s.client.Encrypt(&awskms.EncryptInput{
+		KeyId:     aws.String(s.keyID),
+		Plaintext: plaintext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.CiphertextBlob, nil
+}
+
+// Decrypt implements keystore.KeyManagementService.
+func (s *AWS) Decrypt(ciphertext []byte) ([]byte, error) {
+	result, err := s.client.Decrypt(&awskms.DecryptInput{CiphertextBlob: ciphertext})
+	if err != nil {
+		return nil, err
+	}
+	return result.Plaintext, nil
+}
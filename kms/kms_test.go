@@ -0,0 +1,142 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package kms
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wanchain-kms-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "keys", "master.key")
+
+	if _, err := NewLocal(file, false); !os.IsNotExist(err) {
+		t.Fatalf("missing key file: have %v, want not exist error", err)
+	}
+	kms, err := NewLocal(file, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte("data key")
+	ciphertext, err := kms.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A service reloaded from the key file decrypts
+	reloaded, err := NewLocal(file, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	have, err := reloaded.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(have, plaintext) {
+		t.Errorf("plaintext mismatch: have %q, want %q", have, plaintext)
+	}
+	// Another master key doesn't
+	other, err := NewLocal(filepath.Join(dir, "other.key"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Decrypt(ciphertext); err == nil {
+		t.Error("decrypted with another master key")
+	}
+	if err := ioutil.WriteFile(file, []byte("0123"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewLocal(file, true); err == nil {
+		t.Error("loaded invalid master key file")
+	}
+}
+
+// newTransitServer starts a stand-in of the Vault transit engine for the key
+// "wallet", "encrypting" by base64 encoding the plaintext again.
+func newTransitServer(t *testing.T, token string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reply := func(code int, v interface{}) {
+			w.WriteHeader(code)
+			json.NewEncoder(w).Encode(v)
+		}
+		if r.Header.Get("X-Vault-Token") != token {
+			reply(http.StatusForbidden, map[string][]string{"errors": {"permission denied"}})
+			return
+		}
+		var req map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			reply(http.StatusBadRequest, map[string][]string{"errors": {err.Error()}})
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/wallet":
+			ciphertext := "vault:v1:" + base64.StdEncoding.EncodeToString([]byte(req["plaintext"]))
+			reply(http.StatusOK, map[string]interface{}{"data": map[string]string{"ciphertext": ciphertext}})
+		case "/v1/transit/decrypt/wallet":
+			plaintext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(req["ciphertext"], "vault:v1:"))
+			if err != nil {
+				reply(http.StatusBadRequest, map[string][]string{"errors": {"invalid ciphertext"}})
+				return
+			}
+			reply(http.StatusOK, map[string]interface{}{"data": map[string]string{"plaintext": string(plaintext)}})
+		default:
+			reply(http.StatusNotFound, map[string][]string{"errors": {}})
+		}
+	}))
+}
+
+func TestVault(t *testing.T) {
+	server := newTransitServer(t, "s3cr3t")
+	defer server.Close()
+
+	kms := NewVault(server.URL+"/", "", "wallet", "s3cr3t")
+	plaintext := []byte("data key")
+	ciphertext, err := kms.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(ciphertext), "vault:v1:") {
+		t.Errorf("unexpected ciphertext %q", ciphertext)
+	}
+	have, err := kms.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(have, plaintext) {
+		t.Errorf("plaintext mismatch: have %q, want %q", have, plaintext)
+	}
+
+	if _, err := NewVault(server.URL, "", "wallet", "wrong").Encrypt(plaintext); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("wrong token: have %v, want permission denied", err)
+	}
+	if _, err := NewVault(server.URL, "", "other", "s3cr3t").Encrypt(plaintext); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("unknown key: have %v, want 404 error", err)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package kms
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local is a key management service using a 256 bit master key stored hex
+// encoded in a local file. It brings envelope encryption to nodes without an
+// external service, the master key file being kept apart from the keystore,
+// and stands in for the external services in tests.
+type Local struct {
+	aead cipher.AEAD
+}
+
+// NewLocal loads the master key in file. If the file doesn't exist and create
+// is set, a new random master key is generated and written to it.
+func NewLocal(file string, create bool) (*Local, error) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) && create {
+		return generateLocal(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("invalid master key file, want 64 hex characters")
+	}
+	return newLocal(key)
+}
+
+func generateLocal(file string) (*Local, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(file, []byte(hex.EncodeToString(key)), 0600); err != nil {
+		return nil, err
+	}
+	return newLocal(key)
+}
+
+func newLocal(key []byte) (*Local, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Local{aead: aead}, nil
+}
+
+// Encrypt implements keystore.KeyManagementService, encrypting with AES-256-GCM.
+// The ciphertext is prefixed with its random nonce.
+func (s *Local) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize(), s.aead.NonceSize()+len(plaintext)+s.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt implements keystore.KeyManagementService.
+func (s *Local) Decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < s.aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce := ciphertext[:s.aead.NonceSize()]
+	return s.aead.Open(nil, nonce, ciphertext[len(nonce):], nil)
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package kms
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Vault is a key management service backed by a key of the transit secrets
+// engine of HashiCorp Vault, or of a server implementing its HTTP API.
+type Vault struct {
+	addr   string // Server address, e.g. https://vault:8200
+	mount  string // Mount path of the transit engine
+	key    string // Name of the transit key
+	token  string // Token sent in the X-Vault-Token header
+	client *http.Client
+}
+
+// DefaultVaultMount is the default mount path of the transit engine.
+const DefaultVaultMount = "transit"
+
+// NewVault creates a client of the transit key named key at the server addr,
+// authenticating with token.
+func NewVault(addr, mount, key, token string) *Vault {
+	if mount == "" {
+		mount = DefaultVaultMount
+	}
+	return &Vault{
+		addr:   strings.TrimRight(addr, "/"),
+		mount:  strings.Trim(mount, "/"),
+		key:    key,
+		token:  token,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Encrypt implements keystore.KeyManagementService. The ciphertext is the
+// vault:v<version>:<base64> string returned by the transit engine.
+func (s *Vault) Encrypt(plaintext []byte) ([]byte, error) {
+	var result struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	req := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}
+	if err := s.call("encrypt", req, &result); err != nil {
+		return nil, err
+	}
+	if result.Ciphertext == "" {
+		return nil, errors.New("vault: empty ciphertext")
+	}
+	return []byte(result.Ciphertext), nil
+}
+
+// Decrypt implements keystore.KeyManagementService.
+func (s *Vault) Decrypt(ciphertext []byte) ([]byte, error) {
+	var result struct {
+		Plaintext string `json:"plaintext"`
+	}
+	if err := s.call("decrypt", map[string]string{"ciphertext": string(ciphertext)}, &result); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(result.Plaintext)
+}
+
+// call posts a request to the transit endpoint op of the key, decoding the
+// data of the response into result.
+func (s *Vault) call(op string, req interface{}, result interface{}) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/v1/%s/%s/%s", s.addr, s.mount, op, s.key)
+	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-Vault-Token", s.token)
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []string        `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("vault: %s: %v", resp.Status, err)
+	}
+	if len(reply.Errors) > 0 {
+		return fmt.Errorf("vault: %s", strings.Join(reply.Errors, ", "))
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("vault: %s", resp.Status)
+	}
+	return json.Unmarshal(reply.Data, result)
+}
@@ -50,6 +50,11 @@ func (b *LesApiBackend) CurrentBlock() *types.Block {
 	return types.NewBlockWithHeader(b.eth.BlockChain().CurrentHeader())
 }
 
+// OTAIndex returns nil, light clients don't hold the OTA storage locally.
+func (b *LesApiBackend) OTAIndex() *core.OTAIndex {
+	return nil
+}
+
 func (b *LesApiBackend) SetHead(number uint64) {
 	b.eth.protocolManager.downloader.Cancel()
 	b.eth.blockchain.SetHead(number)
@@ -35,6 +35,7 @@ import (
 	"github.com/wanchain/go-wanchain/event"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/posctx"
 	//"time"
 )
 
@@ -45,6 +46,7 @@ type Backend interface {
 	TxPool() *core.TxPool
 	ChainDb() ethdb.Database
 	Etherbase() (common.Address, error)
+	PosContext() *posctx.PosContext
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -2,7 +2,6 @@ package miner
 
 import (
 	"encoding/hex"
-	"fmt"
 
 	"github.com/wanchain/go-wanchain/accounts"
 	"github.com/wanchain/go-wanchain/accounts/keystore"
@@ -14,14 +13,10 @@ import (
 
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/log"
-	"github.com/wanchain/go-wanchain/pos/cfm"
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
-	"github.com/wanchain/go-wanchain/pos/incentive"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/randombeacon"
-	"github.com/wanchain/go-wanchain/pos/slotleader"
+	"github.com/wanchain/go-wanchain/pos/postx"
 	"github.com/wanchain/go-wanchain/pos/util"
-	"github.com/wanchain/go-wanchain/rpc"
 )
 
 func posWhiteList() {
@@ -37,40 +32,32 @@ func PosInit(s Backend) *epochLeader.Epocher {
 	log.Debug("PosInit is running")
 
 	posconfig.Pow2PosUpgradeBlockNumber = s.BlockChain().Config().PosFirstBlock.Uint64()
+	ctx := s.PosContext()
 	h := s.BlockChain().GetHeaderByNumber(s.BlockChain().Config().PosFirstBlock.Uint64())
 	if nil != h {
 		epochId, _ := util.CalEpSlbyTd(h.Difficulty.Uint64())
-		posconfig.FirstEpochId = epochId
+		ctx.Config.SetFirstEpochID(epochId)
 	}
-	epochSelector := epochLeader.NewEpocher(s.BlockChain())
-	//Set to epochID 0 to get a default leaders for epoch 0.
-	err := epochSelector.SelectLeadersLoop(0)
-	if err != nil {
+	if err := ctx.Init(s.BlockChain()); err != nil {
 		panic("PosInit failed.")
 	}
+	ctx.Activate()
 
-	cfm.InitCFM(s.BlockChain())
+	s.BlockChain().SetSlotValidator(ctx.SLS)
 
-	slotleader.SlsInit()
-	sls := slotleader.GetSlotLeaderSelection()
-	sls.Init(s.BlockChain(), nil, nil)
-
-	incentive.Init(epochSelector.GetEpochProbability, epochSelector.SetEpochIncentive, epochSelector.GetRBProposerGroup)
-
-	s.BlockChain().SetSlotValidator(sls)
-
-	return epochSelector
+	return ctx.Epocher
 }
 
 func posInitMiner(s Backend, key *keystore.Key) {
 	log.Debug("posInitMiner is running")
 
+	ctx := s.PosContext()
 	// config
 	if key != nil {
-		posconfig.Cfg().MinerKey = key
+		ctx.Config.MinerKey = key
+		ctx.SetSubmitter(postx.NewSubmitter(s.TxPool(), s.ChainDb(), s.BlockChain().Config().ChainId, key.PrivateKey, ctx.TxOptions...))
 	}
-	epochSelector := epochLeader.NewEpocher(s.BlockChain())
-	randombeacon.GetRandonBeaconInst().Init(epochSelector)
+	ctx.RB.Init(ctx.Epocher)
 	//if posconfig.EpochBaseTime == 0 {
 	//	//todo:`switch pos from pow,the time is not 1?
 	//	h := s.BlockChain().GetHeaderByNumber(s.BlockChain().Config().PosFirstBlock.Uint64())
@@ -109,13 +96,7 @@ func (self *Miner) backendTimerLoop(s Backend) {
 		pluto.Authorize(eb, wallet.SignHash, key)
 	}
 	posInitMiner(s, key)
-	// get rpcClient
-	url := posconfig.Cfg().NodeCfg.IPCEndpoint()
-	rc, err := rpc.Dial(url)
-	if err != nil {
-		fmt.Println("err:", err)
-		panic(err)
-	}
+	ctx := s.PosContext()
 
 	var epochID, slotID uint64
 	//curBlkNum := uint64(0)
@@ -128,8 +109,8 @@ func (self *Miner) backendTimerLoop(s Backend) {
 		}
 	} else {
 		epochID, slotID = util.CalEpSlbyTd(h.Difficulty.Uint64())
-		posconfig.FirstEpochId = epochID
-		log.Info("backendTimerLoop first pos block exist :", "FirstEpochId", posconfig.FirstEpochId)
+		ctx.Config.SetFirstEpochID(epochID)
+		log.Info("backendTimerLoop first pos block exist :", "FirstEpochId", epochID)
 		// todo: need not reset the slot leader.
 		//if epochID > posconfig.FirstEpochId+2 {
 		//	stop := self.posRestartInit(s, localPublicKey)
@@ -150,7 +131,7 @@ func (self *Miner) backendTimerLoop(s Backend) {
 		//}
 		time.Sleep(time.Second * time.Duration(sleepTime))
 		if !self.Mining() {
-			randombeacon.GetRandonBeaconInst().Stop()
+			ctx.RB.Stop()
 			return
 		}
 
@@ -158,13 +139,18 @@ func (self *Miner) backendTimerLoop(s Backend) {
 		epochID, slotID = util.GetEpochSlotID()
 		log.Debug("get current period", "epochid", epochID, "slotid", slotID)
 
-		sls := slotleader.GetSlotLeaderSelection()
-		sls.Loop(rc, key, epochID, slotID)
+		// track the protocol txs sent in the previous slots
+		if ctx.Submitter != nil {
+			ctx.Submitter.Tick(epochID, slotID)
+		}
+
+		sls := ctx.SLS
+		sls.Loop(key, epochID, slotID)
 
 		prePks, isDefault := sls.GetPreEpochLeadersPK(epochID)
 		targetEpochLeaderID := epochID
 		if isDefault {
-			if epochID > posconfig.FirstEpochId+2 {
+			if epochID > ctx.Config.FirstEpochID()+2 {
 				log.Info("backendTimerLoop use default epoch leader.")
 			}
 			targetEpochLeaderID = 0
@@ -185,7 +171,7 @@ func (self *Miner) backendTimerLoop(s Backend) {
 		stateDb, err := s.BlockChain().State()
 		if err == nil {
 			// random beacon loop
-			randombeacon.GetRandonBeaconInst().Loop(stateDb, rc, epochID, slotID)
+			ctx.RB.Loop(stateDb, epochID, slotID)
 		} else {
 			log.SyslogErr("Failed to get stateDb", "err", err)
 		}
@@ -214,7 +200,7 @@ func (self *Miner) posStartInit(s Backend, localPublicKey string) (stop bool) {
 		slotID += 1
 	}
 
-	leaderPub, _ := slotleader.GetSlotLeaderSelection().GetSlotLeader(0, slotID)
+	leaderPub, _ := s.PosContext().SLS.GetSlotLeader(0, slotID)
 	leader := hex.EncodeToString(crypto.FromECDSAPub(leaderPub))
 	log.Info("posStartInit leader ", "leader", leader)
 
@@ -234,8 +220,8 @@ func (self *Miner) posStartInit(s Backend, localPublicKey string) (stop bool) {
 		if !self.Mining() {
 			return true
 		}
-		posconfig.FirstEpochId = epochID
-		log.Info("backendTimerLoop :", "FirstEpochId", posconfig.FirstEpochId)
+		s.PosContext().Config.SetFirstEpochID(epochID)
+		log.Info("backendTimerLoop :", "FirstEpochId", epochID)
 
 		self.worker.chainSlotTimer <- slotTime
 
@@ -259,8 +245,8 @@ func (self *Miner) posStartInit(s Backend, localPublicKey string) (stop bool) {
 			log.Info("backendTimerLoop sleep,", "FirstEpochId", epochID)
 		} else {
 			epochID, slotID = util.CalEpSlbyTd(h.Difficulty.Uint64())
-			posconfig.FirstEpochId = epochID
-			log.Info("backendTimerLoop download the first pos block :", "FirstEpochId", posconfig.FirstEpochId)
+			s.PosContext().Config.SetFirstEpochID(epochID)
+			log.Info("backendTimerLoop download the first pos block :", "FirstEpochId", epochID)
 
 			break
 		}
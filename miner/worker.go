@@ -450,7 +450,7 @@ func (self *worker) commitNewWork(isPush bool, slotTime uint64) {
 	header := &types.Header{
 		ParentHash: parent.Hash(),
 		Number:     num.Add(num, common.Big1),
-		GasLimit:   core.CalcGasLimit(parent),
+		GasLimit:   core.CalcGasLimit(self.config, parent),
 		GasUsed:    new(big.Int),
 		Extra:      self.extra,
 		Time:       big.NewInt(headTime),
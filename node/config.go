@@ -18,6 +18,9 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -33,6 +36,7 @@ import (
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/p2p"
 	"github.com/wanchain/go-wanchain/p2p/discover"
+	"github.com/wanchain/go-wanchain/rpc"
 )
 
 const (
@@ -41,6 +45,7 @@ const (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+	datadirJWTSecret       = "jwtsecret"          // Path within the datadir to the RPC token secret
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -135,6 +140,24 @@ type Config struct {
 	// *WARNING* Only set this if the node is running in a trusted network, exposing
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
+
+	// IPCLimits, HTTPLimits and WSLimits restrict the methods the clients of each
+	// RPC endpoint may call, how often, in how large batches and for how long.
+	// Methods like the personal_* privacy calls take raw private keys and should
+	// be denied on endpoints reachable by untrusted clients.
+	IPCLimits  rpc.Limits
+	HTTPLimits rpc.Limits
+	WSLimits   rpc.Limits
+
+	// HTTPAuth and WSAuth require the clients of the HTTP and websocket RPC
+	// endpoints to send a bearer token signed with the JWT secret. A token may
+	// restrict the modules its holder can call.
+	HTTPAuth bool `toml:",omitempty"`
+	WSAuth   bool `toml:",omitempty"`
+
+	// JWTSecret is the file holding the hex encoded secret RPC tokens are signed
+	// with. It defaults to a file in the instance directory, created on first use.
+	JWTSecret string `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -317,6 +340,41 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 	return key
 }
 
+// AuthSecret returns the secret RPC tokens are signed with, generating and
+// persisting a new one if none is found.
+func (c *Config) AuthSecret() ([]byte, error) {
+	path := c.JWTSecret
+	if path == "" {
+		path = datadirJWTSecret
+	}
+	if path = c.resolvePath(path); path == "" {
+		return nil, errors.New("no data directory to keep the JWT secret in")
+	}
+	if data, err := ioutil.ReadFile(path); err == nil {
+		secret, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(secret) < 32 {
+			return nil, fmt.Errorf("invalid JWT secret in %s", path)
+		}
+		return secret, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	// No secret found, generate and store a new one.
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, []byte(hex.EncodeToString(secret)), 0600); err != nil {
+		return nil, err
+	}
+	log.Info("Generated JWT secret", "path", path)
+	return secret, nil
+}
+
 // StaticNodes returns a list of node enode URLs configured as static nodes.
 func (c *Config) StaticNodes() []*discover.Node {
 	return c.parsePersistentNodes(c.resolvePath(datadirStaticNodes))
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -51,8 +52,9 @@ type Node struct {
 	serviceFuncs []ServiceConstructor     // Service constructors (in dependency order)
 	services     map[reflect.Type]Service // Currently running services
 
-	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
-	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
+	rpcAPIs       []rpc.API               // List of APIs currently provided by the node
+	httpHandlers  map[string]http.Handler // HTTP handlers of the services, mounted next to the HTTP RPC endpoint
+	inprocHandler *rpc.Server             // In-process RPC request handler to process the API requests
 
 	ipcEndpoint string       // IPC endpoint to listen at (empty = IPC disabled)
 	ipcListener net.Listener // IPC RPC listener socket to serve API requests
@@ -245,9 +247,19 @@ func (n *Node) openDataDir() error {
 func (n *Node) startRPC(services map[reflect.Type]Service) error {
 	// Gather all the possible APIs to surface
 	apis := n.apis()
+	handlers := make(map[string]http.Handler)
 	for _, service := range services {
 		apis = append(apis, service.APIs()...)
+		if service, ok := service.(HTTPService); ok {
+			for path, handler := range service.HTTPHandlers() {
+				if _, exists := handlers[path]; exists {
+					return fmt.Errorf("duplicate HTTP handler for %s", path)
+				}
+				handlers[path] = handler
+			}
+		}
 	}
+	n.httpHandlers = handlers
 	// Start the various API endpoints, terminating all in case of errors
 	if err := n.startInProc(apis); err != nil {
 		return err
@@ -308,6 +320,8 @@ func (n *Node) startIPC(apis []rpc.API) error {
 		}
 		log.Debug(fmt.Sprintf("IPC registered %T under '%s'", api.Service, api.Namespace))
 	}
+	handler.SetLimits(n.config.IPCLimits)
+
 	// All APIs registered, start the IPC listener
 	var (
 		listener net.Listener
@@ -378,6 +392,15 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 			log.Debug(fmt.Sprintf("HTTP registered %T under '%s'", api.Service, api.Namespace))
 		}
 	}
+	handler.SetLimits(n.config.HTTPLimits)
+	if n.config.HTTPAuth {
+		secret, err := n.config.AuthSecret()
+		if err != nil {
+			return err
+		}
+		handler.SetAuthSecret(secret)
+	}
+
 	// All APIs registered, start the HTTP listener
 	var (
 		listener net.Listener
@@ -386,8 +409,11 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	if listener, err = net.Listen("tcp", endpoint); err != nil {
 		return err
 	}
-	go rpc.NewHTTPServer(cors, handler).Serve(listener)
+	go rpc.NewHTTPServer(cors, n.httpMux(handler)).Serve(listener)
 	log.Info(fmt.Sprintf("HTTP endpoint opened: http://%s", endpoint))
+	for path := range n.httpHandlers {
+		log.Info(fmt.Sprintf("HTTP handler mounted: http://%s%s", endpoint, path))
+	}
 
 	// All listeners booted successfully
 	n.httpEndpoint = endpoint
@@ -397,6 +423,21 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	return nil
 }
 
+// httpMux serves the HTTP handlers of the services next to the JSON-RPC server.
+// The handlers are subject to the authentication of the server, a scoped token
+// having to grant the module named after the handler's path.
+func (n *Node) httpMux(srv *rpc.Server) http.Handler {
+	if len(n.httpHandlers) == 0 {
+		return srv
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", srv)
+	for path, handler := range n.httpHandlers {
+		mux.Handle(path, srv.AuthHandler(strings.Trim(path, "/"), handler))
+	}
+	return mux
+}
+
 // stopHTTP terminates the HTTP RPC endpoint.
 func (n *Node) stopHTTP() {
 	if n.httpListener != nil {
@@ -432,6 +473,15 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 			log.Debug(fmt.Sprintf("WebSocket registered %T under '%s'", api.Service, api.Namespace))
 		}
 	}
+	handler.SetLimits(n.config.WSLimits)
+	if n.config.WSAuth {
+		secret, err := n.config.AuthSecret()
+		if err != nil {
+			return err
+		}
+		handler.SetAuthSecret(secret)
+	}
+
 	// All APIs registered, start the HTTP listener
 	var (
 		listener net.Listener
@@ -17,6 +17,7 @@
 package node
 
 import (
+	"net/http"
 	"reflect"
 
 	"github.com/wanchain/go-wanchain/accounts"
@@ -96,3 +97,11 @@ type Service interface {
 	// are all terminated.
 	Stop() error
 }
+
+// HTTPService is implemented by services serving plain HTTP handlers next to the
+// JSON-RPC API on the node's HTTP endpoint.
+type HTTPService interface {
+	// HTTPHandlers retrieves the handlers of the service keyed by the path they
+	// are mounted on, e.g. "/graphql".
+	HTTPHandlers() map[string]http.Handler
+}
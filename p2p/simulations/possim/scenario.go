@@ -0,0 +1,361 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package possim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/p2p/discover"
+	"github.com/wanchain/go-wanchain/p2p/simulations"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+var errNoLeader = errors.New("slot leader is not a running node")
+
+// Step is a step of a scenario: Action runs After the previous step is done.
+type Step struct {
+	Name   string
+	After  time.Duration
+	Action func(ctx context.Context, s *Scenario) error
+}
+
+// Scenario injects faults into a network of POS nodes and checks how the
+// protocol copes with them.
+type Scenario struct {
+	Poll time.Duration // interval between two checks of an expectation
+
+	net *simulations.Network
+
+	mu     sync.Mutex
+	cut    [][2]discover.NodeID                  // connections cut by Partition
+	killed map[discover.NodeID][]discover.NodeID // peers of the nodes stopped by Kill
+}
+
+// NewScenario creates a scenario running on net.
+func NewScenario(net *simulations.Network) *Scenario {
+	return &Scenario{
+		Poll:   time.Duration(posconfig.SlotTime) * time.Second,
+		net:    net,
+		killed: make(map[discover.NodeID][]discover.NodeID),
+	}
+}
+
+// Network returns the network the scenario runs on.
+func (s *Scenario) Network() *simulations.Network {
+	return s.net
+}
+
+// Run runs the steps in order, stopping at the first failing one.
+func (s *Scenario) Run(ctx context.Context, steps ...Step) error {
+	for i, step := range steps {
+		select {
+		case <-time.After(step.After):
+		case <-ctx.Done():
+			return fmt.Errorf("step %d (%s): %v", i, step.Name, ctx.Err())
+		}
+		if err := step.Action(ctx, s); err != nil {
+			return fmt.Errorf("step %d (%s): %v", i, step.Name, err)
+		}
+	}
+	return nil
+}
+
+// Partition disconnects the nodes of every group from the nodes of the other
+// groups. Nodes left out of all groups keep their connections.
+func (s *Scenario) Partition(groups ...[]discover.NodeID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, group := range groups {
+		for _, other := range groups[i+1:] {
+			for _, one := range group {
+				for _, two := range other {
+					conn := s.net.GetConn(one, two)
+					if conn == nil || !conn.Up {
+						continue
+					}
+					if err := s.net.Disconnect(conn.One, conn.Other); err != nil {
+						return err
+					}
+					s.cut = append(s.cut, [2]discover.NodeID{conn.One, conn.Other})
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Heal reconnects the connections cut by Partition between running nodes.
+func (s *Scenario) Heal() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, pair := range s.cut {
+		if err := s.reconnect(pair[0], pair[1]); err != nil {
+			return err
+		}
+	}
+	s.cut = nil
+	return nil
+}
+
+// Kill stops a node, remembering its peers so that Revive can reconnect it.
+func (s *Scenario) Kill(id discover.NodeID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var peers []discover.NodeID
+	for _, node := range s.upNodes() {
+		if conn := s.net.GetConn(id, node.ID()); conn != nil && conn.Up {
+			peers = append(peers, node.ID())
+		}
+	}
+	if err := s.net.Stop(id); err != nil {
+		return err
+	}
+	s.killed[id] = peers
+	return nil
+}
+
+// Revive restarts a node stopped by Kill and reconnects it to its peers.
+func (s *Scenario) Revive(id discover.NodeID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	peers, ok := s.killed[id]
+	if !ok {
+		return fmt.Errorf("node %v was not killed", id)
+	}
+	if err := s.net.Start(id); err != nil {
+		return err
+	}
+	delete(s.killed, id)
+
+	// the peers still hold the stopped node as a static peer, waiting for
+	// their dial history to expire before they try it again, so the revived
+	// node dials them itself
+	for _, peer := range peers {
+		node := s.net.GetNode(peer)
+		if node == nil || !node.Up {
+			continue
+		}
+		if err := s.call(context.Background(), id, nil, "admin_addPeer", string(node.Addr())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconnect connects two nodes again unless one of them is down or they are
+// connected already.
+func (s *Scenario) reconnect(one, other discover.NodeID) error {
+	if node := s.net.GetNode(one); node == nil || !node.Up {
+		return nil
+	}
+	if node := s.net.GetNode(other); node == nil || !node.Up {
+		return nil
+	}
+	if conn := s.net.GetConn(one, other); conn != nil && conn.Up {
+		return nil
+	}
+	return s.net.Connect(one, other)
+}
+
+// SlotLeader returns the running node elected leader of the given slot.
+func (s *Scenario) SlotLeader(ctx context.Context, epochID, slotID uint64) (discover.NodeID, error) {
+	var leader string
+	for _, node := range s.upNodes() {
+		if leader == "" {
+			if err := s.call(ctx, node.ID(), &leader, "pos_getSlotLeaderByEpochIDAndSlotID", epochID, slotID); err != nil {
+				return discover.NodeID{}, err
+			}
+		}
+		var pk string
+		if err := s.call(ctx, node.ID(), &pk, "pos_getLocalPK"); err != nil {
+			return discover.NodeID{}, err
+		}
+		if pk == leader {
+			return node.ID(), nil
+		}
+	}
+	return discover.NodeID{}, errNoLeader
+}
+
+// KillSlotLeader stops the node elected leader of the given slot.
+func (s *Scenario) KillSlotLeader(ctx context.Context, epochID, slotID uint64) (discover.NodeID, error) {
+	id, err := s.SlotLeader(ctx, epochID, slotID)
+	if err != nil {
+		return id, err
+	}
+	return id, s.Kill(id)
+}
+
+// DropTxs makes the given nodes drop their protocol transactions of the
+// given kinds ("sma1", "sma2", "dkg1", "dkg2" or "sigshare").
+func (s *Scenario) DropTxs(ctx context.Context, ids []discover.NodeID, kinds ...string) error {
+	for _, id := range ids {
+		if err := s.call(ctx, id, nil, "possim_dropTxs", kinds); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DelayTxs makes the given nodes hold their protocol transactions of the
+// given kinds back for delay.
+func (s *Scenario) DelayTxs(ctx context.Context, ids []discover.NodeID, delay time.Duration, kinds ...string) error {
+	for _, id := range ids {
+		if err := s.call(ctx, id, nil, "possim_delayTxs", kinds, uint64(delay/time.Second)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClearTxFaults removes the protocol transaction faults of the given nodes.
+func (s *Scenario) ClearTxFaults(ctx context.Context, ids []discover.NodeID) error {
+	for _, id := range ids {
+		if err := s.call(ctx, id, nil, "possim_clearTxFaults"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExpectChainQuality waits until the chain quality of every running node at
+// its current slot is at least min, in thousandths of the slots.
+func (s *Scenario) ExpectChainQuality(ctx context.Context, min uint64) error {
+	return s.expect(ctx, func(id discover.NodeID) (bool, error) {
+		var epochID, slotID, quality uint64
+		if err := s.call(ctx, id, &epochID, "pos_getEpochID"); err != nil {
+			return false, err
+		}
+		if err := s.call(ctx, id, &slotID, "pos_getSlotID"); err != nil {
+			return false, err
+		}
+		if err := s.call(ctx, id, &quality, "pos_getChainQuality", epochID, slotID); err != nil {
+			return false, err
+		}
+		return quality >= min, nil
+	})
+}
+
+// ExpectRandomBeacon waits until every running node has the same random
+// number generated for epochID at its head.
+func (s *Scenario) ExpectRandomBeacon(ctx context.Context, epochID uint64) error {
+	var (
+		mu     sync.Mutex
+		random *big.Int
+	)
+	return s.expect(ctx, func(id discover.NodeID) (bool, error) {
+		var res *hexutil.Big
+		if err := s.call(ctx, id, &res, "pos_getRandom", epochID, -1); err != nil || res == nil {
+			// no random number exists yet
+			return false, nil
+		}
+		r := res.ToInt()
+		mu.Lock()
+		defer mu.Unlock()
+		if random == nil {
+			random = r
+		}
+		if random.Cmp(r) != 0 {
+			return false, fmt.Errorf("node %v has random %v for epoch %d, want %v", id.TerminalString(), r, epochID, random)
+		}
+		return true, nil
+	})
+}
+
+// ExpectStableProgress waits until the stable block of every running node
+// advanced by blocks.
+func (s *Scenario) ExpectStableProgress(ctx context.Context, blocks uint64) error {
+	start := make(map[discover.NodeID]uint64)
+	for _, node := range s.upNodes() {
+		var number uint64
+		if err := s.call(ctx, node.ID(), &number, "pos_getMaxStableBlkNumber"); err != nil {
+			return err
+		}
+		start[node.ID()] = number
+	}
+	return s.expect(ctx, func(id discover.NodeID) (bool, error) {
+		from, ok := start[id]
+		if !ok {
+			return false, fmt.Errorf("node %v started during the check", id.TerminalString())
+		}
+		var number uint64
+		if err := s.call(ctx, id, &number, "pos_getMaxStableBlkNumber"); err != nil {
+			return false, err
+		}
+		return number >= from+blocks, nil
+	})
+}
+
+// expect polls check on every running node until it passed on all of them.
+func (s *Scenario) expect(ctx context.Context, check func(discover.NodeID) (bool, error)) error {
+	passed := make(map[discover.NodeID]bool)
+	for {
+		done := true
+		for _, node := range s.upNodes() {
+			if passed[node.ID()] {
+				continue
+			}
+			ok, err := check(node.ID())
+			if err != nil {
+				return err
+			}
+			passed[node.ID()] = ok
+			done = done && ok
+		}
+		if done {
+			return nil
+		}
+		select {
+		case <-time.After(s.Poll):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *Scenario) upNodes() []*simulations.Node {
+	var nodes []*simulations.Node
+	for _, node := range s.net.GetNodes() {
+		if node.Up {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func (s *Scenario) call(ctx context.Context, id discover.NodeID, result interface{}, method string, args ...interface{}) error {
+	node := s.net.GetNode(id)
+	if node == nil {
+		return fmt.Errorf("unknown node: %v", id)
+	}
+	client, err := node.Client()
+	if err != nil {
+		return err
+	}
+	return client.CallContext(ctx, result, method, args...)
+}
@@ -0,0 +1,262 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package possim
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wanchain/go-wanchain/node"
+	"github.com/wanchain/go-wanchain/p2p"
+	"github.com/wanchain/go-wanchain/p2p/discover"
+	"github.com/wanchain/go-wanchain/p2p/simulations"
+	"github.com/wanchain/go-wanchain/p2p/simulations/adapters"
+	"github.com/wanchain/go-wanchain/pos/postx"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// testChain is the POS state shared by the nodes of a test network.
+type testChain struct {
+	mu      sync.Mutex
+	leader  string
+	quality uint64
+	random  map[string]*big.Int // random number of each node
+
+	services map[string]*testService
+}
+
+// PosStub serves the pos methods the scenarios call.
+type PosStub struct {
+	chain  *testChain
+	pk     string
+	stable uint64
+}
+
+func (api *PosStub) GetLocalPK() string { return api.pk }
+func (api *PosStub) GetEpochID() uint64 { return 1 }
+func (api *PosStub) GetSlotID() uint64  { return 10 }
+
+func (api *PosStub) GetSlotLeaderByEpochIDAndSlotID(epochID, slotID uint64) string {
+	api.chain.mu.Lock()
+	defer api.chain.mu.Unlock()
+	return api.chain.leader
+}
+
+func (api *PosStub) GetChainQuality(epochID, slotID uint64) (uint64, error) {
+	api.chain.mu.Lock()
+	defer api.chain.mu.Unlock()
+	api.chain.quality += 100
+	return api.chain.quality, nil
+}
+
+func (api *PosStub) GetRandom(epochID uint64, blockNr int64) (*big.Int, error) {
+	api.chain.mu.Lock()
+	defer api.chain.mu.Unlock()
+	return api.chain.random[api.pk], nil
+}
+
+func (api *PosStub) GetMaxStableBlkNumber() uint64 {
+	api.chain.mu.Lock()
+	defer api.chain.mu.Unlock()
+	api.stable++
+	return api.stable
+}
+
+// testService stands in for the POS node service, which can't run more than
+// once per process.
+type testService struct {
+	pos    *PosStub
+	faults *FaultAPI
+}
+
+func (s *testService) Protocols() []p2p.Protocol { return nil }
+func (s *testService) Start(*p2p.Server) error   { return nil }
+func (s *testService) Stop() error               { return nil }
+
+func (s *testService) APIs() []rpc.API {
+	return []rpc.API{
+		{Namespace: "pos", Version: "1.0", Service: s.pos, Public: true},
+		{Namespace: "possim", Version: "1.0", Service: s.faults, Public: true},
+	}
+}
+
+func newTestNetwork(t *testing.T, n int) (*Scenario, *testChain, []discover.NodeID) {
+	chain := &testChain{random: make(map[string]*big.Int), services: make(map[string]*testService)}
+	services := adapters.Services{
+		ServiceName: func(ctx *adapters.ServiceContext) (node.Service, error) {
+			service := &testService{pos: &PosStub{chain: chain, pk: ctx.Config.Name}, faults: NewFaultAPI()}
+			chain.mu.Lock()
+			chain.services[ctx.Config.Name] = service
+			chain.mu.Unlock()
+			return service, nil
+		},
+	}
+	net := simulations.NewNetwork(adapters.NewSimAdapter(services), &simulations.NetworkConfig{DefaultService: ServiceName})
+	ids := make([]discover.NodeID, n)
+	for i := range ids {
+		conf := adapters.RandomNodeConfig()
+		conf.Name = NodeName(i)
+		node, err := net.NewNodeWithConfig(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := net.Start(node.ID()); err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = node.ID()
+	}
+	s := NewScenario(net)
+	s.Poll = 10 * time.Millisecond
+	return s, chain, ids
+}
+
+// waitConns waits until the connections between the pairs of ids are up or
+// down.
+func waitConns(t *testing.T, net *simulations.Network, up bool, pairs ...[2]discover.NodeID) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		done := true
+		for _, pair := range pairs {
+			conn := net.GetConn(pair[0], pair[1])
+			done = done && conn != nil && conn.Up == up
+		}
+		if done {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("connections not up=%v", up)
+		}
+	}
+}
+
+func TestScenarioPartition(t *testing.T) {
+	s, chain, ids := newTestNetwork(t, 4)
+	defer s.Network().Shutdown()
+
+	var all [][2]discover.NodeID
+	for i := range ids {
+		for j := i + 1; j < len(ids); j++ {
+			if err := s.Network().Connect(ids[i], ids[j]); err != nil {
+				t.Fatal(err)
+			}
+			all = append(all, [2]discover.NodeID{ids[i], ids[j]})
+		}
+	}
+	waitConns(t, s.Network(), true, all...)
+
+	if err := s.Partition(ids[:2], ids[2:]); err != nil {
+		t.Fatal(err)
+	}
+	waitConns(t, s.Network(), false, [2]discover.NodeID{ids[0], ids[2]}, [2]discover.NodeID{ids[1], ids[3]})
+	waitConns(t, s.Network(), true, [2]discover.NodeID{ids[0], ids[1]}, [2]discover.NodeID{ids[2], ids[3]})
+	time.Sleep(250 * time.Millisecond) // dial ban of the simulation network
+	if err := s.Heal(); err != nil {
+		t.Fatal(err)
+	}
+	waitConns(t, s.Network(), true, all...)
+
+	// the leader of the slot is killed and comes back with its peers
+	chain.mu.Lock()
+	chain.leader = NodeName(2)
+	chain.mu.Unlock()
+	leader, err := s.KillSlotLeader(context.Background(), 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leader != ids[2] || s.Network().GetNode(ids[2]).Up {
+		t.Fatalf("slot leader not killed: have %v, want %v", leader, ids[2])
+	}
+	if _, err := s.SlotLeader(context.Background(), 1, 10); err != errNoLeader {
+		t.Fatalf("error mismatch: have %v, want %v", err, errNoLeader)
+	}
+	time.Sleep(250 * time.Millisecond)
+	if err := s.Revive(ids[2]); err != nil {
+		t.Fatal(err)
+	}
+	waitConns(t, s.Network(), true, all...)
+}
+
+func TestScenarioExpectations(t *testing.T) {
+	s, chain, ids := newTestNetwork(t, 3)
+	defer s.Network().Shutdown()
+
+	for i := range ids {
+		chain.random[NodeName(i)] = big.NewInt(42)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.Run(ctx,
+		Step{Name: "drop sma txs", Action: func(ctx context.Context, s *Scenario) error {
+			return s.DropTxs(ctx, ids[:1], "sma1", "sma2")
+		}},
+		Step{Name: "delay dkg txs", Action: func(ctx context.Context, s *Scenario) error {
+			return s.DelayTxs(ctx, ids, 10*time.Second, "dkg1")
+		}},
+		Step{Name: "stable blocks", Action: func(ctx context.Context, s *Scenario) error {
+			return s.ExpectStableProgress(ctx, 3)
+		}},
+		Step{Name: "chain quality", After: 10 * time.Millisecond, Action: func(ctx context.Context, s *Scenario) error {
+			return s.ExpectChainQuality(ctx, 900)
+		}},
+		Step{Name: "random beacon", Action: func(ctx context.Context, s *Scenario) error {
+			return s.ExpectRandomBeacon(ctx, 1)
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, last := chain.services[NodeName(0)].faults, chain.services[NodeName(2)].faults
+	if drop, delay := first.fault(postx.KindSMA2, 1, 0); !drop || delay != 0 {
+		t.Fatalf("sma2 fault mismatch: drop %v, delay %v", drop, delay)
+	}
+	if drop, delay := last.fault(postx.KindDKG1, 1, 0); drop || delay != 10*time.Second {
+		t.Fatalf("dkg1 fault mismatch: drop %v, delay %v", drop, delay)
+	}
+	if err := s.DropTxs(ctx, ids, "sma3"); err == nil {
+		t.Fatal("unknown kind accepted")
+	}
+	if err := s.ClearTxFaults(ctx, ids); err != nil {
+		t.Fatal(err)
+	}
+	if drop, _ := first.fault(postx.KindSMA2, 1, 0); drop {
+		t.Fatal("faults not cleared")
+	}
+
+	// diverging random numbers fail the beacon check
+	chain.mu.Lock()
+	chain.random[NodeName(1)] = big.NewInt(7)
+	chain.mu.Unlock()
+	if err := s.ExpectRandomBeacon(ctx, 1); err == nil {
+		t.Fatal("diverging random numbers accepted")
+	}
+	// a missing random number times the check out
+	chain.mu.Lock()
+	delete(chain.random, NodeName(0))
+	chain.random[NodeName(1)] = big.NewInt(42)
+	chain.mu.Unlock()
+	short, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := s.ExpectRandomBeacon(short, 1); err != context.DeadlineExceeded {
+		t.Fatalf("error mismatch: have %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package possim runs Pluto POS nodes in p2p simulation networks and scripts
+// fault-injection scenarios against them.
+//
+// The POS workers (slot leader selection, random beacon, epoch leaders and
+// the protocol transaction submitter) keep their state in process-wide
+// singletons, so every POS node of a network needs its own process: use the
+// exec or docker adapter with the services registered by NewServices, e.g.
+//
+//	adapters.RegisterServices(possim.NewServices(cfg))
+//	net := simulations.NewNetwork(adapters.NewExecAdapter(dir), &simulations.NetworkConfig{DefaultService: possim.ServiceName})
+//
+// Nodes have to be named with NodeName so that each one mines with its own key.
+package possim
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/wanchain/go-wanchain/accounts/keystore"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/eth"
+	"github.com/wanchain/go-wanchain/eth/downloader"
+	"github.com/wanchain/go-wanchain/node"
+	"github.com/wanchain/go-wanchain/p2p"
+	"github.com/wanchain/go-wanchain/p2p/simulations/adapters"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/postx"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+// ServiceName is the name of the POS node service.
+const ServiceName = "pos"
+
+// minerPassphrase unlocks the miner accounts imported into the node keystores.
+const minerPassphrase = "possim"
+
+// Config is the configuration shared by the POS nodes of a network.
+type Config struct {
+	Genesis   *core.Genesis       // genesis of the network, must run the Pluto engine
+	NetworkId uint64              // network id, 6 selects the internal POS parameters
+	Miners    []*ecdsa.PrivateKey // miner key of each node, indexed by NodeName
+}
+
+// NodeName returns the name of the node mining with the i-th miner key.
+func NodeName(i int) string {
+	return fmt.Sprintf("pos%02d", i)
+}
+
+// MinerKeys derives n deterministic miner keys, so that every process of an
+// exec network agrees on them.
+func MinerKeys(n int) []*ecdsa.PrivateKey {
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		key, err := crypto.ToECDSA(crypto.Keccak256([]byte(NodeName(i))))
+		if err != nil {
+			panic(err)
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+// WhiteList returns the epoch leader white list electing the miners in turn.
+func (cfg *Config) WhiteList() (list [len(posconfig.WhiteList)]string) {
+	for i := range list {
+		list[i] = hexutil.Encode(crypto.FromECDSAPub(&cfg.Miners[i%len(cfg.Miners)].PublicKey))
+	}
+	return list
+}
+
+// NewServices returns the simulation services running POS nodes of cfg.
+func NewServices(cfg *Config) adapters.Services {
+	return adapters.Services{
+		ServiceName: func(ctx *adapters.ServiceContext) (node.Service, error) {
+			return NewService(ctx, cfg)
+		},
+	}
+}
+
+// Service is a full node running the Pluto engine and mining with the POS
+// workers. It adds the possim API injecting faults into the node.
+type Service struct {
+	*eth.Ethereum
+
+	faults *FaultAPI
+}
+
+// NewService creates the POS node service of the node described by ctx.
+func NewService(ctx *adapters.ServiceContext, cfg *Config) (*Service, error) {
+	var index int
+	if _, err := fmt.Sscanf(ctx.Config.Name, "pos%d", &index); err != nil || index < 0 || index >= len(cfg.Miners) {
+		return nil, fmt.Errorf("no miner key for node %q", ctx.Config.Name)
+	}
+	miner := cfg.Miners[index]
+
+	posconfig.IsDev = true
+	posconfig.MineEnabled = true
+	posconfig.Init(nil, cfg.NetworkId)
+	whiteList := cfg.WhiteList()
+	if err := posconfig.SetWhiteList(whiteList[:]); err != nil {
+		return nil, err
+	}
+
+	ks := ctx.NodeContext.AccountManager.Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	key2, err := crypto.ToECDSA(crypto.Keccak256(crypto.FromECDSA(miner)))
+	if err != nil {
+		return nil, err
+	}
+	account, err := ks.ImportECDSA(miner, key2, minerPassphrase)
+	if err != nil {
+		return nil, err
+	}
+	if err := ks.Unlock(account, minerPassphrase); err != nil {
+		return nil, err
+	}
+
+	config := eth.DefaultConfig
+	config.SyncMode = downloader.FullSync
+	config.NetworkId = cfg.NetworkId
+	config.Genesis = cfg.Genesis
+	config.Etherbase = account.Address
+	ethereum, err := eth.New(ctx.NodeContext, &config)
+	if err != nil {
+		return nil, err
+	}
+	faults := NewFaultAPI()
+	posCtx := ethereum.PosContext()
+	posCtx.TxOptions = append(posCtx.TxOptions, postx.WithFault(faults.fault))
+	return &Service{Ethereum: ethereum, faults: faults}, nil
+}
+
+// APIs implements node.Service.
+func (s *Service) APIs() []rpc.API {
+	return append(s.Ethereum.APIs(), rpc.API{
+		Namespace: "possim",
+		Version:   "1.0",
+		Service:   s.faults,
+		Public:    true,
+	})
+}
+
+// Start implements node.Service, starting to mine once the node is up.
+func (s *Service) Start(srvr *p2p.Server) error {
+	if err := s.Ethereum.Start(srvr); err != nil {
+		return err
+	}
+	return s.Ethereum.StartMining(true)
+}
+
+// Stop implements node.Service.
+func (s *Service) Stop() error {
+	s.faults.ClearTxFaults()
+	return s.Ethereum.Stop()
+}
+
+// FaultAPI injects faults into the protocol transactions of the node.
+type FaultAPI struct {
+	mu    sync.Mutex
+	drop  map[postx.Kind]bool
+	delay map[postx.Kind]time.Duration
+}
+
+// NewFaultAPI creates a fault API injecting no fault.
+func NewFaultAPI() *FaultAPI {
+	return &FaultAPI{drop: make(map[postx.Kind]bool), delay: make(map[postx.Kind]time.Duration)}
+}
+
+// DropTxs drops the protocol transactions of the given kinds.
+func (api *FaultAPI) DropTxs(kinds []string) error {
+	return api.set(kinds, func(kind postx.Kind) { api.drop[kind] = true })
+}
+
+// DelayTxs holds the protocol transactions of the given kinds back for the
+// given number of seconds.
+func (api *FaultAPI) DelayTxs(kinds []string, seconds uint64) error {
+	delay := time.Duration(seconds) * time.Second
+	return api.set(kinds, func(kind postx.Kind) { api.delay[kind] = delay })
+}
+
+// ClearTxFaults removes the faults injected into protocol transactions.
+func (api *FaultAPI) ClearTxFaults() {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	api.drop = make(map[postx.Kind]bool)
+	api.delay = make(map[postx.Kind]time.Duration)
+}
+
+func (api *FaultAPI) set(kinds []string, apply func(postx.Kind)) error {
+	parsed := make([]postx.Kind, len(kinds))
+	for i, name := range kinds {
+		kind, err := postx.ParseKind(name)
+		if err != nil {
+			return fmt.Errorf("%v: %q", err, name)
+		}
+		parsed[i] = kind
+	}
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	for _, kind := range parsed {
+		apply(kind)
+	}
+	return nil
+}
+
+func (api *FaultAPI) fault(kind postx.Kind, epochID, index uint64) (bool, time.Duration) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	return api.drop[kind], api.delay[kind]
+}
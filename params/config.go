@@ -36,6 +36,17 @@ const MainnetPow2PosUpgradeBlockNumber = 4046000
 const TestnetPow2PosUpgradeBlockNumber = 3560000
 const InternalPow2PosUpgradeBlockNumber = 200
 
+// Epoch IDs of the POS protocol upgrades on the public networks.
+const (
+	MainnetApolloEpoch  = 18104
+	MainnetAugustEpoch  = 18116
+	MainnetMercuryEpoch = 18250 //2019.12.20
+
+	TestnetApolloEpoch  = 18104
+	TestnetAugustEpoch  = 18116
+	TestnetMercuryEpoch = 18246 //2019.12.16
+)
+
 var (
 	// MainnetChainConfig is the chain parameters to run a node on the main network.
 	MainnetChainConfig = &ChainConfig{
@@ -54,6 +65,9 @@ var (
 		Ethash:         new(EthashConfig),
 		PosFirstBlock:  big.NewInt(MainnetPow2PosUpgradeBlockNumber), // set as n * epoch_length
 		IsPosActive:    false,
+		ApolloEpoch:    big.NewInt(MainnetApolloEpoch),
+		AugustEpoch:    big.NewInt(MainnetAugustEpoch),
+		MercuryEpoch:   big.NewInt(MainnetMercuryEpoch),
 		Pluto: &PlutoConfig{
 			Period: 10,
 			Epoch:  100,
@@ -73,6 +87,9 @@ var (
 		Ethash:         new(EthashConfig),
 		PosFirstBlock:  big.NewInt(MainnetPow2PosUpgradeBlockNumber), // set as n * epoch_length
 		IsPosActive:    false,                                        // when pos running, the state changed to true by program
+		ApolloEpoch:    big.NewInt(MainnetApolloEpoch),
+		AugustEpoch:    big.NewInt(MainnetAugustEpoch),
+		MercuryEpoch:   big.NewInt(MainnetMercuryEpoch),
 		Pluto: &PlutoConfig{
 			Period: 10,
 			Epoch:  100,
@@ -94,6 +111,9 @@ var (
 		Ethash:        new(EthashConfig),
 		PosFirstBlock: big.NewInt(TestnetPow2PosUpgradeBlockNumber), // set as n * epoch_length
 		IsPosActive:   false,
+		ApolloEpoch:   big.NewInt(TestnetApolloEpoch),
+		AugustEpoch:   big.NewInt(TestnetAugustEpoch),
+		MercuryEpoch:  big.NewInt(TestnetMercuryEpoch),
 		Pluto: &PlutoConfig{
 			Period: 10,
 			Epoch:  100,
@@ -115,6 +135,9 @@ var (
 		Ethash:        new(EthashConfig),
 		PosFirstBlock: big.NewInt(InternalPow2PosUpgradeBlockNumber), // set as n * epoch_length
 		IsPosActive:   false,
+		ApolloEpoch:   big.NewInt(TestnetApolloEpoch),
+		AugustEpoch:   big.NewInt(TestnetAugustEpoch),
+		MercuryEpoch:  big.NewInt(TestnetMercuryEpoch),
 		Pluto: &PlutoConfig{
 			Period: 10,
 			Epoch:  100,
@@ -133,6 +156,9 @@ var (
 		ByzantiumBlock: big.NewInt(0),
 		PosFirstBlock:  big.NewInt(1),
 		IsPosActive:    true,
+		ApolloEpoch:    big.NewInt(TestnetApolloEpoch),
+		AugustEpoch:    big.NewInt(TestnetAugustEpoch),
+		MercuryEpoch:   big.NewInt(0),
 
 		Pluto: &PlutoConfig{
 			Period: 10,
@@ -148,7 +174,7 @@ var (
 	// means that all fields must be set at all times. This forces
 	// anyone adding flags to the config to also have to set these
 	// fields.
-	AllProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), big.NewInt(100), false, new(EthashConfig), nil, nil}
+	AllProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(100), false, big.NewInt(0), big.NewInt(0), big.NewInt(0), new(EthashConfig), nil, nil}
 
 	TestChainConfig = &ChainConfig{
 		ChainId:        big.NewInt(1),
@@ -156,6 +182,9 @@ var (
 		Ethash:         new(EthashConfig),
 		PosFirstBlock:  big.NewInt(TestnetPow2PosUpgradeBlockNumber), // set as n * epoch_length
 		IsPosActive:    false,
+		ApolloEpoch:    big.NewInt(TestnetApolloEpoch),
+		AugustEpoch:    big.NewInt(TestnetAugustEpoch),
+		MercuryEpoch:   big.NewInt(0),
 	}
 
 	TestRules = TestChainConfig.Rules(new(big.Int))
@@ -183,9 +212,17 @@ type ChainConfig struct {
 	//EIP155Block *big.Int `json:"eip155Block,omitempty"` // EIP155 HF block
 	//EIP158Block *big.Int `json:"eip158Block,omitempty"` // EIP158 HF block
 
-	ByzantiumBlock *big.Int `json:"byzantiumBlock,omitempty"` // Byzantium switch block (nil = no fork, 0 = already on byzantium)
-	PosFirstBlock  *big.Int `json:"posFirstBlock,omitempty"`
-	IsPosActive    bool     `json:"isPosActive,omitempty"`
+	ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty"`      // Byzantium switch block (nil = no fork, 0 = already on byzantium)
+	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"` // Constantinople switch block (nil = no fork, 0 = already activated)
+	PetersburgBlock     *big.Int `json:"petersburgBlock,omitempty"`     // Petersburg switch block (nil = same as Constantinople)
+	IstanbulBlock       *big.Int `json:"istanbulBlock,omitempty"`       // Istanbul switch block (nil = no fork, 0 = already on istanbul)
+	PosFirstBlock       *big.Int `json:"posFirstBlock,omitempty"`
+	IsPosActive         bool     `json:"isPosActive,omitempty"`
+
+	// POS protocol upgrades are scheduled by epoch ID (nil = no fork, 0 = already activated)
+	ApolloEpoch  *big.Int `json:"apolloEpoch,omitempty"`  // Apollo switch epoch: one block per slot, staker registration and partners
+	AugustEpoch  *big.Int `json:"augustEpoch,omitempty"`  // August switch epoch: lifts the minimum partner amount
+	MercuryEpoch *big.Int `json:"mercuryEpoch,omitempty"` // Mercury switch epoch: random beacon queries and storage iteration fix
 
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
@@ -216,6 +253,10 @@ func (c *CliqueConfig) String() string {
 type PlutoConfig struct {
 	Period uint64 `json:"period"` // Number of seconds between blocks to enforce
 	Epoch  uint64 `json:"epoch"`  // Epoch length to reset votes and checkpoint
+
+	// WhiteList holds the secp256k1 public keys electing the epoch leaders
+	// in turn. When empty, the list built in for the network id is used.
+	WhiteList []string `json:"whiteList,omitempty"`
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -237,7 +278,7 @@ func (c *ChainConfig) String() string {
 		engine = "unknown"
 	}
 	//return fmt.Sprintf("{ChainID: %v Homestead: %v EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Engine: %v}",
-	return fmt.Sprintf("{ChainID: %v Byzantium: %v Engine: %v}",
+	return fmt.Sprintf("{ChainID: %v Byzantium: %v Constantinople: %v Petersburg: %v Istanbul: %v Apollo: %v August: %v Mercury: %v Engine: %v}",
 		c.ChainId,
 		//c.HomesteadBlock,
 		//c.DAOForkBlock,
@@ -247,6 +288,12 @@ func (c *ChainConfig) String() string {
 		//c.EIP158Block,
 
 		c.ByzantiumBlock,
+		c.ConstantinopleBlock,
+		c.PetersburgBlock,
+		c.IstanbulBlock,
+		c.ApolloEpoch,
+		c.AugustEpoch,
+		c.MercuryEpoch,
 		engine,
 	)
 }
@@ -277,6 +324,58 @@ func (c *ChainConfig) String() string {
 //	return isForked(c.ByzantiumBlock, num)
 //}
 
+// IsConstantinople returns whether num is either equal to the Constantinople fork block or greater.
+func (c *ChainConfig) IsConstantinople(num *big.Int) bool {
+	return isForked(c.ConstantinopleBlock, num)
+}
+
+// IsPetersburg returns whether num is either
+// - equal to or greater than the PetersburgBlock fork block,
+// - OR is nil, and Constantinople is active
+func (c *ChainConfig) IsPetersburg(num *big.Int) bool {
+	return isForked(c.PetersburgBlock, num) || c.PetersburgBlock == nil && isForked(c.ConstantinopleBlock, num)
+}
+
+// IsIstanbul returns whether num is either equal to the Istanbul fork block or greater.
+func (c *ChainConfig) IsIstanbul(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num)
+}
+
+// DefaultEpochForks returns a configuration holding only the POS fork epochs
+// that the network with the given id ran at before the epochs were part of
+// the chain configuration. Chain configurations stored without them take
+// these, so the forks keep activating where they always did.
+func DefaultEpochForks(networkId uint64) *ChainConfig {
+	cfg := &ChainConfig{
+		ApolloEpoch: big.NewInt(MainnetApolloEpoch),
+		AugustEpoch: big.NewInt(MainnetAugustEpoch),
+	}
+	switch networkId {
+	case 1:
+		cfg.MercuryEpoch = big.NewInt(MainnetMercuryEpoch)
+	case 6:
+		cfg.MercuryEpoch = big.NewInt(0)
+	default:
+		cfg.MercuryEpoch = big.NewInt(TestnetMercuryEpoch)
+	}
+	return cfg
+}
+
+// IsApollo returns whether epochID is either equal to the Apollo fork epoch or greater.
+func (c *ChainConfig) IsApollo(epochID uint64) bool {
+	return isEpochForked(c.ApolloEpoch, epochID)
+}
+
+// IsAugust returns whether epochID is either equal to the August fork epoch or greater.
+func (c *ChainConfig) IsAugust(epochID uint64) bool {
+	return isEpochForked(c.AugustEpoch, epochID)
+}
+
+// IsMercury returns whether epochID is either equal to the Mercury fork epoch or greater.
+func (c *ChainConfig) IsMercury(epochID uint64) bool {
+	return isEpochForked(c.MercuryEpoch, epochID)
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
@@ -296,27 +395,41 @@ func (c *ChainConfig) GasTable(num *big.Int) GasTable {
 	//	return GasTableHomestead
 	//}
 
-	return GasTableEIP158
+	switch {
+	case c.IsIstanbul(num):
+		return GasTableIstanbul
+	case c.IsConstantinople(num):
+		return GasTableConstantinople
+	default:
+		return GasTableEIP158
+	}
 }
 
 // CheckCompatible checks whether scheduled fork transitions have been imported
-// with a mismatching chain configuration.
-func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *ConfigCompatError {
+// with a mismatching chain configuration. height is the number of the head block
+// and epochID the POS epoch it belongs to.
+func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64, epochID uint64) *ConfigCompatError {
 	bhead := new(big.Int).SetUint64(height)
+	ehead := new(big.Int).SetUint64(epochID)
 	// Iterate checkCompatible to find the lowest conflict.
 	var lasterr *ConfigCompatError
 	for {
-		err := c.checkCompatible(newcfg, bhead)
-		if err == nil || (lasterr != nil && err.RewindTo == lasterr.RewindTo) {
+		err := c.checkCompatible(newcfg, bhead, ehead)
+		if err == nil || (lasterr != nil && err.Epoch == lasterr.Epoch &&
+			err.RewindTo == lasterr.RewindTo && err.RewindToEpoch == lasterr.RewindToEpoch) {
 			break
 		}
 		lasterr = err
-		bhead.SetUint64(err.RewindTo)
+		if err.Epoch {
+			ehead.SetUint64(err.RewindToEpoch)
+		} else {
+			bhead.SetUint64(err.RewindTo)
+		}
 	}
 	return lasterr
 }
 
-func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *ConfigCompatError {
+func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int, epochHead *big.Int) *ConfigCompatError {
 
 	//if isForkIncompatible(c.HomesteadBlock, newcfg.HomesteadBlock, head) {
 	//	return newCompatError("Homestead fork block", c.HomesteadBlock, newcfg.HomesteadBlock)
@@ -350,6 +463,25 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	//	return newCompatError("Byzantium fork block", c.ByzantiumBlock, newcfg.ByzantiumBlock)
 	//}
 
+	if isForkIncompatible(c.ConstantinopleBlock, newcfg.ConstantinopleBlock, head) {
+		return newCompatError("Constantinople fork block", c.ConstantinopleBlock, newcfg.ConstantinopleBlock)
+	}
+	if isForkIncompatible(c.PetersburgBlock, newcfg.PetersburgBlock, head) {
+		return newCompatError("Petersburg fork block", c.PetersburgBlock, newcfg.PetersburgBlock)
+	}
+	if isForkIncompatible(c.IstanbulBlock, newcfg.IstanbulBlock, head) {
+		return newCompatError("Istanbul fork block", c.IstanbulBlock, newcfg.IstanbulBlock)
+	}
+	if isForkIncompatible(c.ApolloEpoch, newcfg.ApolloEpoch, epochHead) {
+		return newEpochCompatError("Apollo fork epoch", c.ApolloEpoch, newcfg.ApolloEpoch)
+	}
+	if isForkIncompatible(c.AugustEpoch, newcfg.AugustEpoch, epochHead) {
+		return newEpochCompatError("August fork epoch", c.AugustEpoch, newcfg.AugustEpoch)
+	}
+	if isForkIncompatible(c.MercuryEpoch, newcfg.MercuryEpoch, epochHead) {
+		return newEpochCompatError("Mercury fork epoch", c.MercuryEpoch, newcfg.MercuryEpoch)
+	}
+
 	return nil
 }
 
@@ -367,6 +499,14 @@ func isForked(s, head *big.Int) bool {
 	return s.Cmp(head) <= 0
 }
 
+// isEpochForked returns whether a fork scheduled at epoch s is active at epochID.
+func isEpochForked(s *big.Int, epochID uint64) bool {
+	if s == nil || !s.IsUint64() {
+		return false
+	}
+	return s.Uint64() <= epochID
+}
+
 func configNumEqual(x, y *big.Int) bool {
 	if x == nil {
 		return y == nil
@@ -385,6 +525,10 @@ type ConfigCompatError struct {
 	StoredConfig, NewConfig *big.Int
 	// the block number to which the local chain must be rewound to correct the error
 	RewindTo uint64
+	// Epoch is set for POS fork epochs, StoredConfig and NewConfig are epoch IDs then
+	Epoch bool
+	// the epoch ID to which the local chain must be rewound to correct the error
+	RewindToEpoch uint64
 }
 
 func newCompatError(what string, storedblock, newblock *big.Int) *ConfigCompatError {
@@ -397,14 +541,26 @@ func newCompatError(what string, storedblock, newblock *big.Int) *ConfigCompatEr
 	default:
 		rew = newblock
 	}
-	err := &ConfigCompatError{what, storedblock, newblock, 0}
+	err := &ConfigCompatError{What: what, StoredConfig: storedblock, NewConfig: newblock}
 	if rew != nil && rew.Sign() > 0 {
 		err.RewindTo = rew.Uint64() - 1
 	}
 	return err
 }
 
+// newEpochCompatError is newCompatError for forks scheduled by epoch. The caller
+// translates RewindToEpoch into the block number RewindTo.
+func newEpochCompatError(what string, storedepoch, newepoch *big.Int) *ConfigCompatError {
+	err := newCompatError(what, storedepoch, newepoch)
+	err.Epoch = true
+	err.RewindToEpoch, err.RewindTo = err.RewindTo, 0
+	return err
+}
+
 func (err *ConfigCompatError) Error() string {
+	if err.Epoch {
+		return fmt.Sprintf("mismatching %s in database (have %d, want %d, rewindto epoch %d, block %d)", err.What, err.StoredConfig, err.NewConfig, err.RewindToEpoch, err.RewindTo)
+	}
 	return fmt.Sprintf("mismatching %s in database (have %d, want %d, rewindto %d)", err.What, err.StoredConfig, err.NewConfig, err.RewindTo)
 }
 
@@ -417,6 +573,7 @@ type Rules struct {
 	ChainId *big.Int
 	//IsHomestead, IsEIP150, IsEIP155, IsEIP158 bool
 	//IsByzantium                               bool
+	IsConstantinople, IsPetersburg, IsIstanbul bool
 }
 
 func (c *ChainConfig) Rules(num *big.Int) Rules {
@@ -426,7 +583,12 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 	}
 	//return Rules{ChainId: new(big.Int).Set(chainId), IsHomestead: /*c.IsHomestead(num)*/false, IsEIP150: false/*c.IsEIP150(num)*/, IsEIP155: false/*c.IsEIP155(num)*/, IsEIP158:false/* c.IsEIP158(num)*/, IsByzantium: c.IsByzantium(num)}
 
-	return Rules{ChainId: new(big.Int).Set(chainId)}
+	return Rules{
+		ChainId:          new(big.Int).Set(chainId),
+		IsConstantinople: c.IsConstantinople(num),
+		IsPetersburg:     c.IsPetersburg(num),
+		IsIstanbul:       c.IsIstanbul(num),
+	}
 }
 
 func (c *ChainConfig) SetPosActive() {
@@ -17,6 +17,7 @@
 package params
 
 import (
+	"encoding/json"
 	"math/big"
 	"reflect"
 	"testing"
@@ -26,6 +27,7 @@ func TestCheckCompatible(t *testing.T) {
 	type test struct {
 		stored, new *ChainConfig
 		head        uint64
+		headEpoch   uint64
 		wantErr     *ConfigCompatError
 	}
 	tests := []test{
@@ -37,6 +39,61 @@ func TestCheckCompatible(t *testing.T) {
 			head:    9,
 			wantErr: nil,
 		},
+		{
+			stored: &ChainConfig{ConstantinopleBlock: big.NewInt(30)},
+			new:    &ChainConfig{ConstantinopleBlock: big.NewInt(40)},
+			head:   35,
+			wantErr: &ConfigCompatError{
+				What:         "Constantinople fork block",
+				StoredConfig: big.NewInt(30),
+				NewConfig:    big.NewInt(40),
+				RewindTo:     29,
+			},
+		},
+		{
+			stored: AllProtocolChanges,
+			new:    &ChainConfig{ConstantinopleBlock: nil},
+			head:   3,
+			wantErr: &ConfigCompatError{
+				What:         "Constantinople fork block",
+				StoredConfig: big.NewInt(0),
+				NewConfig:    nil,
+				RewindTo:     0,
+			},
+		},
+		{
+			stored:    &ChainConfig{ApolloEpoch: big.NewInt(10), MercuryEpoch: big.NewInt(30)},
+			new:       &ChainConfig{ApolloEpoch: big.NewInt(10), MercuryEpoch: big.NewInt(40)},
+			head:      500,
+			headEpoch: 20,
+			wantErr:   nil,
+		},
+		{
+			stored:    &ChainConfig{ApolloEpoch: big.NewInt(10), MercuryEpoch: big.NewInt(30)},
+			new:       &ChainConfig{ApolloEpoch: big.NewInt(10), MercuryEpoch: big.NewInt(40)},
+			head:      500,
+			headEpoch: 35,
+			wantErr: &ConfigCompatError{
+				What:          "Mercury fork epoch",
+				StoredConfig:  big.NewInt(30),
+				NewConfig:     big.NewInt(40),
+				Epoch:         true,
+				RewindToEpoch: 29,
+			},
+		},
+		{
+			stored:    &ChainConfig{ApolloEpoch: nil, AugustEpoch: big.NewInt(20)},
+			new:       &ChainConfig{ApolloEpoch: big.NewInt(10), AugustEpoch: big.NewInt(15)},
+			head:      500,
+			headEpoch: 25,
+			wantErr: &ConfigCompatError{
+				What:          "Apollo fork epoch",
+				StoredConfig:  nil,
+				NewConfig:     big.NewInt(10),
+				Epoch:         true,
+				RewindToEpoch: 9,
+			},
+		},
 		//{
 		//	stored: AllProtocolChanges,
 		//	new:    &ChainConfig{ByzantiumBlock: nil},
@@ -73,9 +130,51 @@ func TestCheckCompatible(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		err := test.stored.CheckCompatible(test.new, test.head)
+		err := test.stored.CheckCompatible(test.new, test.head, test.headEpoch)
 		if !reflect.DeepEqual(err, test.wantErr) {
-			t.Errorf("error mismatch:\nstored: %v\nnew: %v\nhead: %v\nerr: %v\nwant: %v", test.stored, test.new, test.head, err, test.wantErr)
+			t.Errorf("error mismatch:\nstored: %v\nnew: %v\nhead: %v/%v\nerr: %v\nwant: %v", test.stored, test.new, test.head, test.headEpoch, err, test.wantErr)
+		}
+	}
+}
+
+func TestPosForkEpochs(t *testing.T) {
+	config := &ChainConfig{ApolloEpoch: big.NewInt(10), AugustEpoch: big.NewInt(20)}
+	tests := []struct {
+		epochID                 uint64
+		apollo, august, mercury bool
+	}{
+		{0, false, false, false},
+		{9, false, false, false},
+		{10, true, false, false},
+		{19, true, false, false},
+		{20, true, true, false},
+		{1 << 40, true, true, false},
+	}
+	for _, test := range tests {
+		if got := config.IsApollo(test.epochID); got != test.apollo {
+			t.Errorf("epoch %d: IsApollo = %v, want %v", test.epochID, got, test.apollo)
+		}
+		if got := config.IsAugust(test.epochID); got != test.august {
+			t.Errorf("epoch %d: IsAugust = %v, want %v", test.epochID, got, test.august)
+		}
+		if got := config.IsMercury(test.epochID); got != test.mercury {
+			t.Errorf("epoch %d: IsMercury = %v, want %v", test.epochID, got, test.mercury)
 		}
 	}
 }
+
+func TestPosForkEpochsJSON(t *testing.T) {
+	var config ChainConfig
+	if err := json.Unmarshal([]byte(`{"chainId":6,"apolloEpoch":5,"augustEpoch":6,"mercuryEpoch":7}`), &config); err != nil {
+		t.Fatal(err)
+	}
+	if !config.IsApollo(5) || config.IsApollo(4) {
+		t.Errorf("apolloEpoch not decoded: %v", config.ApolloEpoch)
+	}
+	if !config.IsAugust(6) || config.IsAugust(5) {
+		t.Errorf("augustEpoch not decoded: %v", config.AugustEpoch)
+	}
+	if !config.IsMercury(7) || config.IsMercury(6) {
+		t.Errorf("mercuryEpoch not decoded: %v", config.MercuryEpoch)
+	}
+}
@@ -41,25 +41,44 @@ type BlkStatus struct {
 var c *CFM
 
 func InitCFM(bc *core.BlockChain) {
-	c = &CFM{}
-	c.bc = bc
-	c.whiteList = make(map[common.Address]int, 0)
+	SetCFM(NewCFM(bc))
+	log.Info("InitCFM success")
+}
+
+// NewCFM creates a block confirmation tracker for bc.
+func NewCFM(bc *core.BlockChain) *CFM {
+	cfm := &CFM{}
+	cfm.bc = bc
+	cfm.whiteList = make(map[common.Address]int, 0)
 	for _, value := range posconfig.WhiteList {
 
 		b := hexutil.MustDecode(value)
 		address := crypto.PubkeyToAddress(*(crypto.ToECDSAPub(b)))
-		c.whiteList[address] = 1
+		cfm.whiteList[address] = 1
 	}
-	log.Info("InitCFM success")
+	return cfm
+}
+
+// SetCFM installs cfm as the package-level instance returned by GetCFM.
+func SetCFM(cfm *CFM) {
+	c = cfm
 }
 
 func GetCFM() *CFM {
 	return c
 }
 
+func (c *CFM) posConfig() *posconfig.Config {
+	if c.bc != nil {
+		return c.bc.PosConfig()
+	}
+	return posconfig.Cfg()
+}
+
 func (c *CFM) GetMaxStableBlkNumber() uint64 {
+	firstEpochId := c.posConfig().FirstEpochID()
 	// In pow phase
-	if posconfig.FirstEpochId == 0 {
+	if firstEpochId == 0 {
 		return c.getPowMaxStableBlkNumber(c.getCurrentBlkNumber())
 	}
 	// In pos phase
@@ -72,7 +91,7 @@ func (c *CFM) GetMaxStableBlkNumber() uint64 {
 	log.Debug("GetMaxStableBlkNumber",
 		"maxStableBlkNumber", maxStableBlkNumber,
 		"Pow2PosUpgradeBlockNumber", posconfig.Pow2PosUpgradeBlockNumber,
-		"FirstEpochId", posconfig.FirstEpochId)
+		"FirstEpochId", firstEpochId)
 
 	// get max stable on block of pos phase
 	if maxStableBlkNumber >= posconfig.Pow2PosUpgradeBlockNumber {
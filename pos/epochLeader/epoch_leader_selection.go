@@ -74,20 +74,51 @@ func GetEpocher() *Epocher {
 	return epocherInst
 }
 
+// SetEpocher installs e as the package-level instance returned by GetEpocher
+// and util.GetEpocherInst.
+func SetEpocher(e *Epocher) {
+	epocherInst = e
+	util.SetEpocherInst(e)
+}
+
 func NewEpocherWithLBN(blc *core.BlockChain, rbn string, epdbn string) *Epocher {
 
 	rbdb := posdb.NewDb(rbn)
 	epdb := posdb.NewDb(epdbn)
-	inst := &Epocher{rbdb, epdb, blc}
+	inst := NewEpocherWithDb(blc, rbdb, epdb)
 
 	util.SetEpocherInst(inst)
 	return inst
 }
 
+// NewEpocherWithDb creates an Epocher over the given databases without
+// installing it as the package-level instance.
+func NewEpocherWithDb(blc *core.BlockChain, rbdb *posdb.Db, epdb *posdb.Db) *Epocher {
+	return &Epocher{rbdb, epdb, blc}
+}
+
 func (e *Epocher) GetBlkChain() *core.BlockChain {
 	return e.blkChain
 }
 
+func (e *Epocher) posConfig() *posconfig.Config {
+	if e.blkChain != nil {
+		return e.blkChain.PosConfig()
+	}
+	return posconfig.Cfg()
+}
+
+// localDb returns the local POS database of the chain, where the stake out
+// records are kept.
+func (e *Epocher) localDb() *posdb.Db {
+	if e.blkChain != nil {
+		if pos := e.blkChain.PosContext(); pos != nil {
+			return pos.LocalDb(posconfig.PosLocalDB)
+		}
+	}
+	return posdb.GetDb()
+}
+
 func (e *Epocher) GetCurrentHeader() *types.Header {
 
 	inst := e.blkChain.GetHc()
@@ -129,7 +160,7 @@ func (e *Epocher) GetEpochLastBlkNumber(targetEpochId uint64) uint64 {
 
 	targetBlkNum := curNum
 	epochid, _ := util.CalEpochSlotID(uint64(time.Now().Unix()))
-	if targetEpochId < epochid && targetEpochId >= posconfig.FirstEpochId {
+	if targetEpochId < epochid && targetEpochId >= e.posConfig().FirstEpochID() {
 		util.SetEpochBlock(targetEpochId, targetBlkNum, curBlockHeader.Hash())
 	}
 
@@ -150,7 +181,7 @@ func (e *Epocher) SelectLeadersLoop(epochId uint64) error {
 	if epochIdIn > 0 {
 		epochIdIn--
 	}
-	rb := vm.GetR(stateDb, epochIdIn)
+	rb := vm.GetRWithConfig(e.posConfig(), stateDb, epochIdIn)
 	if rb == nil {
 		log.Error(fmt.Sprintln("vm.GetR return nil at epochId:", epochId))
 		rb = new(big.Int).SetBytes(crypto.Keccak256(big.NewInt(1).Bytes()))
@@ -284,7 +315,7 @@ func (e *Epocher) createStakerProbabilityArray(statedb *state.StateDB, epochID u
 			log.Error(err.Error())
 			return true
 		}
-		_, p, err := CalEpochProbabilityStaker(&staker, epochID)
+		_, p, err := e.CalEpochProbabilityStaker(&staker, epochID)
 		if err != nil || p == nil {
 			// this validator has no enough
 			return true
@@ -435,12 +466,12 @@ func (e *Epocher) randomProposerSelection(r []byte, ps ProposerSorter, epochId u
 }
 
 func (e *Epocher) IsGenerateELSuc(epochID uint64) bool {
-	epArray := posdb.GetEpochLeaderGroup(epochID)
+	epArray := posdb.GetEpochLeaderGroupWithDb(e.epochLeadersDb, epochID)
 	return len(epArray) != 0
 }
 
 func (e *Epocher) IsGenerateRBPSuc(epochID uint64) bool {
-	rbArray := posdb.GetRBProposerGroup(epochID)
+	rbArray := posdb.GetRBProposerGroupWithDb(e.rbLeadersDb, epochID)
 	return len(rbArray) != 0
 }
 
@@ -448,7 +479,7 @@ func (e *Epocher) IsGenerateRBPSuc(epochID uint64) bool {
 func (e *Epocher) GetEpochLeaders(epochID uint64) [][]byte {
 
 	// TODO: how to cache these
-	epArray := posdb.GetEpochLeaderGroup(epochID)
+	epArray := posdb.GetEpochLeaderGroupWithDb(e.epochLeadersDb, epochID)
 	wa, err := e.GetWhiteArrayByEpochId(epochID)
 	if err == nil {
 		if len(epArray) == posconfig.EpochLeaderCount-len(wa) {
@@ -460,7 +491,7 @@ func (e *Epocher) GetEpochLeaders(epochID uint64) [][]byte {
 }
 func (e *Epocher) GetRBProposer(epochID uint64) [][]byte {
 	// TODO: how to cache these
-	rbArray := posdb.GetRBProposerGroup(epochID)
+	rbArray := posdb.GetRBProposerGroupWithDb(e.rbLeadersDb, epochID)
 	return rbArray
 
 }
@@ -590,10 +621,20 @@ func (e *Epocher) GetProposerBn256PK(epochID uint64, idx uint64, addr common.Add
 
 // TODO Is this  right?
 func CalEpochProbabilityStaker(staker *vm.StakerInfo, epochID uint64) (infors []vm.ClientProbability, totalProbability *big.Int, err error) {
+	return calEpochProbabilityStaker(staker, epochID, posconfig.FirstEpochId)
+}
+
+// CalEpochProbabilityStaker is the package-level CalEpochProbabilityStaker for
+// the chain of e.
+func (e *Epocher) CalEpochProbabilityStaker(staker *vm.StakerInfo, epochID uint64) (infors []vm.ClientProbability, totalProbability *big.Int, err error) {
+	return calEpochProbabilityStaker(staker, epochID, e.posConfig().FirstEpochID())
+}
+
+func calEpochProbabilityStaker(staker *vm.StakerInfo, epochID uint64, firstEpochId uint64) (infors []vm.ClientProbability, totalProbability *big.Int, err error) {
 	if staker.StakingEpoch == 0 && staker.LockEpochs != 0 {
-		staker.StakingEpoch = posconfig.FirstEpochId + 2
+		staker.StakingEpoch = firstEpochId + 2
 		for j := 0; j < len(staker.Partners); j++ {
-			staker.Partners[j].StakingEpoch = posconfig.FirstEpochId + 2
+			staker.Partners[j].StakingEpoch = firstEpochId + 2
 		}
 	}
 	// check validator is exiting.
@@ -667,7 +708,7 @@ func (e *Epocher) GetEpochProbability(epochId uint64, addr common.Address) (*vm.
 		return nil, err
 	}
 
-	infors, totalProbability, err := CalEpochProbabilityStaker(&staker, epochId)
+	infors, totalProbability, err := e.CalEpochProbabilityStaker(&staker, epochId)
 	if err != nil {
 		return nil, err
 	}
@@ -708,12 +749,12 @@ func recordStakeOut(infos []RefundInfo, addr common.Address, amount *big.Int)([]
 	infos = append(infos, record)
 	return infos
 }
-func saveStakeOut(stakeOutInfo []RefundInfo, epochID uint64) error {
+func saveStakeOut(db *posdb.Db, stakeOutInfo []RefundInfo, epochID uint64) error {
 	stakeByte, err := rlp.EncodeToBytes(stakeOutInfo)
 	if err != nil {
 		return err
 	}
-	_, err = posdb.GetDb().Put(epochID, posconfig.StakeOutEpochKey,stakeByte)
+	_, err = db.Put(epochID, posconfig.StakeOutEpochKey,stakeByte)
 	if err != nil {
 		log.Error("saveStakeOut Failed:", "error", err)
 		return err
@@ -727,6 +768,15 @@ func coreTransfer(db vm.StateDB, sender, recipient common.Address, amount *big.I
 	}
 }
 func StakeOutRun(stateDb *state.StateDB, epochID uint64) bool {
+	return stakeOutRun(stateDb, epochID, posconfig.FirstEpochId, posdb.GetDb())
+}
+
+// StakeOutRun is the package-level StakeOutRun for the chain of e.
+func (e *Epocher) StakeOutRun(stateDb *state.StateDB, epochID uint64) bool {
+	return stakeOutRun(stateDb, epochID, e.posConfig().FirstEpochID(), e.localDb())
+}
+
+func stakeOutRun(stateDb *state.StateDB, epochID uint64, firstEpochId uint64, db *posdb.Db) bool {
 	if vm.StakeoutIsFinished(stateDb, epochID) {
 		return true
 	}
@@ -744,9 +794,9 @@ func StakeOutRun(stateDb *state.StateDB, epochID uint64) bool {
 
 		// handle the staker registed in pow phase. only once
 		if staker.StakingEpoch == 0 && staker.LockEpochs != 0 {
-			staker.StakingEpoch = posconfig.FirstEpochId + 2
+			staker.StakingEpoch = firstEpochId + 2
 			for j := 0; j < len(staker.Partners); j++ {
-				staker.Partners[j].StakingEpoch = posconfig.FirstEpochId + 2
+				staker.Partners[j].StakingEpoch = firstEpochId + 2
 			}
 			changed = true
 		}
@@ -862,6 +912,6 @@ func StakeOutRun(stateDb *state.StateDB, epochID uint64) bool {
 			vm.UpdateInfo(stateDb, vm.StakersInfoAddr, vm.GetStakeInKeyHash(staker.Address), stakerBytes)
 		}
 	}
-	saveStakeOut(stakeOutInfo, epochID)
+	saveStakeOut(db, stakeOutInfo, epochID)
 	return true
 }
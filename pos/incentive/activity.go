@@ -50,7 +50,7 @@ func getEpochLeaderActivity(stateDb vm.StateDB, epochID uint64) ([]common.Addres
 		return []common.Address{}, []int{}
 	}
 
-	epochLeaders := util.GetEpocherInst().GetEpochLeaders(epochID)
+	epochLeaders := getEpocher().GetEpochLeaders(epochID)
 	if !checkEpochLeaders(epochLeaders) {
 		log.SyslogErr("incentive activity GetEpochLeaders error", "epochID", epochID)
 		return []common.Address{}, []int{}
@@ -1,9 +1,16 @@
 package incentive
 
 import (
+	"math/big"
+	"sync"
+
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/consensus"
+	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/util"
 )
 
 // GetStakerInfoFn is a function use to get staker info
@@ -53,3 +60,167 @@ func setActivityInterface(getEpl GetEpochLeaderInfoFn, getRnp GetRandomProposerI
 func setRBAddressInterface(getRBAddress GetRandomProposerAddressFn) {
 	getRandomProposerAddress = getRBAddress
 }
+
+// Hooks are the outside interfaces of one chain that the incentive reads
+// stakers and activity from and stores its results in.
+type Hooks struct {
+	GetStakerInfo            GetStakerInfoFn
+	SetStakerInfo            SetStakerInfoFn
+	GetRandomProposerAddress GetRandomProposerAddressFn
+	Epocher                  util.SelectLead
+	Db                       *posdb.Db
+	Config                   *posconfig.Config
+}
+
+var (
+	hooksMu sync.Mutex
+	epocher util.SelectLead
+	config  *posconfig.Config
+)
+
+// getEpocher returns the epocher of the running hooks, or the package-level
+// epocher when none was given.
+func getEpocher() util.SelectLead {
+	if epocher != nil {
+		return epocher
+	}
+	return util.GetEpocherInst()
+}
+
+// getConfig returns the POS configuration of the running hooks, or the
+// package-level one when none was given.
+func getConfig() *posconfig.Config {
+	if config != nil {
+		return config
+	}
+	return posconfig.Cfg()
+}
+
+// use runs fn against the interfaces in h instead of the ones installed by
+// Init. A nil h runs fn against the installed ones.
+func (h *Hooks) use(fn func()) {
+	if h == nil {
+		// the chain has no POS services yet
+		fn()
+		return
+	}
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	oldGet, oldSet, oldRbAddr, oldEpocher, oldDb, oldConfig := getStakerInfo, setStakerInfo, getRandomProposerAddress, epocher, localDb, config
+	defer func() {
+		setStakerInterface(oldGet, oldSet)
+		setRBAddressInterface(oldRbAddr)
+		epocher, localDb, config = oldEpocher, oldDb, oldConfig
+	}()
+
+	setStakerInterface(h.GetStakerInfo, h.SetStakerInfo)
+	setRBAddressInterface(h.GetRandomProposerAddress)
+	epocher, config = h.Epocher, h.Config
+	if h.Db != nil {
+		localDb = h.Db
+	}
+	fn()
+}
+
+// RunWithHooks runs the incentive of epochID against the interfaces in h
+// instead of the ones installed by Init, so that several chains in one
+// process can pay out their own incentives.
+func RunWithHooks(h *Hooks, chain consensus.ChainReader, stateDb *state.StateDB, epochID uint64) bool {
+	var ok bool
+	h.use(func() {
+		ok = Run(chain, stateDb, epochID)
+	})
+	return ok
+}
+
+// GetEpochPayDetail is the package-level GetEpochPayDetail for the chain of h.
+func (h *Hooks) GetEpochPayDetail(epochID uint64) (payment [][]vm.ClientIncentive, err error) {
+	h.use(func() {
+		payment, err = GetEpochPayDetail(epochID)
+	})
+	return payment, err
+}
+
+// GetTotalIncentive is the package-level GetTotalIncentive for the chain of h.
+func (h *Hooks) GetTotalIncentive() (total *big.Int, err error) {
+	h.use(func() {
+		total, err = GetTotalIncentive()
+	})
+	return total, err
+}
+
+// GetEpochIncentive is the package-level GetEpochIncentive for the chain of h.
+func (h *Hooks) GetEpochIncentive(epochID uint64) (total *big.Int, err error) {
+	h.use(func() {
+		total, err = GetEpochIncentive(epochID)
+	})
+	return total, err
+}
+
+// GetEpochIncentiveBlockNumber is the package-level
+// GetEpochIncentiveBlockNumber for the chain of h.
+func (h *Hooks) GetEpochIncentiveBlockNumber(epochID uint64) (number *big.Int, err error) {
+	h.use(func() {
+		number, err = GetEpochIncentiveBlockNumber(epochID)
+	})
+	return number, err
+}
+
+// GetEpochRemain is the package-level GetEpochRemain for the chain of h.
+func (h *Hooks) GetEpochRemain(epochID uint64) (remain *big.Int, err error) {
+	h.use(func() {
+		remain, err = GetEpochRemain(epochID)
+	})
+	return remain, err
+}
+
+// GetTotalRemain is the package-level GetTotalRemain for the chain of h.
+func (h *Hooks) GetTotalRemain() (remain *big.Int, err error) {
+	h.use(func() {
+		remain, err = GetTotalRemain()
+	})
+	return remain, err
+}
+
+// GetRunTimes is the package-level GetRunTimes for the chain of h.
+func (h *Hooks) GetRunTimes() (times *big.Int, err error) {
+	h.use(func() {
+		times, err = GetRunTimes()
+	})
+	return times, err
+}
+
+// GetRBAddress is the package-level GetRBAddress for the chain of h.
+func (h *Hooks) GetRBAddress(epochID uint64) (addrs []common.Address) {
+	h.use(func() {
+		addrs = GetRBAddress(epochID)
+	})
+	return addrs
+}
+
+// GetIncentivePool is the package-level GetIncentivePool for the chain of h.
+func (h *Hooks) GetIncentivePool(stateDb *state.StateDB, epochID uint64) (total, foundation, gasPool *big.Int) {
+	h.use(func() {
+		total, foundation, gasPool = GetIncentivePool(stateDb, epochID)
+	})
+	return total, foundation, gasPool
+}
+
+// GetEpochLeaderActivity is the package-level GetEpochLeaderActivity for the
+// chain of h.
+func (h *Hooks) GetEpochLeaderActivity(stateDb vm.StateDB, epochID uint64) (addrs []common.Address, activity []int) {
+	h.use(func() {
+		addrs, activity = GetEpochLeaderActivity(stateDb, epochID)
+	})
+	return addrs, activity
+}
+
+// GetEpochRBLeaderActivity is the package-level GetEpochRBLeaderActivity for
+// the chain of h.
+func (h *Hooks) GetEpochRBLeaderActivity(stateDb vm.StateDB, epochID uint64) (addrs []common.Address, activity []int) {
+	h.use(func() {
+		addrs, activity = GetEpochRBLeaderActivity(stateDb, epochID)
+	})
+	return addrs, activity
+}
@@ -151,3 +151,29 @@ func TestSetStakerInterface(t *testing.T) {
 
 	setStakerInterface(getInfo, setInfo)
 }
+
+func TestRunWithHooksRestoresInterfaces(t *testing.T) {
+	setStakerInterface(getInfo, setInfo)
+	defer setStakerInterface(nil, nil)
+
+	called := false
+	hooks := &Hooks{
+		GetStakerInfo: func(uint64, common.Address) (*vm.ValidatorInfo, error) {
+			called = true
+			return nil, errors.New("not used")
+		},
+		SetStakerInfo: func(uint64, [][]vm.ClientIncentive) error { return nil },
+	}
+	if RunWithHooks(hooks, nil, nil, 0) {
+		t.Fatal("run without chain succeeded")
+	}
+	if called {
+		t.Fatal("hooks used without chain")
+	}
+	if fmt.Sprint(getStakerInfo) != fmt.Sprint(GetStakerInfoFn(getInfo)) || setStakerInfo == nil {
+		t.Fatal("staker interface not restored")
+	}
+	if epocher != nil {
+		t.Fatal("epocher not restored")
+	}
+}
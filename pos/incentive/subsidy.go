@@ -4,8 +4,6 @@ import (
 	"math"
 	"math/big"
 
-	"github.com/wanchain/go-wanchain/pos/posconfig"
-
 	"github.com/wanchain/go-wanchain/core/state"
 	"github.com/wanchain/go-wanchain/log"
 )
@@ -30,11 +28,12 @@ func getBaseSubsidyTotalForEpoch(stateDb *state.StateDB, epochID uint64) *big.In
 		return big.NewInt(0)
 	}
 
-	if epochID < posconfig.FirstEpochId {
+	firstEpochId := getConfig().FirstEpochID()
+	if epochID < firstEpochId {
 		return big.NewInt(0)
 	}
 
-	epochIDOffset := epochID - posconfig.FirstEpochId
+	epochIDOffset := epochID - firstEpochId
 
 	baseSubsidy := calcBaseSubsidy(firstPeriodReward)
 
@@ -42,7 +41,7 @@ func getBaseSubsidyTotalForEpoch(stateDb *state.StateDB, epochID uint64) *big.In
 	baseSubsidyReduction := calcPercent(baseSubsidy, redutionRateNow*100.0)
 
 	log.Info("getBaseSubsidyTotalForEpoch",
-		"FirstEpochId", posconfig.FirstEpochId,
+		"FirstEpochId", firstEpochId,
 		"epochID", epochID,
 		"reduceTimes", epochIDOffset/subsidyReductionInterval,
 		"reduceRate", redutionRateNow,
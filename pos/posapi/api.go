@@ -6,9 +6,9 @@ import (
 	"sort"
 	"time"
 
+	"github.com/wanchain/go-wanchain/consensus"
 	"github.com/wanchain/go-wanchain/core/types"
 
-	"github.com/wanchain/go-wanchain/pos/cfm"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 
 	"github.com/wanchain/go-wanchain/params"
@@ -17,6 +17,7 @@ import (
 	"github.com/wanchain/go-wanchain/rlp"
 
 	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/pos/incentive"
 	"github.com/wanchain/go-wanchain/pos/util"
@@ -32,8 +33,9 @@ import (
 	"github.com/wanchain/go-wanchain/internal/ethapi"
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/posdb"
-	"github.com/wanchain/go-wanchain/pos/slotleader"
+	"github.com/wanchain/go-wanchain/pos/posctx"
+	"github.com/wanchain/go-wanchain/pos/postx"
+	"github.com/wanchain/go-wanchain/pos/randombeacon"
 	"github.com/wanchain/go-wanchain/rpc"
 )
 
@@ -62,18 +64,28 @@ type PosChainReader interface {
 
 	//get chain quality,return quality * 1000
 	ChainQuality(epochid uint64, slotid uint64) (uint64, error)
+
+	// Engine retrieves the blockchain's consensus engine.
+	Engine() consensus.Engine
 }
 
 type PosApi struct {
 	chain   PosChainReader
 	backend ethapi.Backend
+	ctx     *posctx.PosContext
 }
 
-func APIs(chain PosChainReader, backend ethapi.Backend) []rpc.API {
+// APIs returns the POS RPC APIs of the chain running the services of ctx.
+func APIs(chain PosChainReader, backend ethapi.Backend, ctx *posctx.PosContext) []rpc.API {
 	return []rpc.API{{
 		Namespace: "pos",
 		Version:   "1.0",
-		Service:   &PosApi{chain, backend},
+		Service:   &PosApi{chain, backend, ctx},
+		Public:    true,
+	}, {
+		Namespace: "wanexplorer",
+		Version:   "1.0",
+		Service:   &ExplorerApi{PosApi{chain, backend, ctx}},
 		Public:    true,
 	}}
 }
@@ -83,10 +95,10 @@ func (a PosApi) Version() string {
 }
 
 func (a PosApi) GetSlotLeaderByEpochIDAndSlotID(epochID uint64, slotID uint64) string {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage."
 	}
-	slp, err := slotleader.GetSlotLeaderSelection().GetSlotLeader(epochID, slotID)
+	slp, err := a.ctx.SLS.GetSlotLeader(epochID, slotID)
 	if err != nil {
 		return err.Error()
 	}
@@ -94,13 +106,13 @@ func (a PosApi) GetSlotLeaderByEpochIDAndSlotID(epochID uint64, slotID uint64) s
 }
 
 func (a PosApi) GetEpochLeadersByEpochID(epochID uint64) (map[string]string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
 
 	infoMap := make(map[string]string, 0)
 
-	selector := epochLeader.GetEpocher()
+	selector := a.ctx.Epocher
 
 	if selector == nil {
 		return nil, errors.New("GetEpocherInst error")
@@ -116,11 +128,11 @@ func (a PosApi) GetEpochLeadersByEpochID(epochID uint64) (map[string]string, err
 }
 
 func (a PosApi) GetEpochLeadersAddrByEpochID(epochID uint64) ([]common.Address, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
 
-	selector := epochLeader.GetEpocher()
+	selector := a.ctx.Epocher
 	if selector == nil {
 		return nil, errors.New("GetEpocherInst error")
 	}
@@ -140,10 +152,10 @@ func (a PosApi) GetEpochLeadersAddrByEpochID(epochID uint64) ([]common.Address,
 	return addres, nil
 }
 func (a PosApi) GetLeaderGroupByEpochID(epochID uint64) ([]LeaderJson, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	selector := epochLeader.GetEpocher()
+	selector := a.ctx.Epocher
 	if selector == nil {
 		return nil, errors.New("GetEpocherInst error")
 	}
@@ -151,10 +163,10 @@ func (a PosApi) GetLeaderGroupByEpochID(epochID uint64) ([]LeaderJson, error) {
 }
 
 func (a PosApi) GetLocalPK() (string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage.", nil
 	}
-	SLS := slotleader.GetSlotLeaderSelection()
+	SLS := a.ctx.SLS
 	if SLS == nil {
 		return "nil", errors.New("This function can not use in POW stage.")
 	}
@@ -167,24 +179,24 @@ func (a PosApi) GetLocalPK() (string, error) {
 }
 
 func (a PosApi) GetBootNodePK() string {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage."
 	}
 	return posconfig.GenesisPK
 }
 
 func (a PosApi) GetSlotScCallTimesByEpochID(epochID uint64) uint64 {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return 0
 	}
-	return vm.GetSlotScCallTimes(epochID)
+	return vm.GetSlotScCallTimes(a.ctx, epochID)
 }
 
 func (a PosApi) GetSmaByEpochID(epochID uint64) (map[string]string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	pks, _, err := slotleader.GetSlotLeaderSelection().GetSma(epochID)
+	pks, _, err := a.ctx.SLS.GetSma(epochID)
 	if err != nil {
 		return nil, err
 	}
@@ -199,10 +211,10 @@ func (a PosApi) GetSmaByEpochID(epochID uint64) (map[string]string, error) {
 }
 
 func (a PosApi) GetRandomProposersByEpochID(epochID uint64) (map[string]string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	selector := epochLeader.GetEpocher()
+	selector := a.ctx.Epocher
 	if selector == nil {
 		return nil, errors.New("GetEpocherInst error")
 	}
@@ -217,10 +229,10 @@ func (a PosApi) GetRandomProposersByEpochID(epochID uint64) (map[string]string,
 }
 
 func (a PosApi) GetRandomProposersAddrByEpochID(epochID uint64) ([]common.Address, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	selector := epochLeader.GetEpocher()
+	selector := a.ctx.Epocher
 	if selector == nil {
 		return nil, errors.New("GetEpocherInst error")
 	}
@@ -235,14 +247,14 @@ func (a PosApi) GetRandomProposersAddrByEpochID(epochID uint64) ([]common.Addres
 }
 
 func (a PosApi) GetSlotCreateStatusByEpochID(epochID uint64) bool {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return false
 	}
-	return slotleader.GetSlotLeaderSelection().GetSlotCreateStatusByEpochID(epochID)
+	return a.ctx.SLS.GetSlotCreateStatusByEpochID(epochID)
 }
 
 func (a PosApi) GetRandom(epochId uint64, blockNr int64) (*big.Int, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
 
@@ -261,7 +273,7 @@ func (a PosApi) GetRandom(epochId uint64, blockNr int64) (*big.Int, error) {
 		return nil, err
 	}
 
-	r := vm.GetStateR(state, epochId)
+	r := vm.GetStateRWithConfig(a.ctx.Config, state, epochId)
 	if r == nil {
 		return nil, errors.New("no random number exists")
 	}
@@ -270,17 +282,17 @@ func (a PosApi) GetRandom(epochId uint64, blockNr int64) (*big.Int, error) {
 }
 
 func (a PosApi) GetChainQuality(epochid uint64, slotid uint64) (uint64, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return 1000, nil
 	}
 	return a.chain.ChainQuality(epochid, slotid)
 }
 
 func (a PosApi) GetReorgState(epochid uint64) ([]uint64, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	reOrgDb := posdb.GetDbByName(posconfig.ReorgLocalDB)
+	reOrgDb := a.ctx.LocalDb(posconfig.ReorgLocalDB)
 	if reOrgDb == nil {
 		return []uint64{0, 0}, nil
 	}
@@ -303,7 +315,7 @@ func (a PosApi) GetReorgState(epochid uint64) ([]uint64, error) {
 }
 
 func (a PosApi) GetRbSignatureCount(epochId uint64, blockNr int64) (int, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return 0, nil
 	}
 
@@ -328,9 +340,27 @@ func (a PosApi) GetRbSignatureCount(epochId uint64, blockNr int64) (int, error)
 	return j, nil
 }
 
+// GetRBFailureReport reports which random proposers of an epoch failed the
+// random beacon stages already over at the head, and why.
+func (a PosApi) GetRBFailureReport(epochId uint64) (*randombeacon.FailureReport, error) {
+	if !a.isPosStage() {
+		return nil, nil
+	}
+
+	state, header, err := a.backend.StateAndHeaderByNumber(context.Background(), rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	curEpochId, curSlotId := util.CalEpSlbyTd(header.Difficulty.Uint64())
+	if epochId > curEpochId {
+		return nil, errors.New("wrong epochId (It hasn't arrived yet.):" + convert.Uint64ToString(epochId))
+	}
+	return randombeacon.GetFailureReport(state, epochId, curEpochId, curSlotId), nil
+}
+
 func (a PosApi) GetEpochStakerInfo(epochID uint64, addr common.Address) (ApiStakerInfo, error) {
 	skInfo := ApiStakerInfo{}
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := a.ctx.Epocher
 	if epocherInst == nil {
 		return skInfo, errors.New("epocher instance does not exist")
 	}
@@ -356,7 +386,7 @@ func (a PosApi) GetEpochStakerInfo(epochID uint64, addr common.Address) (ApiStak
 // this is the static snap of stekers by the block Number.
 func (a PosApi) GetStakerInfo(targetBlkNum uint64) ([]*StakerJson, error) {
 	stakers := make([]*StakerJson, 0)
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := a.ctx.Epocher
 	if epocherInst == nil {
 		return stakers, errors.New("epocher instance do not exist")
 	}
@@ -402,22 +432,22 @@ func (a PosApi) GetStakerInfo(targetBlkNum uint64) ([]*StakerJson, error) {
 	return stakers, nil
 }
 
-func isPosStage() bool {
-	return posconfig.FirstEpochId != 0
+func (a PosApi) isPosStage() bool {
+	return a.ctx.Config.FirstEpochID() != 0
 }
 
 func (a PosApi) GetPosInfo() (info PosInfoJson) {
-	info.FirstEpochId = posconfig.FirstEpochId
+	info.FirstEpochId = a.ctx.Config.FirstEpochID()
 	info.FirstBlockNumber = posconfig.Pow2PosUpgradeBlockNumber
 	return
 }
 
 func (a PosApi) GetEpochStakerInfoAll(epochID uint64) ([]ApiStakerInfo, error) {
-	targetBlkNum := epochLeader.GetEpocher().GetTargetBlkNumber(epochID)
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := a.ctx.Epocher
 	if epocherInst == nil {
 		return nil, errors.New("epocher instance do not exist")
 	}
+	targetBlkNum := epocherInst.GetTargetBlkNumber(epochID)
 	//block := epocherInst.GetBlkChain().GetBlockByNumber(targetBlkNum)
 	block := epocherInst.GetBlkChain().GetHeaderByNumber(targetBlkNum)
 	if block == nil {
@@ -436,7 +466,7 @@ func (a PosApi) GetEpochStakerInfoAll(epochID uint64) ([]ApiStakerInfo, error) {
 			return true
 		}
 
-		infors, pb, err := epochLeader.CalEpochProbabilityStaker(&staker, epochID)
+		infors, pb, err := epocherInst.CalEpochProbabilityStaker(&staker, epochID)
 		if err != nil || pb == nil {
 			// this validator has no enough
 			return true
@@ -469,10 +499,10 @@ func biToString(value *big.Int, err error) (string, error) {
 }
 
 func (a PosApi) GetEpochIncentivePayDetail(epochID uint64) ([]ValidatorInfo, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	c, err := incentive.GetEpochPayDetail(epochID)
+	c, err := a.ctx.Incentive.GetEpochPayDetail(epochID)
 	if err != nil {
 		return []ValidatorInfo{}, nil
 	}
@@ -505,37 +535,37 @@ func (a PosApi) GetEpochIncentivePayDetail(epochID uint64) ([]ValidatorInfo, err
 }
 
 func (a PosApi) GetTotalIncentive() (string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage.", nil
 	}
-	return biToString(incentive.GetTotalIncentive())
+	return biToString(a.ctx.Incentive.GetTotalIncentive())
 }
 func (a PosApi) GetEpochIncentiveBlockNumber(epochID uint64) (uint64, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return 0, nil
 	}
-	number, err := incentive.GetEpochIncentiveBlockNumber(epochID)
+	number, err := a.ctx.Incentive.GetEpochIncentiveBlockNumber(epochID)
 	if err == nil {
 		return number.Uint64(), nil
 	}
 	return 0, err
 }
 func (a PosApi) GetEpochIncentive(epochID uint64) (string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage.", nil
 	}
-	return biToString(incentive.GetEpochIncentive(epochID))
+	return biToString(a.ctx.Incentive.GetEpochIncentive(epochID))
 }
 
 func (a PosApi) GetEpochRemain(epochID uint64) (string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage.", nil
 	}
-	return biToString(incentive.GetEpochRemain(epochID))
+	return biToString(a.ctx.Incentive.GetEpochRemain(epochID))
 }
 
 func (a PosApi) GetWhiteListConfig() ([]vm.UpgradeWhiteEpochLeaderParam, error) {
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := a.ctx.Epocher
 	infos := make(vm.WhiteInfos, 0)
 	if epocherInst == nil {
 		return infos, errors.New("epocher instance do not exist")
@@ -563,7 +593,7 @@ func (a PosApi) GetWhiteListConfig() ([]vm.UpgradeWhiteEpochLeaderParam, error)
 }
 
 func (a PosApi) GetWhiteListbyEpochID(epochID uint64) ([]string, error) {
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := a.ctx.Epocher
 	if epocherInst == nil {
 		return make([]string, 0), errors.New("epocher instance do not exist")
 	}
@@ -571,24 +601,24 @@ func (a PosApi) GetWhiteListbyEpochID(epochID uint64) ([]string, error) {
 }
 
 func (a PosApi) GetTotalRemain() (string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage.", nil
 	}
-	return biToString(incentive.GetTotalRemain())
+	return biToString(a.ctx.Incentive.GetTotalRemain())
 }
 
 func (a PosApi) GetIncentiveRunTimes() (string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage.", nil
 	}
-	return biToString(incentive.GetRunTimes())
+	return biToString(a.ctx.Incentive.GetRunTimes())
 }
 
 func (a PosApi) GetEpochGasPool(epochID uint64) (string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return "Not POS stage.", nil
 	}
-	s := slotleader.GetSlotLeaderSelection()
+	s := a.ctx.SLS
 	db, err := s.GetCurrentStateDb()
 	if err != nil {
 		return "", err
@@ -597,66 +627,66 @@ func (a PosApi) GetEpochGasPool(epochID uint64) (string, error) {
 }
 
 func (a PosApi) GetRBAddress(epochID uint64) []common.Address {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil
 	}
-	return incentive.GetRBAddress(epochID)
+	return a.ctx.Incentive.GetRBAddress(epochID)
 }
 
 func (a PosApi) GetIncentivePool(epochID uint64) ([]string, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	s := slotleader.GetSlotLeaderSelection()
+	s := a.ctx.SLS
 	db, err := s.GetCurrentStateDb()
 	if err != nil {
 		return nil, err
 	}
-	total, foundation, gasPool := incentive.GetIncentivePool(db, epochID)
+	total, foundation, gasPool := a.ctx.Incentive.GetIncentivePool(db, epochID)
 	return []string{total.String(), foundation.String(), gasPool.String()}, nil
 }
 
 // GetActivity get epoch leader, random proposer, slot leader 's addresses and activity
 func (a PosApi) GetActivity(epochID uint64) (*Activity, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	s := slotleader.GetSlotLeaderSelection()
+	s := a.ctx.SLS
 	db, err := s.GetCurrentStateDb()
 	if err != nil {
 		return nil, err
 	}
 
 	activity := Activity{}
-	activity.EpLeader, activity.EpActivity = incentive.GetEpochLeaderActivity(db, epochID)
-	activity.RpLeader, activity.RpActivity = incentive.GetEpochRBLeaderActivity(db, epochID)
+	activity.EpLeader, activity.EpActivity = a.ctx.Incentive.GetEpochLeaderActivity(db, epochID)
+	activity.RpLeader, activity.RpActivity = a.ctx.Incentive.GetEpochRBLeaderActivity(db, epochID)
 	activity.SltLeader, activity.SlBlocks, activity.SlActivity, activity.SlCtrlCount = incentive.GetSlotLeaderActivity(s.GetChainReader(), epochID)
 	return &activity, nil
 }
 
 // GetEpRnpActivity get epoch leader, random leader proposer activity
 func (a PosApi) GetEpRnpActivity(epochID uint64) (*EpRnpActivity, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	s := slotleader.GetSlotLeaderSelection()
+	s := a.ctx.SLS
 	db, err := s.GetCurrentStateDb()
 	if err != nil {
 		return nil, err
 	}
 
 	activity := EpRnpActivity{}
-	activity.EpLeader, activity.EpActivity = incentive.GetEpochLeaderActivity(db, epochID)
-	activity.RpLeader, activity.RpActivity = incentive.GetEpochRBLeaderActivity(db, epochID)
+	activity.EpLeader, activity.EpActivity = a.ctx.Incentive.GetEpochLeaderActivity(db, epochID)
+	activity.RpLeader, activity.RpActivity = a.ctx.Incentive.GetEpochRBLeaderActivity(db, epochID)
 	return &activity, nil
 }
 
 // GetSlotActivity get slot activity of epoch
 func (a PosApi) GetSlotActivity(epochID uint64) (*SlotActivity, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
-	s := slotleader.GetSlotLeaderSelection()
+	s := a.ctx.SLS
 	activity := SlotActivity{}
 	activity.SltLeader, activity.SlBlocks, activity.SlActivity, activity.SlCtrlCount = incentive.GetSlotLeaderActivity(s.GetChainReader(), epochID)
 	return &activity, nil
@@ -664,7 +694,7 @@ func (a PosApi) GetSlotActivity(epochID uint64) (*SlotActivity, error) {
 
 // GetValidatorActivity get epoch leader, random proposer addresses and activity
 func (a PosApi) GetValidatorActivity(epochID uint64) (*ValidatorActivity, error) {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return nil, nil
 	}
 	epID := a.GetEpochID()
@@ -672,15 +702,15 @@ func (a PosApi) GetValidatorActivity(epochID uint64) (*ValidatorActivity, error)
 		return nil, nil
 	}
 
-	s := slotleader.GetSlotLeaderSelection()
+	s := a.ctx.SLS
 	db, err := s.GetCurrentStateDb()
 	if err != nil {
 		return nil, err
 	}
 
 	activity := ValidatorActivity{}
-	activity.EpLeader, activity.EpActivity = incentive.GetEpochLeaderActivity(db, epochID)
-	activity.RpLeader, activity.RpActivity = incentive.GetEpochRBLeaderActivity(db, epochID)
+	activity.EpLeader, activity.EpActivity = a.ctx.Incentive.GetEpochLeaderActivity(db, epochID)
+	activity.RpLeader, activity.RpActivity = a.ctx.Incentive.GetEpochRBLeaderActivity(db, epochID)
 	if len(activity.EpLeader) == 0 &&
 		len(activity.EpActivity) == 0 &&
 		len(activity.RpLeader) == 0 &&
@@ -709,18 +739,47 @@ func (a PosApi) GetSlotTime() int {
 	return posconfig.SlotTime
 }
 
+// GetProtocolTxStatus returns the slot leader selection and random beacon
+// transactions sent by the local node in an epoch, and whether they got mined.
+func (a PosApi) GetProtocolTxStatus(epochID uint64) ([]ProtocolTxStatus, error) {
+	submitter := a.ctx.Submitter
+	if submitter == nil {
+		return nil, postx.ErrNotReady
+	}
+	records := submitter.Records(epochID)
+	ret := make([]ProtocolTxStatus, len(records))
+	for i, rec := range records {
+		ret[i] = ProtocolTxStatus{
+			Kind:        rec.Kind.String(),
+			EpochID:     rec.EpochID,
+			Index:       rec.Index,
+			Status:      rec.Status.String(),
+			Deadline:    rec.Kind.Deadline(),
+			Nonce:       hexutil.Uint64(rec.Nonce),
+			GasPrice:    (*hexutil.Big)(rec.GasPrice),
+			TxHashes:    rec.Hashes,
+			Attempts:    rec.Attempts,
+			BlockNumber: rec.BlockNumber,
+		}
+		if rec.Err != nil {
+			ret[i].Error = rec.Err.Error()
+		}
+	}
+	return ret, nil
+}
+
 func (a PosApi) GetMaxStableBlkNumber() uint64 {
-	if !isPosStage() {
+	if !a.isPosStage() {
 		return 0
 	}
-	return cfm.GetCFM().GetMaxStableBlkNumber()
+	return a.ctx.CFM.GetMaxStableBlkNumber()
 }
 
 // CalProbability use to calc the probability of a staker with amount by stake wan coins.
 // The probability is different in different time, so you should input each epoch ID you want to calc
 // Such as CalProbability(390, 10000, 60, 360) means begin from epoch 360 lock 60 epochs stake 10000 to calc 390's probability.
 func (a PosApi) CalProbability(amountCoin uint64, lockTime uint64) (string, error) {
-	epocherInst := epochLeader.GetEpocher()
+	epocherInst := a.ctx.Epocher
 	if epocherInst == nil {
 		return "", errors.New("epocher instance do not exist")
 	}
@@ -932,7 +991,7 @@ func (a PosApi) GetEpochIdByBlockNumber(blockNumber uint64) uint64 {
 }
 
 func (a PosApi) GetEpochStakeOut(epochID uint64) ([]RefundInfo, error) {
-	stakeOutByte, err := posdb.GetDb().Get(epochID, posconfig.StakeOutEpochKey)
+	stakeOutByte, err := a.ctx.LocalDb(posconfig.PosLocalDB).Get(epochID, posconfig.StakeOutEpochKey)
 	if err != nil {
 		//return nil, err
 		info := make([]RefundInfo, 0)
@@ -950,7 +1009,7 @@ func (a PosApi) GetEpochStakeOut(epochID uint64) ([]RefundInfo, error) {
 // GetTps used to get tps value
 func (a PosApi) GetTps(fromNumber uint64, toNumber uint64) (string, error) {
 	sRet := fmt.Sprintf("Get tps from %d to %d, ", fromNumber, toNumber)
-	s := slotleader.GetSlotLeaderSelection()
+	s := a.ctx.SLS
 	reader := s.GetChainReader()
 
 	totalTx := uint64(0)
@@ -0,0 +1,230 @@
+package posapi
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/rpc"
+)
+
+const (
+	// maxExplorerBlocks is the most blocks returned by one GetBlocks call.
+	maxExplorerBlocks = 100
+	// maxExplorerEpochs is the most epochs returned by one GetEpochs call.
+	maxExplorerEpochs = 10
+)
+
+var errExplorerCount = errors.New("count must be greater than 0")
+
+// ExplorerApi serves the wanexplorer namespace. It returns blocks enriched
+// with their POS details and per epoch summaries, so explorers don't have to
+// stitch them together from the eth and pos namespaces.
+type ExplorerApi struct {
+	pos PosApi
+}
+
+// GetBlock returns the explorer view of the block with the given number.
+func (e ExplorerApi) GetBlock(number rpc.BlockNumber) (*ExplorerBlock, error) {
+	block, err := e.pos.backend.BlockByNumber(context.Background(), number)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, nil
+	}
+	return e.toExplorerBlock(block), nil
+}
+
+// GetBlocks returns the explorer view of up to count blocks starting at from.
+// It stops at the head block and returns at most maxExplorerBlocks blocks.
+func (e ExplorerApi) GetBlocks(from uint64, count uint64) ([]*ExplorerBlock, error) {
+	if count == 0 {
+		return nil, errExplorerCount
+	}
+	if count > maxExplorerBlocks {
+		count = maxExplorerBlocks
+	}
+	head := e.pos.chain.CurrentHeader().Number.Uint64()
+	blocks := make([]*ExplorerBlock, 0, count)
+	for number := from; number <= head && number-from < count; number++ {
+		block, err := e.pos.backend.BlockByNumber(context.Background(), rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			break
+		}
+		blocks = append(blocks, e.toExplorerBlock(block))
+	}
+	return blocks, nil
+}
+
+// GetEpoch returns the summary of an epoch.
+func (e ExplorerApi) GetEpoch(epochID uint64) (*ExplorerEpoch, error) {
+	if !e.pos.isPosStage() {
+		return nil, nil
+	}
+	return e.epochSummary(epochID)
+}
+
+// GetEpochs returns the summaries of up to count epochs starting at from. It
+// stops at the current epoch and returns at most maxExplorerEpochs epochs.
+func (e ExplorerApi) GetEpochs(from uint64, count uint64) ([]*ExplorerEpoch, error) {
+	if count == 0 {
+		return nil, errExplorerCount
+	}
+	if !e.pos.isPosStage() {
+		return nil, nil
+	}
+	if count > maxExplorerEpochs {
+		count = maxExplorerEpochs
+	}
+	current, _ := util.GetEpochSlotIDFromDifficulty(e.pos.chain.CurrentHeader().Difficulty)
+	epochs := make([]*ExplorerEpoch, 0, count)
+	for epochID := from; epochID <= current && epochID-from < count; epochID++ {
+		epoch, err := e.epochSummary(epochID)
+		if err != nil {
+			return nil, err
+		}
+		epochs = append(epochs, epoch)
+	}
+	return epochs, nil
+}
+
+func (e ExplorerApi) toExplorerBlock(block *types.Block) *ExplorerBlock {
+	header := block.Header()
+	ret := &ExplorerBlock{
+		Number:     block.NumberU64(),
+		Hash:       block.Hash(),
+		ParentHash: block.ParentHash(),
+		Timestamp:  block.Time().Uint64(),
+		Pos:        util.IsPosBlock(block.NumberU64()),
+		TxCount:    len(block.Transactions()),
+		TxCounts:   make(map[string]int),
+		Calls:      make([]ExplorerCall, 0),
+	}
+	if ret.Pos {
+		ret.EpochID, ret.SlotID = util.GetEpochSlotIDFromDifficulty(header.Difficulty)
+	}
+	if leader, err := e.pos.chain.Engine().Author(header); err == nil {
+		ret.SlotLeader = leader
+	}
+	if c := e.pos.ctx.CFM; c != nil {
+		ret.Stable = block.NumberU64() <= c.GetMaxStableBlkNumber()
+	}
+
+	for i, tx := range block.Transactions() {
+		ret.TxCounts[txTypeName(tx.Txtype())]++
+		if tx.To() == nil || !vm.IsAbiPrecompile(*tx.To()) {
+			continue
+		}
+		call := ExplorerCall{
+			TxHash:   tx.Hash(),
+			TxIndex:  uint64(i),
+			Contract: *tx.To(),
+		}
+		decoded, err := vm.DecodePrecompileCall(*tx.To(), tx.Data())
+		if err != nil {
+			call.Error = err.Error()
+		} else {
+			call.Name = decoded.Contract
+			call.Method = decoded.Method
+			call.Args = decoded.Args
+		}
+		ret.Calls = append(ret.Calls, call)
+	}
+	return ret
+}
+
+func (e ExplorerApi) epochSummary(epochID uint64) (*ExplorerEpoch, error) {
+	ret := &ExplorerEpoch{EpochID: epochID}
+
+	var err error
+	if ret.EpochLeaders, err = e.pos.GetEpochLeadersAddrByEpochID(epochID); err != nil {
+		return nil, err
+	}
+	if ret.RandomProposers, err = e.pos.GetRandomProposersAddrByEpochID(epochID); err != nil {
+		return nil, err
+	}
+	// The random number of an epoch that hasn't arrived yet is left out.
+	if r, err := e.pos.GetRandom(epochID, -1); err == nil {
+		ret.Random = (*hexutil.Big)(r)
+	}
+	if ret.BlockCount, err = e.pos.GetEpochBlkCnt(epochID); err != nil {
+		return nil, err
+	}
+	if total, err := e.pos.ctx.Incentive.GetEpochIncentive(epochID); err == nil && total != nil {
+		ret.Incentive = (*hexutil.Big)(total)
+	}
+	if ret.Incentives, err = e.pos.GetEpochIncentivePayDetail(epochID); err != nil {
+		return nil, err
+	}
+	reorg, err := e.pos.GetReorgState(epochID)
+	if err != nil {
+		return nil, err
+	}
+	if len(reorg) == 2 {
+		ret.ReorgCount, ret.ReorgLength = reorg[0], reorg[1]
+	}
+	return ret, nil
+}
+
+func txTypeName(txType uint64) string {
+	switch txType {
+	case types.NORMAL_TX:
+		return "normal"
+	case types.PRIVACY_TX:
+		return "privacy"
+	case types.POS_TX:
+		return "pos"
+	}
+	return strconv.FormatUint(txType, 10)
+}
+
+// ExplorerBlock is a block with its POS details, as served by the wanexplorer
+// namespace.
+type ExplorerBlock struct {
+	Number     uint64         `json:"number"`
+	Hash       common.Hash    `json:"hash"`
+	ParentHash common.Hash    `json:"parentHash"`
+	Timestamp  uint64         `json:"timestamp"`
+	Pos        bool           `json:"pos"`
+	EpochID    uint64         `json:"epochId"`
+	SlotID     uint64         `json:"slotId"`
+	SlotLeader common.Address `json:"slotLeader"`
+	Stable     bool           `json:"stable"`
+	TxCount    int            `json:"txCount"`
+	TxCounts   map[string]int `json:"txCounts"`
+	Calls      []ExplorerCall `json:"precompileCalls"`
+}
+
+// ExplorerCall is a transaction calling a precompiled contract, with its input
+// decoded.
+type ExplorerCall struct {
+	TxHash   common.Hash            `json:"txHash"`
+	TxIndex  uint64                 `json:"txIndex"`
+	Contract common.Address         `json:"contract"`
+	Name     string                 `json:"name,omitempty"`
+	Method   string                 `json:"method,omitempty"`
+	Args     map[string]interface{} `json:"args,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// ExplorerEpoch is the summary of an epoch served by the wanexplorer namespace.
+type ExplorerEpoch struct {
+	EpochID         uint64           `json:"epochId"`
+	EpochLeaders    []common.Address `json:"epochLeaders"`
+	RandomProposers []common.Address `json:"randomProposers"`
+	Random          *hexutil.Big     `json:"random"`
+	BlockCount      uint64           `json:"blockCount"`
+	Incentive       *hexutil.Big     `json:"incentive"`
+	Incentives      []ValidatorInfo  `json:"incentives"`
+	ReorgCount      uint64           `json:"reorgCount"`
+	ReorgLength     uint64           `json:"reorgLength"`
+}
@@ -178,3 +178,17 @@ func ToStakerJson(staker *vm.StakerInfo) *StakerJson {
 
 	return &stakeJson
 }
+
+type ProtocolTxStatus struct {
+	Kind        string         `json:"kind"`
+	EpochID     uint64         `json:"epochId"`
+	Index       uint64         `json:"index"`
+	Status      string         `json:"status"`
+	Deadline    uint64         `json:"deadline"`
+	Nonce       hexutil.Uint64 `json:"nonce"`
+	GasPrice    *hexutil.Big   `json:"gasPrice"`
+	TxHashes    []common.Hash  `json:"txHashes"`
+	Attempts    int            `json:"attempts"`
+	BlockNumber uint64         `json:"blockNumber"`
+	Error       string         `json:"error,omitempty"`
+}
@@ -3,6 +3,7 @@ package posconfig
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"errors"
 	"math/big"
 
 	"github.com/wanchain/go-wanchain/accounts/keystore"
@@ -12,6 +13,7 @@ import (
 	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
 
 	"github.com/wanchain/go-wanchain/node"
+	"github.com/wanchain/go-wanchain/params"
 )
 
 var (
@@ -33,10 +35,6 @@ const (
 	PosLocalDB       = "pos"
 	IncentiveLocalDB = "incentive"
 	ReorgLocalDB     = "forkdb"
-	ApolloEpochID     = 18104
-	AugustEpochID     = 18116  //TODO change it as mainnet 8.8
-
-
 )
 
 var EpochLeadersHold [][]byte
@@ -95,9 +93,6 @@ const (
 	CriticalReorgThreshold  = 3
 	CriticalChainQuality    = 0.618
 	NonCriticalChainQuality = 0.8
-
-	MainnetMercuryEpochId = 18250 //2019.12.20
-	TestnetMercuryEpochId = 18246 //2019.12.16
 )
 
 var TxDelay = K
@@ -126,7 +121,12 @@ type Config struct {
 	SignBegin     uint64
 	SignEnd       uint64
 
-	MercuryEpochId uint64
+	// ChainConfig holds the POS fork schedule for the checks made without a
+	// chain at hand. When unset, as in tests, TestChainConfig's applies.
+	ChainConfig *params.ChainConfig
+
+	firstEpochId uint64 // see FirstEpochID
+	global       bool   // see SetGlobal
 }
 
 var DefaultConfig = Config{
@@ -143,13 +143,66 @@ var DefaultConfig = Config{
 	Stage6K - 1,
 	Stage8K,
 	Stage10K - 1,
+	nil,
 	0,
+	false,
 }
 
 func Cfg() *Config {
 	return &DefaultConfig
 }
 
+// FirstEpochID returns the epoch of the first POS block of the chain, 0 before
+// the switch to POS. DefaultConfig keeps it in the FirstEpochId variable.
+func (c *Config) FirstEpochID() uint64 {
+	if c == &DefaultConfig {
+		return FirstEpochId
+	}
+	return c.firstEpochId
+}
+
+// SetFirstEpochID sets the epoch of the first POS block of the chain.
+func (c *Config) SetFirstEpochID(epochID uint64) {
+	if c == &DefaultConfig {
+		FirstEpochId = epochID
+		return
+	}
+	c.firstEpochId = epochID
+	if c.global {
+		FirstEpochId = epochID
+	}
+}
+
+// SetGlobal makes c keep FirstEpochId and DefaultConfig.ChainConfig in step
+// with its own values, for the code that has no chain at hand.
+func (c *Config) SetGlobal(global bool) {
+	if c == &DefaultConfig {
+		return
+	}
+	c.global = global
+	if global {
+		DefaultConfig.ChainConfig = c.ChainConfig
+		FirstEpochId = c.firstEpochId
+	}
+}
+
+func (c *Config) chainConfig() *params.ChainConfig {
+	if c.ChainConfig == nil {
+		return params.TestChainConfig
+	}
+	return c.ChainConfig
+}
+
+// IsApollo returns whether the Apollo fork is active at epochID.
+func (c *Config) IsApollo(epochID uint64) bool {
+	return c.chainConfig().IsApollo(epochID)
+}
+
+// IsMercury returns whether the Mercury fork is active at epochID.
+func (c *Config) IsMercury(epochID uint64) bool {
+	return c.chainConfig().IsMercury(epochID)
+}
+
 func (c *Config) GetMinerAddr() common.Address {
 	if c.MinerKey == nil {
 		return common.Address{}
@@ -188,9 +241,6 @@ func Init(nodeCfg *node.Config, networkId uint64) {
 		// this is mainnet. *****
 		WhiteList = WhiteListMainnet
 		PosOwnerAddr = PosOwnerAddrMainnet
-
-		DefaultConfig.MercuryEpochId = MainnetMercuryEpochId
-
 	} else if networkId == 6 {
 		PosOwnerAddr = PosOwnerAddrInternal
 		if IsDev { // --plutodev
@@ -201,12 +251,9 @@ func Init(nodeCfg *node.Config, networkId uint64) {
 	} else if networkId == 4 {
 		PosOwnerAddr = PosOwnerAddrInternal
 		WhiteList = WhiteListOrig
-		DefaultConfig.MercuryEpochId = TestnetMercuryEpochId
 	} else { // testnet
 		PosOwnerAddr = PosOwnerAddrTestnet
 		WhiteList = WhiteListTestnet
-
-		DefaultConfig.MercuryEpochId = TestnetMercuryEpochId
 	}
 
 	EpochLeadersHold = make([][]byte, len(WhiteList))
@@ -216,6 +263,31 @@ func Init(nodeCfg *node.Config, networkId uint64) {
 	DefaultConfig.NodeCfg = nodeCfg
 }
 
+// SetWhiteList replaces the epoch leader white list with the secp256k1 public
+// keys pks, repeated to fill every entry of WhiteList.
+func SetWhiteList(pks []string) error {
+	if len(pks) == 0 {
+		return errors.New("empty white list")
+	}
+	for _, pk := range pks {
+		b, err := hexutil.Decode(pk)
+		if err != nil {
+			return err
+		}
+		if crypto.ToECDSAPub(b) == nil {
+			return errors.New("invalid white list public key " + pk)
+		}
+	}
+	for i := range WhiteList {
+		WhiteList[i] = pks[i%len(pks)]
+	}
+	EpochLeadersHold = make([][]byte, len(WhiteList))
+	for i := 0; i < len(WhiteList); i++ {
+		EpochLeadersHold[i] = hexutil.MustDecode(WhiteList[i])
+	}
+	return nil
+}
+
 func GetRandomGenesis() *big.Int {
 	return new(big.Int).SetBytes(crypto.Keccak256(big.NewInt(1).Bytes()))
 }
@@ -0,0 +1,276 @@
+// Package posctx bundles the POS services of one chain into a PosContext, so
+// that several chains can run in the same process.
+package posctx
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/cfm"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/incentive"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/postx"
+	"github.com/wanchain/go-wanchain/pos/randombeacon"
+	"github.com/wanchain/go-wanchain/pos/slotleader"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+var errNoBlockChain = errors.New("pos context needs a block chain")
+
+// localDbs are the local databases every context opens.
+var localDbs = []string{
+	posconfig.PosLocalDB,
+	posconfig.RbLocalDB,
+	posconfig.EpLocalDB,
+	posconfig.StakerLocalDB,
+	posconfig.IncentiveLocalDB,
+	posconfig.ReorgLocalDB,
+}
+
+var (
+	ownerMu sync.Mutex
+	owner   *PosContext // context whose services the package-level getters return
+)
+
+// PosContext holds the POS services of a single chain. The precompiled
+// contracts and the RPC APIs of the chain are given the context, the
+// package-level getters of the pos packages (slotleader.GetSlotLeaderSelection,
+// randombeacon.GetRandonBeaconInst, cfm.GetCFM, ...) only return the services
+// of the context that activated first, see Activate.
+type PosContext struct {
+	Config *posconfig.Config
+
+	Epocher   *epochLeader.Epocher
+	CFM       *cfm.CFM
+	SLS       *slotleader.SLS
+	RB        *randombeacon.RandomBeacon
+	Incentive *incentive.Hooks
+
+	// Submitter sends the protocol transactions of the chain, nil until the
+	// node starts mining.
+	Submitter *postx.Submitter
+	// TxOptions configure the protocol transaction submitter created when
+	// the node starts mining.
+	TxOptions []postx.Option
+
+	dir string
+	tmp bool // dir was created by New and is removed by Close
+	mu  sync.Mutex
+	dbs map[string]*posdb.Db
+}
+
+// New creates a POS context that keeps its local databases in dir, or in a
+// temporary directory removed by Close if dir is empty.
+func New(config *posconfig.Config, dir string) (*PosContext, error) {
+	if config == nil {
+		config = posconfig.Cfg()
+	}
+	c := &PosContext{
+		Config: config,
+		dir:    dir,
+		dbs:    make(map[string]*posdb.Db),
+	}
+	if dir == "" {
+		tmp, err := ioutil.TempDir("", "wanpos_ctx_")
+		if err != nil {
+			return nil, err
+		}
+		c.dir, c.tmp = tmp, true
+	}
+	for _, name := range localDbs {
+		if _, err := c.Db(name); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Db returns the local database called name, opening it on first use.
+func (c *PosContext) Db(name string) (*posdb.Db, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if db, ok := c.dbs[name]; ok {
+		return db, nil
+	}
+	db, err := posdb.OpenDb(filepath.Join(c.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	c.dbs[name] = db
+	return db, nil
+}
+
+// PosConfig implements vm.PosContext.
+func (c *PosContext) PosConfig() *posconfig.Config {
+	return c.Config
+}
+
+// SelectLead implements vm.PosContext.
+func (c *PosContext) SelectLead() util.SelectLead {
+	if c.Epocher == nil {
+		return nil
+	}
+	return c.Epocher
+}
+
+// LocalDb implements vm.PosContext.
+func (c *PosContext) LocalDb(name string) *posdb.Db {
+	db, err := c.Db(name)
+	if err != nil {
+		log.Error("Failed to open pos local database", "name", name, "err", err)
+	}
+	return db
+}
+
+// Init creates the POS services of bc, which must run with c as its
+// PosContext. The services are installed as the package-level instances
+// only if c owns them, see Activate.
+func (c *PosContext) Init(bc *core.BlockChain) error {
+	if bc == nil {
+		return errNoBlockChain
+	}
+
+	posDb, err := c.Db(posconfig.PosLocalDB)
+	if err != nil {
+		return err
+	}
+	rbDb, err := c.Db(posconfig.RbLocalDB)
+	if err != nil {
+		return err
+	}
+	epDb, err := c.Db(posconfig.EpLocalDB)
+	if err != nil {
+		return err
+	}
+	incentiveDb, err := c.Db(posconfig.IncentiveLocalDB)
+	if err != nil {
+		return err
+	}
+
+	c.Epocher = epochLeader.NewEpocherWithDb(bc, rbDb, epDb)
+	// Set to epochID 0 to get a default leaders for epoch 0.
+	if err := c.Epocher.SelectLeadersLoop(0); err != nil {
+		return err
+	}
+
+	c.CFM = cfm.NewCFM(bc)
+
+	c.SLS = slotleader.NewSLS(posDb, c.Epocher)
+	c.SLS.Init(bc, nil)
+
+	// The random beacon loop survives a re-initialisation, e.g. on the
+	// switch from PoW to POS while mining.
+	if c.RB == nil {
+		c.RB = randombeacon.NewRandomBeacon(posDb)
+	}
+	if c.Submitter != nil {
+		c.SLS.SetSubmitter(c.Submitter)
+		c.RB.SetSubmitter(c.Submitter)
+	}
+
+	c.Incentive = &incentive.Hooks{
+		GetStakerInfo:            c.Epocher.GetEpochProbability,
+		SetStakerInfo:            c.Epocher.SetEpochIncentive,
+		GetRandomProposerAddress: c.Epocher.GetRBProposerGroup,
+		Epocher:                  c.Epocher,
+		Db:                       incentiveDb,
+		Config:                   c.Config,
+	}
+
+	ownerMu.Lock()
+	defer ownerMu.Unlock()
+	if owner == c {
+		c.install()
+	}
+	return nil
+}
+
+// SetSubmitter makes the services of c send their protocol transactions
+// through s.
+func (c *PosContext) SetSubmitter(s *postx.Submitter) {
+	c.Submitter = s
+	if c.SLS != nil {
+		c.SLS.SetSubmitter(s)
+	}
+	if c.RB != nil {
+		c.RB.SetSubmitter(s)
+	}
+
+	ownerMu.Lock()
+	defer ownerMu.Unlock()
+	if owner == c {
+		postx.SetSubmitter(s)
+	}
+}
+
+// Activate makes c the owner of the package-level instances, read by the code
+// that has no chain at hand, unless another open context owns them already.
+// The owner's databases are the ones of posdb, and its configuration keeps
+// posconfig.FirstEpochId and Cfg().ChainConfig up to date. It reports whether
+// c owns the package-level instances.
+func (c *PosContext) Activate() bool {
+	ownerMu.Lock()
+	defer ownerMu.Unlock()
+
+	if owner != nil && owner != c {
+		return false
+	}
+	owner = c
+
+	c.mu.Lock()
+	for name, db := range c.dbs {
+		posdb.Register(name, db)
+	}
+	c.mu.Unlock()
+
+	c.Config.SetGlobal(true)
+	if c.Epocher != nil {
+		c.install()
+	}
+	if c.Submitter != nil {
+		postx.SetSubmitter(c.Submitter)
+	}
+	return true
+}
+
+// install sets the services of c as the package-level instances.
+func (c *PosContext) install() {
+	epochLeader.SetEpocher(c.Epocher)
+	cfm.SetCFM(c.CFM)
+	slotleader.SetSlotLeaderSelection(c.SLS)
+	randombeacon.SetRandomBeaconInst(c.RB)
+	incentive.Init(c.Incentive.GetStakerInfo, c.Incentive.SetStakerInfo, c.Incentive.GetRandomProposerAddress)
+}
+
+// Close closes the databases opened by the context and gives up the
+// package-level instances if c owns them, unregistering its databases from
+// posdb.
+func (c *PosContext) Close() {
+	ownerMu.Lock()
+	if owner == c {
+		owner = nil
+		c.Config.SetGlobal(false)
+	}
+	ownerMu.Unlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, db := range c.dbs {
+		posdb.Unregister(name, db)
+		db.DbClose()
+		delete(c.dbs, name)
+	}
+	if c.tmp {
+		os.RemoveAll(c.dir)
+	}
+}
@@ -0,0 +1,134 @@
+package posctx
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+)
+
+func TestContextDbsAreSeparate(t *testing.T) {
+	dir1, err := ioutil.TempDir("", "posctx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir1)
+	dir2, err := ioutil.TempDir("", "posctx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir2)
+
+	ctx1, err := New(nil, dir1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx1.Close()
+	ctx2, err := New(nil, dir2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx2.Close()
+
+	db1, err := ctx1.Db(posconfig.PosLocalDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := ctx1.Db(posconfig.PosLocalDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != db1 {
+		t.Fatal("Db opened the same database twice")
+	}
+	db2, err := ctx2.Db(posconfig.PosLocalDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db1.Put(1, "key", []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := db1.Get(1, "key"); err != nil || !bytes.Equal(got, []byte("one")) {
+		t.Fatalf("db1 value mismatch: %x, %v", got, err)
+	}
+	if got, err := db2.Get(1, "key"); err == nil {
+		t.Fatalf("value leaked into another context: %x", got)
+	}
+}
+
+func TestContextInitNeedsChain(t *testing.T) {
+	ctx, err := New(nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx.Close()
+
+	if err := ctx.Init(nil); err != errNoBlockChain {
+		t.Fatalf("error mismatch: have %v, want %v", err, errNoBlockChain)
+	}
+	if ctx.Config != posconfig.Cfg() {
+		t.Fatal("nil config does not default to posconfig.Cfg()")
+	}
+}
+
+func TestContextTempDir(t *testing.T) {
+	ctx, err := New(nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := ctx.dir
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatalf("temporary directory missing: %v", err)
+	}
+	ctx.Close()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("temporary directory left behind: %v", err)
+	}
+}
+
+func TestContextActivateOwner(t *testing.T) {
+	cfg1, cfg2 := *posconfig.Cfg(), *posconfig.Cfg()
+	ctx1, err := New(&cfg1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx1.Close()
+	ctx2, err := New(&cfg2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ctx2.Close()
+
+	if !ctx1.Activate() {
+		t.Fatal("first context does not own the package-level instances")
+	}
+	if ctx2.Activate() {
+		t.Fatal("second context took over the package-level instances")
+	}
+	if posdb.GetDb() != ctx1.LocalDb(posconfig.PosLocalDB) {
+		t.Fatal("owner database not installed")
+	}
+
+	defer func(old uint64) { posconfig.FirstEpochId = old }(posconfig.FirstEpochId)
+	cfg1.SetFirstEpochID(7)
+	cfg2.SetFirstEpochID(9)
+	if posconfig.FirstEpochId != 7 || cfg2.FirstEpochID() != 9 {
+		t.Fatalf("first epoch mismatch: global %d, second %d", posconfig.FirstEpochId, cfg2.FirstEpochID())
+	}
+
+	closed := ctx1.LocalDb(posconfig.EpLocalDB)
+	ctx1.Close()
+	if posdb.GetDb() == nil || posdb.GetDbByName(posconfig.EpLocalDB) == closed {
+		t.Fatal("closed owner databases still registered")
+	}
+	if !ctx2.Activate() {
+		t.Fatal("closed owner did not release the package-level instances")
+	}
+	if posdb.GetDbByName(posconfig.EpLocalDB) != ctx2.LocalDb(posconfig.EpLocalDB) {
+		t.Fatal("new owner databases not registered")
+	}
+}
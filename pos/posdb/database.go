@@ -71,6 +71,44 @@ func GetDbByName(name string) *Db {
 	return dbInstMap[name]
 }
 
+// Register installs db as the package-level instance returned by NewDb(name)
+// and GetDbByName(name), and by GetDb for posconfig.PosLocalDB.
+func Register(name string, db *Db) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	dbInstMap[name] = db
+	if name == posconfig.PosLocalDB {
+		dbInstance = db
+	}
+}
+
+// Unregister removes db, installed by Register(name, db), from the
+// package-level instances. GetDb falls back to the default instance. Nothing
+// happens if another Db was registered under name since.
+func Unregister(name string, db *Db) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if dbInstMap[name] != db {
+		return
+	}
+	delete(dbInstMap, name)
+	if dbInstance == db {
+		dbInstance = dbInstMap[""]
+	}
+}
+
+// OpenDb opens the leveldb in dirname as a Db. Unlike NewDb, the returned Db
+// is not registered with the package-level instances and the caller owns it.
+func OpenDb(dirname string) (*Db, error) {
+	db, err := ethdb.NewLDBDatabase(dirname, 0, 256)
+	if err != nil {
+		return nil, err
+	}
+	return &Db{db: db}, nil
+}
+
 //DbInit use to init leveldb in this object, user should not use this. It is automate called in init().
 func (s *Db) DbInit(dbPath string) {
 	var dirname string
@@ -263,7 +301,11 @@ func GetRBProposerGroup(epochId uint64) [][]byte {
 		log.SyslogErr("GetRBProposerGroup create db error")
 		return nil
 	}
+	return GetRBProposerGroupWithDb(db, epochId)
+}
 
+// GetRBProposerGroupWithDb is GetRBProposerGroup reading from db.
+func GetRBProposerGroupWithDb(db *Db, epochId uint64) [][]byte {
 	proposersArray := db.GetStorageByteArray(epochId)
 	length := len(proposersArray)
 	g1s := make([][]byte, length, length)
@@ -301,7 +343,11 @@ func GetEpochLeaderGroup(epochId uint64) [][]byte {
 		log.SyslogErr("GetEpochLeaderGroup create db error")
 		return nil
 	}
+	return GetEpochLeaderGroupWithDb(db, epochId)
+}
 
+// GetEpochLeaderGroupWithDb is GetEpochLeaderGroup reading from db.
+func GetEpochLeaderGroupWithDb(db *Db, epochId uint64) [][]byte {
 	proposersArray := db.GetStorageByteArray(epochId)
 	length := len(proposersArray)
 	pks := make([][]byte, length, length)
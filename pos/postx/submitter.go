@@ -0,0 +1,420 @@
+// Package postx submits the transactions of the POS protocols (slot leader
+// selection and random beacon) straight to the local transaction pool, and
+// tracks them until they are mined or the stage they belong to is over.
+package postx
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+const (
+	// retrySlots is the number of slots a transaction may stay unmined before
+	// it's sent again.
+	retrySlots = 6
+
+	// priceBump is the percentage the gas price of a pending transaction is
+	// raised by when it's replaced, above the pool's replacement threshold.
+	priceBump = 20
+
+	// keepEpochs is the number of past epochs whose records are kept.
+	keepEpochs = 2
+)
+
+var (
+	ErrNotReady = errors.New("pos transaction submitter is not initialized")
+	ErrKind     = errors.New("unknown pos transaction kind")
+
+	errDropped = errors.New("pos transaction dropped by fault injection")
+)
+
+// Kind identifies the protocol message a transaction carries.
+type Kind int
+
+const (
+	KindSMA1     Kind = iota // slot leader selection stage one commitment
+	KindSMA2                 // slot leader selection stage two proof
+	KindDKG1                 // random beacon dkg commitments
+	KindDKG2                 // random beacon dkg encrypted shares
+	KindSigShare             // random beacon signature share
+	kindCount
+)
+
+var kindNames = [kindCount]string{"sma1", "sma2", "dkg1", "dkg2", "sigshare"}
+
+func (k Kind) String() string {
+	if k < 0 || k >= kindCount {
+		return "unknown"
+	}
+	return kindNames[k]
+}
+
+// ParseKind returns the kind with the given name.
+func ParseKind(name string) (Kind, error) {
+	for k, n := range kindNames {
+		if n == name {
+			return Kind(k), nil
+		}
+	}
+	return 0, ErrKind
+}
+
+// To returns the precompiled contract the transactions of the kind are sent to.
+func (k Kind) To() common.Address {
+	if k == KindSMA1 || k == KindSMA2 {
+		return vm.GetSlotLeaderSCAddress()
+	}
+	return vm.GetRBAddress()
+}
+
+// Deadline returns the last slot of the epoch in which the transactions of the
+// kind are accepted by the contracts.
+func (k Kind) Deadline() uint64 {
+	switch k {
+	case KindSMA1:
+		return posconfig.Sma1End
+	case KindSMA2:
+		return posconfig.Sma2End
+	case KindDKG1:
+		return posconfig.Cfg().Dkg1End
+	case KindDKG2:
+		return posconfig.Cfg().Dkg2End
+	default:
+		return posconfig.Cfg().SignEnd
+	}
+}
+
+// Status is the progress of a protocol transaction.
+type Status int
+
+const (
+	StatusQueued  Status = iota // waiting for its send delay
+	StatusPending               // in the transaction pool
+	StatusMined                 // mined and executed successfully
+	StatusFailed                // mined but reverted by the contract
+	StatusExpired               // not mined before the stage deadline
+)
+
+var statusNames = []string{"queued", "pending", "mined", "failed", "expired"}
+
+func (s Status) String() string {
+	if s < 0 || int(s) >= len(statusNames) {
+		return "unknown"
+	}
+	return statusNames[s]
+}
+
+// TxPool is the part of core.TxPool the submitter adds transactions to.
+type TxPool interface {
+	AddLocal(tx *types.Transaction) error
+	Get(hash common.Hash) *types.Transaction
+	GasPrice() *big.Int
+	State() *state.ManagedState
+}
+
+// Fault decides what happens to a protocol transaction about to be sent, so
+// that network simulations can lose or hold back protocol messages. A dropped
+// transaction is handled as if it was sent but never reached the pool, a
+// delayed one is queued that much longer before its first attempt.
+type Fault func(kind Kind, epochID, index uint64) (drop bool, delay time.Duration)
+
+// Option configures a submitter.
+type Option func(*Submitter)
+
+// WithFault makes the submitter consult f before sending each transaction.
+func WithFault(f Fault) Option {
+	return func(s *Submitter) { s.fault = f }
+}
+
+// Record tracks a protocol transaction. Hashes holds every transaction sent
+// for it, the last one being the one expected to be mined.
+type Record struct {
+	Kind        Kind
+	EpochID     uint64
+	Index       uint64 // epoch leader index or random proposer id
+	Status      Status
+	Nonce       uint64
+	GasPrice    *big.Int
+	Hashes      []common.Hash
+	Attempts    int
+	BlockNumber uint64
+	Err         error // error of the last attempt
+
+	payload  []byte
+	sendAt   time.Time
+	lastSlot uint64
+}
+
+type recordKey struct {
+	kind    Kind
+	epochID uint64
+	index   uint64
+}
+
+// Submitter signs protocol transactions with the miner key and adds them to
+// the transaction pool.
+type Submitter struct {
+	pool   TxPool
+	db     core.DatabaseReader
+	signer types.Signer
+	key    *ecdsa.PrivateKey
+	from   common.Address
+	fault  Fault
+
+	mu      sync.Mutex
+	records map[recordKey]*Record
+}
+
+// NewSubmitter creates a submitter sending transactions signed by key for the
+// chain chainID, looking their receipts up in db.
+func NewSubmitter(pool TxPool, db core.DatabaseReader, chainID *big.Int, key *ecdsa.PrivateKey, opts ...Option) *Submitter {
+	s := &Submitter{
+		pool:    pool,
+		db:      db,
+		signer:  types.NewEIP155Signer(chainID),
+		key:     key,
+		from:    crypto.PubkeyToAddress(key.PublicKey),
+		records: make(map[recordKey]*Record),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Submit queues the transaction of a protocol message. The transaction is
+// sent after a random delay of up to posconfig.TxDelay seconds, spreading
+// the load of the protocol over the stage, and resent until it's mined or
+// the stage deadline passes. Submitting a message again is a no-op while
+// its previous transaction is still pending or has been mined.
+func (s *Submitter) Submit(kind Kind, epochID, index uint64, payload []byte) error {
+	if kind < 0 || kind >= kindCount {
+		return ErrKind
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := recordKey{kind, epochID, index}
+	if rec, ok := s.records[key]; ok && rec.Status <= StatusMined {
+		log.Debug("Pos tx already submitted", "kind", kind, "epochID", epochID, "index", index, "status", rec.Status)
+		return nil
+	}
+	rec := &Record{Kind: kind, EpochID: epochID, Index: index, Status: StatusQueued, payload: payload}
+	s.records[key] = rec
+
+	delay := s.sendDelay(kind, epochID)
+	if s.fault != nil {
+		_, extra := s.fault(kind, epochID, index)
+		delay += extra
+	}
+	if delay == 0 {
+		_, slotID := util.GetEpochSlotID()
+		return s.send(rec, slotID)
+	}
+	rec.sendAt = time.Now().Add(delay)
+	log.Debug("Pos tx queued", "kind", kind, "epochID", epochID, "index", index, "delay", delay)
+	return nil
+}
+
+// sendDelay picks the delay before the first attempt, leaving at least half
+// of the remaining stage for retries.
+func (s *Submitter) sendDelay(kind Kind, epochID uint64) time.Duration {
+	if posconfig.TxDelay <= 0 {
+		return 0
+	}
+	curEpoch, curSlot := util.GetEpochSlotID()
+	if curEpoch != epochID || curSlot >= kind.Deadline() {
+		return 0
+	}
+	limit := posconfig.TxDelay
+	if left := int((kind.Deadline() - curSlot) * posconfig.SlotTime / 2); left < limit {
+		limit = left
+	}
+	if limit <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Intn(limit)) * time.Second
+}
+
+// Tick advances the records to the given slot: queued transactions whose
+// delay is over are sent, mined ones are recorded and the ones still pending
+// are re-priced or resent.
+func (s *Submitter) Tick(epochID, slotID uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, rec := range s.records {
+		if rec.EpochID+keepEpochs < epochID {
+			delete(s.records, key)
+			continue
+		}
+		if rec.Status == StatusPending && s.checkMined(rec) {
+			continue
+		}
+		if rec.Status != StatusQueued && rec.Status != StatusPending {
+			continue
+		}
+		if rec.EpochID < epochID || slotID > rec.Kind.Deadline() {
+			rec.Status = StatusExpired
+			log.Warn("Pos tx not mined before stage deadline", "kind", rec.Kind, "epochID", rec.EpochID,
+				"index", rec.Index, "attempts", rec.Attempts)
+			continue
+		}
+		if rec.EpochID > epochID {
+			continue
+		}
+		switch {
+		case rec.Status == StatusQueued && !now.Before(rec.sendAt):
+			s.send(rec, slotID)
+		case rec.Status == StatusPending && slotID >= rec.lastSlot+retrySlots:
+			s.send(rec, slotID)
+		}
+	}
+}
+
+// checkMined looks the transactions of rec up in the chain, reporting whether
+// one of them got mined.
+func (s *Submitter) checkMined(rec *Record) bool {
+	for _, hash := range rec.Hashes {
+		receipt, _, number, _ := core.GetReceipt(s.db, hash)
+		if receipt == nil {
+			continue
+		}
+		rec.BlockNumber = number
+		rec.Status = StatusMined
+		if receipt.Status == types.ReceiptStatusFailed {
+			rec.Status = StatusFailed
+		}
+		log.Debug("Pos tx mined", "kind", rec.Kind, "epochID", rec.EpochID, "index", rec.Index,
+			"hash", hash, "number", number, "status", rec.Status)
+		return true
+	}
+	return false
+}
+
+// send signs and adds a transaction for rec to the pool. A transaction still
+// waiting in the pool is replaced with a higher gas price, otherwise a new
+// one is sent with the next nonce.
+func (s *Submitter) send(rec *Record, slotID uint64) error {
+	if s.fault != nil {
+		if drop, _ := s.fault(rec.Kind, rec.EpochID, rec.Index); drop {
+			rec.Attempts++
+			rec.lastSlot = slotID
+			rec.Status = StatusPending
+			rec.Err = errDropped
+			log.Debug("Pos tx dropped", "kind", rec.Kind, "epochID", rec.EpochID, "index", rec.Index, "attempt", rec.Attempts)
+			return nil
+		}
+	}
+	nonce, price := s.pool.State().GetNonce(s.from), s.pool.GasPrice()
+	if n := len(rec.Hashes); n > 0 {
+		if s.pool.Get(rec.Hashes[n-1]) != nil {
+			nonce = rec.Nonce
+			price = new(big.Int).Mul(rec.GasPrice, big.NewInt(100+priceBump))
+			price.Div(price, big.NewInt(100))
+		} else if rec.GasPrice.Cmp(price) > 0 {
+			price = rec.GasPrice
+		}
+	}
+	to := rec.Kind.To()
+	gas := core.IntrinsicGas(rec.payload, &to, true)
+	tx := types.NewTransaction(nonce, to, new(big.Int), gas, price, rec.payload)
+	tx.SetTxtype(types.POS_TX)
+
+	rec.Attempts++
+	rec.lastSlot = slotID
+	rec.Status = StatusPending
+	signed, err := types.SignTx(tx, s.signer, s.key)
+	if err == nil {
+		err = s.pool.AddLocal(signed)
+	}
+	if rec.Err = err; err != nil {
+		log.SyslogErr("Send pos tx failed", "kind", rec.Kind, "epochID", rec.EpochID, "index", rec.Index,
+			"attempt", rec.Attempts, "err", err)
+		return err
+	}
+	rec.Nonce, rec.GasPrice = nonce, price
+	rec.Hashes = append(rec.Hashes, signed.Hash())
+	log.SyslogInfo("Send pos tx success", "kind", rec.Kind, "epochID", rec.EpochID, "index", rec.Index,
+		"attempt", rec.Attempts, "txHash", signed.Hash().String())
+	return nil
+}
+
+// Records returns a copy of the records of epochID, ordered by kind and index.
+func (s *Submitter) Records(epochID uint64) []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records := make([]Record, 0)
+	for _, rec := range s.records {
+		if rec.EpochID == epochID {
+			cpy := *rec
+			cpy.Hashes = append([]common.Hash(nil), rec.Hashes...)
+			records = append(records, cpy)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Kind != records[j].Kind {
+			return records[i].Kind < records[j].Kind
+		}
+		return records[i].Index < records[j].Index
+	})
+	return records
+}
+
+var (
+	submitter   *Submitter
+	submitterMu sync.RWMutex
+)
+
+// Init installs the submitter used by the POS protocols.
+func Init(pool TxPool, db core.DatabaseReader, chainID *big.Int, key *ecdsa.PrivateKey, opts ...Option) {
+	SetSubmitter(NewSubmitter(pool, db, chainID, key, opts...))
+}
+
+// SetSubmitter installs s as the package-level submitter returned by
+// GetSubmitter.
+func SetSubmitter(s *Submitter) {
+	submitterMu.Lock()
+	submitter = s
+	submitterMu.Unlock()
+}
+
+// GetSubmitter returns the submitter installed by Init, nil before.
+func GetSubmitter() *Submitter {
+	submitterMu.RLock()
+	defer submitterMu.RUnlock()
+	return submitter
+}
+
+// Submit queues a protocol transaction on the installed submitter.
+func Submit(kind Kind, epochID, index uint64, payload []byte) error {
+	s := GetSubmitter()
+	if s == nil {
+		return ErrNotReady
+	}
+	return s.Submit(kind, epochID, index, payload)
+}
+
+// Tick advances the installed submitter, if any, to the given slot.
+func Tick(epochID, slotID uint64) {
+	if s := GetSubmitter(); s != nil {
+		s.Tick(epochID, slotID)
+	}
+}
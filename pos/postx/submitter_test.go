@@ -0,0 +1,198 @@
+package postx
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+)
+
+type testPool struct {
+	state *state.ManagedState
+	txs   map[common.Hash]*types.Transaction
+	added []*types.Transaction
+}
+
+func newTestPool() *testPool {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	return &testPool{state: state.ManageState(statedb), txs: make(map[common.Hash]*types.Transaction)}
+}
+
+func (p *testPool) AddLocal(tx *types.Transaction) error {
+	from, err := types.Sender(types.NewEIP155Signer(big.NewInt(1)), tx)
+	if err != nil {
+		return err
+	}
+	p.txs[tx.Hash()] = tx
+	p.added = append(p.added, tx)
+	if p.state.GetNonce(from) <= tx.Nonce() {
+		p.state.SetNonce(from, tx.Nonce()+1)
+	}
+	return nil
+}
+
+func (p *testPool) Get(hash common.Hash) *types.Transaction { return p.txs[hash] }
+func (p *testPool) GasPrice() *big.Int                      { return big.NewInt(180000000000) }
+func (p *testPool) State() *state.ManagedState              { return p.state }
+
+func newTestSubmitter(t *testing.T) (*Submitter, *testPool, ethdb.Database) {
+	posconfig.TxDelay = 0
+	key, _ := crypto.GenerateKey()
+	db, _ := ethdb.NewMemDatabase()
+	pool := newTestPool()
+	return NewSubmitter(pool, db, big.NewInt(1), key), pool, db
+}
+
+// mine writes the transaction lookup entry and receipt of tx in a block.
+func mine(t *testing.T, db ethdb.Database, number int64, tx *types.Transaction, failed bool) {
+	receipts := types.Receipts{types.NewReceipt(nil, failed, big.NewInt(21000))}
+	block := types.NewBlock(&types.Header{Number: big.NewInt(number)}, []*types.Transaction{tx}, nil, receipts)
+	if err := core.WriteTxLookupEntries(db, block); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.WriteBlockReceipts(db, block.Hash(), block.NumberU64(), receipts); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubmitAndMine(t *testing.T) {
+	s, pool, db := newTestSubmitter(t)
+
+	if err := s.Submit(KindSMA1, 0, 3, []byte("commitment")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Submit(KindSMA2, 0, 3, []byte("proof")); err != nil {
+		t.Fatal(err)
+	}
+	// submitting a pending message again doesn't send a new tx
+	if err := s.Submit(KindSMA1, 0, 3, []byte("commitment")); err != nil {
+		t.Fatal(err)
+	}
+	if len(pool.added) != 2 {
+		t.Fatalf("pool txs mismatch: have %d, want 2", len(pool.added))
+	}
+	tx := pool.added[0]
+	if tx.Txtype() != types.POS_TX || *tx.To() != KindSMA1.To() || tx.Nonce() != 0 || pool.added[1].Nonce() != 1 {
+		t.Fatalf("pos tx mismatch: type %d, to %x, nonce %d", tx.Txtype(), tx.To(), tx.Nonce())
+	}
+	from, _ := types.Sender(s.signer, tx)
+	if from != s.from {
+		t.Fatalf("sender mismatch: have %x, want %x", from, s.from)
+	}
+
+	mine(t, db, 7, tx, false)
+	mine(t, db, 8, pool.added[1], true)
+	s.Tick(0, posconfig.Sma1Start+1)
+	records := s.Records(0)
+	if len(records) != 2 {
+		t.Fatalf("records mismatch: have %d, want 2", len(records))
+	}
+	if records[0].Kind != KindSMA1 || records[0].Status != StatusMined || records[0].BlockNumber != 7 {
+		t.Fatalf("mined record mismatch: %+v", records[0])
+	}
+	if records[1].Kind != KindSMA2 || records[1].Status != StatusFailed {
+		t.Fatalf("failed record mismatch: %+v", records[1])
+	}
+}
+
+func TestResendAndExpire(t *testing.T) {
+	s, pool, _ := newTestSubmitter(t)
+
+	if err := s.Submit(KindSMA1, 0, 0, []byte("commitment")); err != nil {
+		t.Fatal(err)
+	}
+	slot := s.Records(0)[0].lastSlot
+
+	// still pending in the pool: replaced with the same nonce and a higher price
+	s.Tick(0, slot+retrySlots-1)
+	if len(pool.added) != 1 {
+		t.Fatalf("tx resent before %d slots", retrySlots)
+	}
+	s.Tick(0, slot+retrySlots)
+	if len(pool.added) != 2 {
+		t.Fatalf("pool txs mismatch: have %d, want 2", len(pool.added))
+	}
+	first, second := pool.added[0], pool.added[1]
+	if second.Nonce() != first.Nonce() || second.GasPrice().Cmp(first.GasPrice()) <= 0 {
+		t.Fatalf("tx not re-priced: nonce %d/%d, price %v/%v", first.Nonce(), second.Nonce(), first.GasPrice(), second.GasPrice())
+	}
+
+	// dropped from the pool: sent again with the next nonce
+	delete(pool.txs, second.Hash())
+	s.Tick(0, slot+2*retrySlots)
+	if len(pool.added) != 3 || pool.added[2].Nonce() != first.Nonce()+1 {
+		t.Fatalf("dropped tx not resent with a new nonce")
+	}
+	rec := s.Records(0)[0]
+	if rec.Attempts != 3 || len(rec.Hashes) != 3 || rec.Status != StatusPending {
+		t.Fatalf("record mismatch: %+v", rec)
+	}
+
+	s.Tick(0, KindSMA1.Deadline()+1)
+	if rec := s.Records(0)[0]; rec.Status != StatusExpired {
+		t.Fatalf("status mismatch: have %v, want %v", rec.Status, StatusExpired)
+	}
+	s.Tick(keepEpochs+1, 0)
+	if len(s.Records(0)) != 0 {
+		t.Fatal("old records not pruned")
+	}
+}
+
+func TestSubmitDelayed(t *testing.T) {
+	s, pool, _ := newTestSubmitter(t)
+	rec := &Record{Kind: KindSMA2, EpochID: 1, Status: StatusQueued, payload: []byte("proof")}
+	s.records[recordKey{KindSMA2, 1, 0}] = rec
+
+	// queued txs of a later epoch wait for their epoch
+	s.Tick(0, 0)
+	if len(pool.added) != 0 || rec.Status != StatusQueued {
+		t.Fatal("tx of a later epoch sent")
+	}
+	s.Tick(1, posconfig.Sma2Start)
+	if len(pool.added) != 1 || rec.Status != StatusPending {
+		t.Fatalf("queued tx not sent: status %v", rec.Status)
+	}
+}
+
+func TestSubmitFault(t *testing.T) {
+	_, pool, db := newTestSubmitter(t)
+	key, _ := crypto.GenerateKey()
+	dropped := true
+	s := NewSubmitter(pool, db, big.NewInt(1), key, WithFault(func(kind Kind, epochID, index uint64) (bool, time.Duration) {
+		if kind == KindSMA1 {
+			return dropped, 0
+		}
+		return false, time.Hour
+	}))
+
+	if err := s.Submit(KindSMA1, 0, 3, []byte("commitment")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Submit(KindSMA2, 0, 3, []byte("proof")); err != nil {
+		t.Fatal(err)
+	}
+	if len(pool.added) != 0 {
+		t.Fatalf("pool txs mismatch: have %d, want 0", len(pool.added))
+	}
+	sma1 := s.records[recordKey{KindSMA1, 0, 3}]
+	if sma1.Status != StatusPending || sma1.Attempts != 1 || sma1.Err != errDropped {
+		t.Fatalf("dropped tx not recorded: status %v, attempts %d, err %v", sma1.Status, sma1.Attempts, sma1.Err)
+	}
+	// delayed txs stay queued, dropped ones are retried once the fault is gone
+	dropped = false
+	s.Tick(0, sma1.lastSlot+retrySlots)
+	if len(pool.added) != 1 || sma1.Attempts != 2 || sma1.Err != nil {
+		t.Fatalf("dropped tx not resent: pool txs %d, attempts %d, err %v", len(pool.added), sma1.Attempts, sma1.Err)
+	}
+	if sma2 := s.records[recordKey{KindSMA2, 0, 3}]; sma2.Status != StatusQueued {
+		t.Fatalf("delayed tx status mismatch: have %v, want %v", sma2.Status, StatusQueued)
+	}
+}
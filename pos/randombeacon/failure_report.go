@@ -0,0 +1,168 @@
+package randombeacon
+
+import (
+	"sync"
+
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/util"
+)
+
+// Reasons a random proposer failed a stage.
+const (
+	FailMissing = "missing" // no data on chain for the stage
+	FailInvalid = "invalid" // rejected by the checks of the stage
+	FailLate    = "late"    // sent after the stage was over
+)
+
+// keepRejectEpochs is the number of epochs whose rejections are kept.
+const keepRejectEpochs = 8
+
+// maxRejects caps the number of rejections kept, one per proposer and stage
+// of the kept epochs.
+var maxRejects = (keepRejectEpochs + 2) * posconfig.RandomProperCount * len(rbStages)
+
+var rbStages = []int{vm.RbDkg1Stage, vm.RbDkg2Stage, vm.RbSignStage}
+
+var stageNames = map[int]string{
+	vm.RbDkg1Stage: "dkg1",
+	vm.RbDkg2Stage: "dkg2",
+	vm.RbSignStage: "sigShare",
+}
+
+// Failure is the reason a random proposer didn't contribute to a stage.
+type Failure struct {
+	Stage  string `json:"stage"`
+	Reason string `json:"reason"`
+	Error  string `json:"error,omitempty"` // error of the rejected transaction
+	Time   uint64 `json:"time,omitempty"`  // time the transaction was rejected at
+}
+
+// ProposerFailures holds the failures of a random proposer.
+type ProposerFailures struct {
+	ProposerId uint32    `json:"proposerId"`
+	Failures   []Failure `json:"failures"`
+}
+
+// FailureReport tells how many random proposers took part in each stage of
+// an epoch and why the others didn't.
+type FailureReport struct {
+	EpochId   uint64             `json:"epochId"`
+	Threshold uint               `json:"threshold"`
+	Dkg1Count int                `json:"dkg1Count"`
+	Dkg2Count int                `json:"dkg2Count"`
+	SigCount  int                `json:"sigCount"`
+	Random    bool               `json:"random"` // random number of the next epoch generated
+	Proposers []ProposerFailures `json:"proposers"`
+}
+
+type rejectKey struct {
+	epochId    uint64
+	proposerId uint32
+	stage      int
+}
+
+var (
+	rejectsMu sync.Mutex
+	rejects   = make(map[rejectKey]Failure)
+)
+
+func init() {
+	vm.RBRejectHook = recordReject
+}
+
+// recordReject keeps the last rejection of the transactions of a proposer in
+// a stage. Rejections are recorded locally, for transactions sent by the
+// proposer itself and checked by the tx pool or executed by this node. Only
+// epochs close to the one of time are kept, whatever epoch the payload claims.
+func recordReject(stage int, epochId uint64, proposerId uint32, time uint64, err error) {
+	eid, sid := util.CalEpochSlotID(time)
+	if epochId > eid+1 || (epochId < eid && eid-epochId > keepRejectEpochs) {
+		return
+	}
+	reason := FailInvalid
+	if cur, _, _ := vm.GetRBStage(sid); eid > epochId || (eid == epochId && cur > stage) {
+		reason = FailLate
+	}
+
+	rejectsMu.Lock()
+	defer rejectsMu.Unlock()
+
+	for key := range rejects {
+		if key.epochId < eid && eid-key.epochId > keepRejectEpochs {
+			delete(rejects, key)
+		}
+	}
+	key := rejectKey{epochId, proposerId, stage}
+	if _, ok := rejects[key]; !ok && len(rejects) >= maxRejects {
+		return
+	}
+	rejects[key] = Failure{
+		Stage:  stageNames[stage],
+		Reason: reason,
+		Error:  err.Error(),
+		Time:   time,
+	}
+}
+
+func getReject(epochId uint64, proposerId uint32, stage int) (Failure, bool) {
+	rejectsMu.Lock()
+	defer rejectsMu.Unlock()
+
+	f, ok := rejects[rejectKey{epochId, proposerId, stage}]
+	return f, ok
+}
+
+// GetFailureReport builds the failure report of epochId from the random beacon
+// data of statedb. curEpochId and curSlotId tell which stages are over: a
+// proposer fails a stage still running only if one of its transactions got
+// rejected.
+func GetFailureReport(statedb vm.StateDB, epochId, curEpochId, curSlotId uint64) *FailureReport {
+	report := &FailureReport{
+		EpochId:   epochId,
+		Threshold: posconfig.Cfg().RBThres,
+		Random:    vm.GetStateR(statedb, epochId+1) != nil,
+		Proposers: make([]ProposerFailures, 0),
+	}
+	curStage, _, _ := vm.GetRBStage(curSlotId)
+
+	for i := 0; i < posconfig.RandomProperCount; i++ {
+		id := uint32(i)
+		var failures []Failure
+		for _, stage := range rbStages {
+			if hasStageData(statedb, epochId, id, stage) {
+				switch stage {
+				case vm.RbDkg1Stage:
+					report.Dkg1Count++
+				case vm.RbDkg2Stage:
+					report.Dkg2Count++
+				default:
+					report.SigCount++
+				}
+				continue
+			}
+			if f, ok := getReject(epochId, id, stage); ok {
+				failures = append(failures, f)
+			} else if curEpochId > epochId || (curEpochId == epochId && curStage > stage) {
+				failures = append(failures, Failure{Stage: stageNames[stage], Reason: FailMissing})
+			}
+		}
+		if len(failures) != 0 {
+			report.Proposers = append(report.Proposers, ProposerFailures{ProposerId: id, Failures: failures})
+		}
+	}
+	return report
+}
+
+func hasStageData(statedb vm.StateDB, epochId uint64, proposerId uint32, stage int) bool {
+	switch stage {
+	case vm.RbDkg1Stage:
+		commit, err := vm.GetCji(statedb, epochId, proposerId)
+		return err == nil && len(commit) != 0
+	case vm.RbDkg2Stage:
+		return vm.IsJoinDKG2(statedb, epochId, proposerId)
+	default:
+		sig, err := vm.GetSig(statedb, epochId, proposerId)
+		return err == nil && sig != nil
+	}
+}
@@ -0,0 +1,123 @@
+package randombeacon
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+func slotTime(epochId, slotId uint64) uint64 {
+	return (epochId*posconfig.SlotCount + slotId) * posconfig.SlotTime
+}
+
+func TestFailureReport(t *testing.T) {
+	db, _ := ethdb.NewMemDatabase()
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	epochId := uint64(5)
+	g1 := new(bn256.G1).ScalarBaseMult(big.NewInt(1))
+	g2 := new(bn256.G2).ScalarBaseMult(big.NewInt(1))
+	cij, _ := rlp.EncodeToBytes([][]byte{g2.Marshal()})
+	ens, _ := rlp.EncodeToBytes([][]byte{g1.Marshal()})
+	for i := 0; i < posconfig.RandomProperCount; i++ {
+		id := uint32(i)
+		if id == 3 {
+			continue
+		}
+		statedb.SetStateByteArray(vm.GetRBAddress(), *vm.GetRBKeyHash([]byte{100}, epochId, id), cij)
+		if id == 17 {
+			continue
+		}
+		statedb.SetStateByteArray(vm.GetRBAddress(), *vm.GetRBKeyHash([]byte{101}, epochId, id), ens)
+		sig, _ := rlp.EncodeToBytes(&vm.RbSIGTxPayload{EpochId: epochId, ProposerId: id, GSignShare: g1})
+		statedb.SetStateByteArray(vm.GetRBAddress(), *vm.GetRBKeyHash(vm.GetSigShareId(), epochId, id), sig)
+	}
+
+	// rejections go through the contract hook
+	vm.RBRejectHook(vm.RbDkg2Stage, epochId, 17, slotTime(epochId, posconfig.Cfg().Dkg2Begin), errors.New("bad share"))
+	// and are left out for the stages whose data got on chain
+	vm.RBRejectHook(vm.RbDkg1Stage, epochId, 17, slotTime(epochId, 1), errors.New("duplicate"))
+
+	// in the dkg2 stage, only the rejected dkg2 and the missing dkg1 fail
+	report := GetFailureReport(statedb, epochId, epochId, posconfig.Cfg().Dkg2Begin+1)
+	want := []ProposerFailures{
+		{3, []Failure{{Stage: "dkg1", Reason: FailMissing}}},
+		{17, []Failure{{Stage: "dkg2", Reason: FailInvalid, Error: "bad share", Time: slotTime(epochId, posconfig.Cfg().Dkg2Begin)}}},
+	}
+	checkFailures(t, report.Proposers, want)
+
+	// after the epoch, the stages without data fail too
+	vm.RBRejectHook(vm.RbSignStage, epochId, 17, slotTime(epochId, posconfig.Cfg().SignEnd+1), errors.New("too late"))
+	report = GetFailureReport(statedb, epochId, epochId+1, 0)
+	if report.Dkg1Count != posconfig.RandomProperCount-1 || report.Dkg2Count != posconfig.RandomProperCount-2 ||
+		report.SigCount != posconfig.RandomProperCount-2 {
+		t.Fatalf("counts mismatch: dkg1 %d, dkg2 %d, sig %d", report.Dkg1Count, report.Dkg2Count, report.SigCount)
+	}
+	if report.Random {
+		t.Fatal("random reported without one on chain")
+	}
+	want = []ProposerFailures{
+		{3, []Failure{{Stage: "dkg1", Reason: FailMissing}, {Stage: "dkg2", Reason: FailMissing}, {Stage: "sigShare", Reason: FailMissing}}},
+		{17, []Failure{
+			{Stage: "dkg2", Reason: FailInvalid, Error: "bad share", Time: slotTime(epochId, posconfig.Cfg().Dkg2Begin)},
+			{Stage: "sigShare", Reason: FailLate, Error: "too late", Time: slotTime(epochId, posconfig.Cfg().SignEnd+1)},
+		}},
+	}
+	checkFailures(t, report.Proposers, want)
+}
+
+func TestRecordRejectBounds(t *testing.T) {
+	epochId := uint64(1000)
+	now := slotTime(epochId, posconfig.Cfg().Dkg2Begin)
+	recordReject(vm.RbDkg2Stage, epochId, 4, now, errors.New("bad share"))
+
+	// claimed epochs far from the current one are ignored and don't prune
+	recordReject(vm.RbDkg2Stage, math.MaxUint64, 5, now, errors.New("forged"))
+	recordReject(vm.RbDkg2Stage, epochId-keepRejectEpochs-1, 5, now, errors.New("old"))
+	if _, ok := getReject(math.MaxUint64, 5, vm.RbDkg2Stage); ok {
+		t.Fatal("rejection of a future epoch recorded")
+	}
+	if _, ok := getReject(epochId-keepRejectEpochs-1, 5, vm.RbDkg2Stage); ok {
+		t.Fatal("rejection of an old epoch recorded")
+	}
+	if _, ok := getReject(epochId, 4, vm.RbDkg2Stage); !ok {
+		t.Fatal("rejection of the current epoch pruned")
+	}
+
+	// the number of rejections kept is capped
+	for i := 0; i < 2*maxRejects; i++ {
+		recordReject(vm.RbDkg1Stage, epochId-uint64(i%keepRejectEpochs), uint32(i), now, errors.New("flood"))
+	}
+	rejectsMu.Lock()
+	count := len(rejects)
+	rejectsMu.Unlock()
+	if count > maxRejects {
+		t.Fatalf("too many rejections kept: %d > %d", count, maxRejects)
+	}
+}
+
+func checkFailures(t *testing.T, have, want []ProposerFailures) {
+	t.Helper()
+	if len(have) != len(want) {
+		t.Fatalf("failed proposers mismatch: have %+v, want %+v", have, want)
+	}
+	for i := range want {
+		if have[i].ProposerId != want[i].ProposerId || len(have[i].Failures) != len(want[i].Failures) {
+			t.Fatalf("proposer %d mismatch: have %+v, want %+v", i, have[i], want[i])
+		}
+		for j := range want[i].Failures {
+			if have[i].Failures[j] != want[i].Failures[j] {
+				t.Errorf("proposer %d failure %d mismatch: have %+v, want %+v", want[i].ProposerId, j, have[i].Failures[j], want[i].Failures[j])
+			}
+		}
+	}
+}
@@ -8,10 +8,6 @@ import (
 	"io"
 	"sync"
 
-	"github.com/wanchain/go-wanchain/common"
-	"github.com/wanchain/go-wanchain/common/hexutil"
-	"github.com/wanchain/go-wanchain/core"
-	"github.com/wanchain/go-wanchain/core/types"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/log"
 
@@ -20,9 +16,8 @@ import (
 	"github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/postx"
 	"github.com/wanchain/go-wanchain/rlp"
-	"github.com/wanchain/go-wanchain/rpc"
 )
 
 type RbEnsDataCollector struct {
@@ -39,11 +34,11 @@ type GetRBProposerGroupFunc func(epochId uint64) []bn256.G1
 type GetCji func(db vm.StateDB, epochId uint64, proposerId uint32) ([]*bn256.G2, error)
 type GetEnsFunc func(db vm.StateDB, epochId uint64, proposerId uint32) ([]*bn256.G1, error)
 type GetRBMFunc func(db vm.StateDB, epochId uint64) ([]byte, error)
+type GetSigFunc func(db vm.StateDB, epochId uint64, proposerId uint32) (*vm.RbSIGTxPayload, error)
 type DoStageWork func() error
 
 type LoopEvent struct {
 	statedb vm.StateDB
-	rc      *rpc.Client
 	eid     uint64
 	sid     uint64
 }
@@ -124,9 +119,10 @@ type RandomBeacon struct {
 	proposerPks  []bn256.G1
 	myPropserIds []uint32
 
-	statedb   vm.StateDB
+	statedb vm.StateDB
 	epocher   *epochLeader.Epocher
-	rpcClient *rpc.Client
+	db        *posdb.Db
+	submitter *postx.Submitter
 
 	wg sync.WaitGroup
 	mutex sync.Mutex
@@ -136,6 +132,7 @@ type RandomBeacon struct {
 	getCji              GetCji
 	getEns              GetEnsFunc
 	getRBM              GetRBMFunc
+	getSig              GetSigFunc
 
 	fDoDKG1s			DoStageWork
 	fDoDKG2s			DoStageWork
@@ -145,7 +142,7 @@ type RandomBeacon struct {
 var (
 	maxUint64      = uint64(1<<64 - 1)
 	loopEventCount = 1000
-	randomBeacon   RandomBeacon
+	randomBeacon   = NewRandomBeacon(nil)
 	rbPloys        = "RB_PLOYS"
 )
 
@@ -160,7 +157,42 @@ var (
 )
 
 func GetRandonBeaconInst() *RandomBeacon {
-	return &randomBeacon
+	return randomBeacon
+}
+
+// SetRandomBeaconInst installs rb as the package-level instance returned by
+// GetRandonBeaconInst.
+func SetRandomBeaconInst(rb *RandomBeacon) {
+	randomBeacon = rb
+}
+
+// NewRandomBeacon creates a random beacon that stores its DKG polynomials in
+// db. A nil db uses the package-level POS database.
+func NewRandomBeacon(db *posdb.Db) *RandomBeacon {
+	return &RandomBeacon{db: db}
+}
+
+func (rb *RandomBeacon) getDb() *posdb.Db {
+	if rb.db != nil {
+		return rb.db
+	}
+	return posdb.GetDb()
+}
+
+func (rb *RandomBeacon) posConfig() *posconfig.Config {
+	if rb.epocher != nil && rb.epocher.GetBlkChain() != nil {
+		return rb.epocher.GetBlkChain().PosConfig()
+	}
+	return posconfig.Cfg()
+}
+
+// SetSubmitter makes rb send its transactions through sub instead of the
+// package-level submitter.
+func (rb *RandomBeacon) SetSubmitter(sub *postx.Submitter) {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+
+	rb.submitter = sub
 }
 
 func (rb *RandomBeacon) Init(epocher *epochLeader.Epocher) {
@@ -176,15 +208,17 @@ func (rb *RandomBeacon) Init(epocher *epochLeader.Epocher) {
 	rb.epochStage = vm.RbDkg1Stage
 	rb.epochId = maxUint64
 	rb.polys = make(PolyMap)
-	rb.rpcClient = nil
 
 	rb.epocher = epocher
 
 	// function
-	rb.getRBProposerGroupF = epochLeader.GetEpocher().GetRBProposerG1
+	rb.getRBProposerGroupF = epocher.GetRBProposerG1
 	rb.getCji = vm.GetCji
 	rb.getEns = vm.GetEncryptShare
-	rb.getRBM = vm.GetRBM
+	rb.getRBM = func(db vm.StateDB, epochId uint64) ([]byte, error) {
+		return vm.GetRBMWithConfig(rb.posConfig(), db, epochId)
+	}
+	rb.getSig = vm.GetSig
 	rb.fDoDKG1s = rb.doDKG1s
 	rb.fDoDKG2s = rb.doDKG2s
 	rb.fDoSIGs = rb.doSIGs
@@ -210,7 +244,7 @@ func (rb *RandomBeacon) Stop() {
 	rb.loopEvents = nil
 }
 
-func (rb *RandomBeacon) Loop(statedb vm.StateDB, rc *rpc.Client, eid uint64, sid uint64) (err error) {
+func (rb *RandomBeacon) Loop(statedb vm.StateDB, eid uint64, sid uint64) (err error) {
 	defer func() {
 		rb.mutex.Unlock()
 		if e := recover(); e != nil {
@@ -224,12 +258,12 @@ func (rb *RandomBeacon) Loop(statedb vm.StateDB, rc *rpc.Client, eid uint64, sid
 		return errUninitialized
 	}
 
-	if statedb == nil || rc == nil {
+	if statedb == nil {
 		log.SyslogErr("invalid RB loop input param")
 		return errInvalidInParam
 	}
 
-	rb.loopEvents <- &LoopEvent{statedb, rc, eid, sid}
+	rb.loopEvents <- &LoopEvent{statedb, eid, sid}
 	return
 }
 
@@ -242,7 +276,7 @@ func (rb *RandomBeacon) LoopRoutine() {
 			break
 		}
 
-		rb.doLoop(event.statedb, event.rc, event.eid, event.sid)
+		rb.doLoop(event.statedb, event.eid, event.sid)
 	}
 }
 
@@ -267,10 +301,9 @@ func (rb *RandomBeacon) updateStage(stage int) {
 	rb.taskTags = nil
 }
 
-func (rb *RandomBeacon) doLoop(statedb vm.StateDB, rc *rpc.Client, epochId uint64, slotId uint64) error {
+func (rb *RandomBeacon) doLoop(statedb vm.StateDB, epochId uint64, slotId uint64) error {
 	log.SyslogInfo("rb doLoop begin", "epochId", epochId, "slotId", slotId, "self epochId", rb.epochId)
 	rb.statedb = statedb
-	rb.rpcClient = rc
 
 	if rb.epochId != maxUint64 && rb.epochId > epochId {
 		log.SyslogErr("RB doloop fail", "err", errEpochIdRollback.Error())
@@ -387,7 +420,7 @@ func (rb *RandomBeacon) getMyRBProposerId(epochId uint64) []uint32 {
 	}
 
 	log.SyslogInfo("get my RBP id", "RBP group pk", pks)
-	selfPk := posconfig.Cfg().GetMinerBn256PK()
+	selfPk := rb.posConfig().GetMinerBn256PK()
 	if selfPk == nil {
 		log.SyslogInfo("get my RBP id, can't get miner bn256 pk")
 		return nil
@@ -411,15 +444,16 @@ func (rb *RandomBeacon) doDKG1s() error {
 			continue
 		}
 
+		var err error
 		if rb.polys[ppId].poly != nil && rb.polys[ppId].s != nil {
-			rb.taskTags[i] = true
-			continue
+			// polynomial of a previous attempt or run
+			err = rb.resendDKG1(ppId)
+		} else {
+			err = rb.doDKG1(ppId)
 		}
 
-		err := rb.doDKG1(ppId)
 		if err == nil {
 			rb.taskTags[i] = true
-			rb.storePolys()
 		} else {
 			// try the best to send every tx,
 			// prevent that one error stop all left task.
@@ -438,21 +472,30 @@ func (rb *RandomBeacon) doDKG1(proposerId uint32) error {
 		return err
 	}
 
+	// store the polynomial before its commitment is sent, so that it can
+	// still be used for dkg2 after a restart
+	rb.storePolys()
 	return rb.sendDKG1(txPayload)
 }
 
-func (rb *RandomBeacon) generateDKG1(proposerId uint32) (*vm.RbDKG1FlatTxPayload, error) {
-	nr := len(rb.proposerPks)
+// resendDKG1 sends the commitment of a stored polynomial again, unless it is
+// on chain already.
+func (rb *RandomBeacon) resendDKG1(proposerId uint32) error {
+	commit, err := rb.getCji(rb.statedb, rb.epochId, proposerId)
+	if err == nil && len(commit) != 0 {
+		return nil
+	}
 
-	// fix the evaluation point: Hash(Pub[1]+1), Hash(Pub[2]+2), ..., Hash(Pub[Nr]+Nr)
-	x := make([]big.Int, nr)
-	for i := 0; i < nr; i++ {
-		x[i].SetBytes(vm.GetPolynomialX(&rb.proposerPks[i], uint32(i)))
-		x[i].Mod(&x[i], bn256.Order)
+	log.SyslogInfo("resend dkg1 of stored poly", "proposerId", proposerId)
+	txPayload, err := rb.dkg1Payload(proposerId)
+	if err != nil {
+		return err
 	}
 
-	sshare := make([]big.Int, nr)
+	return rb.sendDKG1(txPayload)
+}
 
+func (rb *RandomBeacon) generateDKG1(proposerId uint32) (*vm.RbDKG1FlatTxPayload, error) {
 	// fi(x)
 	s, err := rand.Int(rand.Reader, bn256.Order)
 	if err != nil {
@@ -467,11 +510,33 @@ func (rb *RandomBeacon) generateDKG1(proposerId uint32) (*vm.RbDKG1FlatTxPayload
 	}
 
 	rb.polys[proposerId] = PolyInfo{poly, s}
+	txPayload, err := rb.dkg1Payload(proposerId)
+	if err != nil {
+		delete(rb.polys, proposerId)
+		return nil, err
+	}
+
+	return txPayload, nil
+}
+
+// dkg1Payload builds the commitment of the polynomial of proposerId.
+func (rb *RandomBeacon) dkg1Payload(proposerId uint32) (*vm.RbDKG1FlatTxPayload, error) {
+	nr := len(rb.proposerPks)
+
+	// fix the evaluation point: Hash(Pub[1]+1), Hash(Pub[2]+2), ..., Hash(Pub[Nr]+Nr)
+	x := make([]big.Int, nr)
+	for i := 0; i < nr; i++ {
+		x[i].SetBytes(vm.GetPolynomialX(&rb.proposerPks[i], uint32(i)))
+		x[i].Mod(&x[i], bn256.Order)
+	}
+
+	var err error
+	sshare := make([]big.Int, nr)
+	poly := rb.polys[proposerId].poly
 	for i := 0; i < nr; i++ {
 		// share for i is fi(x) evaluation result on x[i]
 		sshare[i], err = rbselection.EvaluatePoly(poly, &x[i], int(posconfig.Cfg().PolymDegree))
 		if err != nil {
-			delete(rb.polys, proposerId)
 			log.SyslogErr("dkg1, evaluate poly fail", "err", err)
 			return nil, err
 		}
@@ -501,8 +566,14 @@ func (rb *RandomBeacon) doDKG2s() error {
 			continue
 		}
 
+		// sent before a restart
+		if ens, err := rb.getEns(rb.statedb, rb.epochId, ppId); err == nil && len(ens) != 0 {
+			rb.taskTags[i] = true
+			continue
+		}
+
 		err := rb.doDKG2(ppId)
-		if err == nil || err == errNoDKG1Data {
+		if err == nil || err == errNoDKG1Data || err == errNoDKG1Poly {
 			rb.taskTags[i] = true
 		} else {
 			// try the best to send every tx,
@@ -591,6 +662,12 @@ func (rb *RandomBeacon) doSIGs() error {
 			continue
 		}
 
+		// sent before a restart
+		if sig, err := rb.getSig(rb.statedb, rb.epochId, id); err == nil && sig != nil {
+			rb.taskTags[i] = true
+			continue
+		}
+
 		err := rb.doSIG(id)
 		if err == nil || err == errInsufficient {
 			rb.taskTags[i] = true
@@ -616,7 +693,7 @@ func (rb *RandomBeacon) doSIG(proposerId uint32) error {
 }
 
 func (rb *RandomBeacon) generateSIG(proposerId uint32) (*vm.RbSIGTxPayload, error) {
-	prikey := posconfig.Cfg().GetMinerBn256SK()
+	prikey := rb.posConfig().GetMinerBn256SK()
 	datas := make([]RbEnsDataCollector, 0)
 
 	for id, pk := range rb.proposerPks {
@@ -670,7 +747,7 @@ func (rb *RandomBeacon) sendDKG1(payloadObj *vm.RbDKG1FlatTxPayload) error {
 		return err
 	}
 
-	return rb.doSendRBTx(payload)
+	return rb.doSendRBTx(postx.KindDKG1, payloadObj.EpochId, payloadObj.ProposerId, payload)
 }
 
 func (rb *RandomBeacon) sendDKG2(payloadObj *vm.RbDKG2FlatTxPayload) error {
@@ -680,7 +757,7 @@ func (rb *RandomBeacon) sendDKG2(payloadObj *vm.RbDKG2FlatTxPayload) error {
 		return err
 	}
 
-	return rb.doSendRBTx(payload)
+	return rb.doSendRBTx(postx.KindDKG2, payloadObj.EpochId, payloadObj.ProposerId, payload)
 }
 
 func (rb *RandomBeacon) sendSIG(payloadObj *vm.RbSIGTxPayload) error {
@@ -690,30 +767,15 @@ func (rb *RandomBeacon) sendSIG(payloadObj *vm.RbSIGTxPayload) error {
 		return err
 	}
 
-	return rb.doSendRBTx(payload)
-}
-
-func (rb *RandomBeacon) doSendRBTx(payload []byte) error {
-	to := vm.GetRBAddress()
-	data := hexutil.Bytes(payload)
-	gas := core.IntrinsicGas(data, &to, true)
-
-	arg := map[string]interface{}{}
-	arg["from"] = rb.getTxFrom()
-	arg["to"] = to
-	arg["value"] = (*hexutil.Big)(big.NewInt(0))
-	arg["gas"] = (*hexutil.Big)(gas)
-	arg["txType"] = types.POS_TX
-	arg["data"] = data
-
-
-	log.SyslogInfo("do send rb tx", "payload len", len(payload))
-	go util.SendPosTx(rb.rpcClient, arg)
-	return nil
+	return rb.doSendRBTx(postx.KindSigShare, payloadObj.EpochId, payloadObj.ProposerId, payload)
 }
 
-func (rb *RandomBeacon) getTxFrom() common.Address {
-	return posconfig.Cfg().GetMinerAddr()
+func (rb *RandomBeacon) doSendRBTx(kind postx.Kind, epochId uint64, proposerId uint32, payload []byte) error {
+	log.SyslogInfo("do send rb tx", "kind", kind, "payload len", len(payload))
+	if rb.submitter != nil {
+		return rb.submitter.Submit(kind, epochId, uint64(proposerId), payload)
+	}
+	return postx.Submit(kind, epochId, uint64(proposerId), payload)
 }
 
 func (rb *RandomBeacon) storePolys() error {
@@ -727,7 +789,7 @@ func (rb *RandomBeacon) storePolys() error {
 		return err
 	}
 
-	_, err = posdb.GetDb().Put(rb.epochId, rbPloys, b)
+	_, err = rb.getDb().Put(rb.epochId, rbPloys, b)
 	if err != nil {
 		log.SyslogErr("random beacon store polys fail", "err", err)
 		return err
@@ -737,7 +799,7 @@ func (rb *RandomBeacon) storePolys() error {
 }
 
 func (rb *RandomBeacon) loadPolys() error {
-	b, err := posdb.GetDb().Get(rb.epochId, rbPloys)
+	b, err := rb.getDb().Get(rb.epochId, rbPloys)
 	if err != nil {
 		log.SyslogDebug("random beacon load polys fail", "err", err)
 		return err
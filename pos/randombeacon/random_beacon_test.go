@@ -13,9 +13,9 @@ import (
 	"github.com/wanchain/go-wanchain/ethdb"
 	"github.com/wanchain/go-wanchain/pos/epochLeader"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/postx"
 	"github.com/wanchain/go-wanchain/pos/rbselection"
 	"github.com/wanchain/go-wanchain/rlp"
-	"github.com/wanchain/go-wanchain/rpc"
 	"io"
 	"math/big"
 	"sync"
@@ -70,11 +70,10 @@ func callRBLoop(rb *RandomBeacon, wg *sync.WaitGroup) {
 		statedb, _ = state.New(common.Hash{}, state.NewDatabase(db))
 		epochId = uint64(0)
 		slotId = uint64(0)
-		rc = new(rpc.Client)
 	)
 
 	for ;; {
-		err := rb.Loop(statedb, rc, epochId, slotId)
+		err := rb.Loop(statedb, epochId, slotId)
 		if err != nil {
 			fmt.Println("callRbLoop break loop, err:", err)
 			break
@@ -293,10 +292,6 @@ func TestRandomBeacon_Init(t *testing.T) {
 		t.Error("invalid rb epocher")
 	}
 
-	if rb.rpcClient != nil {
-		t.Error("invalid rb rpc client")
-	}
-
 	rb.Init(&epocher)
 }
 
@@ -590,7 +585,6 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 		statedb, _ = state.New(common.Hash{}, state.NewDatabase(db))
 		epochId    = uint64(0)
 		slotId     = uint64(0)
-		rc         = new(rpc.Client)
 		epocher    epochLeader.Epocher
 		actureDkg1sCallTimes = 0
 		actureDkg2sCallTimes = 0
@@ -626,7 +620,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 		selfPrivate.D = posconfig.Cfg().GetMinerBn256SK()
 		selfPrivate.G1 = posconfig.Cfg().GetMinerBn256PK()
 
-		err = rb.doLoop(statedb, rc, epochId, slotId)
+		err = rb.doLoop(statedb, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -651,7 +645,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 		}
 
 		epochId++
-		err = rb.doLoop(statedb, rc, epochId, slotId)
+		err = rb.doLoop(statedb, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -674,7 +668,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 	{
 		slotId++
 
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -697,7 +691,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 	{
 		epochId++
 		slotId = 0
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -720,7 +714,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 		posconfig.SelfTestMode = false
 		slotId++
 		rb.fDoDKG1s = DoDKG1sFail
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, epochId, slotId)
 		if err == nil {
 			t.Error("doLoop success. expect fail")
 		}
@@ -744,7 +738,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 	{
 		slotId++
 		posconfig.SelfTestMode = true
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -766,7 +760,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 	{
 		slotId++
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -787,7 +781,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 	{
 		slotId = 40
 		posconfig.SelfTestMode = true
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -811,7 +805,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 		posconfig.SelfTestMode = false
 
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, epochId, slotId)
 		if err == nil {
 			t.Error("doLoop success. expect fail")
 		}
@@ -835,7 +829,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 	{
 		slotId++
 		posconfig.SelfTestMode = true
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -856,7 +850,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 	{
 		slotId++
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -876,7 +870,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 	{
 		slotId = 80
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -898,7 +892,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 		posconfig.SelfTestMode = false
 		slotId = posconfig.Cfg().SignBegin + 1
 		rb.fDoSIGs = DoSIGsFail
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, epochId, slotId)
 		if err == nil {
 			t.Error("doLoop success. expect fail")
 		}
@@ -922,7 +916,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 	{
 		slotId++
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -943,7 +937,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 
 	{
 		slotId++
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -964,7 +958,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 	{
 		epochId++
 		slotId = 0
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, epochId, slotId)
 		if err != nil {
 			t.Error("doLoop fail. err:", err)
 		}
@@ -985,7 +979,7 @@ func TestRandomBeacon_doLoop(t *testing.T) {
 	{
 		epochId--
 		slotId = 0
-		err := rb.doLoop(statedb, rc, epochId, slotId)
+		err := rb.doLoop(statedb, epochId, slotId)
 		if err == nil {
 			t.Error("doLoop success. expect fail")
 		}
@@ -1072,6 +1066,60 @@ func TestPolyMap_loadPolys(t *testing.T) {
 	}
 }
 
+func TestRandomBeacon_resendDKG1(t *testing.T) {
+	var epocher epochLeader.Epocher
+	var rb RandomBeacon
+
+	if err := initKeystore(&rb); err != nil {
+		t.Fatal(err)
+	}
+	rb.Init(&epocher)
+	rb.getRBProposerGroupF = tmpGetRBProposerGroup
+	rb.epochId = uint64(7)
+	rb.proposerPks = rb.getRBProposerGroupF(rb.epochId)
+
+	payload, err := rb.generateDKG1(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rb.storePolys(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the restarted beacon rebuilds the same commitment from the stored poly
+	restarted := RandomBeacon{epochId: rb.epochId, proposerPks: rb.proposerPks, polys: make(PolyMap)}
+	if err := restarted.loadPolys(); err != nil {
+		t.Fatal(err)
+	}
+	again, err := restarted.dkg1Payload(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Commit) != len(payload.Commit) {
+		t.Fatalf("commit length mismatch: have %d, want %d", len(again.Commit), len(payload.Commit))
+	}
+	for i := range payload.Commit {
+		if string(again.Commit[i]) != string(payload.Commit[i]) {
+			t.Fatalf("commit %d mismatch", i)
+		}
+	}
+
+	// the commitment isn't sent again once it's on chain
+	restarted.getCji = func(db vm.StateDB, epochId uint64, proposerId uint32) ([]*bn256.G2, error) {
+		dkg1, err := vm.Dkg1FlatToDkg1(payload)
+		return dkg1.Commit, err
+	}
+	if err := restarted.resendDKG1(0); err != nil {
+		t.Fatal("commitment on chain sent again:", err)
+	}
+	restarted.getCji = func(db vm.StateDB, epochId uint64, proposerId uint32) ([]*bn256.G2, error) {
+		return nil, nil
+	}
+	if err := restarted.resendDKG1(0); err != postx.ErrNotReady {
+		t.Fatalf("missing commitment not sent: err %v", err)
+	}
+}
+
 func TestPolyMap_DecodeRLP(t *testing.T) {
 	poly1 := make(rbselection.Polynomial, 0)
 	poly2 := make(rbselection.Polynomial, 0)
@@ -0,0 +1,509 @@
+// Package sidedata exports and imports the POS data that lives outside the
+// consensus state, so a node that fast synced the chain can still select
+// slot leaders, validate POS blocks and serve the pos_* RPCs.
+package sidedata
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/consensus/pluto"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/rbselection"
+	"github.com/wanchain/go-wanchain/pos/slotleader"
+	"github.com/wanchain/go-wanchain/pos/uleaderselection"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+// extraSeal is the length of the signer seal appended to a pluto header extra.
+const extraSeal = 65
+
+var (
+	ErrNoHeader          = errors.New("side data carries no epoch header")
+	ErrHeaderMismatch    = errors.New("side data header does not belong to epoch")
+	ErrTooManyLeaders    = errors.New("side data carries too many epoch leaders")
+	ErrLeaderCount       = errors.New("side data carries a wrong epoch leader count")
+	ErrLeaderMismatch    = errors.New("side data epoch leaders do not match the stage two records")
+	ErrTooManyProposers  = errors.New("side data carries too many random beacon proposers")
+	ErrInvalidProposer   = errors.New("side data carries an invalid proposer")
+	ErrProposerMismatch  = errors.New("side data random beacon proposers do not match the dkg1 commits")
+	ErrStakerMismatch    = errors.New("side data keys do not match the staker records")
+	ErrSignerNotLeader   = errors.New("epoch block signer is not an epoch leader")
+	ErrNotCanonical      = errors.New("side data epoch block is not canonical")
+	ErrUnknownHeader     = errors.New("side data epoch block is not known locally")
+	ErrSealMismatch      = errors.New("epoch block seal does not match its slot leader")
+	ErrStagingOrder      = errors.New("side data staged out of order")
+	ErrNoPosChain        = errors.New("chain has no pos phase")
+	errStakeOutUndecoded = errors.New("side data stake out record can't be decoded")
+)
+
+// ChainReader is the part of core.BlockChain needed to locate epoch blocks.
+type ChainReader interface {
+	Config() *params.ChainConfig
+	CurrentHeader() *types.Header
+	GetHeaderByNumber(number uint64) *types.Header
+}
+
+// posChain is implemented by the chains running their own POS services.
+type posChain interface {
+	PosContext() vm.PosContext
+}
+
+// localDb returns the local database called name of chain.
+func localDb(chain ChainReader, name string) *posdb.Db {
+	if c, ok := chain.(posChain); ok {
+		if pos := c.PosContext(); pos != nil {
+			return pos.LocalDb(name)
+		}
+	}
+	return posdb.NewDb(name)
+}
+
+// EpochSideData is the POS side data of a single epoch.
+type EpochSideData struct {
+	EpochID      uint64
+	Header       *types.Header // last block of the epoch
+	EpochLeaders [][]byte      // rlp encoded epochLeader.Proposer, in selection order
+	RBProposers  [][]byte      // rlp encoded epochLeader.Proposer, in selection order
+	StakeOut     []byte        // rlp encoded []epochLeader.RefundInfo, empty if none
+}
+
+// Export collects the side data of epochID. It returns nil without error if
+// the epoch has no block in the local chain yet.
+func Export(chain ChainReader, epochID uint64) (*EpochSideData, error) {
+	header, err := epochLastHeader(chain, epochID)
+	if err != nil || header == nil {
+		return nil, err
+	}
+
+	d := &EpochSideData{
+		EpochID:      epochID,
+		Header:       header,
+		EpochLeaders: localDb(chain, posconfig.EpLocalDB).GetStorageByteArray(epochID),
+		RBProposers:  localDb(chain, posconfig.RbLocalDB).GetStorageByteArray(epochID),
+	}
+	if stakeOut, err := localDb(chain, posconfig.PosLocalDB).Get(epochID, posconfig.StakeOutEpochKey); err == nil {
+		d.StakeOut = stakeOut
+	}
+	return d, nil
+}
+
+// Verify checks the side data is well formed: the header must be a pos block
+// of the epoch and every record must decode. It trusts nothing the peer sent,
+// VerifyChain does the checks against the local chain.
+func Verify(d *EpochSideData) error {
+	if d.Header == nil || d.Header.Difficulty == nil {
+		return ErrNoHeader
+	}
+	if epochID, _ := util.GetEpochSlotIDFromDifficulty(d.Header.Difficulty); epochID != d.EpochID {
+		return ErrHeaderMismatch
+	}
+	if len(d.EpochLeaders) > posconfig.EpochLeaderCount {
+		return ErrTooManyLeaders
+	}
+	if len(d.RBProposers) > posconfig.RandomProperCount {
+		return ErrTooManyProposers
+	}
+
+	if _, err := decodeProposers(d.EpochLeaders); err != nil {
+		return err
+	}
+	if _, err := decodeProposers(d.RBProposers); err != nil {
+		return err
+	}
+	if len(d.StakeOut) != 0 {
+		var stakeOut []epochLeader.RefundInfo
+		if err := rlp.DecodeBytes(d.StakeOut, &stakeOut); err != nil {
+			return errStakeOutUndecoded
+		}
+	}
+	return nil
+}
+
+// VerifyChain checks side data that passed Verify against the local chain and
+// returns the part of it that may be imported. The header must be canonical
+// and sealed by the slot leader it names, who must be one of the preLeaders,
+// the already imported epoch leaders of the previous epoch, or of the white
+// list if those are unknown.
+//
+// The epoch leaders and random beacon proposers are checked against stateDb:
+// the epoch leaders must pass the DLEQ proof of a stage two record of the
+// epoch, which covers the key of every leader, the proposers must pass the
+// Reed-Solomon check of a dkg1 commit of the epoch, which covers the bn256 key
+// of every proposer, and the keys of both must be those of their stakers. A
+// list stateDb contradicts is an error, a list it holds nothing to check
+// against is left out. The selection probabilities can't be checked and are
+// never imported.
+func VerifyChain(chain ChainReader, stateDb vm.StateDB, d *EpochSideData, preLeaders [][]byte) (*EpochSideData, error) {
+	if err := CheckCanonical(chain, d); err != nil {
+		return nil, err
+	}
+	signerPk, err := headerSignerPk(d.Header)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := pluto.Ecrecover(d.Header)
+	if err != nil {
+		return nil, err
+	}
+	if pk := crypto.ToECDSAPub(signerPk); pk == nil || pk.X == nil || crypto.PubkeyToAddress(*pk) != signer {
+		return nil, ErrSealMismatch
+	}
+	if len(preLeaders) == 0 {
+		preLeaders = posconfig.EpochLeadersHold
+	}
+	if !containsPk(preLeaders, signerPk) {
+		return nil, ErrSignerNotLeader
+	}
+
+	checked := &EpochSideData{EpochID: d.EpochID, Header: d.Header, StakeOut: d.StakeOut}
+
+	leaders, err := decodeProposers(d.EpochLeaders)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := checkEpochLeaders(stateDb, d.EpochID, leaders); err != nil {
+		return nil, err
+	} else if ok {
+		if checked.EpochLeaders, err = checkStakers(stateDb, leaders); err != nil {
+			return nil, err
+		}
+	}
+
+	proposers, err := decodeProposers(d.RBProposers)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := checkRBProposers(stateDb, d.EpochID, proposers); err != nil {
+		return nil, err
+	} else if ok {
+		if checked.RBProposers, err = checkStakers(stateDb, proposers); err != nil {
+			return nil, err
+		}
+	}
+	return checked, nil
+}
+
+// checkEpochLeaders checks the epoch leaders of epochID against the stage two
+// records of the epoch. The leaders chosen from the white list are not part of
+// the side data. It reports false if there are no leaders or no record to
+// check them with.
+func checkEpochLeaders(stateDb vm.StateDB, epochID uint64, leaders []epochLeader.Proposer) (bool, error) {
+	if len(leaders) == 0 {
+		return false, nil
+	}
+	info := vm.GetEpochWLInfo(stateDb, epochID)
+	wlIndex, wlCount := info.WlIndex.Uint64(), info.WlCount.Uint64()
+	if wlIndex+wlCount > uint64(len(posconfig.EpochLeadersHold)) {
+		return false, nil
+	}
+	white := posconfig.EpochLeadersHold[wlIndex : wlIndex+wlCount]
+
+	var pks []*ecdsa.PublicKey
+	for i := 0; i < posconfig.EpochLeaderCount; i++ {
+		key := vm.GetSlotLeaderStage2KeyHash(convert.Uint64ToBytes(epochID), convert.Uint64ToBytes(uint64(i)))
+		record := stateDb.GetStateByteArray(vm.GetSlotLeaderSCAddress(), key)
+		if len(record) == 0 {
+			continue
+		}
+		if len(leaders)+len(white) != posconfig.EpochLeaderCount {
+			return false, ErrLeaderCount
+		}
+		if len(record) < 4 {
+			return false, ErrLeaderMismatch
+		}
+		_, selfIndex, selfPk, alphaPkis, proof, err := vm.RlpUnpackStage2DataForTx(record)
+		if err != nil || selfIndex != uint64(i) {
+			return false, ErrLeaderMismatch
+		}
+		if pks == nil {
+			// the proof binds the keys of all the leaders, one is enough
+			pks = make([]*ecdsa.PublicKey, 0, posconfig.EpochLeaderCount)
+			for _, leader := range leaders {
+				pks = append(pks, crypto.ToECDSAPub(leader.PubSec256))
+			}
+			for _, pk := range white {
+				pks = append(pks, crypto.ToECDSAPub(pk))
+			}
+			for _, pk := range alphaPkis {
+				if pk == nil || pk.X == nil {
+					return false, ErrLeaderMismatch
+				}
+			}
+			if !uleaderselection.VerifyDleqProof(pks, alphaPkis, proof) {
+				return false, ErrLeaderMismatch
+			}
+		}
+		if !util.PkEqual(selfPk, pks[i]) {
+			return false, ErrLeaderMismatch
+		}
+	}
+	return pks != nil, nil
+}
+
+// checkRBProposers checks the random beacon proposers of epochID against the
+// dkg1 commits of the epoch. It reports false if there are no proposers or no
+// commit to check them with.
+func checkRBProposers(stateDb vm.StateDB, epochID uint64, proposers []epochLeader.Proposer) (bool, error) {
+	if len(proposers) == 0 {
+		return false, nil
+	}
+	for id := 0; id < posconfig.RandomProperCount; id++ {
+		commit, err := vm.GetCji(stateDb, epochID, uint32(id))
+		if err != nil || len(commit) == 0 {
+			continue
+		}
+		// the commit is a polynomial evaluated at a point derived from the
+		// bn256 key of each proposer, one is enough
+		if len(commit) != len(proposers) {
+			return false, ErrProposerMismatch
+		}
+		x := make([]big.Int, len(proposers))
+		points := make([]bn256.G2, len(proposers))
+		for i := range proposers {
+			var pk bn256.G1
+			if _, err := pk.Unmarshal(proposers[i].PubBn256); err != nil {
+				return false, ErrInvalidProposer
+			}
+			x[i].SetBytes(vm.GetPolynomialX(&pk, uint32(i)))
+			x[i].Mod(&x[i], bn256.Order)
+			points[i] = *commit[i]
+		}
+		if !rbselection.RScodeVerify(points, x, int(posconfig.Cfg().PolymDegree)) {
+			return false, ErrProposerMismatch
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// checkStakers checks the keys of every proposer against its staker record
+// and returns them encoded for import. It returns nil if a staker is gone.
+func checkStakers(stateDb vm.StateDB, proposers []epochLeader.Proposer) ([][]byte, error) {
+	vals := make([][]byte, len(proposers))
+	for i, proposer := range proposers {
+		addr := crypto.PubkeyToAddress(*crypto.ToECDSAPub(proposer.PubSec256))
+		stakerBytes := stateDb.GetStateByteArray(vm.StakersInfoAddr, vm.GetStakeInKeyHash(addr))
+		if len(stakerBytes) == 0 {
+			return nil, nil
+		}
+		var staker vm.StakerInfo
+		if err := rlp.DecodeBytes(stakerBytes, &staker); err != nil {
+			return nil, nil
+		}
+		if !bytes.Equal(staker.PubSec256, proposer.PubSec256) || !bytes.Equal(staker.PubBn256, proposer.PubBn256) {
+			return nil, ErrStakerMismatch
+		}
+		val, err := rlp.EncodeToBytes(&epochLeader.Proposer{PubSec256: proposer.PubSec256, PubBn256: proposer.PubBn256})
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = val
+	}
+	return vals, nil
+}
+
+// Import stores side data returned by VerifyChain in the local POS databases
+// of chain the same way the epoch leader selection and stake out procedures
+// would.
+func Import(chain ChainReader, d *EpochSideData) error {
+	epDb := localDb(chain, posconfig.EpLocalDB)
+	for i, val := range d.EpochLeaders {
+		if _, err := epDb.PutWithIndex(d.EpochID, uint64(i), "", val); err != nil {
+			return err
+		}
+	}
+	rbDb := localDb(chain, posconfig.RbLocalDB)
+	for i, val := range d.RBProposers {
+		if _, err := rbDb.PutWithIndex(d.EpochID, uint64(i), "", val); err != nil {
+			return err
+		}
+	}
+	if len(d.StakeOut) != 0 {
+		if _, err := localDb(chain, posconfig.PosLocalDB).Put(d.EpochID, posconfig.StakeOutEpochKey, d.StakeOut); err != nil {
+			return err
+		}
+	}
+	util.SetEpochBlock(d.EpochID, d.Header.Number.Uint64(), d.Header.Hash())
+
+	log.Debug("Imported pos side data", "epochID", d.EpochID, "block", d.Header.Number,
+		"leaders", len(d.EpochLeaders), "proposers", len(d.RBProposers))
+	return nil
+}
+
+// Staging holds side data received from a peer until the headers it refers
+// to are part of the local canonical chain. Nothing reaches the POS databases
+// before Commit has checked it against the chain.
+type Staging struct {
+	data []*EpochSideData // in epoch order
+	err  error            // first commit failure, the staging is unusable after it
+}
+
+// NewStaging creates an empty staging area.
+func NewStaging() *Staging {
+	return &Staging{}
+}
+
+// Add verifies d is well formed and stages it. Side data must be added in
+// increasing epoch order.
+func (s *Staging) Add(d *EpochSideData) error {
+	if n := len(s.data); n > 0 && d.EpochID <= s.data[n-1].EpochID {
+		return ErrStagingOrder
+	}
+	if err := Verify(d); err != nil {
+		return err
+	}
+	s.data = append(s.data, d)
+	return nil
+}
+
+// Len returns the number of side data records still staged.
+func (s *Staging) Len() int {
+	return len(s.data)
+}
+
+// Commit checks the staged side data whose epoch block is at or below number
+// with VerifyChain and imports what it confirms, in epoch order. The rest stays staged. A
+// failure is sticky: the peer sent data that does not match the chain, so
+// nothing more of it is imported.
+func (s *Staging) Commit(chain ChainReader, stateDb vm.StateDB, number uint64) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	committed := 0
+	for len(s.data) > 0 && s.data[0].Header.Number.Uint64() <= number {
+		d := s.data[0]
+		preLeaders := posdb.GetEpochLeaderGroupWithDb(localDb(chain, posconfig.EpLocalDB), d.EpochID-1)
+		checked, err := VerifyChain(chain, stateDb, d, preLeaders)
+		if err != nil {
+			s.data, s.err = nil, err
+			return committed, err
+		}
+		if err := Import(chain, checked); err != nil {
+			s.data, s.err = nil, err
+			return committed, err
+		}
+		s.data = s.data[1:]
+		committed++
+	}
+	return committed, nil
+}
+
+// CheckCanonical makes sure the epoch block of the side data is part of the
+// local canonical chain. It is meant to run once the headers the side data
+// refers to have been downloaded, a missing header is an error.
+func CheckCanonical(chain ChainReader, d *EpochSideData) error {
+	local := chain.GetHeaderByNumber(d.Header.Number.Uint64())
+	if local == nil {
+		return fmt.Errorf("%v: epoch %d, block %d", ErrUnknownHeader, d.EpochID, d.Header.Number.Uint64())
+	}
+	if local.Hash() != d.Header.Hash() {
+		return fmt.Errorf("%v: epoch %d, block %d", ErrNotCanonical, d.EpochID, d.Header.Number.Uint64())
+	}
+	return nil
+}
+
+func decodeProposers(vals [][]byte) ([]epochLeader.Proposer, error) {
+	proposers := make([]epochLeader.Proposer, len(vals))
+	for i, val := range vals {
+		if err := rlp.DecodeBytes(val, &proposers[i]); err != nil {
+			return nil, ErrInvalidProposer
+		}
+		if crypto.ToECDSAPub(proposers[i].PubSec256) == nil {
+			return nil, ErrInvalidProposer
+		}
+		if _, err := new(bn256.G1).Unmarshal(proposers[i].PubBn256); err != nil {
+			return nil, ErrInvalidProposer
+		}
+	}
+	return proposers, nil
+}
+
+func containsPk(pks [][]byte, pk []byte) bool {
+	for _, val := range pks {
+		if bytes.Equal(val, pk) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerSignerPk returns the slot leader public key carried in the header
+// extra. It is only trusted once checked against the seal.
+func headerSignerPk(header *types.Header) ([]byte, error) {
+	if len(header.Extra) <= extraSeal {
+		return nil, ErrSignerNotLeader
+	}
+	var info slotleader.Pack
+	if err := rlp.DecodeBytes(header.Extra[:len(header.Extra)-extraSeal], &info); err != nil {
+		return nil, err
+	}
+	if len(info.ProofMeg) == 0 {
+		return nil, ErrSignerNotLeader
+	}
+	return info.ProofMeg[0], nil
+}
+
+// epochLastHeader returns the last canonical header of epochID, preferring the
+// block recorded by util.SetEpochBlock and falling back to a binary search of
+// the pos part of the chain.
+func epochLastHeader(chain ChainReader, epochID uint64) (*types.Header, error) {
+	if number, hash, ok := util.GetEpochBlockRecord(epochID); ok {
+		if header := chain.GetHeaderByNumber(number); header != nil && header.Hash() == hash {
+			return header, nil
+		}
+	}
+
+	posFirst := chain.Config().PosFirstBlock
+	if posFirst == nil {
+		return nil, ErrNoPosChain
+	}
+	current := chain.CurrentHeader()
+	if current == nil || current.Number.Cmp(posFirst) < 0 {
+		return nil, nil
+	}
+	if curEpoch, _ := util.GetEpochSlotIDFromDifficulty(current.Difficulty); curEpoch <= epochID {
+		// the epoch is still in progress or in the future
+		return nil, nil
+	}
+
+	// search the first block whose epoch is beyond epochID
+	lo, hi := posFirst.Uint64(), current.Number.Uint64()
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		header := chain.GetHeaderByNumber(mid)
+		if header == nil {
+			return nil, fmt.Errorf("missing header %d", mid)
+		}
+		if e, _ := util.GetEpochSlotIDFromDifficulty(header.Difficulty); e > epochID {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	if lo <= posFirst.Uint64() {
+		return nil, nil
+	}
+	header := chain.GetHeaderByNumber(lo - 1)
+	if header == nil {
+		return nil, fmt.Errorf("missing header %d", lo-1)
+	}
+	if e, _ := util.GetEpochSlotIDFromDifficulty(header.Difficulty); e != epochID {
+		// no block was produced in this epoch
+		return nil, nil
+	}
+	return header, nil
+}
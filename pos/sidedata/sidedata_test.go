@@ -0,0 +1,502 @@
+package sidedata
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	bn256 "github.com/wanchain/go-wanchain/crypto/bn256/cloudflare"
+	"github.com/wanchain/go-wanchain/crypto/sha3"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/params"
+	"github.com/wanchain/go-wanchain/pos/epochLeader"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/posdb"
+	"github.com/wanchain/go-wanchain/pos/rbselection"
+	"github.com/wanchain/go-wanchain/pos/slotleader"
+	"github.com/wanchain/go-wanchain/pos/uleaderselection"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+type testChain struct {
+	config  *params.ChainConfig
+	headers []*types.Header
+}
+
+func (c *testChain) Config() *params.ChainConfig  { return c.config }
+func (c *testChain) CurrentHeader() *types.Header { return c.headers[len(c.headers)-1] }
+func (c *testChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number >= uint64(len(c.headers)) {
+		return nil
+	}
+	return c.headers[number]
+}
+
+func epochDifficulty(epochID, slotID uint64) *big.Int {
+	return new(big.Int).SetUint64(epochID<<32 | slotID<<8)
+}
+
+func newProposer(t *testing.T) ([]byte, []byte) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encodeProposer(t, key)
+}
+
+func encodeProposer(t *testing.T, key *ecdsa.PrivateKey) ([]byte, []byte) {
+	_, g1, err := bn256.RandomG1(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk := crypto.FromECDSAPub(&key.PublicKey)
+	val, err := rlp.EncodeToBytes(&epochLeader.Proposer{PubSec256: pk, PubBn256: g1.Marshal(), Probabilities: big.NewInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pk, val
+}
+
+func newHeader(t *testing.T, number, epochID, slotID uint64, signer []byte) *types.Header {
+	pack, err := rlp.EncodeToBytes(&slotleader.Pack{ProofMeg: [][]byte{signer}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &types.Header{
+		Number:     new(big.Int).SetUint64(number),
+		Difficulty: epochDifficulty(epochID, slotID),
+		Extra:      append(pack, make([]byte, extraSeal)...),
+	}
+}
+
+// sealHeader signs header the way the pluto engine does.
+func sealHeader(t *testing.T, header *types.Header, key *ecdsa.PrivateKey) {
+	hasher := sha3.NewKeccak256()
+	rlp.Encode(hasher, []interface{}{
+		header.ParentHash,
+		header.UncleHash,
+		header.Coinbase,
+		header.Root,
+		header.TxHash,
+		header.ReceiptHash,
+		header.Bloom,
+		header.Difficulty,
+		header.Number,
+		header.GasLimit,
+		header.GasUsed,
+		header.Time,
+		header.Extra[:len(header.Extra)-extraSeal],
+		header.MixDigest,
+		header.Nonce,
+	})
+	sig, err := crypto.Sign(hasher.Sum(nil), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+}
+
+// newLeaders returns the keys of a full epoch leader group, whose last
+// entries are the white list, and its leaders encoded as in the side data.
+func newLeaders(t *testing.T) ([]*ecdsa.PrivateKey, [][]byte) {
+	wlCount := int(vm.UpgradeWhiteEpochLeaderDefault.WlCount.Int64())
+	keys := make([]*ecdsa.PrivateKey, posconfig.EpochLeaderCount)
+	leaders := make([][]byte, posconfig.EpochLeaderCount-wlCount)
+	posconfig.EpochLeadersHold = make([][]byte, wlCount)
+	for i := range keys {
+		var err error
+		if keys[i], err = crypto.GenerateKey(); err != nil {
+			t.Fatal(err)
+		}
+		if i < len(leaders) {
+			_, leaders[i] = encodeProposer(t, keys[i])
+		} else {
+			posconfig.EpochLeadersHold[i-len(leaders)] = crypto.FromECDSAPub(&keys[i].PublicKey)
+		}
+	}
+	return keys, leaders
+}
+
+// putStage2 stores the stage two record the epoch leader index of epochID
+// sends for the group of keys.
+func putStage2(t *testing.T, stateDb *state.StateDB, epochID, index uint64, keys []*ecdsa.PrivateKey) {
+	alpha, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pks := make([]*ecdsa.PublicKey, len(keys))
+	alphaPki := make([]*ecdsa.PublicKey, len(keys))
+	for i, key := range keys {
+		pks[i] = &key.PublicKey
+		alphaPki[i] = new(ecdsa.PublicKey)
+		alphaPki[i].Curve = crypto.S256()
+		alphaPki[i].X, alphaPki[i].Y = crypto.S256().ScalarMult(key.X, key.Y, alpha.D.Bytes())
+	}
+	proof, err := uleaderselection.DleqProofGeneration(pks, alphaPki, alpha.D)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := vm.RlpPackStage2DataForTx(epochID, index, pks[index], alphaPki, proof, vm.GetSlotLeaderScAbiString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := vm.GetSlotLeaderStage2KeyHash(convert.Uint64ToBytes(epochID), convert.Uint64ToBytes(index))
+	stateDb.SetStateByteArray(vm.GetSlotLeaderSCAddress(), key, data)
+}
+
+// putDkg1 stores the dkg1 commit the random beacon proposer id of epochID
+// sends for proposers.
+func putDkg1(t *testing.T, stateDb *state.StateDB, epochID uint64, id uint32, proposers [][]byte) {
+	degree := int(posconfig.Cfg().PolymDegree)
+	poly, err := rbselection.RandPoly(degree, *big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := make([][]byte, len(proposers))
+	for i, val := range proposers {
+		var proposer epochLeader.Proposer
+		if err := rlp.DecodeBytes(val, &proposer); err != nil {
+			t.Fatal(err)
+		}
+		var pk bn256.G1
+		if _, err := pk.Unmarshal(proposer.PubBn256); err != nil {
+			t.Fatal(err)
+		}
+		x := new(big.Int).SetBytes(vm.GetPolynomialX(&pk, uint32(i)))
+		x.Mod(x, bn256.Order)
+		share, err := rbselection.EvaluatePoly(poly, x, degree)
+		if err != nil {
+			t.Fatal(err)
+		}
+		commit[i] = new(bn256.G2).ScalarBaseMult(&share).Marshal()
+	}
+	data, err := rlp.EncodeToBytes(commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 100 is the kind of the dkg1 commits in the random beacon contract
+	stateDb.SetStateByteArray(vm.GetRBAddress(), *vm.GetRBKeyHash([]byte{100}, epochID, id), data)
+}
+
+// putStakers stores the staker records of the encoded proposers.
+func putStakers(t *testing.T, stateDb *state.StateDB, proposers [][]byte) {
+	for _, val := range proposers {
+		var proposer epochLeader.Proposer
+		if err := rlp.DecodeBytes(val, &proposer); err != nil {
+			t.Fatal(err)
+		}
+		info := &vm.StakerInfo{
+			Address:   crypto.PubkeyToAddress(*crypto.ToECDSAPub(proposer.PubSec256)),
+			PubSec256: proposer.PubSec256,
+			PubBn256:  proposer.PubBn256,
+		}
+		if err := vm.StoreStakerInfo(stateDb, info); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newProposers(t *testing.T) [][]byte {
+	proposers := make([][]byte, posconfig.RandomProperCount)
+	for i := range proposers {
+		_, proposers[i] = newProposer(t)
+	}
+	return proposers
+}
+
+func TestVerify(t *testing.T) {
+	pk, _ := newProposer(t)
+	_, proposer := newProposer(t)
+	_, leaders := newLeaders(t)
+
+	d := &EpochSideData{
+		EpochID:      10,
+		Header:       newHeader(t, 100, 10, 5, pk),
+		EpochLeaders: leaders,
+		RBProposers:  [][]byte{proposer},
+	}
+	if err := Verify(d); err != nil {
+		t.Fatalf("valid side data rejected: %v", err)
+	}
+
+	d.Header = newHeader(t, 100, 11, 5, pk)
+	if err := Verify(d); err != ErrHeaderMismatch {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrHeaderMismatch)
+	}
+
+	d.Header = newHeader(t, 100, 10, 5, pk)
+	d.EpochLeaders = make([][]byte, posconfig.EpochLeaderCount+1)
+	for i := range d.EpochLeaders {
+		d.EpochLeaders[i] = proposer
+	}
+	if err := Verify(d); err != ErrTooManyLeaders {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrTooManyLeaders)
+	}
+
+	d.EpochLeaders = leaders
+	d.RBProposers = [][]byte{{0x01, 0x02}}
+	if err := Verify(d); err != ErrInvalidProposer {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrInvalidProposer)
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	keys, leaders := newLeaders(t)
+	proposers := newProposers(t)
+	signerKey := keys[0]
+	signerPk := crypto.FromECDSAPub(&signerKey.PublicKey)
+	preLeaders := [][]byte{signerPk}
+
+	header := newHeader(t, 2, 10, 5, signerPk)
+	sealHeader(t, header, signerKey)
+	chain := &testChain{config: &params.ChainConfig{PosFirstBlock: big.NewInt(2)}}
+	chain.headers = []*types.Header{
+		{Number: big.NewInt(0), Difficulty: big.NewInt(1)},
+		{Number: big.NewInt(1), Difficulty: big.NewInt(1)},
+		header,
+	}
+
+	// without stage two records and dkg1 commits nothing can be imported
+	db, _ := ethdb.NewMemDatabase()
+	stateDb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	putStakers(t, stateDb, leaders)
+	putStakers(t, stateDb, proposers)
+
+	d := &EpochSideData{EpochID: 10, Header: header, EpochLeaders: leaders, RBProposers: proposers}
+	checked, err := VerifyChain(chain, stateDb, d, preLeaders)
+	if err != nil {
+		t.Fatalf("valid side data rejected: %v", err)
+	}
+	if len(checked.EpochLeaders) != 0 || len(checked.RBProposers) != 0 {
+		t.Fatal("unchecked side data returned for import")
+	}
+
+	// the leaders of indexes 0 and 3 sent their stage two records, the
+	// proposer 2 its dkg1 commit
+	putStage2(t, stateDb, 10, 0, keys)
+	putStage2(t, stateDb, 10, 3, keys)
+	putDkg1(t, stateDb, 10, 2, proposers)
+	if checked, err = VerifyChain(chain, stateDb, d, preLeaders); err != nil {
+		t.Fatalf("valid side data rejected: %v", err)
+	}
+	if len(checked.EpochLeaders) != len(leaders) || len(checked.RBProposers) != len(proposers) {
+		t.Fatal("checked side data not returned for import")
+	}
+	var leader epochLeader.Proposer
+	if err := rlp.DecodeBytes(checked.EpochLeaders[1], &leader); err != nil {
+		t.Fatal(err)
+	}
+	if crypto.ToECDSAPub(leader.PubSec256).X.Cmp(keys[1].X) != 0 || leader.Probabilities.Sign() != 0 {
+		t.Fatalf("checked leader mismatch: %v", leader)
+	}
+
+	// the signer must be a leader of the previous epoch
+	other, _ := newProposer(t)
+	if _, err := VerifyChain(chain, stateDb, d, [][]byte{other}); err != ErrSignerNotLeader {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrSignerNotLeader)
+	}
+
+	// leaders that contradict the stage two records, even at an index that
+	// sent none, or a wrong number of them
+	for _, i := range []int{3, 5} {
+		forged := append([][]byte{}, leaders...)
+		_, forged[i] = newProposer(t)
+		putStakers(t, stateDb, forged[i:i+1])
+		d := &EpochSideData{EpochID: 10, Header: header, EpochLeaders: forged}
+		if _, err := VerifyChain(chain, stateDb, d, preLeaders); err != ErrLeaderMismatch {
+			t.Fatalf("index %d: error mismatch: have %v, want %v", i, err, ErrLeaderMismatch)
+		}
+	}
+	d = &EpochSideData{EpochID: 10, Header: header, EpochLeaders: leaders[1:]}
+	if _, err := VerifyChain(chain, stateDb, d, preLeaders); err != ErrLeaderCount {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrLeaderCount)
+	}
+
+	// proposers that contradict the dkg1 commit
+	forged := append([][]byte{}, proposers...)
+	forged[0], forged[1] = forged[1], forged[0]
+	d = &EpochSideData{EpochID: 10, Header: header, RBProposers: forged}
+	if _, err := VerifyChain(chain, stateDb, d, preLeaders); err != ErrProposerMismatch {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrProposerMismatch)
+	}
+
+	// a bn256 key that is not the one of the staker
+	pk, _ := newProposer(t)
+	forged = append([][]byte{}, proposers...)
+	var proposer epochLeader.Proposer
+	if err := rlp.DecodeBytes(forged[4], &proposer); err != nil {
+		t.Fatal(err)
+	}
+	proposer.PubSec256 = pk
+	if forged[4], err = rlp.EncodeToBytes(&proposer); err != nil {
+		t.Fatal(err)
+	}
+	putStakers(t, stateDb, [][]byte{leaders[0]})
+	info := &vm.StakerInfo{Address: crypto.PubkeyToAddress(*crypto.ToECDSAPub(pk)), PubSec256: pk, PubBn256: []byte{1}}
+	if err := vm.StoreStakerInfo(stateDb, info); err != nil {
+		t.Fatal(err)
+	}
+	d = &EpochSideData{EpochID: 10, Header: header, RBProposers: forged}
+	if _, err := VerifyChain(chain, stateDb, d, preLeaders); err != ErrStakerMismatch {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrStakerMismatch)
+	}
+
+	// the slot leader named in the extra must have sealed the header
+	forgedHeader := newHeader(t, 2, 10, 5, signerPk)
+	sealHeader(t, forgedHeader, keys[1])
+	chain.headers[2] = forgedHeader
+	d = &EpochSideData{EpochID: 10, Header: forgedHeader, EpochLeaders: leaders}
+	if _, err := VerifyChain(chain, stateDb, d, preLeaders); err != ErrSealMismatch {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrSealMismatch)
+	}
+
+	// the header must be known and canonical
+	chain.headers = chain.headers[:2]
+	if _, err := VerifyChain(chain, stateDb, d, preLeaders); err == nil {
+		t.Fatal("side data of an unknown header accepted")
+	}
+}
+
+func TestStaging(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sidedata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	posdb.DbInitAll(dir)
+
+	keys, leaders := newLeaders(t)
+	signerPk := crypto.FromECDSAPub(&keys[0].PublicKey)
+	chain := &testChain{config: &params.ChainConfig{PosFirstBlock: big.NewInt(1)}}
+	chain.headers = []*types.Header{{Number: big.NewInt(0), Difficulty: big.NewInt(1)}}
+	for i, epochID := range []uint64{20, 21, 22} {
+		header := newHeader(t, uint64(i+1), epochID, 5, signerPk)
+		sealHeader(t, header, keys[0])
+		chain.headers = append(chain.headers, header)
+	}
+	db, _ := ethdb.NewMemDatabase()
+	stateDb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	putStakers(t, stateDb, leaders)
+	for _, epochID := range []uint64{20, 21, 22} {
+		putStage2(t, stateDb, epochID, 1, keys)
+	}
+
+	// epoch 20 is signed by a leader of epoch 19, the later ones by the
+	// leaders of the previous epoch imported before them
+	_, leaders19 := encodeProposer(t, keys[0])
+	epDb := posdb.NewDb(posconfig.EpLocalDB)
+	if _, err := epDb.PutWithIndex(19, 0, "", leaders19); err != nil {
+		t.Fatal(err)
+	}
+
+	staging := NewStaging()
+	for i, epochID := range []uint64{20, 21, 22} {
+		d := &EpochSideData{EpochID: epochID, Header: chain.headers[i+1], EpochLeaders: leaders}
+		if err := staging.Add(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := staging.Add(&EpochSideData{EpochID: 21, Header: chain.headers[2]}); err != ErrStagingOrder {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrStagingOrder)
+	}
+	if len(posdb.GetEpochLeaderGroup(20)) != 0 {
+		t.Fatal("staged side data imported")
+	}
+
+	// only the side data up to the given block is imported
+	if n, err := staging.Commit(chain, stateDb, 2); err != nil || n != 2 {
+		t.Fatalf("commit mismatch: have %d %v, want 2", n, err)
+	}
+	if len(posdb.GetEpochLeaderGroup(21)) != len(leaders) || len(posdb.GetEpochLeaderGroup(22)) != 0 {
+		t.Fatal("side data imported beyond the committed block")
+	}
+
+	// side data that doesn't match the chain is dropped, and the staging with it
+	chain.headers[3] = newHeader(t, 3, 22, 9, signerPk)
+	if _, err := staging.Commit(chain, stateDb, 3); err == nil {
+		t.Fatal("non canonical side data committed")
+	}
+	if n, err := staging.Commit(chain, stateDb, 3); err == nil || n != 0 || staging.Len() != 0 {
+		t.Fatalf("failed staging reused: %d %v", n, err)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sidedata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	posdb.DbInitAll(dir)
+
+	pk, leader := newProposer(t)
+	_, proposer := newProposer(t)
+
+	// blocks 0-1 are pow, then two blocks in epoch 7, one in epoch 9 and one in epoch 10
+	chain := &testChain{config: &params.ChainConfig{PosFirstBlock: big.NewInt(2)}}
+	chain.headers = []*types.Header{
+		{Number: big.NewInt(0), Difficulty: big.NewInt(1)},
+		{Number: big.NewInt(1), Difficulty: big.NewInt(1)},
+		newHeader(t, 2, 7, 1, pk),
+		newHeader(t, 3, 7, 2, pk),
+		newHeader(t, 4, 9, 1, pk),
+		newHeader(t, 5, 10, 1, pk),
+	}
+
+	d := &EpochSideData{
+		EpochID:      7,
+		Header:       chain.headers[3],
+		EpochLeaders: [][]byte{leader},
+		RBProposers:  [][]byte{proposer},
+	}
+	if err := Import(chain, d); err != nil {
+		t.Fatal(err)
+	}
+	if number, hash, ok := util.GetEpochBlockRecord(7); !ok || number != 3 || hash != chain.headers[3].Hash() {
+		t.Fatalf("epoch block not recorded: %d %x %v", number, hash, ok)
+	}
+	if len(posdb.GetEpochLeaderGroup(7)) != 1 || len(posdb.GetRBProposerGroup(7)) != 1 {
+		t.Fatal("leaders not imported")
+	}
+
+	exported, err := Export(chain, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exported == nil || exported.Header.Hash() != d.Header.Hash() || len(exported.EpochLeaders) != 1 {
+		t.Fatalf("exported side data mismatch: %v", exported)
+	}
+	if err := CheckCanonical(chain, exported); err != nil {
+		t.Fatal(err)
+	}
+
+	// epoch 9 is located by searching the header chain
+	if exported, err = Export(chain, 9); err != nil || exported == nil || exported.Header.Number.Uint64() != 4 {
+		t.Fatalf("epoch 9 not found: %v %v", exported, err)
+	}
+	// epoch 8 has no block and epoch 10 is still in progress
+	for _, epochID := range []uint64{8, 10} {
+		if exported, err = Export(chain, epochID); err != nil || exported != nil {
+			t.Fatalf("epoch %d exported: %v %v", epochID, exported, err)
+		}
+	}
+
+	d.Header = newHeader(t, 3, 7, 9, pk)
+	if err := CheckCanonical(chain, d); err == nil {
+		t.Fatal("non canonical side data accepted")
+	}
+	d.Header = newHeader(t, 9, 7, 9, pk)
+	if err := CheckCanonical(chain, d); err == nil {
+		t.Fatal("side data of a missing header accepted")
+	}
+}
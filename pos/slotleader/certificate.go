@@ -0,0 +1,393 @@
+package slotleader
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/uleaderselection"
+	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+	"github.com/wanchain/go-wanchain/rlp"
+	"github.com/wanchain/go-wanchain/trie"
+)
+
+var (
+	ErrCertAnchorMismatch = errors.New("leader certificate anchor header mismatch")
+	ErrCertLeaderCount    = errors.New("leader certificate has a wrong epoch leader count")
+	ErrCertInvalidLeader  = errors.New("leader certificate has an invalid epoch leader")
+	ErrCertRandomBeacon   = errors.New("leader certificate random beacon is not proven")
+	ErrCertStage2         = errors.New("leader certificate stage two record is not proven")
+	ErrCertNoStage2       = errors.New("leader certificate has no valid stage two record")
+	ErrCertNotVerified    = errors.New("leader certificate is not verified")
+	ErrCertSlotLeader     = errors.New("header signer is not the certified slot leader")
+	ErrCertGenesis        = errors.New("leader certificate claims genesis leaders for an epoch with stage two records")
+)
+
+// LeaderCertificate carries what is needed to check the slot leader proofs of
+// the headers of one epoch without a state database. The random beacon and the
+// stage two records are proven against the state root of the anchor header,
+// the last block of the previous epoch. Genesis certificates are used for the
+// epochs whose slot leaders are drawn from the white list. Their leaders are
+// checked against the local white list; past the first epochs they must also
+// prove that no stage two record of the previous epoch made it on chain.
+type LeaderCertificate struct {
+	EpochID         uint64
+	Genesis         bool
+	AnchorNumber    uint64
+	AnchorHash      common.Hash
+	PreEpochLeaders [][]byte // epoch leaders of EpochID-1, in selection order
+	RandomBeacon    []byte
+	SlotLeaders     [][]byte // slot leader of every slot, empty if not known
+
+	RBAccountProof   []rlp.RawValue
+	RBStorageProof   []rlp.RawValue
+	SlotAccountProof []rlp.RawValue
+	Stage2           [][]byte         // stage two record per epoch leader index, empty if not sent
+	Stage2Proofs     [][]rlp.RawValue // storage proof of every stage two record
+
+	// filled by Verify
+	verified   bool
+	genesis    bool
+	leaders    []*ecdsa.PublicKey
+	validIndex []bool
+	alphaPki   [][posconfig.EpochLeaderCount]*ecdsa.PublicKey
+}
+
+// NewLeaderCertificate builds a certificate of epochID from the state of the
+// anchor header, which must be the last block of epoch epochID-1.
+func NewLeaderCertificate(stateDb *state.StateDB, anchor *types.Header, epochID uint64,
+	preEpochLeaders []*ecdsa.PublicKey) (*LeaderCertificate, error) {
+
+	cert := &LeaderCertificate{
+		EpochID:         epochID,
+		AnchorNumber:    anchor.Number.Uint64(),
+		AnchorHash:      anchor.Hash(),
+		PreEpochLeaders: convert.PkArrayToByteArray(preEpochLeaders),
+		Stage2:          make([][]byte, posconfig.EpochLeaderCount),
+		Stage2Proofs:    make([][]rlp.RawValue, posconfig.EpochLeaderCount),
+	}
+
+	var err error
+	rbAddr := vm.GetRBAddress()
+	rbKey := vm.GetRBRKeyHash(epochID)
+	cert.RandomBeacon = stateDb.GetStateByteArray(rbAddr, *rbKey)
+	if cert.RBAccountProof, err = stateDb.GetProof(rbAddr); err != nil {
+		return nil, err
+	}
+	// absent values are proven too, so a certificate can't hide a record
+	if cert.RBStorageProof, err = stateDb.GetStorageProof(rbAddr, *rbKey); err != nil {
+		return nil, err
+	}
+
+	slotAddr := vm.GetSlotLeaderSCAddress()
+	if cert.SlotAccountProof, err = stateDb.GetProof(slotAddr); err != nil {
+		return nil, err
+	}
+	for i := 0; i < posconfig.EpochLeaderCount; i++ {
+		key := vm.GetSlotLeaderStage2KeyHash(convert.Uint64ToBytes(epochID-1), convert.Uint64ToBytes(uint64(i)))
+		cert.Stage2[i] = stateDb.GetStateByteArray(slotAddr, key)
+		if cert.Stage2Proofs[i], err = stateDb.GetStorageProof(slotAddr, key); err != nil {
+			return nil, err
+		}
+	}
+	return cert, nil
+}
+
+// NewGenesisLeaderCertificate builds a certificate for an epoch whose slot
+// leader proofs are made by the genesis epoch leaders.
+func NewGenesisLeaderCertificate(epochID uint64, genesisLeaders []*ecdsa.PublicKey) *LeaderCertificate {
+	return &LeaderCertificate{
+		EpochID:         epochID,
+		Genesis:         true,
+		PreEpochLeaders: convert.PkArrayToByteArray(genesisLeaders),
+		RandomBeacon:    posconfig.GetRandomGenesis().Bytes(),
+	}
+}
+
+// Verify checks the certificate against the anchor header, which the caller
+// must already trust, and prepares it for VerifySlotProof. firstEpochID is the
+// first POS epoch of the chain, see posconfig.Config.FirstEpochID. The anchor
+// is not used for the first epochs of POS and may be nil there.
+func (c *LeaderCertificate) Verify(anchor *types.Header, firstEpochID uint64) error {
+	c.verified, c.genesis = false, false
+	if len(c.PreEpochLeaders) != posconfig.EpochLeaderCount {
+		return ErrCertLeaderCount
+	}
+	c.leaders = make([]*ecdsa.PublicKey, len(c.PreEpochLeaders))
+	for i, buf := range c.PreEpochLeaders {
+		if c.leaders[i] = crypto.ToECDSAPub(buf); c.leaders[i] == nil || c.leaders[i].X == nil {
+			return ErrCertInvalidLeader
+		}
+	}
+	if len(c.SlotLeaders) != 0 && len(c.SlotLeaders) != posconfig.SlotCount {
+		return ErrCertSlotLeader
+	}
+
+	// the first epochs always use the genesis leaders, whatever the certificate says
+	genesis := c.EpochID <= firstEpochID+2
+	if !genesis && c.Genesis {
+		// later epochs fall back to them only without any stage two record
+		if anchor == nil || anchor.Hash() != c.AnchorHash || anchor.Number.Uint64() != c.AnchorNumber {
+			return ErrCertAnchorMismatch
+		}
+		switch err := c.verifyStage2(anchor.Root); err {
+		case ErrCertNoStage2:
+		case nil:
+			return ErrCertGenesis
+		default:
+			return err
+		}
+		genesis = true
+	}
+	if genesis {
+		if !bytes.Equal(c.RandomBeacon, posconfig.GetRandomGenesis().Bytes()) {
+			return ErrCertRandomBeacon
+		}
+		if !isGenesisLeaders(c.PreEpochLeaders) {
+			return ErrCertInvalidLeader
+		}
+		c.validIndex, c.alphaPki = genesisStageTwo(c.leaders)
+		c.genesis, c.verified = true, true
+		return nil
+	}
+
+	if anchor == nil || anchor.Hash() != c.AnchorHash || anchor.Number.Uint64() != c.AnchorNumber {
+		return ErrCertAnchorMismatch
+	}
+	if err := c.verifyRandomBeacon(anchor.Root); err != nil {
+		return err
+	}
+	if err := c.verifyStage2(anchor.Root); err != nil {
+		return err
+	}
+	c.verified = true
+	return nil
+}
+
+// VerifySlotProof checks the slot leader proof of slotID in a verified
+// certificate, and the certified slot leader list when it is present.
+func (c *LeaderCertificate) VerifySlotProof(slotID uint64, proof []*big.Int, proofMeg []*ecdsa.PublicKey) error {
+	if !c.verified {
+		return ErrCertNotVerified
+	}
+	if len(c.SlotLeaders) != 0 {
+		if slotID >= uint64(len(c.SlotLeaders)) || !bytes.Equal(c.SlotLeaders[slotID], crypto.FromECDSAPub(proofMeg[0])) {
+			return ErrCertSlotLeader
+		}
+	}
+	skGtEpochID := c.EpochID
+	if c.genesis {
+		skGtEpochID = 0
+	}
+	if !verifySlotProofWithData(skGtEpochID, slotID, proof, proofMeg, c.leaders, c.randomBeacon().Bytes(),
+		c.validIndex, c.alphaPki) {
+		return uleaderselection.ErrInvalidProof
+	}
+	return nil
+}
+
+// randomBeacon returns the random beacon the slot proofs are made with. As in
+// vm.GetR, an epoch without a beacon falls back to the first epoch one.
+func (c *LeaderCertificate) randomBeacon() *big.Int {
+	if len(c.RandomBeacon) == 0 {
+		return new(big.Int).SetBytes(crypto.Keccak256(big.NewInt(1).Bytes()))
+	}
+	return new(big.Int).SetBytes(c.RandomBeacon)
+}
+
+func (c *LeaderCertificate) verifyRandomBeacon(root common.Hash) error {
+	storageRoot, err := proveAccountStorageRoot(root, vm.GetRBAddress(), c.RBAccountProof)
+	if err != nil {
+		return err
+	}
+	value, err := proveStorage(storageRoot, *vm.GetRBRKeyHash(c.EpochID), c.RBStorageProof)
+	if err != nil || !bytes.Equal(value, c.RandomBeacon) {
+		return ErrCertRandomBeacon
+	}
+	return nil
+}
+
+func (c *LeaderCertificate) verifyStage2(root common.Hash) error {
+	if len(c.Stage2) != posconfig.EpochLeaderCount || len(c.Stage2Proofs) != posconfig.EpochLeaderCount {
+		return ErrCertStage2
+	}
+	storageRoot, err := proveAccountStorageRoot(root, vm.GetSlotLeaderSCAddress(), c.SlotAccountProof)
+	if err != nil {
+		return err
+	}
+
+	hasValid := false
+	c.validIndex = make([]bool, posconfig.EpochLeaderCount)
+	c.alphaPki = make([][posconfig.EpochLeaderCount]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+	for i := 0; i < posconfig.EpochLeaderCount; i++ {
+		key := vm.GetSlotLeaderStage2KeyHash(convert.Uint64ToBytes(c.EpochID-1), convert.Uint64ToBytes(uint64(i)))
+		value, err := proveStorage(storageRoot, key, c.Stage2Proofs[i])
+		if err != nil || !bytes.Equal(value, c.Stage2[i]) {
+			return ErrCertStage2
+		}
+		if len(value) == 0 {
+			continue
+		}
+		if len(value) < 4 {
+			return ErrCertStage2
+		}
+
+		epochID, selfIndex, selfPk, alphaPki, _, err := vm.RlpUnpackStage2DataForTx(value)
+		if err != nil || epochID != c.EpochID-1 || selfIndex != uint64(i) || len(alphaPki) != posconfig.EpochLeaderCount {
+			return ErrCertStage2
+		}
+		// a proven stage two record ties the sender to its epoch leader slot, the
+		// decompressed key lives on the btcec curve so only coordinates are compared
+		if selfPk.X.Cmp(c.leaders[i].X) != 0 || selfPk.Y.Cmp(c.leaders[i].Y) != 0 {
+			return ErrCertInvalidLeader
+		}
+		copy(c.alphaPki[i][:], alphaPki)
+		c.validIndex[i] = true
+		hasValid = true
+	}
+	if !hasValid {
+		return ErrCertNoStage2
+	}
+	return nil
+}
+
+// proveAccountStorageRoot verifies the account proof of addr and returns the
+// root of its storage trie.
+func proveAccountStorageRoot(root common.Hash, addr common.Address, proof []rlp.RawValue) (common.Hash, error) {
+	enc, err := trie.VerifyProof(root, crypto.Keccak256(addr.Bytes()), proof)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if enc == nil {
+		return common.Hash{}, errors.New("account does not exist")
+	}
+	var account state.Account
+	if err := rlp.DecodeBytes(enc, &account); err != nil {
+		return common.Hash{}, err
+	}
+	return account.Root, nil
+}
+
+// proveStorage verifies a storage proof and returns the proven value, nil if
+// the proof shows the key is absent. An empty proof is accepted for an empty
+// storage trie only.
+func proveStorage(root common.Hash, key common.Hash, proof []rlp.RawValue) ([]byte, error) {
+	if len(proof) == 0 && root == types.EmptyRootHash {
+		return nil, nil
+	}
+	return trie.VerifyProof(root, crypto.Keccak256(key.Bytes()), proof)
+}
+
+// isGenesisLeaders reports whether leaders are the genesis epoch leaders: a run
+// of the white list, repeated to fill every leader slot (see
+// SLS.GetEpochDefaultLeadersPK).
+func isGenesisLeaders(leaders [][]byte) bool {
+	whiteList := genesisWhiteList()
+	for start := range whiteList {
+		for count := 1; start+count <= len(whiteList); count++ {
+			match := true
+			for i, leader := range leaders {
+				if !bytes.Equal(leader, whiteList[start+i%count]) {
+					match = false
+					break
+				}
+			}
+			if match {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// genesisWhiteList returns the white list the genesis epoch leaders are taken
+// from, encoded as in the certificates.
+func genesisWhiteList() [][]byte {
+	var hexKeys []string
+	if posconfig.SelfTestMode {
+		hexKeys = posconfig.WhiteListOrig[:]
+	} else {
+		hexKeys = posconfig.WhiteList[:]
+	}
+	whiteList := make([][]byte, 0, len(hexKeys))
+	for _, hexKey := range hexKeys {
+		pk := crypto.ToECDSAPub(common.FromHex(hexKey))
+		if pk == nil || pk.X == nil {
+			continue
+		}
+		whiteList = append(whiteList, crypto.FromECDSAPub(pk))
+	}
+	return whiteList
+}
+
+// genesisStageTwo derives the stage two alpha*Pki records of the genesis epoch
+// leaders, alpha being the hash of each leader public key (see SLS.initSma).
+func genesisStageTwo(leaders []*ecdsa.PublicKey) ([]bool, [][posconfig.EpochLeaderCount]*ecdsa.PublicKey) {
+	validIndex := make([]bool, posconfig.EpochLeaderCount)
+	alphaPki := make([][posconfig.EpochLeaderCount]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+	for i := 0; i < posconfig.EpochLeaderCount; i++ {
+		validIndex[i] = true
+		alpha := crypto.Keccak256(crypto.FromECDSAPub(leaders[i]))
+		for j := 0; j < posconfig.EpochLeaderCount; j++ {
+			pk := new(ecdsa.PublicKey)
+			pk.Curve = crypto.S256()
+			pk.X, pk.Y = crypto.S256().ScalarMult(leaders[j].X, leaders[j].Y, alpha)
+			alphaPki[i][j] = pk
+		}
+	}
+	return validIndex, alphaPki
+}
+
+// GetLeaderCertificate builds the leader certificate of epochID from the local
+// chain, following the same rules as VerifySlotProof to decide whether the
+// epoch is checked against the genesis leaders.
+func (s *SLS) GetLeaderCertificate(epochID uint64) (*LeaderCertificate, error) {
+	var cert *LeaderCertificate
+	if epochID <= s.posConfig().FirstEpochID()+2 {
+		cert = NewGenesisLeaderCertificate(epochID, s.epochLeadersPtrArrayGenesis[:])
+	} else {
+		preLeaders, isDefault := s.GetPreEpochLeadersPK(epochID)
+		anchor := s.blockChain.GetHeaderByNumber(util.GetEpochBlock(epochID - 1))
+		if anchor == nil {
+			return nil, errors.New("unknown anchor block")
+		}
+		stateDb, err := s.blockChain.StateAt(anchor.Root)
+		if err != nil {
+			return nil, err
+		}
+		if cert, err = NewLeaderCertificate(stateDb, anchor, epochID, preLeaders); err != nil {
+			return nil, err
+		}
+		if isDefault || !hasStage2(cert.Stage2) {
+			// no stage two record made it on chain, the genesis leaders are used
+			// and the stage two proofs show the verifier why
+			genesis := NewGenesisLeaderCertificate(epochID, s.epochLeadersPtrArrayGenesis[:])
+			cert.Genesis = true
+			cert.PreEpochLeaders = genesis.PreEpochLeaders
+			cert.RandomBeacon = genesis.RandomBeacon
+			cert.RBAccountProof, cert.RBStorageProof = nil, nil
+		}
+	}
+
+	if slotLeaders := s.GetAllSlotLeaders(epochID); len(slotLeaders) == posconfig.SlotCount {
+		cert.SlotLeaders = convert.PkArrayToByteArray(slotLeaders)
+	}
+	return cert, nil
+}
+
+func hasStage2(records [][]byte) bool {
+	for _, record := range records {
+		if len(record) != 0 {
+			return true
+		}
+	}
+	return false
+}
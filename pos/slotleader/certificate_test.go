@@ -0,0 +1,294 @@
+package slotleader
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/wanchain/go-wanchain/common"
+	"github.com/wanchain/go-wanchain/common/hexutil"
+	"github.com/wanchain/go-wanchain/core/state"
+	"github.com/wanchain/go-wanchain/core/types"
+	"github.com/wanchain/go-wanchain/core/vm"
+	"github.com/wanchain/go-wanchain/crypto"
+	"github.com/wanchain/go-wanchain/ethdb"
+	"github.com/wanchain/go-wanchain/pos/posconfig"
+	"github.com/wanchain/go-wanchain/pos/uleaderselection"
+	"github.com/wanchain/go-wanchain/pos/util/convert"
+	"github.com/wanchain/go-wanchain/rlp"
+)
+
+func scalarMult(pk *ecdsa.PublicKey, k []byte) *ecdsa.PublicKey {
+	ret := new(ecdsa.PublicKey)
+	ret.Curve = crypto.S256()
+	ret.X, ret.Y = crypto.S256().ScalarMult(pk.X, pk.Y, k)
+	return ret
+}
+
+func testLeaders(t *testing.T) (*ecdsa.PrivateKey, []*ecdsa.PublicKey) {
+	prvKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaders := make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+	for i := range leaders {
+		leaders[i] = &prvKey.PublicKey
+	}
+	return prvKey, leaders
+}
+
+// testSma returns alpha*G of every leader, alpha being the hash of the leader
+// public key as for the genesis leaders.
+func testSma(leaders []*ecdsa.PublicKey) []*ecdsa.PublicKey {
+	sma := make([]*ecdsa.PublicKey, len(leaders))
+	for i, pk := range leaders {
+		alpha := crypto.Keccak256(crypto.FromECDSAPub(pk))
+		sma[i] = new(ecdsa.PublicKey)
+		sma[i].Curve = crypto.S256()
+		sma[i].X, sma[i].Y = crypto.S256().ScalarBaseMult(alpha)
+	}
+	return sma
+}
+
+// useWhiteList makes pk the only white list entry until the returned function
+// restores the previous white list.
+func useWhiteList(t *testing.T, pk *ecdsa.PublicKey) func() {
+	whiteList, hold, selfTest := posconfig.WhiteList, posconfig.EpochLeadersHold, posconfig.SelfTestMode
+	if err := posconfig.SetWhiteList([]string{hexutil.Encode(crypto.FromECDSAPub(pk))}); err != nil {
+		t.Fatal(err)
+	}
+	posconfig.SelfTestMode = false
+	return func() {
+		posconfig.WhiteList, posconfig.EpochLeadersHold, posconfig.SelfTestMode = whiteList, hold, selfTest
+	}
+}
+
+func TestGenesisLeaderCertificate(t *testing.T) {
+	prvKey, leaders := testLeaders(t)
+	defer useWhiteList(t, &prvKey.PublicKey)()
+	cert := NewGenesisLeaderCertificate(posconfig.FirstEpochId+2, leaders)
+
+	proofMeg, proof, err := uleaderselection.GenerateSlotLeaderProof(prvKey, testSma(leaders), leaders,
+		cert.RandomBeacon, 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.VerifySlotProof(5, proof, proofMeg); err != ErrCertNotVerified {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrCertNotVerified)
+	}
+	if err := cert.Verify(nil, posconfig.FirstEpochId); err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.VerifySlotProof(5, proof, proofMeg); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+	forged := []*big.Int{new(big.Int).Add(proof[0], big.NewInt(1)), proof[1]}
+	if err := cert.VerifySlotProof(5, forged, proofMeg); err == nil {
+		t.Fatal("forged proof accepted")
+	}
+
+	// the genesis flag is not trusted, the epoch decides
+	cert.Genesis = false
+	if err := cert.Verify(nil, posconfig.FirstEpochId); err != nil {
+		t.Fatalf("genesis epoch certificate rejected: %v", err)
+	}
+	later := NewGenesisLeaderCertificate(posconfig.FirstEpochId+3, leaders)
+	if err := later.Verify(nil, posconfig.FirstEpochId); err != ErrCertAnchorMismatch {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrCertAnchorMismatch)
+	}
+
+	cert.RandomBeacon = big.NewInt(1).Bytes()
+	if err := cert.Verify(nil, posconfig.FirstEpochId); err != ErrCertRandomBeacon {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrCertRandomBeacon)
+	}
+}
+
+func TestGenesisLeaderCertificateFirstEpoch(t *testing.T) {
+	prvKey, leaders := testLeaders(t)
+	defer useWhiteList(t, &prvKey.PublicKey)()
+
+	// an offline verifier never ran the chain, so the global is unset
+	defer func(firstEpochId uint64) { posconfig.FirstEpochId = firstEpochId }(posconfig.FirstEpochId)
+	posconfig.FirstEpochId = 0
+
+	firstEpochID := uint64(18000)
+	cert := NewGenesisLeaderCertificate(firstEpochID+1, leaders)
+	if err := cert.Verify(nil, firstEpochID); err != nil {
+		t.Fatalf("first epoch certificate rejected: %v", err)
+	}
+	proofMeg, proof, err := uleaderselection.GenerateSlotLeaderProof(prvKey, testSma(leaders), leaders,
+		cert.RandomBeacon, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.VerifySlotProof(3, proof, proofMeg); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+	if err := cert.Verify(nil, 0); err != ErrCertAnchorMismatch {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrCertAnchorMismatch)
+	}
+}
+
+func TestGenesisLeaderCertificateForeignLeaders(t *testing.T) {
+	whiteKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer useWhiteList(t, &whiteKey.PublicKey)()
+
+	// leaders outside the white list can't make proofs for the genesis epochs
+	prvKey, leaders := testLeaders(t)
+	cert := NewGenesisLeaderCertificate(posconfig.FirstEpochId+2, leaders)
+	if err := cert.Verify(nil, posconfig.FirstEpochId); err != ErrCertInvalidLeader {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrCertInvalidLeader)
+	}
+	proofMeg, proof, err := uleaderselection.GenerateSlotLeaderProof(prvKey, testSma(leaders), leaders,
+		cert.RandomBeacon, 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.VerifySlotProof(5, proof, proofMeg); err != ErrCertNotVerified {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrCertNotVerified)
+	}
+
+	// a single foreign leader is enough to reject the certificate
+	mixed := make([]*ecdsa.PublicKey, len(leaders))
+	for i := range mixed {
+		mixed[i] = &whiteKey.PublicKey
+	}
+	mixed[len(mixed)-1] = leaders[0]
+	if err := NewGenesisLeaderCertificate(posconfig.FirstEpochId+2, mixed).Verify(nil, posconfig.FirstEpochId); err != ErrCertInvalidLeader {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrCertInvalidLeader)
+	}
+}
+
+func TestLeaderCertificate(t *testing.T) {
+	const epochID = 20
+	prvKey, leaders := testLeaders(t)
+	sma := testSma(leaders)
+	rb := crypto.Keccak256([]byte("random beacon"))
+
+	// store the random beacon and every stage two record but the last one
+	db, _ := ethdb.NewMemDatabase()
+	stateDb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	stateDb.SetStateByteArray(vm.GetRBAddress(), *vm.GetRBRKeyHash(epochID), rb)
+	for i := 0; i < posconfig.EpochLeaderCount-1; i++ {
+		alpha := crypto.Keccak256(crypto.FromECDSAPub(leaders[i]))
+		alphaPki := make([]*ecdsa.PublicKey, posconfig.EpochLeaderCount)
+		for j := range alphaPki {
+			alphaPki[j] = scalarMult(leaders[j], alpha)
+		}
+		data, err := vm.RlpPackStage2DataForTx(epochID-1, uint64(i), leaders[i], alphaPki,
+			[]*big.Int{big.NewInt(1), big.NewInt(2)}, vm.GetSlotLeaderScAbiString())
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := vm.GetSlotLeaderStage2KeyHash(convert.Uint64ToBytes(epochID-1), convert.Uint64ToBytes(uint64(i)))
+		stateDb.SetStateByteArray(vm.GetSlotLeaderSCAddress(), key, data)
+	}
+	root, err := stateDb.CommitTo(db, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anchor := &types.Header{Number: big.NewInt(1000), Root: root, Difficulty: big.NewInt(1)}
+
+	cert, err := NewLeaderCertificate(stateDb, anchor, epochID, leaders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the certificate travels rlp encoded
+	enc, err := rlp.EncodeToBytes(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert = new(LeaderCertificate)
+	if err := rlp.DecodeBytes(enc, cert); err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.Verify(anchor, posconfig.FirstEpochId); err != nil {
+		t.Fatal(err)
+	}
+	if cert.validIndex[posconfig.EpochLeaderCount-1] || !cert.validIndex[0] {
+		t.Fatalf("valid stage two records mismatch: %v", cert.validIndex)
+	}
+
+	proofMeg, proof, err := uleaderselection.GenerateSlotLeaderProof(prvKey, sma, leaders, rb, 7, epochID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.VerifySlotProof(7, proof, proofMeg); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+
+	// the certificate is bound to its anchor
+	other := &types.Header{Number: big.NewInt(1000), Root: common.Hash{1}, Difficulty: big.NewInt(1)}
+	if err := cert.Verify(other, posconfig.FirstEpochId); err != ErrCertAnchorMismatch {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrCertAnchorMismatch)
+	}
+
+	// tampered random beacon and stage two records are not proven
+	cert.RandomBeacon = crypto.Keccak256([]byte("forged"))
+	if err := cert.Verify(anchor, posconfig.FirstEpochId); err != ErrCertRandomBeacon {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrCertRandomBeacon)
+	}
+	cert.RandomBeacon = rb
+	cert.Stage2[3] = nil
+	if err := cert.Verify(anchor, posconfig.FirstEpochId); err != ErrCertStage2 {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrCertStage2)
+	}
+	cert.Stage2[3] = cert.Stage2[2]
+
+	// the genesis leaders can't be claimed while stage two records are on chain
+	defer useWhiteList(t, &prvKey.PublicKey)()
+	cert, err = NewLeaderCertificate(stateDb, anchor, epochID, leaders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert.Genesis = true
+	cert.RandomBeacon = posconfig.GetRandomGenesis().Bytes()
+	if err := cert.Verify(anchor, posconfig.FirstEpochId); err != ErrCertGenesis {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrCertGenesis)
+	}
+}
+
+func TestGenesisFallbackLeaderCertificate(t *testing.T) {
+	const epochID = 20
+	prvKey, leaders := testLeaders(t)
+	defer useWhiteList(t, &prvKey.PublicKey)()
+
+	// no stage two record of the previous epoch is on chain
+	db, _ := ethdb.NewMemDatabase()
+	stateDb, _ := state.New(common.Hash{}, state.NewDatabase(db))
+	stateDb.SetStateByteArray(vm.GetRBAddress(), *vm.GetRBRKeyHash(epochID), crypto.Keccak256([]byte("rb")))
+	// only a record of an older epoch
+	oldKey := vm.GetSlotLeaderStage2KeyHash(convert.Uint64ToBytes(epochID-2), convert.Uint64ToBytes(0))
+	stateDb.SetStateByteArray(vm.GetSlotLeaderSCAddress(), oldKey, []byte{1, 2, 3, 4})
+	root, err := stateDb.CommitTo(db, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anchor := &types.Header{Number: big.NewInt(1000), Root: root, Difficulty: big.NewInt(1)}
+
+	cert, err := NewLeaderCertificate(stateDb, anchor, epochID, leaders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert.Genesis = true
+	cert.RandomBeacon = posconfig.GetRandomGenesis().Bytes()
+	if err := cert.Verify(anchor, posconfig.FirstEpochId); err != nil {
+		t.Fatalf("genesis fallback rejected: %v", err)
+	}
+	proofMeg, proof, err := uleaderselection.GenerateSlotLeaderProof(prvKey, testSma(leaders), leaders,
+		cert.RandomBeacon, 9, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.VerifySlotProof(9, proof, proofMeg); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+
+	// the fallback is bound to its anchor
+	if err := cert.Verify(nil, posconfig.FirstEpochId); err != ErrCertAnchorMismatch {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrCertAnchorMismatch)
+	}
+}
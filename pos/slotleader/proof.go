@@ -28,7 +28,7 @@ const (
 //ProofMes 	= [PK, Gt, skGt] 	[]*PublicKey
 //Proof 	= [e,z] 			[]*big.Int
 func (s *SLS) VerifySlotProof(block *types.Block, epochID uint64, slotID uint64, Proof []*big.Int, ProofMeg []*ecdsa.PublicKey) bool {
-	if epochID <= posconfig.FirstEpochId+2 {
+	if epochID <= s.posConfig().FirstEpochID()+2 {
 		return s.verifySlotProofByGenesis(epochID, slotID, Proof, ProofMeg)
 	}
 
@@ -71,6 +71,17 @@ func (s *SLS) VerifySlotProof(block *types.Block, epochID uint64, slotID uint64,
 		return s.verifySlotProofByGenesis(epochID, slotID, Proof, ProofMeg)
 	}
 
+	return verifySlotProofWithData(epochID, slotID, Proof, ProofMeg, epochLeadersPtrPre, rbBytes,
+		validEpochLeadersIndex[:], stageTwoAlphaPKi[:])
+}
+
+// verifySlotProofWithData checks a slot leader proof against the previous epoch
+// leaders, the random beacon and the stage two alpha*Pki records. skGtEpochID is
+// the epoch mixed into skGt, which is 0 for proofs made by the genesis leaders.
+func verifySlotProofWithData(skGtEpochID uint64, slotID uint64, Proof []*big.Int, ProofMeg []*ecdsa.PublicKey,
+	epochLeadersPtrPre []*ecdsa.PublicKey, rbBytes []byte, validEpochLeadersIndex []bool,
+	stageTwoAlphaPKi [][posconfig.EpochLeaderCount]*ecdsa.PublicKey) bool {
+
 	var publicKey *ecdsa.PublicKey
 	publicKey = ProofMeg[0]
 
@@ -87,7 +98,7 @@ func (s *SLS) VerifySlotProof(block *types.Block, epochID uint64, slotID uint64,
 	for _, index := range publicKeyIndexes {
 
 		smaPieces := make([]*ecdsa.PublicKey, 0)
-		for i := 0; i < posconfig.EpochLeaderCount; i++ {
+		for i := 0; i < len(stageTwoAlphaPKi) && i < len(validEpochLeadersIndex); i++ {
 			if validEpochLeadersIndex[i] {
 				smaPieces = append(smaPieces, stageTwoAlphaPKi[i][index])
 			}
@@ -98,17 +109,11 @@ func (s *SLS) VerifySlotProof(block *types.Block, epochID uint64, slotID uint64,
 			return false
 		}
 
-		log.Debug("VerifySlotLeaderProofskGT aphaiPki", "index", index, "epochID", epochID, "slotID", slotID)
-		log.Debug("VerifySlotLeaderProofskGT", "epochID", epochID, "slotID", slotID, "slotLeaderRb", rbBytes[:])
-
-		smaPiecesHexStr := make([]string, 0)
-		for _, value := range smaPieces {
-			smaPiecesHexStr = append(smaPiecesHexStr, hex.EncodeToString(crypto.FromECDSAPub(value)))
-		}
-		log.Debug("VerifySlotLeaderProof", "epochID", epochID, "slotID", slotID, "smaPiecesHexStr", smaPiecesHexStr)
+		log.Debug("VerifySlotLeaderProofskGT aphaiPki", "index", index, "epochID", skGtEpochID, "slotID", slotID)
+		log.Debug("VerifySlotLeaderProofskGT", "epochID", skGtEpochID, "slotID", slotID, "slotLeaderRb", rbBytes[:])
 
 		// get skGT from trans
-		skGt := s.getSkGtFromTrans(epochLeadersPtrPre, epochID, slotID, rbBytes[:], smaPieces[:])
+		skGt := getSkGt(epochLeadersPtrPre, skGtEpochID, slotID, rbBytes[:], smaPieces[:])
 
 		if uleaderselection.PublicKeyEqual(skGt, ProofMeg[2]) {
 			skGtValid = true
@@ -117,10 +122,10 @@ func (s *SLS) VerifySlotProof(block *types.Block, epochID uint64, slotID uint64,
 	}
 
 	if !skGtValid {
-		log.Warn("VerifySlotLeaderProof Fail skGt is not valid", "epochID", epochID, "slotID", slotID)
+		log.Warn("VerifySlotLeaderProof Fail skGt is not valid", "epochID", skGtEpochID, "slotID", slotID)
 		return false
 	}
-	log.Debug("VerifySlotLeaderProof skGt is verified successfully.", "epochID", epochID, "slotID", slotID)
+	log.Debug("VerifySlotLeaderProof skGt is verified successfully.", "epochID", skGtEpochID, "slotID", slotID)
 
 	// verify slot leader proof
 	return uleaderselection.VerifySlotLeaderProof(Proof[:], ProofMeg[:], epochLeadersPtrPre[:], rbBytes[:])
@@ -140,6 +145,12 @@ func (s *SLS) PackSlotProof(epochID uint64, slotID uint64, prvKey *ecdsa.Private
 }
 
 func (s *SLS) GetInfoFromHeadExtra(epochID uint64, input []byte) ([]*big.Int, []*ecdsa.PublicKey, error) {
+	return ParseHeadExtra(epochID, input)
+}
+
+// ParseHeadExtra decodes the slot leader proof packed into a header extra,
+// without the trailing seal.
+func ParseHeadExtra(epochID uint64, input []byte) ([]*big.Int, []*ecdsa.PublicKey, error) {
 	var info Pack
 	err := rlp.DecodeBytes(input, &info)
 	if err != nil {
@@ -193,7 +204,7 @@ func (s *SLS) getSlotLeaderProofByGenesis(PrivateKey *ecdsa.PrivateKey, epochID
 
 func (s *SLS) getSlotLeaderProof(PrivateKey *ecdsa.PrivateKey, epochID uint64,
 	slotID uint64) ([]*ecdsa.PublicKey, []*big.Int, error) {
-	if epochID <= posconfig.FirstEpochId+2 {
+	if epochID <= s.posConfig().FirstEpochID()+2 {
 		return s.getSlotLeaderProofByGenesis(PrivateKey, 0, slotID)
 	}
 	epochLeadersPtrPre, isDefault := s.GetPreEpochLeadersPK(epochID)
@@ -237,53 +248,25 @@ func (s *SLS) getSlotLeaderProof(PrivateKey *ecdsa.PrivateKey, epochID uint64,
 func (s *SLS) verifySlotProofByGenesis(epochID uint64, slotID uint64, Proof []*big.Int,
 	ProofMeg []*ecdsa.PublicKey) bool {
 
-	var publicKey *ecdsa.PublicKey
-	publicKey = ProofMeg[0]
-
-	publicKeyIndexes := make([]int, 0)
-	for index, value := range s.epochLeadersPtrArrayGenesis {
-		if uleaderselection.PublicKeyEqual(publicKey, value) {
-			publicKeyIndexes = append(publicKeyIndexes, index)
-		}
+	validEpochLeadersIndex := make([]bool, posconfig.EpochLeaderCount)
+	for i := range validEpochLeadersIndex {
+		validEpochLeadersIndex[i] = true
 	}
 
-	// Verify skGt
-	var skGtValid bool
-	skGtValid = false
-	for _, index := range publicKeyIndexes {
-
-		smaPieces := make([]*ecdsa.PublicKey, 0)
-		for i := 0; i < posconfig.EpochLeaderCount; i++ {
-			smaPieces = append(smaPieces, s.stageTwoAlphaPKiGenesis[i][index])
-		}
-
-		if len(smaPieces) == 0 {
-			return false
-		}
-
-		log.Debug("verifySlotProofByGenesis", "epochID", epochID, "slotID", slotID, "slotLeaderRb",
-			hex.EncodeToString(s.randomGenesis.Bytes()))
-		log.Debug("verifySlotProofByGenesis aphaiPki", "index", index, "epochID", epochID, "slotID", slotID)
-		eps := s.epochLeadersPtrArrayGenesis
-		skGt := s.getSkGtFromTrans(eps[:], 0, slotID, s.randomGenesis.Bytes()[:],
-			smaPieces[:])
-		if uleaderselection.PublicKeyEqual(skGt, ProofMeg[2]) {
-			skGtValid = true
-			break
-		}
-	}
-	if !skGtValid {
-		log.Warn("verifySlotProofByGenesis Fail skGt is not valid", "epochID", epochID, "slotID", slotID)
-		return false
-	}
-	log.Debug("verifySlotProofByGenesis skGt is verified successfully.", "epochID", epochID, "slotID", slotID)
+	log.Debug("verifySlotProofByGenesis", "epochID", epochID, "slotID", slotID, "slotLeaderRb",
+		hex.EncodeToString(s.randomGenesis.Bytes()))
 	eps := s.epochLeadersPtrArrayGenesis
-	return uleaderselection.VerifySlotLeaderProof(Proof[:], ProofMeg[:], eps[:],
-		s.randomGenesis.Bytes()[:])
+	return verifySlotProofWithData(0, slotID, Proof, ProofMeg, eps[:], s.randomGenesis.Bytes(),
+		validEpochLeadersIndex, s.stageTwoAlphaPKiGenesis[:])
 }
 
 func (s *SLS) getSkGtFromTrans(epochLeadersPtrPre []*ecdsa.PublicKey, epochID uint64, slotID uint64, rbBytes []byte,
 	smaPieces []*ecdsa.PublicKey) (skGtRet *ecdsa.PublicKey) {
+	return getSkGt(epochLeadersPtrPre, epochID, slotID, rbBytes, smaPieces)
+}
+
+func getSkGt(epochLeadersPtrPre []*ecdsa.PublicKey, epochID uint64, slotID uint64, rbBytes []byte,
+	smaPieces []*ecdsa.PublicKey) (skGtRet *ecdsa.PublicKey) {
 
 	var buffer bytes.Buffer
 	buffer.Write(rbBytes[:])
@@ -340,7 +323,7 @@ func (s *SLS) getStageTwoFromTrans(epochID uint64) (validEpochLeadersIndex [posc
 		ckey := crypto.Keccak256Hash(bkey)
 
 		var alphaPki []*ecdsa.PublicKey
-		alphaPkiCached, ok := APkiCache.Get(ckey)
+		alphaPkiCached, ok := s.aPkiCache.Get(ckey)
 		if !ok {
 			var err error
 			statedb, _ := s.getCurrentStateDb()
@@ -350,7 +333,7 @@ func (s *SLS) getStageTwoFromTrans(epochID uint64) (validEpochLeadersIndex [posc
 				validEpochLeadersIndex[i] = false
 				continue
 			}
-			APkiCache.Add(ckey, alphaPki)
+			s.aPkiCache.Add(ckey, alphaPki)
 		} else {
 			alphaPki = alphaPkiCached.([]*ecdsa.PublicKey)
 		}
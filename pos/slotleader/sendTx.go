@@ -1,41 +1,20 @@
 package slotleader
 
 import (
-	"errors"
-	"math/big"
-
-	"github.com/wanchain/go-wanchain/common/hexutil"
-	"github.com/wanchain/go-wanchain/core"
-	"github.com/wanchain/go-wanchain/core/types"
-	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/log"
-	"github.com/wanchain/go-wanchain/rpc"
-)
-
-var (
-	errRCNotReady = errors.New("rc is not ready")
+	"github.com/wanchain/go-wanchain/pos/postx"
 )
 
-type SendTxFn func(rc *rpc.Client, tx map[string]interface{})
+// SendTxFn submits the transaction carrying a slot leader selection message.
+type SendTxFn func(kind postx.Kind, epochID uint64, index uint64, payload []byte) error
 
-func (s *SLS) sendSlotTx(payload []byte, posSender SendTxFn) error {
-	if s.rc == nil {
-		return errRCNotReady
-	}
-
-	to := vm.GetSlotLeaderSCAddress()
-	data := hexutil.Bytes(payload)
-	gas := core.IntrinsicGas(data, &to, true)
-
-	arg := map[string]interface{}{}
-	arg["from"] = s.key.Address
-	arg["to"] = vm.GetSlotLeaderSCAddress()
-	arg["value"] = (*hexutil.Big)(big.NewInt(0))
-	arg["gas"] = (*hexutil.Big)(gas)
-	arg["txType"] = types.POS_TX
-	arg["data"] = data
-	log.Debug("Write data of payload", "length", len(data))
+// SetSubmitter makes s send its transactions through sub instead of the
+// package-level submitter.
+func (s *SLS) SetSubmitter(sub *postx.Submitter) {
+	s.sendTransactionFn = sub.Submit
+}
 
-	go posSender(s.rc, arg)
-	return nil
+func (s *SLS) sendSlotTx(kind postx.Kind, epochID uint64, index uint64, payload []byte, posSender SendTxFn) error {
+	log.Debug("Write data of payload", "kind", kind, "length", len(payload))
+	return posSender(kind, epochID, index, payload)
 }
@@ -1,17 +1,17 @@
 package slotleader
 
 import (
-	"github.com/wanchain/go-wanchain/rpc"
+	"github.com/wanchain/go-wanchain/pos/postx"
 )
 
 
 //func testInit() {
 //	SlsInit()
-//	GetSlotLeaderSelection().Init(nil, &rpc.Client{}, &keystore.Key{})
+//	GetSlotLeaderSelection().Init(nil, &keystore.Key{})
 //}
 
-func testSender(rc *rpc.Client, tx map[string]interface{}) {
-	return
+func testSender(kind postx.Kind, epochID uint64, index uint64, payload []byte) error {
+	return nil
 }
 
 //func TestSendStage1Tx(t *testing.T) {
@@ -26,7 +26,6 @@ import (
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 
 	lru "github.com/hashicorp/golang-lru"
-	"github.com/wanchain/go-wanchain/rpc"
 
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/pos/uleaderselection"
@@ -59,7 +58,6 @@ var (
 type SLS struct {
 	workingEpochID uint64
 	workStage      int
-	rc             *rpc.Client
 	key            *keystore.Key
 	stateDbTest    *state.StateDB
 
@@ -90,6 +88,11 @@ type SLS struct {
 	smaGenesis                  [posconfig.EpochLeaderCount]*ecdsa.PublicKey
 
 	sendTransactionFn SendTxFn
+
+	db        *posdb.Db
+	epocher   *epochLeader.Epocher
+	aPkiCache *lru.ARCCache
+	rndCache  *lru.ARCCache
 }
 
 var slotLeaderSelection *SLS
@@ -125,7 +128,7 @@ func (s *SLS) GetSlotLeader(epochID uint64, slotID uint64) (slotLeader *ecdsa.Pu
 		return nil, vm.ErrSlotIDOutOfRange
 	}
 
-	if epochID <= posconfig.FirstEpochId+2 {
+	if epochID <= s.posConfig().FirstEpochID()+2 {
 		if s.getDefaultSlotLeader(slotID) != nil {
 			log.Info("GetSlotLeader:getDefaultSlotLeader",
 				"epochID", epochID,
@@ -166,7 +169,7 @@ func (s *SLS) getSlotLeader(epochID uint64, slotID uint64) (slotLeader *ecdsa.Pu
 
 	epochIDGet := epochID
 	epochLeadersPtrArray, isDefault := s.GetPreEpochLeadersPK(epochIDGet)
-	if isDefault && epochID > posconfig.FirstEpochId+2 {
+	if isDefault && epochID > s.posConfig().FirstEpochID()+2 {
 		log.Info("generateSlotLeadsGroup use default epochLeader", "epochID", epochID)
 		epochIDGet = 0
 	}
@@ -216,22 +219,58 @@ func (s *SLS) GetSma(epochID uint64) (ret []*ecdsa.PublicKey, isGenesis bool, er
 }
 
 func SlsInit() {
-	var err error
-	APkiCache, err = lru.NewARC(1000)
-	if err != nil || APkiCache == nil {
+	SetSlotLeaderSelection(NewSLS(nil, nil))
+}
+
+// SetSlotLeaderSelection installs sls as the package-level instance returned
+// by GetSlotLeaderSelection.
+func SetSlotLeaderSelection(sls *SLS) {
+	slotLeaderSelection = sls
+	APkiCache = sls.aPkiCache
+	RndCache = sls.rndCache
+}
+
+// NewSLS creates a slot leader selection that keeps its local data in db and
+// reads epoch leaders from epocher. Nil arguments fall back to the
+// package-level POS database and epocher.
+func NewSLS(db *posdb.Db, epocher *epochLeader.Epocher) *SLS {
+	aPkiCache, err := lru.NewARC(1000)
+	if err != nil || aPkiCache == nil {
 		log.SyslogErr("APkiCache failed")
 	}
 
-	RndCache, err = lru.NewARC(10)
-	if err != nil || RndCache == nil {
+	rndCache, err := lru.NewARC(10)
+	if err != nil || rndCache == nil {
 		log.SyslogErr("RndCache failed")
 	}
 
-	slotLeaderSelection = &SLS{}
-	slotLeaderSelection.epochLeadersMap = make(map[string][]uint64)
-	slotLeaderSelection.epochLeadersArray = make([]string, 0)
-	slotLeaderSelection.slotCreateStatus = make(map[uint64]bool)
-	slotLeaderSelection.slotCreateStatusLockCh = make(chan int, 1)
+	sls := &SLS{db: db, epocher: epocher, aPkiCache: aPkiCache, rndCache: rndCache}
+	sls.epochLeadersMap = make(map[string][]uint64)
+	sls.epochLeadersArray = make([]string, 0)
+	sls.slotCreateStatus = make(map[uint64]bool)
+	sls.slotCreateStatusLockCh = make(chan int, 1)
+	return sls
+}
+
+func (s *SLS) getDb() *posdb.Db {
+	if s.db != nil {
+		return s.db
+	}
+	return posdb.GetDb()
+}
+
+func (s *SLS) posConfig() *posconfig.Config {
+	if s.blockChain != nil {
+		return s.blockChain.PosConfig()
+	}
+	return posconfig.Cfg()
+}
+
+func (s *SLS) getEpocher() util.SelectLead {
+	if s.epocher != nil {
+		return s.epocher
+	}
+	return util.GetEpocherInst()
 }
 
 func (s *SLS) getSlotLeaderStage2TxIndexes(epochID uint64) (indexesSentTran []bool, err error) {
@@ -266,7 +305,7 @@ func (s *SLS) getAlpha(epochID uint64, selfIndex uint64) (*big.Int, error) {
 		ret := big.NewInt(123)
 		return ret, nil
 	}
-	buf, err := posdb.GetDb().GetWithIndex(epochID, selfIndex, "alpha")
+	buf, err := s.getDb().GetWithIndex(epochID, selfIndex, "alpha")
 	if err != nil {
 		return nil, err
 	}
@@ -291,7 +330,7 @@ func (s *SLS) getEpochLeaders(epochID uint64) [][]byte {
 	//test := false
 	if posconfig.SelfTestMode {
 		//test: generate test publicKey
-		epochLeaderAllBytes, err := posdb.GetDb().Get(epochID, EpochLeaders)
+		epochLeaderAllBytes, err := s.getDb().Get(epochID, EpochLeaders)
 		if err != nil {
 			return nil
 		}
@@ -312,7 +351,7 @@ func (s *SLS) getEpochLeaders(epochID uint64) [][]byte {
 			GetEpochLeaders(epochID uint64) [][]byte
 		}
 
-		selector := util.GetEpocherInst() //TODO:CHECK INIT
+		selector := s.getEpocher() //TODO:CHECK INIT
 
 		if selector == nil {
 			return nil
@@ -338,7 +377,7 @@ func (s *SLS) getEpochLeadersPK(epochID uint64) []*ecdsa.PublicKey {
 }
 
 func (s *SLS) GetPreEpochLeadersPK(epochID uint64) (pks []*ecdsa.PublicKey, isDefault bool) {
-	if epochID <= posconfig.FirstEpochId+2 {
+	if epochID <= s.posConfig().FirstEpochID()+2 {
 		return s.GetEpochDefaultLeadersPK(0), true
 	}
 
@@ -364,7 +403,10 @@ func (s *SLS) GetEpochDefaultLeadersPK(epochID uint64) []*ecdsa.PublicKey {
 			pks[i] = initPksStr[i%len(initPksStr)]
 		}
 	} else {
-		selector := epochLeader.GetEpocher()
+		selector := s.epocher
+		if selector == nil {
+			selector = epochLeader.GetEpocher()
+		}
 		initPksStr, err := selector.GetWhiteByEpochId(epochID)
 		if err != nil {
 			log.SyslogErr("GetEpochDefaultLeadersPK error", "err", err)
@@ -450,7 +492,7 @@ func (s *SLS) dumpPreEpochLeaders() {
 	log.Debug("\n")
 	currentEpochID := s.getWorkingEpochID()
 	log.Debug("dumpPreEpochLeaders", "currentEpochID", currentEpochID)
-	if currentEpochID == posconfig.FirstEpochId {
+	if currentEpochID == s.posConfig().FirstEpochID() {
 		return
 	}
 
@@ -465,7 +507,7 @@ func (s *SLS) dumpCurrentEpochLeaders() {
 	log.Debug("\n")
 	currentEpochID := s.getWorkingEpochID()
 	log.Debug("dumpCurrentEpochLeaders", "currentEpochID", currentEpochID)
-	if currentEpochID == posconfig.FirstEpochId {
+	if currentEpochID == s.posConfig().FirstEpochID() {
 		return
 	}
 
@@ -480,7 +522,7 @@ func (s *SLS) dumpSlotLeaders() {
 	log.Debug("\n")
 	currentEpochID := s.getWorkingEpochID()
 	log.Debug("dumpSlotLeaders", "currentEpochID", currentEpochID)
-	if currentEpochID == posconfig.FirstEpochId {
+	if currentEpochID == s.posConfig().FirstEpochID() {
 		return
 	}
 
@@ -536,7 +578,7 @@ func (s *SLS) isEpochLeaderMapReady() bool {
 
 func (s *SLS) getRandom(block *types.Block, epochID uint64) (ret *big.Int, err error) {
 
-	rnd, ok := RndCache.Get(epochID)
+	rnd, ok := s.rndCache.Get(epochID)
 	if ok {
 		return rnd.(*big.Int), nil
 	}
@@ -549,7 +591,7 @@ func (s *SLS) getRandom(block *types.Block, epochID uint64) (ret *big.Int, err e
 			log.SyslogErr("SLS.getRandom getStateDb return error, use a default value", "epochID", epochID)
 			rb := posconfig.GetRandomGenesis()
 
-			RndCache.Add(epochID, rb)
+			s.rndCache.Add(epochID, rb)
 
 			return rb, nil
 		}
@@ -561,7 +603,7 @@ func (s *SLS) getRandom(block *types.Block, epochID uint64) (ret *big.Int, err e
 			log.SyslogErr("Update stateDb error in SLS.updateToLastStateDb", "error", err.Error())
 			rb := posconfig.GetRandomGenesis()
 
-			RndCache.Add(epochID, rb)
+			s.rndCache.Add(epochID, rb)
 
 			return rb, nil
 		}
@@ -573,7 +615,7 @@ func (s *SLS) getRandom(block *types.Block, epochID uint64) (ret *big.Int, err e
 		rb = posconfig.GetRandomGenesis()
 	}
 
-	RndCache.Add(epochID, rb)
+	s.rndCache.Add(epochID, rb)
 
 	return rb, nil
 }
@@ -582,13 +624,13 @@ func (s *SLS) getRandom(block *types.Block, epochID uint64) (ret *big.Int, err e
 // It had been +1 when save into db, so do not -1 in get.
 func (s *SLS) getSMAPieces(epochID uint64) (ret []*ecdsa.PublicKey, isGenesis bool, err error) {
 	piecesPtr := make([]*ecdsa.PublicKey, 0)
-	if epochID <= posconfig.FirstEpochId+2 {
+	if epochID <= s.posConfig().FirstEpochID()+2 {
 		return s.smaGenesis[:], true, nil
 	} else {
 		// pieces: alpha[1]*G, alpha[2]*G, .....
-		pieces, err := posdb.GetDb().Get(epochID, SecurityMsg)
+		pieces, err := s.getDb().Get(epochID, SecurityMsg)
 		if err != nil {
-			if epochID > posconfig.FirstEpochId+2 {
+			if epochID > s.posConfig().FirstEpochID()+2 {
 				log.Warn("getSMAPieces error use the first epoch SMA", "epochID", epochID, "SecurityMsg", SecurityMsg)
 			}
 			return s.smaGenesis[:], true, nil
@@ -775,7 +817,7 @@ func (s *SLS) generateSecurityMsg(epochID uint64, PrivateKey *ecdsa.PrivateKey)
 		log.Debug(fmt.Sprintf("epochID+1 = %d set security message is %v\n", epochID+1,
 			hex.EncodeToString(crypto.FromECDSAPub(value))))
 	}
-	_, err = posdb.GetDb().Put(uint64(epochID+1), SecurityMsg, smasBytes.Bytes())
+	_, err = s.getDb().Put(uint64(epochID+1), SecurityMsg, smasBytes.Bytes())
 	if err != nil {
 		log.SyslogCrit("generateSecurityMsg:Put", "epochid", epochID, "error", err.Error())
 		return err
@@ -8,7 +8,6 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/log"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/posdb"
 	"math/big"
 )
 
@@ -64,7 +63,7 @@ func (s *SLS) GetAllSlotLeaders(epochID uint64) (slotLeader []*ecdsa.PublicKey)
 	slotLeadersPtrArray := make([]*ecdsa.PublicKey,0)
 	// read from local db
 	for i := 0; i < posconfig.SlotCount; i++ {
-		pkByte, err := posdb.GetDb().GetWithIndex(epochID, uint64(i), SlotLeader)
+		pkByte, err := s.getDb().GetWithIndex(epochID, uint64(i), SlotLeader)
 		if err != nil {
 			return nil
 		}
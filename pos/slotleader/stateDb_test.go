@@ -11,7 +11,6 @@ import (
 	"github.com/wanchain/go-wanchain/core"
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/ethdb"
-	"github.com/wanchain/go-wanchain/rpc"
 )
 
 var s *SLS
@@ -28,7 +27,7 @@ func testInitSlotleader() {
 	ce := ethash.NewFaker(db)
 	bc, _ := core.NewBlockChain(db, gspec.Config, ce, vm.Config{},nil)
 
-	s.Init(bc, &rpc.Client{}, &keystore.Key{})
+	s.Init(bc, &keystore.Key{})
 
 	s.sendTransactionFn = testSender
 
@@ -10,7 +10,7 @@ import (
 
 	"github.com/wanchain/go-wanchain/core/vm"
 	"github.com/wanchain/go-wanchain/pos/posconfig"
-	"github.com/wanchain/go-wanchain/pos/util"
+	"github.com/wanchain/go-wanchain/pos/postx"
 	"github.com/wanchain/go-wanchain/pos/util/convert"
 
 	"github.com/wanchain/go-wanchain/accounts/keystore"
@@ -18,9 +18,7 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/functrace"
 	"github.com/wanchain/go-wanchain/log"
-	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/pos/uleaderselection"
-	"github.com/wanchain/go-wanchain/rpc"
 )
 
 var (
@@ -44,15 +42,16 @@ func (s *SLS) GenerateDefaultSlotLeaders() error {
 }
 
 // Init use to initial slotleader module and input some params.
-func (s *SLS) Init(blockChain *core.BlockChain, rc *rpc.Client, key *keystore.Key) {
+func (s *SLS) Init(blockChain *core.BlockChain, key *keystore.Key) {
 	s.blockChain = blockChain
-	s.rc = rc
 	s.key = key
 	if blockChain != nil {
 		log.Info("SLS init success")
 	}
 
-	s.sendTransactionFn = util.SendPosTx
+	if s.sendTransactionFn == nil {
+		s.sendTransactionFn = postx.Submit
+	}
 	s.initSma()
 	s.GenerateDefaultSlotLeaders()
 }
@@ -124,8 +123,7 @@ func (s *SLS) initSma() {
 //Loop check work every Slot time. Called by backend loop.
 //It's all slotLeaderSelection's main workflow loop.
 //It does not loop at all, it is loop called by the backend.
-func (s *SLS) Loop(rc *rpc.Client, key *keystore.Key, epochID uint64, slotID uint64) {
-	s.rc = rc
+func (s *SLS) Loop(key *keystore.Key, epochID uint64, slotID uint64) {
 	s.key = key
 
 	log.Info("Now epchoID and slotID:", "epochID", convert.Uint64ToString(epochID), "slotID",
@@ -237,7 +235,7 @@ func (s *SLS) startStage1Work() error {
 				log.Error("generateCommitment error", "error", err.Error())
 				continue
 			}
-			err = s.sendSlotTx(data, s.sendTransactionFn)
+			err = s.sendSlotTx(postx.KindSMA1, workingEpochID, selfPublicKeyIndex[i], data, s.sendTransactionFn)
 			if err != nil {
 				log.Error("sendSlotTx error", "error", err.Error())
 				continue
@@ -275,7 +273,7 @@ func (s *SLS) startStage2Work() error {
 				log.Error("buildStage2TxPayload error", "error", err.Error())
 				continue
 			}
-			err = s.sendSlotTx(data, s.sendTransactionFn)
+			err = s.sendSlotTx(postx.KindSMA2, workingEpochID, selfPublicKeyIndex[i], data, s.sendTransactionFn)
 			if err != nil {
 				log.Error("sendSlotTx error", "error", err.Error())
 				continue
@@ -313,7 +311,7 @@ func (s *SLS) generateCommitment(publicKey *ecdsa.PublicKey,
 	buffer, err := vm.RlpPackStage1DataForTx(epochID, selfIndexInEpochLeader, commitment[1],
 		vm.GetSlotLeaderScAbiString())
 
-	posdb.GetDb().PutWithIndex(epochID, selfIndexInEpochLeader, "alpha", alpha.Bytes())
+	s.getDb().PutWithIndex(epochID, selfIndexInEpochLeader, "alpha", alpha.Bytes())
 
 	log.Debug(fmt.Sprintf("----Put alpha epochID:%d, selfIndex:%d, alpha:%s, mi:%s, pk:%s", epochID,
 		selfIndexInEpochLeader, alpha.String(), hex.EncodeToString(crypto.FromECDSAPub(commitment[1])),
@@ -331,10 +329,10 @@ func (s *SLS) checkNewEpochStart(epochID uint64) {
 }
 
 func (s *SLS) getWorkingEpochID() uint64 {
-	ret, err := posdb.GetDb().Get(0, "slotLeaderCurrentSlotID")
+	ret, err := s.getDb().Get(0, "slotLeaderCurrentSlotID")
 	if err != nil {
 		if err.Error() == "leveldb: not found" {
-			posdb.GetDb().Put(0, "slotLeaderCurrentSlotID", convert.Uint64ToBytes(0))
+			s.getDb().Put(0, "slotLeaderCurrentSlotID", convert.Uint64ToBytes(0))
 			return 0
 		}
 	}
@@ -343,12 +341,12 @@ func (s *SLS) getWorkingEpochID() uint64 {
 }
 
 func (s *SLS) setWorkingEpochID(workingEpochID uint64) error {
-	_, err := posdb.GetDb().Put(0, "slotLeaderCurrentSlotID", convert.Uint64ToBytes(workingEpochID))
+	_, err := s.getDb().Put(0, "slotLeaderCurrentSlotID", convert.Uint64ToBytes(workingEpochID))
 	return err
 }
 
 func (s *SLS) getWorkStage(epochID uint64) int {
-	ret, err := posdb.GetDb().Get(epochID, "slotLeaderWorkStage")
+	ret, err := s.getDb().Get(epochID, "slotLeaderWorkStage")
 	if err != nil {
 		if err.Error() == "leveldb: not found" {
 			s.setWorkStage(epochID, slotLeaderSelectionInit)
@@ -363,6 +361,6 @@ func (s *SLS) getWorkStage(epochID uint64) int {
 
 func (s *SLS) setWorkStage(epochID uint64, workStage int) error {
 	workStageBig := big.NewInt(int64(workStage))
-	_, err := posdb.GetDb().Put(epochID, "slotLeaderWorkStage", workStageBig.Bytes())
+	_, err := s.getDb().Put(epochID, "slotLeaderWorkStage", workStageBig.Bytes())
 	return err
 }
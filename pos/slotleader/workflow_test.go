@@ -20,7 +20,6 @@ import (
 	"github.com/wanchain/go-wanchain/crypto"
 	"github.com/wanchain/go-wanchain/pos/posdb"
 	"github.com/wanchain/go-wanchain/rlp"
-	"github.com/wanchain/go-wanchain/rpc"
 )
 
 var (
@@ -81,11 +80,11 @@ func TestLoop(t *testing.T) {
 	epochIDStart := time.Now().Second()
 
 	for i := 0; i < posconfig.SlotCount; i++ {
-		s.Loop(&rpc.Client{}, key, uint64(epochIDStart+0), uint64(i))
+		s.Loop(key, uint64(epochIDStart+0), uint64(i))
 	}
 
 	for i := 0; i < posconfig.SlotCount; i++ {
-		s.Loop(&rpc.Client{}, key, uint64(epochIDStart+1), uint64(i))
+		s.Loop(key, uint64(epochIDStart+1), uint64(i))
 	}
 	RmDB("test")
 	posconfig.SelfTestMode = false
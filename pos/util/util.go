@@ -163,6 +163,16 @@ func GetEpochBlock(epochID uint64) uint64 {
 	return b
 }
 
+// GetEpochBlockRecord returns the recorded last block of an epoch without
+// falling back to a header scan. ok is false if nothing was recorded.
+func GetEpochBlockRecord(epochID uint64) (blockNumber uint64, hash common.Hash, ok bool) {
+	lbe.Lock()
+	blockNumber, ok = lastBlockEpoch[epochID]
+	hash = lastBlockHashEpoch[epochID]
+	lbe.Unlock()
+	return blockNumber, hash, ok
+}
+
 func GetEpochBlockHash(epochID uint64) common.Hash {
 	lbe.Lock()
 	bh := lastBlockHashEpoch[epochID]
@@ -0,0 +1,162 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+var (
+	ErrNoAuthToken      = errors.New("missing bearer token")
+	ErrInvalidAuthToken = errors.New("invalid bearer token")
+)
+
+// authClaims are the claims of the HMAC signed tokens accepted by a server.
+// Modules scopes the token to some RPC modules, all being allowed when empty.
+type authClaims struct {
+	Modules []string `json:"modules,omitempty"`
+	jwt.StandardClaims
+}
+
+// NewAuthToken issues a token signed with secret granting access to modules,
+// or to every module enabled on the endpoint if none are given. The token
+// never expires if ttl is zero.
+func NewAuthToken(secret []byte, modules []string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := &authClaims{Modules: modules}
+	claims.IssuedAt = now.Unix()
+	if ttl > 0 {
+		claims.ExpiresAt = now.Add(ttl).Unix()
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// SetAuthSecret makes the HTTP and websocket handlers of the server require a
+// bearer token signed with secret. Connections are restricted to the modules
+// the token grants.
+func (s *Server) SetAuthSecret(secret []byte) {
+	s.limitsMu.Lock()
+	s.authSecret = secret
+	s.limitsMu.Unlock()
+}
+
+// authenticate checks the bearer token of an HTTP request, returning the
+// modules it grants. A server without auth secret accepts every request.
+func (s *Server) authenticate(r *http.Request) ([]string, error) {
+	s.limitsMu.RLock()
+	secret := s.authSecret
+	s.limitsMu.RUnlock()
+	if secret == nil {
+		return nil, nil
+	}
+
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, ErrNoAuthToken
+	}
+	claims := new(authClaims)
+	_, err := jwt.ParseWithClaims(strings.TrimPrefix(header, "Bearer "), claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return secret, nil
+	})
+	if err != nil {
+		return nil, ErrInvalidAuthToken
+	}
+	return claims.Modules, nil
+}
+
+// AuthHandler wraps a plain HTTP handler served next to the server, making it
+// require the same bearer token as the server. A token scoped to some modules
+// has to grant module.
+func (s *Server) AuthHandler(module string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		modules, err := s.authenticate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !scopeAllows(withAuthScope(r.Context(), modules), module) {
+			http.Error(w, "token doesn't grant "+module, http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+type authScopeKey struct{}
+
+// withAuthScope records the modules a connection is allowed to call.
+func withAuthScope(ctx context.Context, modules []string) context.Context {
+	if len(modules) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, authScopeKey{}, modules)
+}
+
+// scopeAllows tells whether the connection may call methods of module. The
+// metadata module stays reachable so clients can discover what they may call.
+func scopeAllows(ctx context.Context, module string) bool {
+	modules, ok := ctx.Value(authScopeKey{}).([]string)
+	if !ok || module == MetadataApi {
+		return true
+	}
+	for _, m := range modules {
+		if m == module {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientOption configures how a client connects to a server.
+type ClientOption func(*clientConfig)
+
+type clientConfig struct {
+	header http.Header
+}
+
+func newClientConfig(options []ClientOption) *clientConfig {
+	cfg := &clientConfig{header: make(http.Header)}
+	for _, option := range options {
+		option(cfg)
+	}
+	return cfg
+}
+
+// WithHeader sets an HTTP header sent with the HTTP requests or the websocket
+// handshake of the client.
+func WithHeader(key, value string) ClientOption {
+	return func(cfg *clientConfig) {
+		cfg.header.Set(key, value)
+	}
+}
+
+// WithAuthToken authenticates the client with a bearer token, as issued by
+// NewAuthToken.
+func WithAuthToken(token string) ClientOption {
+	return WithHeader("Authorization", "Bearer "+token)
+}
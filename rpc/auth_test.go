@@ -0,0 +1,177 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testAuthSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func newAuthServer(t *testing.T) *Server {
+	server := newTestServer("test", new(Service))
+	if err := server.RegisterName("other", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	server.SetAuthSecret(testAuthSecret)
+	return server
+}
+
+func newAuthToken(t *testing.T, secret []byte, modules []string, ttl time.Duration) string {
+	token, err := NewAuthToken(secret, modules, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAuthHTTP(t *testing.T) {
+	hs := httptest.NewServer(newAuthServer(t))
+	defer hs.Close()
+
+	body := `{"jsonrpc":"2.0","id":1,"method":"test_rets"}`
+	resp, err := http.Post(hs.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status mismatch without token: have %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	tests := []struct {
+		token string
+		err   string
+	}{
+		{token: newAuthToken(t, testAuthSecret, nil, time.Minute)},
+		{token: newAuthToken(t, []byte("another secret of thirty-two byt"), nil, 0), err: "401"},
+		{token: "not a token", err: "401"},
+	}
+	for i, test := range tests {
+		client, err := DialHTTP(hs.URL, WithAuthToken(test.token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = client.Call(new(string), "test_rets")
+		if test.err == "" && err != nil {
+			t.Errorf("test %d: valid token rejected: %v", i, err)
+		} else if test.err != "" {
+			expectError(t, err, test.err)
+		}
+		client.Close()
+	}
+}
+
+func TestAuthExpiry(t *testing.T) {
+	server := newAuthServer(t)
+	req, _ := http.NewRequest("POST", "http://localhost", nil)
+
+	// tokens without ttl never expire
+	req.Header.Set("Authorization", "Bearer "+newAuthToken(t, testAuthSecret, nil, 0))
+	if _, err := server.authenticate(req); err != nil {
+		t.Fatalf("token without expiry rejected: %v", err)
+	}
+	token := newAuthToken(t, testAuthSecret, nil, time.Second)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if _, err := server.authenticate(req); err != nil {
+		t.Fatalf("fresh token rejected: %v", err)
+	}
+	time.Sleep(2100 * time.Millisecond)
+	if _, err := server.authenticate(req); err != ErrInvalidAuthToken {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrInvalidAuthToken)
+	}
+}
+
+func TestAuthScope(t *testing.T) {
+	for _, transport := range []string{"http", "ws"} {
+		server := newAuthServer(t)
+		var hs *httptest.Server
+		if transport == "ws" {
+			hs = httptest.NewServer(server.WebsocketHandler([]string{"*"}))
+		} else {
+			hs = httptest.NewServer(server)
+		}
+		endpoint := transport + "://" + hs.Listener.Addr().String()
+
+		token := newAuthToken(t, testAuthSecret, []string{"test"}, 0)
+		client, err := DialContext(context.Background(), endpoint, WithAuthToken(token))
+		if err != nil {
+			t.Fatalf("%s: %v", transport, err)
+		}
+		if err := client.Call(new(string), "test_rets"); err != nil {
+			t.Errorf("%s: granted module rejected: %v", transport, err)
+		}
+		expectError(t, client.Call(new(string), "other_rets"), "not allowed")
+		if _, err := client.SupportedModules(); err != nil {
+			t.Errorf("%s: metadata module rejected: %v", transport, err)
+		}
+		client.Close()
+		hs.Close()
+	}
+}
+
+func TestAuthHandler(t *testing.T) {
+	server := newAuthServer(t)
+	handler := server.AuthHandler("graphql", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		token  string
+		status int
+	}{
+		{token: "", status: http.StatusUnauthorized},
+		{token: newAuthToken(t, testAuthSecret, nil, 0), status: http.StatusNoContent},
+		{token: newAuthToken(t, testAuthSecret, []string{"graphql"}, 0), status: http.StatusNoContent},
+		{token: newAuthToken(t, testAuthSecret, []string{"test"}, 0), status: http.StatusForbidden},
+	}
+	for i, test := range tests {
+		req := httptest.NewRequest("POST", "/graphql", nil)
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != test.status {
+			t.Errorf("test %d: status mismatch: have %d, want %d", i, rec.Code, test.status)
+		}
+	}
+}
+
+func TestAuthWebsocketHandshake(t *testing.T) {
+	hs := httptest.NewServer(newAuthServer(t).WebsocketHandler([]string{"*"}))
+	defer hs.Close()
+	endpoint := "ws://" + hs.Listener.Addr().String()
+
+	if _, err := DialWebsocket(context.Background(), endpoint, ""); err == nil {
+		t.Fatal("handshake succeeded without token")
+	}
+	client, err := DialWebsocket(context.Background(), endpoint, "", WithAuthToken(newAuthToken(t, testAuthSecret, nil, 0)))
+	if err != nil {
+		t.Fatalf("handshake with token failed: %v", err)
+	}
+	defer client.Close()
+	if err := client.Call(new(string), "other_rets"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -64,3 +64,39 @@ type shutdownError struct{}
 func (e *shutdownError) ErrorCode() int { return -32000 }
 
 func (e *shutdownError) Error() string { return "server is shutting down" }
+
+// issued when a request calls a method the endpoint limits don't allow.
+type accessDeniedError struct{ method string }
+
+func (e *accessDeniedError) ErrorCode() int { return -32601 }
+
+func (e *accessDeniedError) Error() string {
+	return fmt.Sprintf("The method %s is not allowed on this endpoint", e.method)
+}
+
+// issued when a client exceeds the rate limit of a method.
+type rateLimitError struct{ method string }
+
+func (e *rateLimitError) ErrorCode() int { return -32005 }
+
+func (e *rateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %s", e.method)
+}
+
+// issued when a batch holds more requests than the endpoint allows.
+type batchTooLargeError struct{ limit int }
+
+func (e *batchTooLargeError) ErrorCode() int { return -32600 }
+
+func (e *batchTooLargeError) Error() string {
+	return fmt.Sprintf("batch too large, at most %d requests allowed", e.limit)
+}
+
+// issued when a call runs longer than the endpoint request timeout.
+type requestTimeoutError struct{ method string }
+
+func (e *requestTimeoutError) ErrorCode() int { return -32002 }
+
+func (e *requestTimeoutError) Error() string {
+	return fmt.Sprintf("request %s timed out", e.method)
+}
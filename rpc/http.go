@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -63,11 +64,12 @@ func (hc *httpConn) Close() error {
 }
 
 // DialHTTP creates a new RPC clients that connection to an RPC server over HTTP.
-func DialHTTP(endpoint string) (*Client, error) {
+func DialHTTP(endpoint string, options ...ClientOption) (*Client, error) {
 	req, err := http.NewRequest("POST", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
+	req.Header = newClientConfig(options).header
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 
@@ -122,6 +124,11 @@ func (hc *httpConn) doRequest(ctx context.Context, msg interface{}) (io.ReadClos
 	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// rejected before reaching the RPC server, e.g. by authentication
+		resp.Body.Close()
+		return nil, errors.New(resp.Status)
+	}
 	return resp.Body, nil
 }
 
@@ -139,7 +146,7 @@ func (t *httpReadWriteNopCloser) Close() error {
 // NewHTTPServer creates a new HTTP RPC server around an API provider.
 //
 // Deprecated: Server implements http.Handler
-func NewHTTPServer(cors []string, srv *Server) *http.Server {
+func NewHTTPServer(cors []string, srv http.Handler) *http.Server {
 	return &http.Server{Handler: newCorsHandler(srv, cors)}
 }
 
@@ -151,6 +158,11 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			http.StatusRequestEntityTooLarge)
 		return
 	}
+	modules, err := srv.authenticate(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
 	w.Header().Set("content-type", "application/json")
 
 	// create a codec that reads direct from the request body until
@@ -158,10 +170,11 @@ func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// a single request.
 	codec := NewJSONCodec(&httpReadWriteNopCloser{r.Body, w})
 	defer codec.Close()
-	srv.ServeSingleRequest(codec, OptionMethodInvocation)
+	ctx := withAuthScope(withRemote(context.Background(), r.RemoteAddr), modules)
+	srv.serveRequest(ctx, codec, true, OptionMethodInvocation)
 }
 
-func newCorsHandler(srv *Server, allowedOrigins []string) http.Handler {
+func newCorsHandler(srv http.Handler, allowedOrigins []string) http.Handler {
 	// disable CORS support if user has not specified a custom CORS configuration
 	if len(allowedOrigins) == 0 {
 		return srv
@@ -0,0 +1,187 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxIdleBuckets is the number of rate limit buckets kept before the ones that
+// are full again get dropped.
+const maxIdleBuckets = 4096
+
+// Limits restricts which methods the clients of an endpoint may call and how
+// often. The zero value doesn't restrict anything.
+type Limits struct {
+	// Allow lists the methods clients may call, as "module_method" or "module_*".
+	// All registered methods may be called if the list is empty.
+	Allow []string `toml:",omitempty"`
+
+	// Deny lists the methods clients may not call, in the same format as Allow.
+	// It takes precedence over Allow.
+	Deny []string `toml:",omitempty"`
+
+	// RateLimit is the number of calls per second a client may make to a single
+	// method, clients being told apart by their IP address. Zero disables it.
+	RateLimit float64 `toml:",omitempty"`
+
+	// RateBurst is the number of calls a client may make to a method at once
+	// before RateLimit applies. It defaults to RateLimit rounded up.
+	RateBurst int `toml:",omitempty"`
+
+	// MaxBatchSize is the maximum number of requests in a batch. Zero disables it.
+	MaxBatchSize int `toml:",omitempty"`
+
+	// RequestTimeout is the time a call may run before the client is answered
+	// with an error. Zero disables it.
+	RequestTimeout time.Duration `toml:",omitempty"`
+}
+
+// SetLimits applies limits to the requests served from now on.
+func (s *Server) SetLimits(limits Limits) {
+	l := &limiter{
+		limits:  limits,
+		buckets: make(map[string]*bucket),
+	}
+	if l.limits.RateBurst <= 0 {
+		l.limits.RateBurst = int(math.Ceil(l.limits.RateLimit))
+	}
+	s.limitsMu.Lock()
+	s.limiter = l
+	s.limitsMu.Unlock()
+}
+
+func (s *Server) getLimiter() *limiter {
+	s.limitsMu.RLock()
+	defer s.limitsMu.RUnlock()
+	return s.limiter
+}
+
+// limiter enforces Limits on the requests of a server.
+type limiter struct {
+	limits Limits
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// bucket is a token bucket holding the calls a client may make to a method.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// allowed tells whether method may be called according to the allow and deny
+// lists. A nil limiter allows everything.
+func (l *limiter) allowed(method string) bool {
+	if l == nil {
+		return true
+	}
+	if matchMethod(l.limits.Deny, method) {
+		return false
+	}
+	return len(l.limits.Allow) == 0 || matchMethod(l.limits.Allow, method)
+}
+
+// take consumes a call of method by remote, reporting false if the client
+// exceeded its rate.
+func (l *limiter) take(remote, method string) bool {
+	if l == nil || l.limits.RateLimit <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if len(l.buckets) >= maxIdleBuckets {
+		l.dropFullBuckets(now)
+	}
+	key := remote + " " + method
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.limits.RateBurst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.limits.RateBurst), b.tokens+now.Sub(b.last).Seconds()*l.limits.RateLimit)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// dropFullBuckets forgets the clients whose bucket has refilled, since a new
+// bucket would be identical.
+func (l *limiter) dropFullBuckets(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.limits.RateLimit >= float64(l.limits.RateBurst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// batchAllowed tells whether a batch of size requests may be served.
+func (l *limiter) batchAllowed(size int) bool {
+	return l == nil || l.limits.MaxBatchSize <= 0 || size <= l.limits.MaxBatchSize
+}
+
+// timeout returns the time a call may run, zero if unlimited.
+func (l *limiter) timeout() time.Duration {
+	if l == nil {
+		return 0
+	}
+	return l.limits.RequestTimeout
+}
+
+// matchMethod tells whether method is in the list, "module_*" matching every
+// method of the module.
+func matchMethod(list []string, method string) bool {
+	for _, pattern := range list {
+		if pattern == method {
+			return true
+		}
+		if strings.HasSuffix(pattern, serviceMethodSeparator+"*") &&
+			strings.HasPrefix(method, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+type remoteKey struct{}
+
+// withRemote records the address of the client a connection is served for.
+func withRemote(ctx context.Context, addr string) context.Context {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return context.WithValue(ctx, remoteKey{}, addr)
+}
+
+// remoteFromContext returns the client address recorded by withRemote, empty
+// for connections without one such as IPC.
+func remoteFromContext(ctx context.Context) string {
+	remote, _ := ctx.Value(remoteKey{}).(string)
+	return remote
+}
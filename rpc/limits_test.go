@@ -0,0 +1,150 @@
+// Copyright 2018 Wanchain Foundation Ltd
+//
+// This file is part of the go-wanchain library.
+//
+// The go-wanchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-wanchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-wanchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newLimitedClient(t *testing.T, limits Limits) *Client {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	server.SetLimits(limits)
+	return DialInProc(server)
+}
+
+func expectError(t *testing.T, err error, want string) {
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("error mismatch: have %v, want %q", err, want)
+	}
+}
+
+func TestMatchMethod(t *testing.T) {
+	list := []string{"personal_*", "eth_sign"}
+	tests := map[string]bool{
+		"personal_genRingSignData": true,
+		"eth_sign":                 true,
+		"eth_signTransaction":      false,
+		"personalx_unlock":         false,
+		"wan_getOTABalance":        false,
+	}
+	for method, want := range tests {
+		if have := matchMethod(list, method); have != want {
+			t.Errorf("%s: have %v, want %v", method, have, want)
+		}
+	}
+}
+
+func TestLimitsAccessControl(t *testing.T) {
+	client := newLimitedClient(t, Limits{Allow: []string{"test_*", "rpc_modules"}, Deny: []string{"test_rets"}})
+	defer client.Close()
+
+	var result Result
+	if err := client.Call(&result, "test_echo", "x", 1, &Args{"y"}); err != nil {
+		t.Fatal(err)
+	}
+	expectError(t, client.Call(nil, "test_rets"), "not allowed")
+
+	// the allow list also applies when it doesn't match
+	client = newLimitedClient(t, Limits{Allow: []string{"rpc_modules"}})
+	defer client.Close()
+	expectError(t, client.Call(&result, "test_echo", "x", 1, &Args{"y"}), "not allowed")
+	if _, err := client.SupportedModules(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLimitsRate(t *testing.T) {
+	client := newLimitedClient(t, Limits{RateLimit: 10, RateBurst: 2})
+	defer client.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := client.Call(nil, "test_noArgsRets"); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	expectError(t, client.Call(nil, "test_noArgsRets"), "rate limit exceeded")
+
+	// buckets are kept per method and refill over time
+	if err := client.Call(nil, "test_rets"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if err := client.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLimitsBatchSize(t *testing.T) {
+	client := newLimitedClient(t, Limits{MaxBatchSize: 2})
+	defer client.Close()
+
+	batch := []BatchElem{{Method: "test_rets", Result: new(string)}, {Method: "test_rets", Result: new(string)}}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	for _, elem := range batch {
+		if elem.Error != nil {
+			t.Fatal(elem.Error)
+		}
+	}
+
+	batch = append(batch, BatchElem{Method: "test_rets", Result: new(string)})
+	for i := range batch {
+		batch[i].Error = nil
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	for _, elem := range batch {
+		expectError(t, elem.Error, "batch too large")
+	}
+}
+
+func TestLimitsTimeout(t *testing.T) {
+	client := newLimitedClient(t, Limits{RequestTimeout: 50 * time.Millisecond})
+	defer client.Close()
+
+	if err := client.Call(nil, "test_sleep", 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	expectError(t, client.Call(nil, "test_sleep", 5*time.Second), "timed out")
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("timed out call answered after %v", elapsed)
+	}
+}
+
+func TestLimitsRemote(t *testing.T) {
+	ctx := withRemote(context.Background(), "10.0.0.1:30303")
+	if remote := remoteFromContext(ctx); remote != "10.0.0.1" {
+		t.Fatalf("remote mismatch: have %q", remote)
+	}
+	l := &limiter{limits: Limits{RateLimit: 1, RateBurst: 1}, buckets: make(map[string]*bucket)}
+	if !l.take("10.0.0.1", "test_echo") || l.take("10.0.0.1", "test_echo") {
+		t.Fatal("rate limit not applied")
+	}
+	if !l.take("10.0.0.2", "test_echo") {
+		t.Fatal("rate limit shared between clients")
+	}
+}
@@ -125,7 +125,7 @@ func (s *Server) RegisterName(name string, rcvr interface{}) error {
 // If singleShot is true it will process a single request, otherwise it will handle
 // requests until the codec returns an error when reading a request (in most cases
 // an EOF). It executes requests in parallel when singleShot is false.
-func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecOption) error {
+func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot bool, options CodecOption) error {
 	var pend sync.WaitGroup
 
 	defer func() {
@@ -141,7 +141,7 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 		s.codecsMu.Unlock()
 	}()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	// if the codec supports notification include a notifier that callbacks can use
@@ -215,15 +215,20 @@ func (s *Server) serveRequest(codec ServerCodec, singleShot bool, options CodecO
 // response back using the given codec. It will block until the codec is closed or the server is
 // stopped. In either case the codec is closed.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(context.Background(), codec, options)
+}
+
+// serveCodec is ServeCodec for connections whose context records the client.
+func (s *Server) serveCodec(ctx context.Context, codec ServerCodec, options CodecOption) {
 	defer codec.Close()
-	s.serveRequest(codec, false, options)
+	s.serveRequest(ctx, codec, false, options)
 }
 
 // ServeSingleRequest reads and processes a single RPC request from the given codec. It will not
 // close the codec unless a non-recoverable error has occurred. Note, this method will return after
 // a single request has been processed!
 func (s *Server) ServeSingleRequest(codec ServerCodec, options CodecOption) {
-	s.serveRequest(codec, true, options)
+	s.serveRequest(context.Background(), codec, true, options)
 }
 
 // Stop will stop reading new requests, wait for stopPendingRequestTimeout to allow pending requests to finish,
@@ -277,6 +282,13 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 		return codec.CreateErrorResponse(&req.id, &invalidParamsError{"Expected subscription id as first argument"}), nil
 	}
 
+	if !scopeAllows(ctx, req.svcname) {
+		return codec.CreateErrorResponse(&req.id, &accessDeniedError{req.method}), nil
+	}
+	if !s.getLimiter().take(remoteFromContext(ctx), req.method) {
+		return codec.CreateErrorResponse(&req.id, &rateLimitError{req.method}), nil
+	}
+
 	if req.callb.isSubscribe {
 		subid, err := s.createSubscription(ctx, codec, req)
 		if err != nil {
@@ -309,7 +321,10 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	}
 
 	// execute RPC method and return result
-	reply := req.callb.method.Func.Call(arguments)
+	reply, ok := s.call(ctx, req, arguments)
+	if !ok {
+		return codec.CreateErrorResponse(&req.id, &requestTimeoutError{req.method}), nil
+	}
 	if len(reply) == 0 {
 		return codec.CreateResponse(req.id, nil), nil
 	}
@@ -324,6 +339,32 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	return codec.CreateResponse(req.id, reply[0].Interface()), nil
 }
 
+// call runs the callback of a regular RPC call. When the endpoint has a request
+// timeout the call context is cancelled once it expires, and the request is
+// answered even if the callback ignores the context and keeps running.
+func (s *Server) call(ctx context.Context, req *serverRequest, arguments []reflect.Value) ([]reflect.Value, bool) {
+	timeout := s.getLimiter().timeout()
+	if timeout <= 0 {
+		return req.callb.method.Func.Call(arguments), true
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if req.callb.hasCtx {
+		arguments[1] = reflect.ValueOf(ctx)
+	}
+
+	done := make(chan []reflect.Value, 1)
+	go func() {
+		done <- req.callb.method.Func.Call(arguments)
+	}()
+	select {
+	case reply := <-done:
+		return reply, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
 // exec executes the given request and writes the result back using the codec.
 func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest) {
 	var response interface{}
@@ -349,6 +390,17 @@ func (s *Server) exec(ctx context.Context, codec ServerCodec, req *serverRequest
 // It will only write the response back when the last request is processed.
 func (s *Server) execBatch(ctx context.Context, codec ServerCodec, requests []*serverRequest) {
 	responses := make([]interface{}, len(requests))
+	if l := s.getLimiter(); !l.batchAllowed(len(requests)) {
+		for i, req := range requests {
+			responses[i] = codec.CreateErrorResponse(&req.id, &batchTooLargeError{l.limits.MaxBatchSize})
+		}
+		if err := codec.Write(responses); err != nil {
+			log.Error(fmt.Sprintf("%v\n", err))
+			codec.Close()
+		}
+		return
+	}
+
 	var callbacks []func()
 	for i, req := range requests {
 		if req.err != nil {
@@ -382,6 +434,7 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 	}
 
 	requests := make([]*serverRequest, len(reqs))
+	limiter := s.getLimiter()
 
 	// verify requests
 	for i, r := range reqs {
@@ -411,7 +464,12 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 
 		if r.isPubSub { // eth_subscribe, r.method contains the subscription method name
 			if callb, ok := svc.subscriptions[r.method]; ok {
-				requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
+				method := r.service + subscribeMethodSuffix
+				if !limiter.allowed(method) {
+					requests[i] = &serverRequest{id: r.id, err: &accessDeniedError{method}}
+					continue
+				}
+				requests[i] = &serverRequest{id: r.id, svcname: svc.name, method: method, callb: callb}
 				if r.params != nil && len(callb.argTypes) > 0 {
 					argTypes := []reflect.Type{reflect.TypeOf("")}
 					argTypes = append(argTypes, callb.argTypes...)
@@ -428,7 +486,12 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 		}
 
 		if callb, ok := svc.callbacks[r.method]; ok { // lookup RPC method
-			requests[i] = &serverRequest{id: r.id, svcname: svc.name, callb: callb}
+			method := r.service + serviceMethodSeparator + r.method
+			if !limiter.allowed(method) {
+				requests[i] = &serverRequest{id: r.id, err: &accessDeniedError{method}}
+				continue
+			}
+			requests[i] = &serverRequest{id: r.id, svcname: svc.name, method: method, callb: callb}
 			if r.params != nil && len(callb.argTypes) > 0 {
 				if args, err := codec.ParseRequestArguments(callb.argTypes, r.params); err == nil {
 					requests[i].args = args
@@ -57,6 +57,7 @@ type service struct {
 type serverRequest struct {
 	id            interface{}
 	svcname       string
+	method        string // full method name, as checked against the endpoint limits
 	callb         *callback
 	args          []reflect.Value
 	isUnsubscribe bool
@@ -74,6 +75,10 @@ type Server struct {
 	run      int32
 	codecsMu sync.Mutex
 	codecs   *set.Set
+
+	limitsMu   sync.RWMutex
+	limiter    *limiter
+	authSecret []byte
 }
 
 // rpcRequest represents a raw incoming RPC request
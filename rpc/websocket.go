@@ -37,10 +37,23 @@ import (
 // allowedOrigins should be a comma-separated list of allowed origin URLs.
 // To allow connections with any origin, pass "*".
 func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
+	validateOrigin := wsHandshakeValidator(allowedOrigins)
 	return websocket.Server{
-		Handshake: wsHandshakeValidator(allowedOrigins),
+		Handshake: func(cfg *websocket.Config, req *http.Request) error {
+			if _, err := srv.authenticate(req); err != nil {
+				return err
+			}
+			return validateOrigin(cfg, req)
+		},
 		Handler: func(conn *websocket.Conn) {
-			srv.ServeCodec(NewJSONCodec(conn), OptionMethodInvocation|OptionSubscriptions)
+			// the token was checked during the handshake but may expire since
+			modules, err := srv.authenticate(conn.Request())
+			if err != nil {
+				conn.Close()
+				return
+			}
+			ctx := withAuthScope(withRemote(context.Background(), conn.Request().RemoteAddr), modules)
+			srv.serveCodec(ctx, NewJSONCodec(conn), OptionMethodInvocation|OptionSubscriptions)
 		},
 	}
 }
@@ -95,7 +108,7 @@ func wsHandshakeValidator(allowedOrigins []string) func(*websocket.Config, *http
 //
 // The context is used for the initial connection establishment. It does not
 // affect subsequent interactions with the client.
-func DialWebsocket(ctx context.Context, endpoint, origin string) (*Client, error) {
+func DialWebsocket(ctx context.Context, endpoint, origin string, options ...ClientOption) (*Client, error) {
 	if origin == "" {
 		var err error
 		if origin, err = os.Hostname(); err != nil {
@@ -111,6 +124,7 @@ func DialWebsocket(ctx context.Context, endpoint, origin string) (*Client, error
 	if err != nil {
 		return nil, err
 	}
+	config.Header = newClientConfig(options).header
 
 	return newClient(ctx, func(ctx context.Context) (net.Conn, error) {
 		return wsDialContext(ctx, config)
@@ -30,7 +30,7 @@ func TestBlockchain(t *testing.T) {
 	bt.skipLoad(`^bcForgedTest/bcForkUncle\.json`)
 	bt.skipLoad(`^bcMultiChainTest/(ChainAtoChainB_blockorder|CallContractFromNotBestBlock)`)
 	bt.skipLoad(`^bcTotalDifficultyTest/(lotsOfLeafs|lotsOfBranches|sideChainWithMoreTransactions)`)
-	// Constantinople is not implemented yet.
+	// Constantinople block tests expect the EIP-1234 block reward, which doesn't apply here.
 	bt.skipLoad(`(?i)(constantinople)`)
 
 	// Still failing tests
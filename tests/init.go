@@ -55,6 +55,37 @@ var Forks = map[string]*params.ChainConfig{
 		//DAOForkBlock:   big.NewInt(0),
 		ByzantiumBlock: big.NewInt(0),
 	},
+	"Constantinople": &params.ChainConfig{
+		ChainId:             big.NewInt(1),
+		ByzantiumBlock:      big.NewInt(0),
+		ConstantinopleBlock: big.NewInt(0),
+		PetersburgBlock:     big.NewInt(10000000),
+	},
+	"ConstantinopleFix": &params.ChainConfig{
+		ChainId:             big.NewInt(1),
+		ByzantiumBlock:      big.NewInt(0),
+		ConstantinopleBlock: big.NewInt(0),
+		PetersburgBlock:     big.NewInt(0),
+	},
+	"Istanbul": &params.ChainConfig{
+		ChainId:             big.NewInt(1),
+		ByzantiumBlock:      big.NewInt(0),
+		ConstantinopleBlock: big.NewInt(0),
+		PetersburgBlock:     big.NewInt(0),
+		IstanbulBlock:       big.NewInt(0),
+	},
+	"ByzantiumToConstantinopleAt5": &params.ChainConfig{
+		ChainId:             big.NewInt(1),
+		ByzantiumBlock:      big.NewInt(0),
+		ConstantinopleBlock: big.NewInt(5),
+	},
+	"ConstantinopleFixToIstanbulAt5": &params.ChainConfig{
+		ChainId:             big.NewInt(1),
+		ByzantiumBlock:      big.NewInt(0),
+		ConstantinopleBlock: big.NewInt(0),
+		PetersburgBlock:     big.NewInt(0),
+		IstanbulBlock:       big.NewInt(5),
+	},
 //
 //	"FrontierToHomesteadAt5": &params.ChainConfig{
 //		ChainId:        big.NewInt(1),
@@ -21,6 +21,7 @@ import (
 
 	"github.com/wanchain/go-wanchain/common"
 	"github.com/wanchain/go-wanchain/log"
+	"github.com/wanchain/go-wanchain/rlp"
 )
 
 var secureKeyPrefix = []byte("secure-key-")
@@ -129,6 +130,12 @@ func (t *SecureTrie) TryDelete(key []byte) error {
 	return t.trie.TryDelete(hk)
 }
 
+// Prove constructs a merkle proof for key, see Trie.Prove. The proof is made
+// for the hashed key, as that is what the underlying trie stores.
+func (t *SecureTrie) Prove(key []byte) []rlp.RawValue {
+	return t.trie.Prove(common.CopyBytes(t.hashKey(key)))
+}
+
 // GetKey returns the sha3 preimage of a hashed key that was
 // previously used to store a value.
 func (t *SecureTrie) GetKey(shaKey []byte) []byte {